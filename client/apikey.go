@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	oc "github.com/cordialsys/offchain"
+)
+
+// ApiKeyInfo describes the permissions of the API key used by a client, as reported by the exchange.
+type ApiKeyInfo struct {
+	// The permissions as named by the exchange
+	Permissions []string `json:"permissions"`
+	// The key can only read
+	ReadOnly bool `json:"read_only"`
+	// The key can withdraw funds
+	Withdraw bool `json:"withdraw"`
+	// The key can only be used from the listed IP addresses
+	IpRestricted bool     `json:"ip_restricted"`
+	Ips          []string `json:"ips,omitempty"`
+	// When the key expires, if it does
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// When the key was created, if the exchange reports it
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+// ApiKeyInspector is implemented by clients for exchanges that report the permissions of API keys.
+type ApiKeyInspector interface {
+	GetApiKeyInfo(ctx context.Context) (*ApiKeyInfo, error)
+}
+
+// SubAccountApiKey is a newly created API key of a sub-account.
+type SubAccountApiKey struct {
+	ApiKey    string
+	SecretKey string
+	// Only used by some exchanges
+	Passphrase string
+}
+
+type CreateApiKeyArgs struct {
+	subaccount  oc.AccountId
+	label       string
+	permissions []string
+	readOnly    bool
+	ips         []string
+}
+
+// NewCreateApiKeyArgs creates a key for the sub-account with the same permissions and IP addresses as the existing key.
+func NewCreateApiKeyArgs(subaccount oc.AccountId, label string, like *ApiKeyInfo) CreateApiKeyArgs {
+	return CreateApiKeyArgs{
+		subaccount:  subaccount,
+		label:       label,
+		permissions: like.Permissions,
+		readOnly:    like.ReadOnly,
+		ips:         like.Ips,
+	}
+}
+
+func (args *CreateApiKeyArgs) GetSubAccount() oc.AccountId {
+	return args.subaccount
+}
+func (args *CreateApiKeyArgs) GetLabel() string {
+	return args.label
+}
+
+// GetPermissions returns the permissions as named by GetApiKeyInfo.
+func (args *CreateApiKeyArgs) GetPermissions() []string {
+	return args.permissions
+}
+func (args *CreateApiKeyArgs) IsReadOnly() bool {
+	return args.readOnly
+}
+func (args *CreateApiKeyArgs) GetIps() []string {
+	return args.ips
+}
+
+// ApiKeyManager is implemented by clients for exchanges that let a main account create and revoke the
+// API keys of its sub-accounts.
+type ApiKeyManager interface {
+	// Get the permissions of an API key of the sub-account
+	GetSubAccountApiKeyInfo(ctx context.Context, subaccount oc.AccountId, apiKey string) (*ApiKeyInfo, error)
+	CreateSubAccountApiKey(ctx context.Context, args CreateApiKeyArgs) (*SubAccountApiKey, error)
+	DeleteSubAccountApiKey(ctx context.Context, subaccount oc.AccountId, apiKey string) error
+}
@@ -1,6 +1,8 @@
 package client
 
 import (
+	"context"
+
 	oc "github.com/cordialsys/offchain"
 )
 
@@ -28,6 +30,41 @@ type WithdrawalResponse struct {
 	// any ID returned by the exchange
 	ID     string
 	Status OperationStatus
+	// Set by an offchain server when the withdrawal is waiting for approval
+	IntentId string `json:",omitempty"`
+}
+
+type WithdrawalQuote struct {
+	Symbol  oc.SymbolId  `json:"symbol"`
+	Network oc.NetworkId `json:"network"`
+	// The amount requested to withdraw
+	Amount oc.Amount `json:"amount"`
+	// The network fee the exchange will charge
+	Fee oc.Amount `json:"fee"`
+	// The asset the fee is charged in (normally the same as the symbol)
+	FeeSymbol oc.SymbolId `json:"fee_symbol"`
+	// The amount that will arrive at the destination address
+	NetAmount oc.Amount `json:"net_amount"`
+	// If true, the fee is deducted from the amount.  Otherwise the fee is charged on top of the amount.
+	FeeDeducted bool `json:"fee_deducted"`
+}
+
+// Create a quote given the fee, and whether the exchange deducts it from the withdrawal amount.
+func NewWithdrawalQuote(args WithdrawalArgs, fee oc.Amount, feeSymbol oc.SymbolId, feeDeducted bool) *WithdrawalQuote {
+	amount := args.GetAmount()
+	net := amount
+	if feeDeducted && feeSymbol == args.GetSymbol() {
+		net = oc.Amount(amount.Decimal().Sub(fee.Decimal()))
+	}
+	return &WithdrawalQuote{
+		Symbol:      args.GetSymbol(),
+		Network:     args.GetNetwork(),
+		Amount:      amount,
+		Fee:         fee,
+		FeeSymbol:   feeSymbol,
+		NetAmount:   net,
+		FeeDeducted: feeDeducted,
+	}
 }
 
 type TransferStatus struct {
@@ -47,22 +84,48 @@ type WithdrawalHistory struct {
 	Notes         map[string]string `json:"notes,omitempty"`
 }
 
+type TransferHistory struct {
+	ID       string          `json:"id"`
+	Status   OperationStatus `json:"status"`
+	Symbol   oc.SymbolId     `json:"symbol"`
+	Amount   oc.Amount       `json:"amount"`
+	From     oc.AccountId    `json:"from,omitempty"`
+	To       oc.AccountId    `json:"to,omitempty"`
+	FromType oc.AccountType  `json:"from_type,omitempty"`
+	ToType   oc.AccountType  `json:"to_type,omitempty"`
+	// Unix milliseconds of when the transfer was created
+	Timestamp int64             `json:"timestamp"`
+	Comment   string            `json:"comment,omitempty"`
+	Notes     map[string]string `json:"notes,omitempty"`
+	// Page token to list the transfers after this one
+	PageToken string `json:"page_token,omitempty"`
+}
+
 type Client interface {
 	// List all of the support assets on the exchange
-	ListAssets() ([]*oc.Asset, error)
+	ListAssets(ctx context.Context) ([]*oc.Asset, error)
 
 	// List of all of the balances on an account
-	ListBalances(args GetBalanceArgs) ([]*BalanceDetail, error)
+	ListBalances(ctx context.Context, args GetBalanceArgs) ([]*BalanceDetail, error)
 
 	// Create a transfer between accounts (e.g. main to sub, sub to sub, etc) on the exchange
-	CreateAccountTransfer(args AccountTransferArgs) (*TransferStatus, error)
+	CreateAccountTransfer(ctx context.Context, args AccountTransferArgs) (*TransferStatus, error)
+
+	// Look up the status of a previously created transfer between accounts
+	GetTransferStatus(ctx context.Context, id string) (*TransferStatus, error)
 
 	// Withdraw funds to an external wallet
-	CreateWithdrawal(args WithdrawalArgs) (*WithdrawalResponse, error)
+	CreateWithdrawal(ctx context.Context, args WithdrawalArgs) (*WithdrawalResponse, error)
+
+	// Estimate the fee and net amount of a withdrawal, without executing it
+	EstimateWithdrawal(ctx context.Context, args WithdrawalArgs) (*WithdrawalQuote, error)
 
 	// Get a deposit address for an asset
-	GetDepositAddress(args GetDepositAddressArgs) (oc.Address, error)
+	GetDepositAddress(ctx context.Context, args GetDepositAddressArgs) (oc.Address, error)
 
 	// List paginated withdrawal history on an account in descending order
-	ListWithdrawalHistory(args WithdrawalHistoryArgs) ([]*WithdrawalHistory, error)
+	ListWithdrawalHistory(ctx context.Context, args WithdrawalHistoryArgs) ([]*WithdrawalHistory, error)
+
+	// List paginated internal account transfer history on an account in descending order
+	ListTransferHistory(ctx context.Context, args TransferHistoryArgs) ([]*TransferHistory, error)
 }
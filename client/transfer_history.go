@@ -0,0 +1,137 @@
+package client
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type TransferHistoryArgs struct {
+	limit         int
+	nextPageToken string
+}
+
+func NewTransferHistoryArgs() TransferHistoryArgs {
+	return TransferHistoryArgs{
+		limit:         100,
+		nextPageToken: "",
+	}
+}
+func (args TransferHistoryArgs) WithPageToken(nextPageToken string) TransferHistoryArgs {
+	args.nextPageToken = nextPageToken
+	return args
+}
+
+func (args TransferHistoryArgs) WithLimit(limit int) TransferHistoryArgs {
+	args.limit = limit
+	return args
+}
+
+func (args *TransferHistoryArgs) GetLimit() int {
+	return args.limit
+}
+
+// The page token is the page_token of the last transfer on the previous page.
+func (args *TransferHistoryArgs) GetPageToken() string {
+	return args.nextPageToken
+}
+
+// GetCursor parses the page token, returning nil when the first page is requested.
+func (args *TransferHistoryArgs) GetCursor() (*TransferCursor, error) {
+	if args.nextPageToken == "" {
+		return nil, nil
+	}
+	return ParseTransferCursor(args.nextPageToken)
+}
+
+func (args *TransferHistoryArgs) SetPageToken(nextPageToken string) {
+	args.nextPageToken = nextPageToken
+}
+
+func (args *TransferHistoryArgs) SetLimit(limit int) {
+	args.limit = limit
+}
+
+// TransferCursor is where the next page of transfer history starts.  Several transfers may share a timestamp,
+// so the boundary is inclusive and the transfers at it that were already listed are skipped.
+type TransferCursor struct {
+	// Unix milliseconds; transfers at or before this time are listed
+	Before int64
+	// IDs of the transfers at Before that were already listed
+	Seen []string
+}
+
+// ParseTransferCursor parses `<unix-millis>[:<id>,<id>...]`.
+func ParseTransferCursor(token string) (*TransferCursor, error) {
+	millis, seen, _ := strings.Cut(token, ":")
+	before, err := strconv.ParseInt(millis, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page token %q: %w", token, err)
+	}
+	cursor := &TransferCursor{Before: before}
+	if seen != "" {
+		cursor.Seen = strings.Split(seen, ",")
+	}
+	return cursor, nil
+}
+
+func (c *TransferCursor) String() string {
+	if len(c.Seen) == 0 {
+		return strconv.FormatInt(c.Before, 10)
+	}
+	return fmt.Sprintf("%d:%s", c.Before, strings.Join(c.Seen, ","))
+}
+
+// SeenCount is the number of transfers that are skipped at the start of the page.
+func (c *TransferCursor) SeenCount() int {
+	if c == nil {
+		return 0
+	}
+	return len(c.Seen)
+}
+
+// Includes returns true if the transfer belongs on the page that starts at the cursor.
+func (c *TransferCursor) Includes(transfer *TransferHistory) bool {
+	if c == nil {
+		return true
+	}
+	if transfer.Timestamp == c.Before {
+		return !slices.Contains(c.Seen, transfer.ID)
+	}
+	return transfer.Timestamp < c.Before
+}
+
+// PageTransferHistory sorts the transfers newest first, drops those that are not on the page starting at the
+// cursor, truncates it to the limit and sets the page token of each transfer.
+func PageTransferHistory(history []*TransferHistory, cursor *TransferCursor, limit int) []*TransferHistory {
+	page := []*TransferHistory{}
+	seen := map[string]bool{}
+	for _, transfer := range history {
+		if cursor.Includes(transfer) && !seen[transfer.ID] {
+			seen[transfer.ID] = true
+			page = append(page, transfer)
+		}
+	}
+	sort.SliceStable(page, func(i, j int) bool {
+		return page[i].Timestamp > page[j].Timestamp
+	})
+	if limit > 0 && len(page) > limit {
+		page = page[:limit]
+	}
+
+	next := &TransferCursor{}
+	if cursor != nil {
+		next.Before = cursor.Before
+		next.Seen = slices.Clone(cursor.Seen)
+	}
+	for _, transfer := range page {
+		if transfer.Timestamp != next.Before {
+			next = &TransferCursor{Before: transfer.Timestamp}
+		}
+		next.Seen = append(next.Seen, transfer.ID)
+		transfer.PageToken = next.String()
+	}
+	return page
+}
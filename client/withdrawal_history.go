@@ -1,8 +1,13 @@
 package client
 
+import "time"
+
 type WithdrawalHistoryArgs struct {
 	limit         int
 	nextPageToken string
+	// Only the withdrawal with this id, on exchanges that can look it up
+	id        string
+	startTime *time.Time
 }
 
 func NewWithdrawalHistoryArgs() WithdrawalHistoryArgs {
@@ -36,3 +41,23 @@ func (args *WithdrawalHistoryArgs) SetPageToken(nextPageToken string) {
 func (args *WithdrawalHistoryArgs) SetLimit(limit int) {
 	args.limit = limit
 }
+
+// WithId looks up a single withdrawal.  Exchanges that can't filter by id return the other withdrawals too.
+func (args WithdrawalHistoryArgs) WithId(id string) WithdrawalHistoryArgs {
+	args.id = id
+	return args
+}
+
+func (args *WithdrawalHistoryArgs) GetId() string {
+	return args.id
+}
+
+// WithStartTime only lists the withdrawals made since the time.
+func (args WithdrawalHistoryArgs) WithStartTime(startTime time.Time) WithdrawalHistoryArgs {
+	args.startTime = &startTime
+	return args
+}
+
+func (args *WithdrawalHistoryArgs) GetStartTime() *time.Time {
+	return args.startTime
+}
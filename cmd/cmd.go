@@ -3,6 +3,7 @@ package cmd
 import (
 	"log/slog"
 
+	"github.com/cordialsys/offchain/pkg/logging"
 	"github.com/spf13/cobra"
 )
 
@@ -16,12 +17,12 @@ func SetVerbosityFromCmd(cmd *cobra.Command) {
 func SetVerbosity(verbose int) {
 	switch verbose {
 	case 0:
-		slog.SetLogLoggerLevel(slog.LevelWarn)
+		logging.Level.Set(slog.LevelWarn)
 	case 1:
-		slog.SetLogLoggerLevel(slog.LevelInfo)
+		logging.Level.Set(slog.LevelInfo)
 	case 2:
-		slog.SetLogLoggerLevel(slog.LevelDebug)
+		logging.Level.Set(slog.LevelDebug)
 	default:
-		slog.SetLogLoggerLevel(slog.LevelDebug)
+		logging.Level.Set(slog.LevelDebug)
 	}
 }
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	oc "github.com/cordialsys/offchain"
+	"github.com/cordialsys/offchain/pkg/audit"
+	"github.com/cordialsys/offchain/server"
+	"github.com/spf13/cobra"
+)
+
+func NewAuditCmd() *cobra.Command {
+	var configPath string
+	cmd := &cobra.Command{
+		SilenceUsage: true,
+		Use:          "audit",
+		Short:        "Inspect the audit log of requests made to the server",
+	}
+	cmd.PersistentFlags().StringVarP(
+		&configPath,
+		"config",
+		"c",
+		"",
+		fmt.Sprintf("path to the config file, used to find the audit log (may set %s)", oc.ENV_OFFCHAIN_CONFIG),
+	)
+	cmd.AddCommand(NewAuditVerifyCmd(&configPath))
+	cmd.AddCommand(NewAuditQueryCmd(&configPath))
+	return cmd
+}
+
+// openAuditLog opens the log given as an argument, or the one in the server configuration.
+func openAuditLog(configPath string, args []string) (*os.File, error) {
+	path := ""
+	if len(args) > 0 {
+		path = args[0]
+	} else {
+		serverConfig, err := server.LoadConfig(configPath)
+		if err != nil {
+			return nil, err
+		}
+		path = serverConfig.Audit.File
+		if path == "" {
+			return nil, fmt.Errorf("no audit log file configured, pass the path to the audit log")
+		}
+	}
+	return os.Open(path)
+}
+
+func NewAuditVerifyCmd(configPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		SilenceUsage: true,
+		Use:          "verify [audit-log]",
+		Short:        "Check that the audit log has not been modified",
+		Args:         cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := openAuditLog(*configPath, args)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			last, err := audit.Verify(f)
+			if err != nil {
+				return fmt.Errorf("audit log is invalid: %w", err)
+			}
+			if last == nil {
+				fmt.Println("audit log is empty")
+				return nil
+			}
+			fmt.Printf("audit log is intact: %d entries, last hash %s\n", last.Seq+1, last.Hash)
+			return nil
+		},
+	}
+	return cmd
+}
+
+// parseTime accepts either an RFC3339 time, or a duration relative to now (e.g. "24h").
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected RFC3339 or a duration", value)
+}
+
+func NewAuditQueryCmd(configPath *string) *cobra.Command {
+	var exchange string
+	var keyId string
+	var since string
+	var until string
+	cmd := &cobra.Command{
+		SilenceUsage: true,
+		Use:          "query [audit-log]",
+		Short:        "Print entries of the audit log",
+		Args:         cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filter := audit.Filter{
+				Exchange: exchange,
+				KeyId:    keyId,
+			}
+			var err error
+			if filter.Since, err = parseTime(since); err != nil {
+				return err
+			}
+			if filter.Until, err = parseTime(until); err != nil {
+				return err
+			}
+
+			f, err := openAuditLog(*configPath, args)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			entries, err := audit.Query(f, filter)
+			if err != nil {
+				return err
+			}
+			printJson(entries)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&exchange, "exchange", "", "Only show requests to this exchange")
+	cmd.Flags().StringVar(&keyId, "key", "", "Only show requests authenticated by this key or token id")
+	cmd.Flags().StringVar(&since, "since", "", "Only show requests at or after this time (RFC3339, or a duration like 24h)")
+	cmd.Flags().StringVar(&until, "until", "", "Only show requests before this time (RFC3339, or a duration like 1h)")
+	return cmd
+}
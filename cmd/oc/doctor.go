@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	oc "github.com/cordialsys/offchain"
+	"github.com/cordialsys/offchain/loader"
+	"github.com/cordialsys/offchain/server/health"
+	"github.com/spf13/cobra"
+)
+
+func NewDoctorCmd() *cobra.Command {
+	var configPath string
+	var exchanges []string
+	var jsonOutput bool
+	cmd := &cobra.Command{
+		SilenceUsage: true,
+		Use:          "doctor",
+		Short:        "Check that the secrets and API keys of each configured account work",
+		Long: `Loads the secrets of each configured account, and makes an authenticated request to the exchange.
+Where the exchange reports them, the permissions of each API key are checked too.  These are the same
+checks reported by the server's /health/exchanges endpoint.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := loader.LoadValidatedConfig(configPath)
+			if err != nil {
+				return err
+			}
+			if len(exchanges) > 0 {
+				filtered := &oc.Config{Exchanges: map[oc.ExchangeId]*oc.ExchangeConfig{}}
+				for _, id := range exchanges {
+					exchangeCfg, ok := config.GetExchange(oc.ExchangeId(id))
+					if !ok {
+						return fmt.Errorf("exchange %s is not configured", id)
+					}
+					filtered.Exchanges[exchangeCfg.ExchangeId] = exchangeCfg
+				}
+				config = filtered
+			}
+
+			report := health.Check(cmd.Context(), config)
+			if jsonOutput {
+				printJson(report)
+			} else {
+				for _, account := range report.Accounts {
+					fmt.Printf("%-8s %s/%s (%dms)\n", account.Status, account.Exchange, account.Account, account.LatencyMs)
+					if account.Error != "" {
+						fmt.Printf("         error: %s\n", account.Error)
+					}
+					if account.ApiKey != nil {
+						fmt.Printf("         permissions: %s\n", strings.Join(account.ApiKey.Permissions, ", "))
+						if created := account.ApiKey.CreatedAt; created != nil {
+							fmt.Printf("         created: %s (%d days ago)\n", created.Format(time.DateOnly), int(time.Since(*created).Hours()/24))
+						}
+					}
+					for _, warning := range account.Warnings {
+						fmt.Printf("         warning: %s\n", warning)
+					}
+				}
+			}
+			if !report.Ready() {
+				return fmt.Errorf("some accounts are not usable")
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(
+		&configPath,
+		"config",
+		"c",
+		"",
+		fmt.Sprintf("path to the config file (may set %s)", oc.ENV_OFFCHAIN_CONFIG),
+	)
+	cmd.Flags().StringSliceVar(&exchanges, "exchange", []string{}, "only check these exchanges")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "print the report as JSON")
+	return cmd
+}
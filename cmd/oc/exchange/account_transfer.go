@@ -1,6 +1,7 @@
 package exchange
 
 import (
+	"context"
 	"fmt"
 	"slices"
 	"strings"
@@ -11,8 +12,8 @@ import (
 	"github.com/spf13/cobra"
 )
 
-func resolveAccountType(cli loader.Client, typeOrAlias string) (*oc.AccountTypeConfig, bool, error) {
-	accountTypes, err := cli.ListAccountTypes()
+func resolveAccountType(ctx context.Context, cli loader.Client, typeOrAlias string) (*oc.AccountTypeConfig, bool, error) {
+	accountTypes, err := cli.ListAccountTypes(ctx)
 	options := []string{}
 	if err != nil {
 		return nil, false, fmt.Errorf("failed to list account types: %s", err)
@@ -30,8 +31,8 @@ func resolveAccountType(cli loader.Client, typeOrAlias string) (*oc.AccountTypeC
 	return nil, false, fmt.Errorf("account type or alias %s not found; options: %s", typeOrAlias, strings.Join(options, ", "))
 }
 
-func resolveFirstAccountType(cli loader.Client) (*oc.AccountTypeConfig, bool, error) {
-	accountTypes, err := cli.ListAccountTypes()
+func resolveFirstAccountType(ctx context.Context, cli loader.Client) (*oc.AccountTypeConfig, bool, error) {
+	accountTypes, err := cli.ListAccountTypes(ctx)
 	if err != nil {
 		return nil, false, fmt.Errorf("failed to list account types: %s", err)
 	}
@@ -41,8 +42,8 @@ func resolveFirstAccountType(cli loader.Client) (*oc.AccountTypeConfig, bool, er
 	return accountTypes[0], true, nil
 }
 
-func resolveSubAccount(cli loader.Client, idOrAlias string) (accountCfg *oc.SubAccountHeader, err error) {
-	subaccounts, err := cli.ListSubaccounts()
+func resolveSubAccount(ctx context.Context, cli loader.Client, idOrAlias string) (accountCfg *oc.SubAccountHeader, err error) {
+	subaccounts, err := cli.ListSubaccounts(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list subaccounts: %s", err)
 	}
@@ -67,7 +68,8 @@ func NewAccountTransferCmd() *cobra.Command {
 		Use:          "transfer",
 		Short:        "Transfer funds between accounts on exchange",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			cli := unwrapClient(cmd.Context())
+			ctx := cmd.Context()
+			cli := unwrapClient(ctx)
 			amount, err := oc.NewAmountFromString(amountS)
 			if err != nil {
 				return err
@@ -85,7 +87,7 @@ func NewAccountTransferCmd() *cobra.Command {
 
 			if fromType != "" {
 
-				accountTypes, err := cli.ListAccountTypes()
+				accountTypes, err := cli.ListAccountTypes(ctx)
 				if err != nil {
 					return fmt.Errorf("failed to list account types: %s", err)
 				}
@@ -94,14 +96,14 @@ func NewAccountTransferCmd() *cobra.Command {
 					}
 				}
 
-				resolvedFromType, ok, message := resolveAccountType(cli, fromType)
+				resolvedFromType, ok, message := resolveAccountType(ctx, cli, fromType)
 				if !ok {
 					return fmt.Errorf("%s", message)
 				}
 				transferArgs.SetFromType(resolvedFromType.Type)
 			} else {
 				// use the first account type by default
-				first, ok, err := resolveFirstAccountType(cli)
+				first, ok, err := resolveFirstAccountType(ctx, cli)
 				if err != nil {
 					return err
 				}
@@ -110,14 +112,14 @@ func NewAccountTransferCmd() *cobra.Command {
 				}
 			}
 			if toType != "" {
-				resolvedToType, ok, message := resolveAccountType(cli, toType)
+				resolvedToType, ok, message := resolveAccountType(ctx, cli, toType)
 				if !ok {
 					return fmt.Errorf("%s", message)
 				}
 				transferArgs.SetToType(resolvedToType.Type)
 			} else {
 				// use the first account type by default
-				first, ok, err := resolveFirstAccountType(cli)
+				first, ok, err := resolveFirstAccountType(ctx, cli)
 				if err != nil {
 					return err
 				}
@@ -127,14 +129,14 @@ func NewAccountTransferCmd() *cobra.Command {
 			}
 
 			if from != "" {
-				fromSubaccount, err := resolveSubAccount(cli, from)
+				fromSubaccount, err := resolveSubAccount(ctx, cli, from)
 				if err != nil {
 					return err
 				}
 				transferArgs.SetFrom(fromSubaccount.Id)
 			}
 			if to != "" {
-				toSubaccount, err := resolveSubAccount(cli, to)
+				toSubaccount, err := resolveSubAccount(ctx, cli, to)
 				if err != nil {
 					return err
 				}
@@ -145,7 +147,7 @@ func NewAccountTransferCmd() *cobra.Command {
 				return fmt.Errorf("must specify at least one of --to, --from, --from-type, --to-type")
 			}
 
-			resp, err := cli.CreateAccountTransfer(transferArgs)
+			resp, err := cli.CreateAccountTransfer(ctx, transferArgs)
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,189 @@
+package exchange
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/cordialsys/offchain/cmd"
+	"github.com/cordialsys/offchain/pkg/httpsignature/signer"
+	"github.com/cordialsys/offchain/pkg/intent"
+	"github.com/cordialsys/offchain/server/client"
+	"github.com/cordialsys/offchain/server/client/api"
+	"github.com/spf13/cobra"
+)
+
+const serverClientKey contextKey = "server_client"
+
+func unwrapServerClient(ctx context.Context) *client.Client {
+	return ctx.Value(serverClientKey).(*client.Client)
+}
+
+// intentFromApi rebuilds the fields of the intent that are covered by its digest.
+func intentFromApi(i *api.WithdrawalIntent) *intent.Intent {
+	return &intent.Intent{
+		Id: i.Id,
+		Withdrawal: intent.Withdrawal{
+			Exchange:   i.Exchange,
+			SubAccount: api.DerefOrZero(i.SubAccount),
+			Address:    i.Withdrawal.Address,
+			Symbol:     api.DerefOrZero(i.Withdrawal.Symbol),
+			Network:    api.DerefOrZero(i.Withdrawal.Network),
+			Amount:     i.Withdrawal.Amount,
+		},
+		ExpiresAt: time.UnixMilli(i.ExpiresAt),
+	}
+}
+
+// checkDigest recomputes the digest of the intent, rather than trusting the one reported by the server.
+func checkDigest(i *api.WithdrawalIntent) ([]byte, error) {
+	digest := intentFromApi(i).Digest()
+	reported, err := hex.DecodeString(i.Digest)
+	if err != nil || !bytes.Equal(digest, reported) {
+		return nil, fmt.Errorf("the digest reported by the server (%s) does not match intent %s (%s)", i.Digest, i.Id, hex.EncodeToString(digest))
+	}
+	return digest, nil
+}
+
+// serverClientPreRun makes a client for the offchain server available to the subcommands.
+func serverClientPreRun(preCmd *cobra.Command, args []string) error {
+	cmd.SetVerbosityFromCmd(preCmd)
+	cli, err := newServerClient(preCmd)
+	if err != nil {
+		return err
+	}
+	preCmd.SetContext(context.WithValue(preCmd.Context(), serverClientKey, cli))
+	return nil
+}
+
+func NewApprovalsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "approvals",
+		Short:             "Approve or reject withdrawals that are waiting for approval on an offchain server",
+		SilenceUsage:      true,
+		PersistentPreRunE: serverClientPreRun,
+	}
+	cmd.AddCommand(newListIntentsCmd("List the withdrawal intents, newest first", api.IntentPendingApproval))
+	cmd.AddCommand(NewShowApprovalCmd())
+	cmd.AddCommand(NewDecideApprovalCmd(intent.Approve))
+	cmd.AddCommand(NewDecideApprovalCmd(intent.Reject))
+	addServerClientFlags(cmd)
+	return cmd
+}
+
+func newListIntentsCmd(short string, defaultStatus api.WithdrawalIntentStatus) *cobra.Command {
+	var status string
+	cmd := &cobra.Command{
+		SilenceUsage: true,
+		Use:          "list",
+		Short:        short,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			intents, err := unwrapServerClient(cmd.Context()).ListWithdrawalIntents(cmd.Context())
+			if err != nil {
+				return err
+			}
+			filtered := []*api.WithdrawalIntent{}
+			for _, i := range intents {
+				if status == "" || string(i.Status) == status {
+					filtered = append(filtered, i)
+				}
+			}
+			printJson(filtered)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&status, "status", string(defaultStatus), "Only list intents with this status (all if empty)")
+	return cmd
+}
+
+func NewShowApprovalCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		SilenceUsage: true,
+		Use:          "show <intent-id>",
+		Short:        "Show a withdrawal intent, and the canonical form that approvers sign the digest of",
+		Args:         cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			i, err := unwrapServerClient(cmd.Context()).GetWithdrawalIntent(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			if _, err := checkDigest(i); err != nil {
+				return err
+			}
+			printJson(i)
+			fmt.Print(intentFromApi(i).Canonical())
+			return nil
+		},
+	}
+	return cmd
+}
+
+func NewDecideApprovalCmd(decision intent.Decision) *cobra.Command {
+	var approveWith string
+	var approverId string
+	cmd := &cobra.Command{
+		SilenceUsage: true,
+		Use:          fmt.Sprintf("%s <intent-id>", decision),
+		Short:        fmt.Sprintf("Sign and submit an approver's decision to %s a withdrawal intent", decision),
+		Args:         cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			cli := unwrapServerClient(ctx)
+			i, err := cli.GetWithdrawalIntent(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			digest, err := checkDigest(i)
+			if err != nil {
+				return err
+			}
+
+			if approveWith == "" {
+				approveWith = cmd.Flag("sign-with").Value.String()
+			}
+			if approveWith == "" {
+				return fmt.Errorf("--approve-with or --sign-with is required")
+			}
+			kr, err := loadKeyring(cmd)
+			if err != nil {
+				return err
+			}
+			approver, err := loadSigner(ctx, kr, approveWith, approverId)
+			if err != nil {
+				return err
+			}
+			keyed, ok := approver.(signer.KeyedSigner)
+			if !ok {
+				return fmt.Errorf("--approver-id is required to approve with %s", approveWith)
+			}
+			signature, err := approver.Sign(intent.DecisionMessage(decision, digest))
+			if err != nil {
+				return fmt.Errorf("could not sign: %w", err)
+			}
+			slog.Info("signed withdrawal intent", "intent", i.Id, "decision", decision, "approver", keyed.KeyId(),
+				"exchange", i.Exchange, "address", i.Withdrawal.Address, "amount", i.Withdrawal.Amount,
+				"symbol", api.DerefOrZero(i.Withdrawal.Symbol), "network", api.DerefOrZero(i.Withdrawal.Network))
+
+			request := &api.IntentDecision{
+				KeyId:     keyed.KeyId(),
+				Signature: hex.EncodeToString(signature),
+			}
+			if decision == intent.Approve {
+				i, err = cli.ApproveWithdrawalIntent(ctx, i.Id, request)
+			} else {
+				i, err = cli.RejectWithdrawalIntent(ctx, i.Id, request)
+			}
+			if err != nil {
+				return err
+			}
+			printJson(i)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&approveWith, "approve-with", "", "The approver's key to sign the intent with, if not the key that signs requests (see --sign-with)")
+	cmd.Flags().StringVar(&approverId, "approver-id", "", "The id of the approver in the server's approvals (defaults to the name of the key in the keyring)")
+	return cmd
+}
@@ -2,6 +2,7 @@ package exchange
 
 import (
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log/slog"
@@ -13,8 +14,10 @@ import (
 	"github.com/cordialsys/offchain/exchanges/offchain"
 	"github.com/cordialsys/offchain/loader"
 	"github.com/cordialsys/offchain/pkg/httpsignature/signer"
+	"github.com/cordialsys/offchain/pkg/httpsignature/verifier"
 	"github.com/cordialsys/offchain/pkg/keyring"
 	"github.com/cordialsys/offchain/pkg/secret"
+	"github.com/cordialsys/offchain/pkg/tlsconfig"
 	"github.com/cordialsys/offchain/server/client"
 	"github.com/spf13/cobra"
 )
@@ -43,9 +46,11 @@ func addExchangeCommands(cmd *cobra.Command) {
 	cmd.AddCommand(NewGetAssetsCmd())
 	cmd.AddCommand(NewListBalancesCmd())
 	cmd.AddCommand(NewAccountTransferCmd())
+	cmd.AddCommand(NewGetTransferStatusCmd())
 	cmd.AddCommand(NewWithdrawCmd())
 	cmd.AddCommand(NewGetDepositAddressCmd())
 	cmd.AddCommand(NewListWithdrawalHistoryCmd())
+	cmd.AddCommand(NewListTransferHistoryCmd())
 	cmd.AddCommand(NewListSubaccountsCmd())
 	cmd.AddCommand(NewListAccountTypesCmd())
 }
@@ -89,13 +94,13 @@ func exchangePreRun(preCmd *cobra.Command, args []string) error {
 			if account == nil {
 				return fmt.Errorf("subaccount %s not found in configuration for %s", subaccountId, exchange)
 			}
-			err = account.LoadSecrets()
+			err = account.LoadSecrets(preCmd.Context())
 			if err != nil {
 				return fmt.Errorf("could not load secrets for %s subaccount %s: %w", exchange, subaccountId, err)
 			}
 		} else {
 			account = exchangeConfig.AsAccount()
-			err = account.LoadSecrets()
+			err = account.LoadSecrets(preCmd.Context())
 			if err != nil {
 				return fmt.Errorf("could not load secrets for %s: %w", exchange, err)
 			}
@@ -103,7 +108,7 @@ func exchangePreRun(preCmd *cobra.Command, args []string) error {
 	} else {
 		account = NopAccount
 	}
-	cli, err := loader.NewClient(exchangeConfig, account)
+	cli, err := loader.NewClient(preCmd.Context(), exchangeConfig, account)
 	if err != nil {
 		return fmt.Errorf("could not create client for %s: %w", exchange, err)
 	}
@@ -113,52 +118,136 @@ func exchangePreRun(preCmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func offchainPreRun(preCmd *cobra.Command, args []string) error {
-	cmd.SetVerbosityFromCmd(preCmd)
-	apiUrl := preCmd.Flag("api").Value.String()
-	// configPath := preCmd.Flag("config").Value.String()
-	subaccountId := preCmd.Flag("subaccount").Value.String()
-	signWith := preCmd.Flag("sign-with").Value.String()
-	exchange := preCmd.Flag("exchange").Value.String()
-	_bearerToken := preCmd.Flag("bearer-token").Value.String()
-	bearerToken := secret.Secret(_bearerToken)
+// loadSigner loads the key in the keyring (or at the path), or the remote signer, to sign with.  The key id
+// defaults to the name of the key in the keyring.
+func loadSigner(ctx context.Context, kr keyring.Keyring, signWith string, keyId string) (signer.SignerI, error) {
+	if remote, ok, err := cmd.LoadRemoteSigner(ctx, signWith); ok {
+		if err != nil {
+			return nil, fmt.Errorf("could not create remote signer %s: %w", signWith, err)
+		}
+		slog.Debug("signing with remote key", "signer", signWith, "public_key", hex.EncodeToString(remote.PublicKey()))
+		if keyId != "" {
+			return signer.WithKeyId(remote, keyId), nil
+		}
+		return remote, nil
+	}
+	if _, err := os.Stat(signWith); err != nil {
+		signWith = strings.TrimPrefix(signWith, "client-keys/")
+	}
+	key, err := kr.Load(signWith)
+	if err != nil {
+		return nil, fmt.Errorf("could not load key %s: %w", signWith, err)
+	}
+	ed25519Signer, err := signer.NewEd25519Signer(string(key.Secret))
+	if err != nil {
+		return nil, fmt.Errorf("could not create ed25519 signer for %s: %w", signWith, err)
+	}
+	// the server looks up the key by its id, which is usually the name of the key
+	if keyId == "" {
+		keyId = key.Name.Id()
+	}
+	if keyId != "" {
+		return signer.WithKeyId(ed25519Signer, keyId), nil
+	}
+	return ed25519Signer, nil
+}
 
+// loadKeyring opens the keyring set by the --keyring-dir and --keyring-passphrase flags.
+func loadKeyring(preCmd *cobra.Command) (keyring.Keyring, error) {
 	keyringDir, err := preCmd.Flags().GetString("keyring-dir")
 	if err != nil {
-		return err
+		return keyring.Keyring{}, err
 	}
 	kr := keyring.New(keyring.KeyringDirOrTreasuryHome(keyringDir))
+	if passphraseRef := preCmd.Flag("keyring-passphrase").Value.String(); passphraseRef != "" {
+		passphrase, err := secret.Secret(passphraseRef).Load(preCmd.Context())
+		if err != nil {
+			return keyring.Keyring{}, fmt.Errorf("could not load keyring passphrase: %w", err)
+		}
+		kr.SetPassphrase(keyring.StaticPassphrase(passphrase))
+	}
+	return kr, nil
+}
 
-	clientOptions := []client.ClientOption{}
+// newServerClient creates a client for the offchain server, using the flags added by addServerClientFlags.
+func newServerClient(preCmd *cobra.Command, clientOptions ...client.ClientOption) (*client.Client, error) {
+	apiUrl := preCmd.Flag("api").Value.String()
+	signWith := preCmd.Flag("sign-with").Value.String()
+	keyId := preCmd.Flag("key-id").Value.String()
+	_bearerToken := preCmd.Flag("bearer-token").Value.String()
+	bearerToken := secret.Secret(_bearerToken)
 
-	if subaccountId != "" {
-		clientOptions = append(clientOptions, client.WithSubAccount(subaccountId))
+	kr, err := loadKeyring(preCmd)
+	if err != nil {
+		return nil, err
 	}
 
 	if signWith != "" {
-		if _, err := os.Stat(signWith); err != nil {
-			signWith = strings.TrimPrefix(signWith, "client-keys/")
-		}
-		key, err := kr.Load(signWith)
-		if err != nil {
-			return fmt.Errorf("could not load key %s: %w", signWith, err)
-		}
-		signer, err := signer.NewEd25519Signer(string(key.Secret))
+		s, err := loadSigner(preCmd.Context(), kr, signWith, keyId)
 		if err != nil {
-			return fmt.Errorf("could not create ed25519 signer for %s: %w", signWith, err)
+			return nil, err
 		}
-		clientOptions = append(clientOptions, client.WithSigner(signer))
+		clientOptions = append(clientOptions, client.WithSigner(s))
 	} else {
 		// try to use a bearer token
-		token, err := bearerToken.Load()
+		token, err := bearerToken.Load(preCmd.Context())
 		if err != nil {
-			return fmt.Errorf("could not load bearer token: %w", err)
+			return nil, fmt.Errorf("could not load bearer token: %w", err)
 		}
 		if token != "" {
 			clientOptions = append(clientOptions, client.WithBearerToken(token))
 		}
 	}
-	cli := client.NewClient(apiUrl, clientOptions...)
+	tlsCert := secret.Secret(preCmd.Flag("tls-cert").Value.String())
+	tlsKey := secret.Secret(preCmd.Flag("tls-key").Value.String())
+	tlsCa := secret.Secret(preCmd.Flag("tls-ca").Value.String())
+	if tlsCert != "" || tlsKey != "" || tlsCa != "" {
+		tlsConfig, err := tlsconfig.Client(preCmd.Context(), tlsCert, tlsKey, tlsCa)
+		if err != nil {
+			return nil, fmt.Errorf("could not load tls config: %w", err)
+		}
+		clientOptions = append(clientOptions, client.WithTLSConfig(tlsConfig))
+	}
+
+	serverKey := preCmd.Flag("server-key").Value.String()
+	if serverKey != "" {
+		pubKey, err := hex.DecodeString(serverKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --server-key: %w", err)
+		}
+		responseVerifier, err := verifier.NewEd25519Verifier(pubKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --server-key: %w", err)
+		}
+		serverKeyId := preCmd.Flag("server-key-id").Value.String()
+		clientOptions = append(clientOptions, client.WithResponseVerifier(verifier.WithKeyId(responseVerifier, serverKeyId)))
+	} else if preCmd.Name() == "deposit" {
+		// a deposit address that was swapped in transit would send funds elsewhere
+		if insecure, _ := preCmd.Flags().GetBool("insecure"); !insecure {
+			return nil, fmt.Errorf("--server-key is required to verify the deposit address came from the server, or pass --insecure to skip verifying it")
+		}
+		slog.Warn("not verifying the deposit address came from the server, set --server-key to the server's response signing key")
+	}
+
+	return client.NewClient(apiUrl, clientOptions...), nil
+}
+
+func offchainPreRun(preCmd *cobra.Command, args []string) error {
+	cmd.SetVerbosityFromCmd(preCmd)
+	// configPath := preCmd.Flag("config").Value.String()
+	subaccountId := preCmd.Flag("subaccount").Value.String()
+	exchange := preCmd.Flag("exchange").Value.String()
+
+	clientOptions := []client.ClientOption{}
+
+	if subaccountId != "" {
+		clientOptions = append(clientOptions, client.WithSubAccount(subaccountId))
+	}
+
+	cli, err := newServerClient(preCmd, clientOptions...)
+	if err != nil {
+		return err
+	}
 	offchainClient, err := offchain.NewClient(cli, oc.ExchangeId(exchange))
 	if err != nil {
 		return fmt.Errorf("could not create offchain client: %w", err)
@@ -216,10 +305,6 @@ func NewOffchainClientCmd() *cobra.Command {
 	var configPath string
 	var exchange string
 	var subaccountId string
-	var apiUrl string
-	var keyringDir string
-	var signWith string
-	var apiKey string
 	cmd := &cobra.Command{
 		Use:               "api",
 		Short:             "Interact with a running offchain server",
@@ -253,6 +338,24 @@ func NewOffchainClientCmd() *cobra.Command {
 		fmt.Sprintf("path to the config file (may set %s)", oc.ENV_OFFCHAIN_CONFIG),
 	)
 
+	addServerClientFlags(cmd)
+	return cmd
+}
+
+var signWithUsage = "The ID of the key (or path of key file) to sign with, or a remote signer:\n" + cmd.RemoteSignerUsage
+
+// addServerClientFlags adds the flags used to connect and authenticate to the offchain server.
+func addServerClientFlags(cmd *cobra.Command) {
+	var apiUrl string
+	var keyringDir string
+	var signWith string
+	var apiKey string
+	var tlsCert string
+	var tlsKey string
+	var tlsCa string
+	var serverKey string
+	var serverKeyId string
+	var insecure bool
 	cmd.PersistentFlags().StringVarP(
 		&apiUrl,
 		"api",
@@ -273,7 +376,19 @@ func NewOffchainClientCmd() *cobra.Command {
 		&signWith,
 		"sign-with",
 		"",
-		"The ID of the key (or path of key file) to sign with.",
+		signWithUsage,
+	)
+
+	cmd.PersistentFlags().String(
+		"key-id",
+		"",
+		"The id of the signing key in the server's public_keys, sent with signatures (defaults to the name of the key in the keyring).",
+	)
+
+	cmd.PersistentFlags().String(
+		"keyring-passphrase",
+		"",
+		fmt.Sprintf("Secret reference to the passphrase of an encrypted key (default is $%s, or to prompt).", keyring.ENV_KEYRING_PASSPHRASE),
 	)
 
 	cmd.PersistentFlags().StringVar(
@@ -282,5 +397,46 @@ func NewOffchainClientCmd() *cobra.Command {
 		"env:BEARER_TOKEN",
 		"Set a secret reference for a bearer token for the API (only valid for read endpoints).",
 	)
-	return cmd
+
+	cmd.PersistentFlags().StringVar(
+		&tlsCert,
+		"tls-cert",
+		"",
+		"Secret reference for a PEM client certificate, if the server requires one (e.g. file:client.pem).",
+	)
+
+	cmd.PersistentFlags().StringVar(
+		&tlsKey,
+		"tls-key",
+		"",
+		"Secret reference for the PEM private key of the client certificate.",
+	)
+
+	cmd.PersistentFlags().StringVar(
+		&tlsCa,
+		"tls-ca",
+		"",
+		"Secret reference for a PEM bundle of CAs to trust for the server, instead of the system roots.",
+	)
+
+	cmd.PersistentFlags().StringVar(
+		&serverKey,
+		"server-key",
+		"",
+		"Hex encoded ed25519 public key the server signs responses with.  If set, responses that aren't signed by it are rejected.",
+	)
+
+	cmd.PersistentFlags().StringVar(
+		&serverKeyId,
+		"server-key-id",
+		"offchain",
+		"The keyid the server signs responses with, its response_signing_key_id.",
+	)
+
+	cmd.PersistentFlags().BoolVar(
+		&insecure,
+		"insecure",
+		false,
+		"Print deposit addresses without --server-key, so without verifying they came from the server.",
+	)
 }
@@ -10,8 +10,9 @@ func NewGetAssetsCmd() *cobra.Command {
 		Use:          "assets",
 		Short:        "list the asset symbols and networks of the exchange",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			cli := unwrapClient(cmd.Context())
-			assets, err := cli.ListAssets()
+			ctx := cmd.Context()
+			cli := unwrapClient(ctx)
+			assets, err := cli.ListAssets(ctx)
 			if err != nil {
 				return err
 			}
@@ -2,9 +2,9 @@ package exchange
 
 import (
 	"fmt"
+	"log/slog"
 
 	"github.com/cordialsys/offchain/client"
-	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
@@ -15,29 +15,28 @@ func NewListBalancesCmd() *cobra.Command {
 		Use:          "balances",
 		Short:        "List your balances on the exchange",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			cli := unwrapClient(cmd.Context())
+			ctx := cmd.Context()
+			cli := unwrapClient(ctx)
 			balanceArgs := client.NewGetBalanceArgs("")
 			if accountType != "" {
-				at, ok, message := resolveAccountType(cli, accountType)
+				at, ok, message := resolveAccountType(ctx, cli, accountType)
 				if !ok {
 					return fmt.Errorf("%s", message)
 				}
 				balanceArgs.SetAccountType(at.Type)
 			} else {
 				// try taking the first account type
-				at, ok, err := resolveFirstAccountType(cli)
+				at, ok, err := resolveFirstAccountType(ctx, cli)
 				if err != nil {
 					return err
 				}
 				if ok {
-					logrus.WithFields(logrus.Fields{
-						"type": at.Type,
-					}).Infof("using default account type")
+					slog.Info("using default account type", "type", at.Type)
 					balanceArgs.SetAccountType(at.Type)
 				}
 			}
 
-			assets, err := cli.ListBalances(balanceArgs)
+			assets, err := cli.ListBalances(ctx, balanceArgs)
 			if err != nil {
 				return err
 			}
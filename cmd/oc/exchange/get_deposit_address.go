@@ -17,7 +17,8 @@ func NewGetDepositAddressCmd() *cobra.Command {
 		Use:          "deposit",
 		Short:        "Get a deposit address for a symbol and network",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			cli := unwrapClient(cmd.Context())
+			ctx := cmd.Context()
+			cli := unwrapClient(ctx)
 			if symbol == "" {
 				return fmt.Errorf("--symbol is required")
 			}
@@ -30,7 +31,7 @@ func NewGetDepositAddressCmd() *cobra.Command {
 				options = append(options, client.WithSubaccount(oc.AccountId(subaccount)))
 			}
 
-			resp, err := cli.GetDepositAddress(client.NewGetDepositAddressArgs(
+			resp, err := cli.GetDepositAddress(ctx, client.NewGetDepositAddressArgs(
 				oc.SymbolId(symbol),
 				oc.NetworkId(network),
 				options...,
@@ -23,8 +23,9 @@ func NewListAccountTypesCmd() *cobra.Command {
 		Use:          "account-types",
 		Short:        "List valid account types for an exchange",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			cli := unwrapClient(cmd.Context())
-			accountTypes, err := cli.ListAccountTypes()
+			ctx := cmd.Context()
+			cli := unwrapClient(ctx)
+			accountTypes, err := cli.ListAccountTypes(ctx)
 			if err != nil {
 				return err
 			}
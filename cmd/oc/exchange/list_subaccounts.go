@@ -13,8 +13,9 @@ func NewListSubaccountsCmd() *cobra.Command {
 		Aliases:      []string{"sub-accounts"},
 
 		RunE: func(cmd *cobra.Command, args []string) error {
-			cli := unwrapClient(cmd.Context())
-			subaccounts, err := cli.ListSubaccounts()
+			ctx := cmd.Context()
+			cli := unwrapClient(ctx)
+			subaccounts, err := cli.ListSubaccounts(ctx)
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,34 @@
+package exchange
+
+import (
+	"github.com/cordialsys/offchain/client"
+	"github.com/spf13/cobra"
+)
+
+func NewListTransferHistoryCmd() *cobra.Command {
+	var limit int
+	var pageToken string
+	cmd := &cobra.Command{
+		Use:   "transfer-history",
+		Short: "List internal account transfer history",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			cli := unwrapClient(ctx)
+
+			resp, err := cli.ListTransferHistory(
+				ctx,
+				client.NewTransferHistoryArgs().
+					WithLimit(limit).
+					WithPageToken(pageToken),
+			)
+			if err != nil {
+				return err
+			}
+			printJson(resp)
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&limit, "limit", 100, "The number of items to return")
+	cmd.Flags().StringVar(&pageToken, "page-token", "", "The page_token of the last transfer on the previous page")
+	return cmd
+}
@@ -0,0 +1,56 @@
+package exchange
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/cordialsys/offchain/client"
+	"github.com/spf13/cobra"
+)
+
+func NewGetTransferStatusCmd() *cobra.Command {
+	var id string
+	var wait bool
+	var interval time.Duration
+	var timeout time.Duration
+	cmd := &cobra.Command{
+		SilenceUsage: true,
+		Use:          "transfer-status",
+		Short:        "Get the status of an account transfer",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			cli := unwrapClient(ctx)
+			if id == "" {
+				return fmt.Errorf("--id is required")
+			}
+
+			deadline := time.Now().Add(timeout)
+			for {
+				resp, err := cli.GetTransferStatus(ctx, id)
+				if err != nil {
+					return err
+				}
+				if !wait || resp.Status != client.OperationStatusPending {
+					printJson(resp)
+					return nil
+				}
+				if time.Now().After(deadline) {
+					printJson(resp)
+					return fmt.Errorf("transfer %s still pending after %s", id, timeout)
+				}
+				slog.Info("transfer pending", "id", id, "retry_in", interval)
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(interval):
+				}
+			}
+		},
+	}
+	cmd.Flags().StringVar(&id, "id", "", "The exchange ID of the transfer")
+	cmd.Flags().BoolVar(&wait, "wait", false, "Poll until the transfer is no longer pending")
+	cmd.Flags().DurationVar(&interval, "interval", 5*time.Second, "Time between polls when waiting")
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Minute, "Maximum time to wait for the transfer to complete")
+	return cmd
+}
@@ -12,23 +12,36 @@ func NewWithdrawCmd() *cobra.Command {
 	var symbol string
 	var network string
 	var amountS string
+	var quote bool
 	cmd := &cobra.Command{
 		SilenceUsage: true,
 		Use:          "withdraw",
 		Short:        "Withdraw funds from the exchange",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			cli := unwrapClient(cmd.Context())
+			ctx := cmd.Context()
+			cli := unwrapClient(ctx)
 			amount, err := oc.NewAmountFromString(amountS)
 			if err != nil {
 				return err
 			}
 
-			resp, err := cli.CreateWithdrawal(client.NewWithdrawalArgs(
+			withdrawalArgs := client.NewWithdrawalArgs(
 				oc.Address(to),
 				oc.SymbolId(symbol),
 				oc.NetworkId(network),
 				amount,
-			))
+			)
+
+			if quote {
+				resp, err := cli.EstimateWithdrawal(ctx, withdrawalArgs)
+				if err != nil {
+					return err
+				}
+				printJson(resp)
+				return nil
+			}
+
+			resp, err := cli.CreateWithdrawal(ctx, withdrawalArgs)
 
 			if err != nil {
 				return err
@@ -42,5 +55,6 @@ func NewWithdrawCmd() *cobra.Command {
 	cmd.Flags().StringVar(&symbol, "symbol", "", "The symbol to withdraw")
 	cmd.Flags().StringVar(&network, "network", "", "The network to transact on")
 	cmd.Flags().StringVar(&amountS, "amount", "", "The amount to withdraw")
+	cmd.Flags().BoolVar(&quote, "quote", false, "Only estimate the fee and net amount of the withdrawal, without executing it")
 	return cmd
 }
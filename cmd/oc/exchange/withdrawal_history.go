@@ -14,7 +14,8 @@ func NewListWithdrawalHistoryCmd() *cobra.Command {
 		Use:   "history",
 		Short: "List withdrawal history",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			cli := unwrapClient(cmd.Context())
+			ctx := cmd.Context()
+			cli := unwrapClient(ctx)
 
 			if cmd.Flags().Changed("limit") {
 				return fmt.Errorf("--limit is not yet supported")
@@ -23,7 +24,7 @@ func NewListWithdrawalHistoryCmd() *cobra.Command {
 				return fmt.Errorf("--page-token is not yet supported")
 			}
 
-			resp, err := cli.ListWithdrawalHistory(client.NewWithdrawalHistoryArgs())
+			resp, err := cli.ListWithdrawalHistory(ctx, client.NewWithdrawalHistoryArgs())
 			if err != nil {
 				return err
 			}
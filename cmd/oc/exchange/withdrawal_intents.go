@@ -0,0 +1,37 @@
+package exchange
+
+import (
+	"github.com/cordialsys/offchain/server/client/api"
+	"github.com/spf13/cobra"
+)
+
+func NewWithdrawalIntentsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "withdraw",
+		Short:             "Manage withdrawals that are delayed or waiting for approval on an offchain server",
+		SilenceUsage:      true,
+		PersistentPreRunE: serverClientPreRun,
+	}
+	cmd.AddCommand(newListIntentsCmd("List the delayed withdrawals, newest first, with when they're scheduled", api.IntentScheduled))
+	cmd.AddCommand(NewCancelWithdrawalCmd())
+	addServerClientFlags(cmd)
+	return cmd
+}
+
+func NewCancelWithdrawalCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		SilenceUsage: true,
+		Use:          "cancel <intent-id>",
+		Short:        "Cancel a withdrawal that is delayed or waiting for approval",
+		Args:         cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			i, err := unwrapServerClient(cmd.Context()).CancelWithdrawalIntent(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			printJson(i)
+			return nil
+		},
+	}
+	return cmd
+}
@@ -1,6 +1,10 @@
 package keys
 
 import (
+	"fmt"
+
+	"github.com/cordialsys/offchain/pkg/keyring"
+	"github.com/cordialsys/offchain/pkg/secret"
 	"github.com/spf13/cobra"
 )
 
@@ -16,11 +20,53 @@ func NewKeysCmd() *cobra.Command {
 		"",
 		"The directory to manage keyring files",
 	)
+	cmd.PersistentFlags().String(
+		"keyring-passphrase",
+		"",
+		fmt.Sprintf("Secret reference to the passphrase of encrypted keys (default is $%s, or to prompt)", keyring.ENV_KEYRING_PASSPHRASE),
+	)
 
 	cmd.AddCommand(NewGenerateCmd())
 	cmd.AddCommand(NewConfigCmd())
 	cmd.AddCommand(NewLsCmd())
 	cmd.AddCommand(NewGetCmd())
+	cmd.AddCommand(NewEncryptCmd())
+	cmd.AddCommand(NewChangePassphraseCmd())
 
 	return cmd
 }
+
+func loadKeyring(cmd *cobra.Command) (keyring.Keyring, error) {
+	keyringDir, err := cmd.Flags().GetString("keyring-dir")
+	if err != nil {
+		return keyring.Keyring{}, err
+	}
+	kr := keyring.New(keyring.KeyringDirOrTreasuryHome(keyringDir))
+	passphraseRef, err := cmd.Flags().GetString("keyring-passphrase")
+	if err != nil {
+		return kr, err
+	}
+	if passphraseRef != "" {
+		passphrase, err := secret.Secret(passphraseRef).Load(cmd.Context())
+		if err != nil {
+			return kr, fmt.Errorf("could not load keyring passphrase: %w", err)
+		}
+		kr.SetPassphrase(keyring.StaticPassphrase(passphrase))
+	}
+	return kr, nil
+}
+
+// newPassphrase loads the new passphrase from the secret reference, or prompts for it.
+func newPassphrase(cmd *cobra.Command, newPassphraseRef string) (string, error) {
+	if newPassphraseRef == "" {
+		return keyring.PromptNewPassphrase()
+	}
+	passphrase, err := secret.Secret(newPassphraseRef).Load(cmd.Context())
+	if err != nil {
+		return "", fmt.Errorf("could not load new passphrase: %w", err)
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("new passphrase must not be empty")
+	}
+	return passphrase, nil
+}
@@ -0,0 +1,80 @@
+package keys
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func NewEncryptCmd() *cobra.Command {
+	var newPassphraseRef string
+	cmd := &cobra.Command{
+		Use:   "encrypt [id]",
+		Short: "Encrypt a plaintext key with a passphrase",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kr, err := loadKeyring(cmd)
+			if err != nil {
+				return err
+			}
+			k, err := kr.Read(args[0])
+			if err != nil {
+				return err
+			}
+			if k.IsEncrypted() {
+				return fmt.Errorf("key %s is already encrypted, use change-passphrase instead", k.Name.Id())
+			}
+			passphrase, err := newPassphrase(cmd, newPassphraseRef)
+			if err != nil {
+				return err
+			}
+			if err := k.Encrypt(passphrase); err != nil {
+				return err
+			}
+			if err := kr.Save(k); err != nil {
+				return err
+			}
+			fmt.Println(k.Name)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&newPassphraseRef, "new-passphrase", "", "Secret reference to the passphrase to encrypt the key with (default is to prompt)")
+	return cmd
+}
+
+func NewChangePassphraseCmd() *cobra.Command {
+	var newPassphraseRef string
+	cmd := &cobra.Command{
+		Use:   "change-passphrase [id]",
+		Short: "Change the passphrase of an encrypted key",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kr, err := loadKeyring(cmd)
+			if err != nil {
+				return err
+			}
+			k, err := kr.Load(args[0])
+			if err != nil {
+				return err
+			}
+			if !k.IsEncrypted() {
+				return fmt.Errorf("key %s is not encrypted, use encrypt instead", k.Name.Id())
+			}
+			passphrase, err := newPassphrase(cmd, newPassphraseRef)
+			if err != nil {
+				return err
+			}
+			k = k.Decrypted()
+			if err := k.Encrypt(passphrase); err != nil {
+				return err
+			}
+			if err := kr.Save(k); err != nil {
+				return err
+			}
+			fmt.Println(k.Name)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&newPassphraseRef, "new-passphrase", "", "Secret reference to the new passphrase (default is to prompt)")
+	return cmd
+}
@@ -10,24 +10,32 @@ import (
 
 func NewGenerateCmd() *cobra.Command {
 	var overwrite bool
+	var encrypt bool
+	var newPassphraseRef string
 	cmd := &cobra.Command{
 		Use:   "generate [id]",
 		Short: "Generate and store a new key",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			keyringDir, err := cmd.Flags().GetString("keyring-dir")
+			kr, err := loadKeyring(cmd)
 			if err != nil {
 				return err
 			}
-			kr := keyring.New(keyring.KeyringDirOrTreasuryHome(keyringDir))
 
-			if _, err = kr.Load(args[0]); err == nil {
+			if _, err = kr.Read(args[0]); err == nil {
 				if !overwrite {
 					return fmt.Errorf("key already exists")
 				}
 			}
 
-			k, err := kr.New(keyring.NewClientKeyName(args[0]), keyring.Ed25519)
+			passphrase := ""
+			if encrypt || newPassphraseRef != "" {
+				passphrase, err = newPassphrase(cmd, newPassphraseRef)
+				if err != nil {
+					return err
+				}
+			}
+			k, err := kr.New(keyring.NewClientKeyName(args[0]), keyring.Ed25519, passphrase)
 			if err != nil {
 				return err
 			}
@@ -48,5 +56,7 @@ func NewGenerateCmd() *cobra.Command {
 		false,
 		"Overwrite any existing key",
 	)
+	cmd.Flags().BoolVar(&encrypt, "encrypt", false, "Encrypt the key with a passphrase")
+	cmd.Flags().StringVar(&newPassphraseRef, "new-passphrase", "", "Secret reference to the passphrase to encrypt the key with (implies --encrypt, default is to prompt)")
 	return cmd
 }
@@ -4,22 +4,31 @@ import (
 	"encoding/hex"
 	"fmt"
 
-	"github.com/cordialsys/offchain/pkg/keyring"
+	occmd "github.com/cordialsys/offchain/cmd"
 	"github.com/spf13/cobra"
 )
 
 func NewGetCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "get [id]",
-		Short: "Get a key",
+		Short: "Get the public key of a key, or of a remote signer",
+		Long:  "Prints the public key of a key in the keyring, or of a remote signer:\n" + occmd.RemoteSignerUsage,
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			keyringDir, err := cmd.Flags().GetString("keyring-dir")
+			remote, ok, err := occmd.LoadRemoteSigner(cmd.Context(), args[0])
+			if ok {
+				if err != nil {
+					return err
+				}
+				fmt.Println(hex.EncodeToString(remote.PublicKey()))
+				return nil
+			}
+			kr, err := loadKeyring(cmd)
 			if err != nil {
 				return err
 			}
-			kr := keyring.New(keyring.KeyringDirOrTreasuryHome(keyringDir))
-			k, err := kr.Load(args[0])
+			// the public key can be read without unlocking the key
+			k, err := kr.Read(args[0])
 			if err != nil {
 				return err
 			}
@@ -1,14 +1,17 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/cordialsys/offchain/cmd"
 	"github.com/cordialsys/offchain/cmd/oc/exchange"
 	"github.com/cordialsys/offchain/cmd/oc/keys"
+	"github.com/cordialsys/offchain/pkg/logging"
 	"github.com/spf13/cobra"
 )
 
@@ -35,9 +38,13 @@ func NewRootCmd() *cobra.Command {
 	}
 	cmd.AddCommand(NewConfigCmd())
 	cmd.AddCommand(NewSecretCmd())
+	cmd.AddCommand(NewAuditCmd())
+	cmd.AddCommand(NewDoctorCmd())
 	cmd.AddCommand(NewStartCmd())
 	cmd.AddCommand(exchange.NewExchangeCmd())
 	cmd.AddCommand(exchange.NewOffchainClientCmd())
+	cmd.AddCommand(exchange.NewApprovalsCmd())
+	cmd.AddCommand(exchange.NewWithdrawalIntentsCmd())
 	cmd.AddCommand(keys.NewKeysCmd())
 	cmd.PersistentFlags().CountVarP(
 		&verbose,
@@ -50,8 +57,12 @@ func NewRootCmd() *cobra.Command {
 }
 
 func main() {
-	slog.SetLogLoggerLevel(slog.LevelDebug)
-	if err := NewRootCmd().Execute(); err != nil {
+	logging.Setup(os.Stderr, logging.FormatText)
+	// cancel in-flight requests on interrupt
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := NewRootCmd().ExecuteContext(ctx); err != nil {
+		stop()
 		os.Exit(1)
 	}
 }
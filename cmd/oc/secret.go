@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	oc "github.com/cordialsys/offchain"
+	"github.com/cordialsys/offchain/loader"
 	"github.com/cordialsys/offchain/pkg/secret"
 	"github.com/spf13/cobra"
 )
@@ -11,7 +12,7 @@ import (
 func NewSecretCmd() *cobra.Command {
 	multi := false
 	help := ""
-	for _, t := range secret.Types {
+	for _, t := range secret.Types() {
 		help += fmt.Sprintf("%s: %s\n", t, t.Usage())
 	}
 	cmd := &cobra.Command{
@@ -29,13 +30,13 @@ func NewSecretCmd() *cobra.Command {
 				multiSecret := oc.MultiSecret{
 					SecretsRef: secret.Secret(args[0]),
 				}
-				err = multiSecret.LoadSecrets()
+				err = multiSecret.LoadSecrets(cmd.Context())
 				if err != nil {
 					return err
 				}
 				printJson(multiSecret)
 			} else {
-				sec, err := secret.Secret(args[0]).Load()
+				sec, err := secret.Secret(args[0]).Load(cmd.Context())
 				if err != nil {
 					return err
 				}
@@ -46,5 +47,60 @@ func NewSecretCmd() *cobra.Command {
 		},
 	}
 	cmd.Flags().BoolVar(&multi, "multi", false, "Parse the secret value, expecting multi-part exchange secrets")
+	cmd.AddCommand(NewSecretRotateCmd())
+	return cmd
+}
+
+func NewSecretRotateCmd() *cobra.Command {
+	var configPath string
+	var exchange string
+	var subaccountId string
+	opts := loader.DefaultRotateOptions()
+	cmd := &cobra.Command{
+		SilenceUsage: true,
+		Use:          "rotate",
+		Short:        "Replace the API key of a sub-account",
+		Long: fmt.Sprintf(`Creates a new API key for the sub-account using the main account's API key, with the same
+permissions and IP addresses as the current key.  The new key is written to the sub-account's
+configured secrets as a new version, read back and checked, and then the old key is revoked.
+If the new key can't be written or used, the previous secrets are restored and the new key is deleted.
+
+Supported for bybit, okx, and binance broker sub-accounts.  The secrets must be writable (%v)
+and not pinned to a version.`, secret.StorableTypes()),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := loader.LoadValidatedConfig(configPath)
+			if err != nil {
+				return err
+			}
+			exchangeConfig, ok := config.GetExchange(oc.ExchangeId(exchange))
+			if !ok {
+				return fmt.Errorf("exchange not found. options are: %v", oc.ValidExchangeIds)
+			}
+			subaccount, ok := exchangeConfig.ResolveSubAccount(subaccountId)
+			if !ok {
+				return fmt.Errorf("subaccount %s not found in configuration for %s", subaccountId, exchange)
+			}
+			result, err := loader.RotateSubAccountKey(cmd.Context(), exchangeConfig, subaccount, opts)
+			if result != nil {
+				printJson(result)
+			}
+			return err
+		},
+	}
+	cmd.Flags().StringVarP(
+		&configPath,
+		"config",
+		"c",
+		"",
+		fmt.Sprintf("path to the config file (may set %s)", oc.ENV_OFFCHAIN_CONFIG),
+	)
+	cmd.Flags().StringVarP(&exchange, "exchange", "x", "", fmt.Sprintf("The exchange to use (%v)", oc.ValidExchangeIds))
+	cmd.Flags().StringVarP(&subaccountId, "subaccount", "s", "", "The id or alias of the subaccount")
+	cmd.Flags().StringVar(&opts.Label, "label", opts.Label, "Label of the new key on the exchange")
+	cmd.Flags().DurationVar(&opts.Grace, "grace", 0, "Wait before revoking the old key, e.g. for running servers to reload the secrets")
+	cmd.Flags().BoolVar(&opts.KeepOld, "keep-old", false, "Don't revoke the old key")
+	_ = cmd.MarkFlagRequired("exchange")
+	_ = cmd.MarkFlagRequired("subaccount")
 	return cmd
 }
@@ -1,13 +1,25 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/hex"
 	"fmt"
 	"log/slog"
+	"os"
+	"time"
 
 	oc "github.com/cordialsys/offchain"
 	"github.com/cordialsys/offchain/loader"
+	"github.com/cordialsys/offchain/pkg/audit"
+	"github.com/cordialsys/offchain/pkg/httpsignature/ecc"
 	"github.com/cordialsys/offchain/pkg/httpsignature/verifier"
+	"github.com/cordialsys/offchain/pkg/intent"
+	"github.com/cordialsys/offchain/pkg/logging"
+	"github.com/cordialsys/offchain/pkg/secret"
+	"github.com/cordialsys/offchain/pkg/tracing"
 	"github.com/cordialsys/offchain/server"
+	"github.com/cordialsys/offchain/server/health"
 	"github.com/spf13/cobra"
 )
 
@@ -17,10 +29,23 @@ func NewStartCmd() *cobra.Command {
 	var anyOrigin bool
 	var origins []string
 	var publicReadEndpoints bool
+	var watchConfig bool
+	var logFormat string
 	cmd := &cobra.Command{
 		Use:   "start",
 		Short: "Serve the offchain server",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			switch logging.Format(logFormat) {
+			case logging.FormatJson, logging.FormatText:
+				logging.Setup(os.Stderr, logging.Format(logFormat))
+			default:
+				return fmt.Errorf("invalid log format %q, expected %q or %q", logFormat, logging.FormatJson, logging.FormatText)
+			}
+			if verbose, _ := cmd.Flags().GetCount("verbose"); verbose == 0 {
+				// requests are logged at info
+				logging.Level.Set(slog.LevelInfo)
+			}
+
 			config, err := loader.LoadValidatedConfig(configPath)
 			if err != nil {
 				return err
@@ -41,26 +66,19 @@ func NewStartCmd() *cobra.Command {
 			if len(origins) > 0 {
 				serverConfig.Origins = origins
 			}
-			bearers := make([]string, len(serverConfig.BearerTokens))
-			for i, bearer := range serverConfig.BearerTokens {
-				bearers[i], err = bearer.Token.Load()
-				if err != nil {
-					return fmt.Errorf("failed to load bearer token %s: %w", bearer.Id, err)
-				}
-			}
-			publicKeys := make([]verifier.VerifierI, len(serverConfig.PublicKeys))
-			for i, key := range serverConfig.PublicKeys {
-				switch key.Algorithm {
-				case server.Ed25519, "":
-					publicKeys[i], err = verifier.NewEd25519Verifier(key.Key.Bytes())
-					if err != nil {
-						return fmt.Errorf("failed to create ed25519 verifier: %w", err)
-					}
-				default:
-					return fmt.Errorf("unsupported algorithm: %s", key.Algorithm)
-				}
+			// reuse secrets and clients between requests
+			var clients *loader.ClientCache
+			if !serverConfig.SecretCache.Disabled {
+				cache := secret.NewCache(serverConfig.SecretCache.Options())
+				secret.EnableCache(cache)
+				clients = loader.NewClientCache()
+				clients.InvalidateOnRotation(cache)
 			}
 
+			bearers, publicKeys, err := loadCredentials(cmd.Context(), serverConfig)
+			if err != nil {
+				return err
+			}
 			if len(bearers) == 0 && len(publicKeys) == 0 && !publicReadEndpoints {
 				slog.Warn("no authentication means configured, read-only endpoints will be unreachable")
 			}
@@ -68,6 +86,68 @@ func NewStartCmd() *cobra.Command {
 				slog.Warn("no public keys configured, write-endpoints will be unreachable")
 			}
 
+			tlsConfig, err := serverConfig.TLSConfig(cmd.Context())
+			if err != nil {
+				return err
+			}
+			approvals, err := loadApprovalPolicy(serverConfig)
+			if err != nil {
+				return err
+			}
+			if approvals.Enabled() {
+				slog.Info("withdrawals need approval", "quorum", approvals.Quorum, "approvers", len(approvals.Approvers), "expiry", approvals.Expiry.String())
+			}
+			intents, err := serverConfig.IntentStore()
+			if err != nil {
+				return err
+			}
+			for _, delay := range serverConfig.Delays {
+				slog.Info("withdrawals are delayed", "exchange", delay.Exchange, "symbol", delay.Symbol, "threshold", delay.Threshold.String(), "delay", delay.Delay.String())
+			}
+			responseSigner, err := serverConfig.ResponseSigner(cmd.Context())
+			if err != nil {
+				return err
+			}
+			if responseSigner != nil {
+				slog.Info("signing responses", "public_key", hex.EncodeToString(responseSigner.PublicKey()), "key_id", serverConfig.ResponseSigningKeyId)
+			}
+
+			shutdownTracing, err := tracing.Setup(cmd.Context(), serverConfig.Tracing)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				// flush any remaining spans
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				if err := shutdownTracing(ctx); err != nil {
+					slog.Warn("failed to flush traces", "error", err)
+				}
+			}()
+
+			var auditLog *audit.Log
+			if serverConfig.Audit.Enabled() {
+				auditLog, err = audit.Open(serverConfig.Audit)
+				if err != nil {
+					return err
+				}
+				defer auditLog.Close()
+			}
+
+			webhookWatcher, err := serverConfig.Webhooks.NewWatcher(cmd.Context())
+			if err != nil {
+				return err
+			}
+			watcherDone := make(chan struct{})
+			if webhookWatcher != nil {
+				go func() {
+					webhookWatcher.Run(cmd.Context())
+					close(watcherDone)
+				}()
+			} else {
+				close(watcherDone)
+			}
+
 			serverArgs := server.ServerArgs{
 				Listen:              serverConfig.Listen,
 				AnyOrigin:           serverConfig.AnyOrigin,
@@ -75,9 +155,47 @@ func NewStartCmd() *cobra.Command {
 				BearerTokens:        bearers,
 				PublicKeys:          publicKeys,
 				PublicReadEndpoints: serverConfig.PublicReadEndpoints,
+				Audit:               auditLog,
+				Watcher:             webhookWatcher,
+				TLS:                 tlsConfig,
+				Health:              health.NewChecker(serverConfig.Health.CacheTtl),
+				Clients:             clients,
+				Limits:              serverConfig.Limits,
+				ResponseSigner:      responseSigner,
+				Approvals:           approvals,
+				Delays:              serverConfig.Delays,
+				Intents:             intents,
 			}
-			server := server.New(config, serverArgs)
-			return server.Start()
+			srv := server.New(config, serverArgs)
+			go srv.RunIntents(cmd.Context())
+
+			if watchConfig {
+				// only the exchanges and credentials are reloaded; other settings need a restart
+				reload := func(ctx context.Context) error {
+					config, err := loader.LoadValidatedConfig(configPath)
+					if err != nil {
+						return err
+					}
+					serverConfig, err := server.LoadConfig(configPath)
+					if err != nil {
+						return err
+					}
+					bearers, publicKeys, err := loadCredentials(ctx, serverConfig)
+					if err != nil {
+						return err
+					}
+					srv.Reload(config, bearers, publicKeys)
+					return nil
+				}
+				if err := server.WatchConfig(cmd.Context(), resolvedConfigPath(configPath), reload); err != nil {
+					return fmt.Errorf("failed to watch config file: %w", err)
+				}
+			}
+
+			err = srv.Start()
+			// undelivered webhooks are dead-lettered on shutdown
+			<-watcherDone
+			return err
 		},
 	}
 	cmd.Flags().StringVarP(
@@ -91,5 +209,114 @@ func NewStartCmd() *cobra.Command {
 	cmd.Flags().BoolVar(&anyOrigin, "any-origin", false, "allow any origin for CORS")
 	cmd.Flags().StringSliceVar(&origins, "origins", []string{}, "origins to allow for CORS")
 	cmd.Flags().BoolVar(&publicReadEndpoints, "public-read-endpoints", false, "Disable authentication for read endpoints")
+	cmd.Flags().StringVar(&logFormat, "log-format", string(logging.FormatJson), "format of the logs, json or text")
+	cmd.Flags().BoolVar(&watchConfig, "watch-config", true, "reload the exchanges, bearer tokens and public keys when the config file changes or on SIGHUP")
 	return cmd
 }
+
+func resolvedConfigPath(configPath string) string {
+	if configPath == "" {
+		return os.Getenv(oc.ENV_OFFCHAIN_CONFIG)
+	}
+	return configPath
+}
+
+// loadCredentials loads the bearer tokens and public keys allowed to make requests.
+func loadCredentials(ctx context.Context, serverConfig *server.Config) ([]server.Token, []server.PublicKey, error) {
+	var err error
+	bearers := make([]server.Token, len(serverConfig.BearerTokens))
+	for i, bearer := range serverConfig.BearerTokens {
+		bearers[i].Id = bearer.Id
+		bearers[i].ClientCert = bearer.ClientCert
+		bearers[i].Token, err = bearer.Token.Load(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load bearer token %s: %w", bearer.Id, err)
+		}
+	}
+	publicKeys := make([]server.PublicKey, len(serverConfig.PublicKeys))
+	keyIds := map[string]bool{}
+	for i, key := range serverConfig.PublicKeys {
+		// signatures name their key by id, so it must be unique
+		if key.Id != "" && keyIds[key.Id] {
+			return nil, nil, fmt.Errorf("public key id %s is used more than once", key.Id)
+		}
+		keyIds[key.Id] = true
+		publicKeys[i].Id = key.Id
+		publicKeys[i].ClientCert = key.ClientCert
+		publicKeys[i].VerifierI, err = loadVerifier(key)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return bearers, publicKeys, nil
+}
+
+// loadVerifier creates the verifier for a configured public key.
+func loadVerifier(key server.HttpPublicKey) (verifier.VerifierI, error) {
+	switch key.Algorithm {
+	case server.Ed25519, "":
+		v, err := verifier.NewEd25519Verifier(key.Key.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create ed25519 verifier: %w", err)
+		}
+		return v, nil
+	case server.P256Sha256:
+		v, err := verifier.NewEcdsaVerifier(ecc.P256, key.Key.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create p256 verifier for %s: %w", key.Id, err)
+		}
+		return v, nil
+	case server.K256Sha256:
+		v, err := verifier.NewEcdsaVerifier(ecc.K256, key.Key.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create k256 verifier for %s: %w", key.Id, err)
+		}
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unsupported algorithm: %s", key.Algorithm)
+	}
+}
+
+// sameKey returns true if the verifiers have the same public key.
+func sameKey(a, b verifier.VerifierI) bool {
+	aKey, ok := a.(verifier.PublicKeyVerifier)
+	if !ok {
+		return false
+	}
+	bKey, ok := b.(verifier.PublicKeyVerifier)
+	return ok && bytes.Equal(aKey.PublicKey(), bKey.PublicKey())
+}
+
+// loadApprovalPolicy loads the approvers of withdrawals, or returns nil if withdrawals don't need approval.
+func loadApprovalPolicy(serverConfig *server.Config) (*intent.Policy, error) {
+	cfg := serverConfig.Approvals
+	if cfg.Quorum == 0 && len(cfg.Approvers) == 0 {
+		return nil, nil
+	}
+	policy := &intent.Policy{
+		Quorum: cfg.Quorum,
+		Expiry: cfg.Expiry,
+	}
+	for _, key := range cfg.Approvers {
+		v, err := loadVerifier(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid approver %s: %w", key.Id, err)
+		}
+		// approvers can't decide on their own withdrawals.  The keys are compared once parsed, as the
+		// same key can be encoded differently.
+		for _, publicKey := range serverConfig.PublicKeys {
+			requester, err := loadVerifier(publicKey)
+			if err != nil {
+				return nil, fmt.Errorf("invalid public key %s: %w", publicKey.Id, err)
+			}
+			if sameKey(requester, v) && publicKey.Id != key.Id {
+				return nil, fmt.Errorf("approver %s has the same key as public key %s, it must use the same id", key.Id, publicKey.Id)
+			}
+		}
+		policy.Approvers = append(policy.Approvers, intent.Approver{Id: key.Id, VerifierI: v})
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid approvals: %w", err)
+	}
+	return policy, nil
+}
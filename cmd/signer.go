@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cordialsys/offchain/pkg/httpsignature/signer"
+	"github.com/cordialsys/offchain/pkg/secret"
+)
+
+// Remote signers keep the private key in a KMS or HSM, and are referenced as <type>:<args>
+const (
+	RemoteSignerAwsKms = "awskms"
+	RemoteSignerGcpKms = "gcpkms"
+	RemoteSignerPkcs11 = "pkcs11"
+)
+
+const RemoteSignerUsage = `awskms:<key-id>[,region]
+gcpkms:projects/<project>/locations/<location>/keyRings/<ring>/cryptoKeys/<key>/cryptoKeyVersions/<version>
+pkcs11:<module.so>,<token-label>,<key-label>,<pin-secret-reference>`
+
+// LoadRemoteSigner creates the signer for a remote signer reference.  It returns false if the reference isn't
+// for a remote signer, e.g. it's the name of a key in the keyring.
+func LoadRemoteSigner(ctx context.Context, ref string) (signer.SignerI, bool, error) {
+	signerType, value, ok := strings.Cut(ref, ":")
+	if !ok {
+		return nil, false, nil
+	}
+	args := strings.Split(value, ",")
+	switch signerType {
+	case RemoteSignerAwsKms:
+		if len(args) > 2 {
+			return nil, true, fmt.Errorf("expected %s:<key-id>[,region]", RemoteSignerAwsKms)
+		}
+		region := ""
+		if len(args) > 1 {
+			region = args[1]
+		}
+		s, err := signer.NewAwsKmsSigner(ctx, args[0], region)
+		return s, true, err
+	case RemoteSignerGcpKms:
+		s, err := signer.NewGcpKmsSigner(ctx, value)
+		return s, true, err
+	case RemoteSignerPkcs11:
+		if len(args) < 4 {
+			return nil, true, fmt.Errorf("expected %s:<module.so>,<token-label>,<key-label>,<pin-secret-reference>", RemoteSignerPkcs11)
+		}
+		// the pin reference may have its own commas
+		pin, err := secret.Secret(strings.Join(args[3:], ",")).Load(ctx)
+		if err != nil {
+			return nil, true, fmt.Errorf("could not load pkcs11 pin: %w", err)
+		}
+		s, err := signer.NewPkcs11Signer(args[0], args[1], args[2], pin)
+		return s, true, err
+	default:
+		return nil, false, nil
+	}
+}
@@ -8,7 +8,6 @@ import (
 
 	"github.com/cordialsys/offchain/pkg/secret"
 	"github.com/ilyakaznacheev/cleanenv"
-	"github.com/sirupsen/logrus"
 )
 
 type Config struct {
@@ -52,7 +51,7 @@ func LoadUnvalidatedConfig(configPathMaybe string) (*Config, error) {
 		return nil, fmt.Errorf("config path is required (maybe set %s)", ENV_OFFCHAIN_CONFIG)
 	}
 
-	logrus.WithField("config", configPathMaybe).Info("loading configuration")
+	slog.Info("loading configuration", "config", configPathMaybe)
 	err := cleanenv.ReadConfig(configPathMaybe, &section)
 	if err != nil {
 		return nil, fmt.Errorf("could not read configuration: %v", err)
@@ -67,13 +66,13 @@ func LoadUnvalidatedConfig(configPathMaybe string) (*Config, error) {
 
 		exchange.ExchangeId = key
 		if exchange.ApiKeyRef.IsType(secret.Raw) {
-			slog.Warn("raw api-key in config file is unsafe, should use a secret manager", "exchange", key)
+			slog.Warn("raw api-key in config file is unsafe, should use a secret manager or a sops encrypted file", "exchange", key)
 		}
 		if exchange.SecretKeyRef.IsType(secret.Raw) {
-			slog.Warn("raw secret-key in config file is unsafe, should use a secret manager", "exchange", key)
+			slog.Warn("raw secret-key in config file is unsafe, should use a secret manager or a sops encrypted file", "exchange", key)
 		}
 		if exchange.PassphraseRef.IsType(secret.Raw) {
-			slog.Warn("raw passphrase in config file is unsafe, should use a secret manager", "exchange", key)
+			slog.Warn("raw passphrase in config file is unsafe, should use a secret manager or a sops encrypted file", "exchange", key)
 		}
 	}
 
@@ -1,11 +1,13 @@
 package offchain
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
 
 	"github.com/cordialsys/offchain/pkg/secret"
+	"github.com/cordialsys/offchain/pkg/tracing"
 )
 
 type ExchangeId string
@@ -37,6 +39,9 @@ type MultiSecret struct {
 type ExchangeClientConfig struct {
 	ApiUrl string `yaml:"api_url"`
 
+	// Deadlines for operations on the exchange.
+	Timeouts Timeouts `yaml:"timeouts"`
+
 	// The account types supported by the exchange.
 	AccountTypes   []*AccountTypeConfig `yaml:"account_types"`
 	NoAccountTypes *bool                `yaml:"no_account_types,omitempty"`
@@ -149,15 +154,18 @@ func (cfg *Account) IsMain() bool {
 	return !cfg.SubAccount
 }
 
-func (cfg *Account) LoadSecrets() error {
-	return cfg.MultiSecret.LoadSecrets()
+func (cfg *Account) LoadSecrets(ctx context.Context) error {
+	return cfg.MultiSecret.LoadSecrets(ctx)
 }
 
-func (c *MultiSecret) LoadSecrets() error {
+func (c *MultiSecret) LoadSecrets(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "LoadSecrets")
+	defer func() { tracing.End(span, err) }()
+
 	if c.SecretsRef == "" {
 		return nil
 	}
-	value, err := c.SecretsRef.Load()
+	value, err := c.SecretsRef.Load(ctx)
 	if err != nil {
 		return err
 	}
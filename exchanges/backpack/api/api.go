@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"crypto/ed25519"
 	"encoding/base64"
 	"encoding/json"
@@ -12,8 +13,14 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/cordialsys/offchain/pkg/logging"
+	"github.com/cordialsys/offchain/pkg/transport"
 )
 
+// rate limits and retries shared by all clients of the venue
+var transportOptions = transport.DefaultOptions()
+
 type Client struct {
 	apiKey     string // Base64 encoded public key
 	privateKey ed25519.PrivateKey
@@ -28,7 +35,7 @@ func NewClient(apiKey string, privateKey ed25519.PrivateKey) (*Client, error) {
 		apiKey:     apiKey,
 		privateKey: privateKey,
 		baseURL:    "https://api.backpack.exchange",
-		httpClient: &http.Client{},
+		httpClient: transport.NewClient("backpack", transportOptions),
 		window:     5000, // Default window value
 	}, nil
 }
@@ -72,13 +79,12 @@ func createSigningString(instruction string, params map[string]string, timestamp
 // sign creates the signature for authentication
 func (c *Client) sign(instruction string, params map[string]string, timestamp int64) string {
 	signingString := createSigningString(instruction, params, timestamp, c.window)
-	fmt.Println("signingString", signingString)
 	signature := ed25519.Sign(c.privateKey, []byte(signingString))
 	return base64.StdEncoding.EncodeToString(signature)
 }
 
 // Request makes an authenticated HTTP request to the Backpack API
-func (c *Client) Request(method, path, instruction string, input interface{}, output interface{}, query url.Values) ([]byte, error) {
+func (c *Client) Request(ctx context.Context, method, path, instruction string, input interface{}, output interface{}, query url.Values) ([]byte, error) {
 	method = strings.ToUpper(method)
 	apiUrl := c.baseURL + path
 
@@ -115,13 +121,15 @@ func (c *Client) Request(method, path, instruction string, input interface{}, ou
 		}
 	}
 
-	log.Debug("request", "body", bodyStr, "params", params)
-
-	// Generate timestamp
-	timestamp := time.Now().UnixMilli()
+	log.DebugContext(ctx, "request", "body", logging.Body(bodyStr), "query", query)
 
-	// Generate signature
-	signature := c.sign(instruction, params, timestamp)
+	// Sign with the current timestamp, again on every retry
+	sign := func(req *http.Request) error {
+		timestamp := time.Now().UnixMilli()
+		req.Header.Set("X-Timestamp", fmt.Sprintf("%d", timestamp))
+		req.Header.Set("X-Signature", c.sign(instruction, params, timestamp))
+		return nil
+	}
 
 	// Create request
 	var reqBody io.Reader
@@ -134,7 +142,7 @@ func (c *Client) Request(method, path, instruction string, input interface{}, ou
 		apiUrl += "?" + query.Encode()
 	}
 
-	req, err := http.NewRequest(method, apiUrl, reqBody)
+	req, err := http.NewRequestWithContext(transport.WithSigner(ctx, sign), method, apiUrl, reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -142,9 +150,10 @@ func (c *Client) Request(method, path, instruction string, input interface{}, ou
 	// Set headers
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-API-Key", c.apiKey)
-	req.Header.Set("X-Timestamp", fmt.Sprintf("%d", timestamp))
 	req.Header.Set("X-Window", fmt.Sprintf("%d", c.window))
-	req.Header.Set("X-Signature", signature)
+	if err := sign(req); err != nil {
+		return nil, err
+	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -156,7 +165,7 @@ func (c *Client) Request(method, path, instruction string, input interface{}, ou
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
-	log.Debug("response", "status", resp.StatusCode, "body", string(respBody))
+	log.DebugContext(ctx, "response", "status", resp.StatusCode, "body", logging.Body(respBody))
 
 	if resp.StatusCode != http.StatusOK {
 		var backpackError struct {
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"context"
+
 	oc "github.com/cordialsys/offchain"
 )
 
@@ -24,12 +26,12 @@ type Asset struct {
 }
 
 // https://docs.backpack.exchange/#tag/Assets/operation/get_assets
-func (c *Client) GetAssets() ([]Asset, error) {
+func (c *Client) GetAssets(ctx context.Context) ([]Asset, error) {
 	var response []Asset
 
 	// No instruction type is specified in the docs, using a generic "assetQuery"
 	// This might need to be updated based on actual API requirements
-	_, err := c.Request("GET", "/api/v1/assets", "assetQuery", nil, &response, nil)
+	_, err := c.Request(ctx, "GET", "/api/v1/assets", "assetQuery", nil, &response, nil)
 	if err != nil {
 		return nil, err
 	}
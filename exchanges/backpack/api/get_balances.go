@@ -1,6 +1,8 @@
 package api
 
 import (
+	"context"
+
 	oc "github.com/cordialsys/offchain"
 )
 
@@ -14,11 +16,11 @@ type Balance struct {
 type BalanceResponse map[string]Balance
 
 // https://docs.backpack.exchange/#tag/Capital/operation/get_balances
-func (c *Client) GetBalances() (BalanceResponse, error) {
+func (c *Client) GetBalances(ctx context.Context) (BalanceResponse, error) {
 	var response BalanceResponse
 
 	// No query parameters or request body needed for this endpoint
-	_, err := c.Request("GET", "/api/v1/capital", "balanceQuery", nil, &response, nil)
+	_, err := c.Request(ctx, "GET", "/api/v1/capital", "balanceQuery", nil, &response, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 
@@ -16,7 +17,7 @@ type DepositAddressResponse struct {
 	Address string `json:"address"`
 }
 
-func (c *Client) GetDepositAddress(req *DepositAddressRequest) (*DepositAddressResponse, error) {
+func (c *Client) GetDepositAddress(ctx context.Context, req *DepositAddressRequest) (*DepositAddressResponse, error) {
 	if req == nil || req.Blockchain == "" {
 		return nil, fmt.Errorf("blockchain is required")
 	}
@@ -25,7 +26,7 @@ func (c *Client) GetDepositAddress(req *DepositAddressRequest) (*DepositAddressR
 	query.Set("blockchain", string(req.Blockchain))
 
 	var response DepositAddressResponse
-	_, err := c.Request("GET", "/wapi/v1/capital/deposit/address", "depositAddressQuery", nil, &response, query)
+	_, err := c.Request(ctx, "GET", "/wapi/v1/capital/deposit/address", "depositAddressQuery", nil, &response, query)
 	if err != nil {
 		return nil, err
 	}
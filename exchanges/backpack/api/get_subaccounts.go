@@ -1,5 +1,7 @@
 package api
 
+import "context"
+
 type SubaccountMapping struct {
 	Id   int    `json:"id"`
 	Name string `json:"name"`
@@ -8,9 +10,9 @@ type SubaccountMapping struct {
 type SubaccountResponse []SubaccountMapping
 
 // No documentation for this endpoint, had to snoop it on the browser
-func (c *Client) GetSubaccounts() (SubaccountResponse, error) {
+func (c *Client) GetSubaccounts(ctx context.Context) (SubaccountResponse, error) {
 	var response SubaccountResponse
-	_, err := c.Request("GET", "/wapi/v1/subaccount", "subaccountQueryAll", nil, &response, nil)
+	_, err := c.Request(ctx, "GET", "/wapi/v1/subaccount", "subaccountQueryAll", nil, &response, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"net/url"
 	"strconv"
 )
@@ -13,7 +14,7 @@ type WithdrawalHistoryRequest struct {
 }
 
 // https://docs.backpack.exchange/#tag/Capital/operation/get_withdrawals
-func (c *Client) GetWithdrawals(req *WithdrawalHistoryRequest) ([]WithdrawalResponse, error) {
+func (c *Client) GetWithdrawals(ctx context.Context, req *WithdrawalHistoryRequest) ([]WithdrawalResponse, error) {
 	query := url.Values{}
 
 	if req != nil {
@@ -32,7 +33,7 @@ func (c *Client) GetWithdrawals(req *WithdrawalHistoryRequest) ([]WithdrawalResp
 	}
 
 	var response []WithdrawalResponse
-	_, err := c.Request("GET", "/wapi/v1/capital/withdrawals", "withdrawalQueryAll", nil, &response, query)
+	_, err := c.Request(ctx, "GET", "/wapi/v1/capital/withdrawals", "withdrawalQueryAll", nil, &response, query)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 
 	oc "github.com/cordialsys/offchain"
@@ -37,7 +38,7 @@ type WithdrawalResponse struct {
 }
 
 // https://docs.backpack.exchange/#tag/Capital/operation/request_withdrawal
-func (c *Client) RequestWithdrawal(req *WithdrawalRequest) (*WithdrawalResponse, error) {
+func (c *Client) RequestWithdrawal(ctx context.Context, req *WithdrawalRequest) (*WithdrawalResponse, error) {
 	if req == nil {
 		return nil, fmt.Errorf("withdrawal request cannot be nil")
 	}
@@ -55,7 +56,7 @@ func (c *Client) RequestWithdrawal(req *WithdrawalRequest) (*WithdrawalResponse,
 	}
 
 	var response WithdrawalResponse
-	_, err := c.Request("POST", "/wapi/v1/capital/withdrawals", "withdraw", req, &response, nil)
+	_, err := c.Request(ctx, "POST", "/wapi/v1/capital/withdrawals", "withdraw", req, &response, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -2,6 +2,7 @@ package backpack
 
 import (
 	"bytes"
+	"context"
 	"crypto/ed25519"
 	"encoding/base64"
 	"fmt"
@@ -18,13 +19,13 @@ type Client struct {
 
 var _ client.Client = &Client{}
 
-func NewClient(config *oc.ExchangeClientConfig, account *oc.Account) (*Client, error) {
-	apiKey, err := account.ApiKeyRef.Load()
+func NewClient(ctx context.Context, config *oc.ExchangeClientConfig, account *oc.Account) (*Client, error) {
+	apiKey, err := account.ApiKeyRef.Load(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load api key: %w", err)
 	}
 
-	secretKey, err := account.SecretKeyRef.Load()
+	secretKey, err := account.SecretKeyRef.Load(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load secret key: %w", err)
 	}
@@ -66,8 +67,8 @@ func NewClient(config *oc.ExchangeClientConfig, account *oc.Account) (*Client, e
 	return &Client{api: apiClient}, nil
 }
 
-func (c *Client) ListAssets() ([]*oc.Asset, error) {
-	response, err := c.api.GetAssets()
+func (c *Client) ListAssets(ctx context.Context) ([]*oc.Asset, error) {
+	response, err := c.api.GetAssets(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get assets: %w", err)
 	}
@@ -93,8 +94,8 @@ func (c *Client) ListAssets() ([]*oc.Asset, error) {
 	return assets, nil
 }
 
-func (c *Client) ListBalances(args client.GetBalanceArgs) ([]*client.BalanceDetail, error) {
-	response, err := c.api.GetBalances()
+func (c *Client) ListBalances(ctx context.Context, args client.GetBalanceArgs) ([]*client.BalanceDetail, error) {
+	response, err := c.api.GetBalances(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get balances: %w", err)
 	}
@@ -113,9 +114,9 @@ func (c *Client) ListBalances(args client.GetBalanceArgs) ([]*client.BalanceDeta
 }
 
 // Need some slight additions to Backpack exchange API to support this
-func (c *Client) CreateAccountTransfer(args client.AccountTransferArgs) (*client.TransferStatus, error) {
+func (c *Client) CreateAccountTransfer(ctx context.Context, args client.AccountTransferArgs) (*client.TransferStatus, error) {
 	// // This currently fails since it's not a supported API endpoint
-	// mapping, err := c.api.GetSubaccounts()
+	// mapping, err := c.api.GetSubaccounts(ctx)
 	// if err != nil {
 	// 	return nil, fmt.Errorf("failed to get subaccounts: %w", err)
 	// }
@@ -125,7 +126,11 @@ func (c *Client) CreateAccountTransfer(args client.AccountTransferArgs) (*client
 	return nil, fmt.Errorf("transfers not currently implemented for Backpack exchange")
 }
 
-func (c *Client) CreateWithdrawal(args client.WithdrawalArgs) (*client.WithdrawalResponse, error) {
+func (c *Client) GetTransferStatus(ctx context.Context, id string) (*client.TransferStatus, error) {
+	return nil, fmt.Errorf("transfers not currently implemented for Backpack exchange")
+}
+
+func (c *Client) CreateWithdrawal(ctx context.Context, args client.WithdrawalArgs) (*client.WithdrawalResponse, error) {
 	request := api.WithdrawalRequest{
 		Address:    args.GetAddress(),
 		Blockchain: args.GetNetwork(),
@@ -133,7 +138,7 @@ func (c *Client) CreateWithdrawal(args client.WithdrawalArgs) (*client.Withdrawa
 		Symbol:     args.GetSymbol(),
 	}
 
-	response, err := c.api.RequestWithdrawal(&request)
+	response, err := c.api.RequestWithdrawal(ctx, &request)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create withdrawal: %w", err)
 	}
@@ -149,12 +154,32 @@ func (c *Client) CreateWithdrawal(args client.WithdrawalArgs) (*client.Withdrawa
 	}, nil
 }
 
-func (c *Client) GetDepositAddress(args client.GetDepositAddressArgs) (oc.Address, error) {
+func (c *Client) EstimateWithdrawal(ctx context.Context, args client.WithdrawalArgs) (*client.WithdrawalQuote, error) {
+	response, err := c.api.GetAssets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assets: %w", err)
+	}
+	for _, asset := range response {
+		if oc.SymbolId(asset.Symbol) != args.GetSymbol() {
+			continue
+		}
+		for _, token := range asset.Tokens {
+			if token.Blockchain != args.GetNetwork() {
+				continue
+			}
+			// backpack charges the fee on top of the withdrawal quantity
+			return client.NewWithdrawalQuote(args, token.WithdrawalFee, args.GetSymbol(), false), nil
+		}
+	}
+	return nil, fmt.Errorf("no withdrawal fee found for %s on network %s", args.GetSymbol(), args.GetNetwork())
+}
+
+func (c *Client) GetDepositAddress(ctx context.Context, args client.GetDepositAddressArgs) (oc.Address, error) {
 	request := api.DepositAddressRequest{
 		Blockchain: args.GetNetwork(),
 	}
 
-	response, err := c.api.GetDepositAddress(&request)
+	response, err := c.api.GetDepositAddress(ctx, &request)
 	if err != nil {
 		return "", fmt.Errorf("failed to get deposit address: %w", err)
 	}
@@ -162,20 +187,15 @@ func (c *Client) GetDepositAddress(args client.GetDepositAddressArgs) (oc.Addres
 	return oc.Address(response.Address), nil
 }
 
-func (c *Client) ListWithdrawalHistory(args client.WithdrawalHistoryArgs) ([]*client.WithdrawalHistory, error) {
+func (c *Client) ListWithdrawalHistory(ctx context.Context, args client.WithdrawalHistoryArgs) ([]*client.WithdrawalHistory, error) {
 	// Prepare request parameters
 	var request api.WithdrawalHistoryRequest
 
 	// Apply filters from args if provided
-	// if args.GetStartTime() != nil {
-	// 	startTime := args.GetStartTime().UnixMilli()
-	// 	request.From = &startTime
-	// }
-
-	// if args.GetEndTime() != nil {
-	// 	endTime := args.GetEndTime().UnixMilli()
-	// 	request.To = &endTime
-	// }
+	if args.GetStartTime() != nil {
+		startTime := args.GetStartTime().UnixMilli()
+		request.From = &startTime
+	}
 
 	if args.GetLimit() > 0 {
 		limit := uint64(args.GetLimit())
@@ -183,7 +203,7 @@ func (c *Client) ListWithdrawalHistory(args client.WithdrawalHistoryArgs) ([]*cl
 	}
 
 	// Get withdrawal history from API
-	response, err := c.api.GetWithdrawals(&request)
+	response, err := c.api.GetWithdrawals(ctx, &request)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get withdrawal history: %w", err)
 	}
@@ -214,3 +234,7 @@ func (c *Client) ListWithdrawalHistory(args client.WithdrawalHistoryArgs) ([]*cl
 
 	return history, nil
 }
+
+func (c *Client) ListTransferHistory(ctx context.Context, args client.TransferHistoryArgs) ([]*client.TransferHistory, error) {
+	return nil, fmt.Errorf("transfer history not currently implemented for Backpack exchange")
+}
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"context"
+
 	oc "github.com/cordialsys/offchain"
 )
 
@@ -48,9 +50,9 @@ type CoinInformation struct {
 }
 
 // https://developers.binance.com/docs/wallet/capital
-func (c *Client) GetAllCoinsInformation() ([]CoinInformation, error) {
+func (c *Client) GetAllCoinsInformation(ctx context.Context) ([]CoinInformation, error) {
 	var response []CoinInformation
-	_, err := c.Request("GET", "/sapi/v1/capital/config/getall", nil, &response, nil)
+	_, err := c.Request(ctx, "GET", "/sapi/v1/capital/config/getall", nil, &response, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/json"
@@ -11,8 +12,19 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/cordialsys/offchain/pkg/logging"
+	"github.com/cordialsys/offchain/pkg/transport"
 )
 
+// rate limits and retries shared by all clients of the venue
+var transportOptions = transport.DefaultOptions().
+	WithRate(10, 20).
+	WithThrottle(transport.Throttles(
+		transport.UsedWeightThrottle("X-Mbx-Used-Weight-1m", 6000, time.Minute),
+		transport.UsedWeightThrottle("X-Sapi-Used-Ip-Weight-1m", 12000, time.Minute),
+	))
+
 type Client struct {
 	apiKey     string
 	secretKey  string
@@ -26,7 +38,7 @@ func NewClient(apiKey, secretKey string) (*Client, error) {
 		apiKey:     apiKey,
 		secretKey:  secretKey,
 		baseURL:    "https://api.binance.com",
-		httpClient: &http.Client{},
+		httpClient: transport.NewClient("binance", transportOptions),
 	}, nil
 }
 
@@ -42,7 +54,7 @@ func (c *Client) sign(params url.Values) string {
 }
 
 // Request makes an authenticated HTTP request to the Binance API
-func (c *Client) Request(method, path string, input interface{}, output interface{}, query url.Values) ([]byte, error) {
+func (c *Client) Request(ctx context.Context, method, path string, input interface{}, output interface{}, query url.Values) ([]byte, error) {
 	method = strings.ToUpper(method)
 	apiUrl := c.baseURL + path
 
@@ -53,18 +65,21 @@ func (c *Client) Request(method, path string, input interface{}, output interfac
 		query = url.Values{}
 	}
 
-	// Add timestamp for signed endpoints
-	query.Set("timestamp", fmt.Sprintf("%d", time.Now().UnixMilli()))
-
-	// Sign the request
-	signature := c.sign(query)
-	query.Set("signature", signature)
-
 	// Append query to path
 	if len(query) > 0 {
 		apiUrl += "?" + query.Encode()
 	}
 
+	// Sign with the current timestamp, again on every retry
+	sign := func(req *http.Request) error {
+		query := req.URL.Query()
+		query.Del("signature")
+		query.Set("timestamp", fmt.Sprintf("%d", time.Now().UnixMilli()))
+		query.Set("signature", c.sign(query))
+		req.URL.RawQuery = query.Encode()
+		return nil
+	}
+
 	var bodyStr string
 	if input != nil {
 		jsonBody, err := json.Marshal(input)
@@ -73,17 +88,19 @@ func (c *Client) Request(method, path string, input interface{}, output interfac
 		}
 		bodyStr = string(jsonBody)
 	}
-	log.Debug("request", "body", bodyStr)
+	log.DebugContext(ctx, "request", "body", logging.Body(bodyStr))
 
-	req, err := http.NewRequest(method, apiUrl, strings.NewReader(bodyStr))
+	req, err := http.NewRequestWithContext(transport.WithSigner(ctx, sign), method, apiUrl, strings.NewReader(bodyStr))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	if err := sign(req); err != nil {
+		return nil, err
+	}
 
 	// Set headers
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-MBX-APIKEY", c.apiKey)
-	fmt.Println("request", req.Header)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -95,7 +112,7 @@ func (c *Client) Request(method, path string, input interface{}, output interfac
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
-	log.Debug("response", "status", resp.StatusCode, "body", string(respBody))
+	log.DebugContext(ctx, "response", "status", resp.StatusCode, "body", logging.Body(respBody))
 
 	if resp.StatusCode != http.StatusOK {
 		var binanceError struct {
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"context"
+)
+
+type ApiRestrictions struct {
+	IpRestrict                     bool  `json:"ipRestrict"`
+	CreateTime                     int64 `json:"createTime"`
+	EnableReading                  bool  `json:"enableReading"`
+	EnableWithdrawals              bool  `json:"enableWithdrawals"`
+	EnableInternalTransfer         bool  `json:"enableInternalTransfer"`
+	PermitsUniversalTransfer       bool  `json:"permitsUniversalTransfer"`
+	EnableSpotAndMarginTrading     bool  `json:"enableSpotAndMarginTrading"`
+	EnableMargin                   bool  `json:"enableMargin"`
+	EnableFutures                  bool  `json:"enableFutures"`
+	EnableVanillaOptions           bool  `json:"enableVanillaOptions"`
+	EnablePortfolioMarginTrading   bool  `json:"enablePortfolioMarginTrading"`
+	TradingAuthorityExpirationTime int64 `json:"tradingAuthorityExpirationTime"`
+}
+
+// https://developers.binance.com/docs/wallet/account/api-key-permission
+func (c *Client) GetApiRestrictions(ctx context.Context) (*ApiRestrictions, error) {
+	var response ApiRestrictions
+	_, err := c.Request(ctx, "GET", "/sapi/v1/account/apiRestrictions", nil, &response, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// The broker endpoints are only available to Binance Link (broker) accounts, and only manage the
+// sub-accounts created through them.
+
+type BrokerSubAccount struct {
+	SubAccountId string `json:"subaccountId"`
+	Email        string `json:"email"`
+	Tag          string `json:"tag"`
+}
+
+// https://developers.binance.com/docs/binance_link/exchange-link/account/Query-Sub-Account
+func (c *Client) GetBrokerSubAccounts(ctx context.Context, page int) ([]BrokerSubAccount, error) {
+	var response []BrokerSubAccount
+	query := url.Values{}
+	query.Set("page", strconv.Itoa(page))
+	query.Set("size", "500")
+	_, err := c.Request(ctx, "GET", "/sapi/v1/broker/subAccount", nil, &response, query)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+type BrokerSubAccountApiKey struct {
+	SubAccountId string `json:"subaccountId"`
+	ApiKey       string `json:"apikey"`
+	// Only returned when the key is created
+	SecretKey    string `json:"secretkey,omitempty"`
+	CanTrade     bool   `json:"canTrade"`
+	MarginTrade  bool   `json:"marginTrade"`
+	FuturesTrade bool   `json:"futuresTrade"`
+}
+
+type CreateBrokerSubAccountApiKeyRequest struct {
+	SubAccountId string
+	CanTrade     bool
+	MarginTrade  bool
+	FuturesTrade bool
+}
+
+// https://developers.binance.com/docs/binance_link/exchange-link/api-management/Create-Api-Key-for-Sub-Account
+func (c *Client) CreateBrokerSubAccountApiKey(ctx context.Context, args *CreateBrokerSubAccountApiKeyRequest) (*BrokerSubAccountApiKey, error) {
+	var response BrokerSubAccountApiKey
+	query := url.Values{}
+	query.Set("subAccountId", args.SubAccountId)
+	query.Set("canTrade", strconv.FormatBool(args.CanTrade))
+	query.Set("marginTrade", strconv.FormatBool(args.MarginTrade))
+	query.Set("futuresTrade", strconv.FormatBool(args.FuturesTrade))
+	_, err := c.Request(ctx, "POST", "/sapi/v1/broker/subAccountApi", nil, &response, query)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// https://developers.binance.com/docs/binance_link/exchange-link/api-management/Query-Sub-Account-Api-Key
+func (c *Client) GetBrokerSubAccountApiKey(ctx context.Context, subAccountId string, apiKey string) ([]BrokerSubAccountApiKey, error) {
+	var response []BrokerSubAccountApiKey
+	query := url.Values{}
+	query.Set("subAccountId", subAccountId)
+	query.Set("subAccountApiKey", apiKey)
+	_, err := c.Request(ctx, "GET", "/sapi/v1/broker/subAccountApi", nil, &response, query)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// https://developers.binance.com/docs/binance_link/exchange-link/api-management/Delete-Sub-Account-Api-Key
+func (c *Client) DeleteBrokerSubAccountApiKey(ctx context.Context, subAccountId string, apiKey string) error {
+	query := url.Values{}
+	query.Set("subAccountId", subAccountId)
+	query.Set("subAccountApiKey", apiKey)
+	_, err := c.Request(ctx, "DELETE", "/sapi/v1/broker/subAccountApi", nil, nil, query)
+	return err
+}
+
+type BrokerSubAccountApiIpRestriction struct {
+	SubAccountId string   `json:"subaccountId"`
+	IpRestrict   string   `json:"ipRestrict"`
+	ApiKey       string   `json:"apikey"`
+	IpList       []string `json:"ipList"`
+	UpdateTime   int64    `json:"updateTime"`
+}
+
+// https://developers.binance.com/docs/binance_link/exchange-link/api-management/Query-Sub-Account-Api-Ip-Restriction
+func (c *Client) GetBrokerSubAccountApiIpRestriction(ctx context.Context, subAccountId string, apiKey string) (*BrokerSubAccountApiIpRestriction, error) {
+	var response BrokerSubAccountApiIpRestriction
+	query := url.Values{}
+	query.Set("subAccountId", subAccountId)
+	query.Set("subAccountApiKey", apiKey)
+	_, err := c.Request(ctx, "GET", "/sapi/v1/broker/subAccountApi/ipRestriction", nil, &response, query)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Restricts the key to the IP addresses.
+// https://developers.binance.com/docs/binance_link/exchange-link/api-management/Update-IP-Restriction-for-Sub-Account-API-key
+func (c *Client) SetBrokerSubAccountApiIpRestriction(ctx context.Context, subAccountId string, apiKey string, ips []string) error {
+	query := url.Values{}
+	query.Set("subAccountId", subAccountId)
+	query.Set("subAccountApiKey", apiKey)
+	// 2 restricts access to the listed addresses
+	query.Set("status", "2")
+	query.Set("ipAddress", strings.Join(ips, ","))
+	_, err := c.Request(ctx, "POST", "/sapi/v2/broker/subAccountApi/ipRestriction", nil, nil, query)
+	return err
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"time"
@@ -22,7 +23,7 @@ type DepositAddressResponse struct {
 }
 
 // https://developers.binance.com/docs/wallet/capital/deposite-address
-func (c *Client) GetDepositAddress(args *DepositAddressRequest) (*DepositAddressResponse, error) {
+func (c *Client) GetDepositAddress(ctx context.Context, args *DepositAddressRequest) (*DepositAddressResponse, error) {
 	var response DepositAddressResponse
 	query := url.Values{}
 
@@ -38,7 +39,7 @@ func (c *Client) GetDepositAddress(args *DepositAddressRequest) (*DepositAddress
 		query.Set("amount", args.Amount.String())
 	}
 
-	_, err := c.Request("GET", "/sapi/v1/capital/deposit/address", nil, &response, query)
+	_, err := c.Request(ctx, "GET", "/sapi/v1/capital/deposit/address", nil, &response, query)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"time"
@@ -24,7 +25,7 @@ type UserAssetInfo struct {
 }
 
 // https://developers.binance.com/docs/wallet/asset/user-assets
-func (c *Client) GetUserAsset(args *UserAssetRequest) ([]UserAssetInfo, error) {
+func (c *Client) GetUserAsset(ctx context.Context, args *UserAssetRequest) ([]UserAssetInfo, error) {
 	var response []UserAssetInfo
 	query := url.Values{}
 
@@ -42,7 +43,7 @@ func (c *Client) GetUserAsset(args *UserAssetRequest) ([]UserAssetInfo, error) {
 	}
 	query.Set("timestamp", fmt.Sprintf("%d", time.Now().UnixNano()/1000))
 
-	_, err := c.Request("POST", "/sapi/v3/asset/getUserAsset", nil, &response, query)
+	_, err := c.Request(ctx, "POST", "/sapi/v3/asset/getUserAsset", nil, &response, query)
 	if err != nil {
 		return nil, err
 	}
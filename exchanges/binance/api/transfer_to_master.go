@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"strconv"
@@ -21,7 +22,7 @@ type SubToMasterTransferResponse struct {
 
 // SubToMasterTransfer transfers assets from a sub-account to the master account
 // https://binance-docs.github.io/apidocs/spot/en/#transfer-to-master-for-sub-account
-func (c *Client) SubToMasterTransfer(args *SubToMasterTransferRequest) (*SubToMasterTransferResponse, error) {
+func (c *Client) SubToMasterTransfer(ctx context.Context, args *SubToMasterTransferRequest) (*SubToMasterTransferResponse, error) {
 	if args == nil {
 		return nil, fmt.Errorf("args cannot be nil")
 	}
@@ -39,7 +40,7 @@ func (c *Client) SubToMasterTransfer(args *SubToMasterTransferRequest) (*SubToMa
 		query.Set("recvWindow", strconv.FormatInt(*args.RecvWindow, 10))
 	}
 
-	_, err := c.Request("POST", "/sapi/v1/sub-account/transfer/subToMaster", nil, &response, query)
+	_, err := c.Request(ctx, "POST", "/sapi/v1/sub-account/transfer/subToMaster", nil, &response, query)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"strconv"
@@ -21,7 +22,7 @@ type SubToSubTransferResponse struct {
 }
 
 // https://developers.binance.com/docs/sub_account/asset-management/Transfer-to-Master
-func (c *Client) SubToSubTransfer(args *SubToSubTransferRequest) (*SubToSubTransferResponse, error) {
+func (c *Client) SubToSubTransfer(ctx context.Context, args *SubToSubTransferRequest) (*SubToSubTransferResponse, error) {
 	if args == nil {
 		return nil, fmt.Errorf("args cannot be nil")
 	}
@@ -40,7 +41,7 @@ func (c *Client) SubToSubTransfer(args *SubToSubTransferRequest) (*SubToSubTrans
 		query.Set("recvWindow", strconv.FormatInt(*args.RecvWindow, 10))
 	}
 
-	_, err := c.Request("POST", "/sapi/v1/sub-account/transfer/subToSub", nil, &response, query)
+	_, err := c.Request(ctx, "POST", "/sapi/v1/sub-account/transfer/subToSub", nil, &response, query)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"time"
@@ -36,7 +37,7 @@ type UniversalTransferResponse struct {
 
 // UniversalTransfer performs a universal transfer between accounts
 // https://developers.binance.com/docs/sub_account/asset-management/Universal-Transfer
-func (c *Client) UniversalTransfer(args *UniversalTransferRequest) (*UniversalTransferResponse, error) {
+func (c *Client) UniversalTransfer(ctx context.Context, args *UniversalTransferRequest) (*UniversalTransferResponse, error) {
 	var response UniversalTransferResponse
 	query := url.Values{}
 
@@ -61,7 +62,7 @@ func (c *Client) UniversalTransfer(args *UniversalTransferRequest) (*UniversalTr
 		query.Set("symbol", string(args.Symbol))
 	}
 
-	_, err := c.Request("POST", "/sapi/v1/sub-account/universalTransfer", nil, &response, query)
+	_, err := c.Request(ctx, "POST", "/sapi/v1/sub-account/universalTransfer", nil, &response, query)
 	if err != nil {
 		return nil, err
 	}
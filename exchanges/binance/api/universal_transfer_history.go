@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	oc "github.com/cordialsys/offchain"
+)
+
+type UniversalTransferStatus string
+
+const (
+	UniversalTransferStatusProcess UniversalTransferStatus = "PROCESS"
+	UniversalTransferStatusSuccess UniversalTransferStatus = "SUCCESS"
+	UniversalTransferStatusFailure UniversalTransferStatus = "FAILURE"
+)
+
+type UniversalTransferHistoryRequest struct {
+	FromEmail    oc.AccountId `json:"fromEmail,omitempty"`
+	ToEmail      oc.AccountId `json:"toEmail,omitempty"`
+	ClientTranId string       `json:"clientTranId,omitempty"`
+	StartTime    *int64       `json:"startTime,omitempty"`
+	EndTime      *int64       `json:"endTime,omitempty"`
+	Page         *int         `json:"page,omitempty"`
+	Limit        *int         `json:"limit,omitempty"`
+}
+
+type UniversalTransferRecord struct {
+	TranId          int64                   `json:"tranId"`
+	FromEmail       oc.AccountId            `json:"fromEmail"`
+	ToEmail         oc.AccountId            `json:"toEmail"`
+	Asset           oc.SymbolId             `json:"asset"`
+	Amount          oc.Amount               `json:"amount"`
+	CreateTimeStamp int64                   `json:"createTimeStamp"`
+	FromAccountType AccountType             `json:"fromAccountType"`
+	ToAccountType   AccountType             `json:"toAccountType"`
+	Status          UniversalTransferStatus `json:"status"`
+	ClientTranId    string                  `json:"clientTranId"`
+}
+
+type UniversalTransferHistoryResponse struct {
+	Result     []UniversalTransferRecord `json:"result"`
+	TotalCount int                       `json:"totalCount"`
+}
+
+// GetUniversalTransferHistory queries the universal transfer history of the master account
+// https://developers.binance.com/docs/sub_account/asset-management/Query-Universal-Transfer-History
+func (c *Client) GetUniversalTransferHistory(ctx context.Context, args *UniversalTransferHistoryRequest) (*UniversalTransferHistoryResponse, error) {
+	var response UniversalTransferHistoryResponse
+	query := url.Values{}
+	query.Set("timestamp", fmt.Sprintf("%d", time.Now().UnixNano()/1000))
+
+	if args != nil {
+		if args.FromEmail != "" {
+			query.Set("fromEmail", string(args.FromEmail))
+		}
+		if args.ToEmail != "" {
+			query.Set("toEmail", string(args.ToEmail))
+		}
+		if args.ClientTranId != "" {
+			query.Set("clientTranId", args.ClientTranId)
+		}
+		if args.StartTime != nil {
+			query.Set("startTime", strconv.FormatInt(*args.StartTime, 10))
+		}
+		if args.EndTime != nil {
+			query.Set("endTime", strconv.FormatInt(*args.EndTime, 10))
+		}
+		if args.Page != nil {
+			query.Set("page", strconv.Itoa(*args.Page))
+		}
+		if args.Limit != nil {
+			query.Set("limit", strconv.Itoa(*args.Limit))
+		}
+	}
+
+	_, err := c.Request(ctx, "GET", "/sapi/v1/sub-account/universalTransfer", nil, &response, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"time"
@@ -31,7 +32,7 @@ const WalletTypeSpot WalletType = 0
 const WalletTypeFunding WalletType = 1
 
 // https://developers.binance.com/docs/wallet/capital/withdraw
-func (c *Client) Withdraw(args *WithdrawRequest) (*WithdrawResponse, error) {
+func (c *Client) Withdraw(ctx context.Context, args *WithdrawRequest) (*WithdrawResponse, error) {
 	var response WithdrawResponse
 	query := url.Values{}
 
@@ -65,7 +66,7 @@ func (c *Client) Withdraw(args *WithdrawRequest) (*WithdrawResponse, error) {
 		query.Set("walletType", fmt.Sprintf("%d", *args.WalletType))
 	}
 
-	_, err := c.Request("POST", "/sapi/v1/capital/withdraw/apply", nil, &response, query)
+	_, err := c.Request(ctx, "POST", "/sapi/v1/capital/withdraw/apply", nil, &response, query)
 	if err != nil {
 		return nil, err
 	}
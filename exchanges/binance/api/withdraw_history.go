@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"strconv"
@@ -86,7 +87,7 @@ type WithdrawalRecord struct {
 }
 
 // https://developers.binance.com/docs/wallet/capital/withdraw-history
-func (c *Client) GetWithdrawalHistory(args *WithdrawalHistoryRequest) ([]WithdrawalRecord, error) {
+func (c *Client) GetWithdrawalHistory(ctx context.Context, args *WithdrawalHistoryRequest) ([]WithdrawalRecord, error) {
 	var response []WithdrawalRecord
 	query := url.Values{}
 	query.Set("timestamp", fmt.Sprintf("%d", time.Now().UnixNano()/1000))
@@ -126,7 +127,7 @@ func (c *Client) GetWithdrawalHistory(args *WithdrawalHistoryRequest) ([]Withdra
 		}
 	}
 
-	_, err := c.Request("GET", "/sapi/v1/capital/withdraw/history", nil, &response, query)
+	_, err := c.Request(ctx, "GET", "/sapi/v1/capital/withdraw/history", nil, &response, query)
 	if err != nil {
 		return nil, err
 	}
@@ -1,7 +1,11 @@
 package binance
 
 import (
+	"context"
 	"fmt"
+	"slices"
+	"strconv"
+	"time"
 
 	oc "github.com/cordialsys/offchain"
 	"github.com/cordialsys/offchain/client"
@@ -14,13 +18,15 @@ type Client struct {
 }
 
 var _ client.Client = &Client{}
+var _ client.ApiKeyInspector = &Client{}
+var _ client.ApiKeyManager = &Client{}
 
-func NewClient(config *oc.ExchangeClientConfig, account *oc.Account) (*Client, error) {
-	apiKey, err := account.ApiKeyRef.Load()
+func NewClient(ctx context.Context, config *oc.ExchangeClientConfig, account *oc.Account) (*Client, error) {
+	apiKey, err := account.ApiKeyRef.Load(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load api key: %w", err)
 	}
-	secretKey, err := account.SecretKeyRef.Load()
+	secretKey, err := account.SecretKeyRef.Load(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load secret key: %w", err)
 	}
@@ -31,8 +37,8 @@ func NewClient(config *oc.ExchangeClientConfig, account *oc.Account) (*Client, e
 	return &Client{api: api}, nil
 }
 
-func (c *Client) ListAssets() ([]*oc.Asset, error) {
-	response, err := c.api.GetAllCoinsInformation()
+func (c *Client) ListAssets(ctx context.Context) ([]*oc.Asset, error) {
+	response, err := c.api.GetAllCoinsInformation(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get assets: %w", err)
 	}
@@ -50,8 +56,8 @@ func (c *Client) ListAssets() ([]*oc.Asset, error) {
 	return assets, nil
 }
 
-func (c *Client) ListBalances(args client.GetBalanceArgs) ([]*client.BalanceDetail, error) {
-	response, err := c.api.GetUserAsset(nil)
+func (c *Client) ListBalances(ctx context.Context, args client.GetBalanceArgs) ([]*client.BalanceDetail, error) {
+	response, err := c.api.GetUserAsset(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get assets: %w", err)
 	}
@@ -71,7 +77,7 @@ func (c *Client) ListBalances(args client.GetBalanceArgs) ([]*client.BalanceDeta
 	return balances, nil
 }
 
-func (c *Client) CreateAccountTransfer(args client.AccountTransferArgs) (*client.TransferStatus, error) {
+func (c *Client) CreateAccountTransfer(ctx context.Context, args client.AccountTransferArgs) (*client.TransferStatus, error) {
 	from, fromType := args.GetFrom()
 	to, toType := args.GetTo()
 	req := api.UniversalTransferRequest{
@@ -83,7 +89,7 @@ func (c *Client) CreateAccountTransfer(args client.AccountTransferArgs) (*client
 		Amount:          args.GetAmount(),
 	}
 
-	response, err := c.api.UniversalTransfer(&req)
+	response, err := c.api.UniversalTransfer(ctx, &req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create account transfer: %w", err)
 	}
@@ -93,7 +99,41 @@ func (c *Client) CreateAccountTransfer(args client.AccountTransferArgs) (*client
 	}, nil
 }
 
-func (c *Client) CreateWithdrawal(args client.WithdrawalArgs) (*client.WithdrawalResponse, error) {
+func (c *Client) GetTransferStatus(ctx context.Context, id string) (*client.TransferStatus, error) {
+	// binance has no lookup by transfer ID, so search the recent history
+	limit := 500
+	for page := 1; ; page++ {
+		response, err := c.api.GetUniversalTransferHistory(ctx, &api.UniversalTransferHistoryRequest{
+			Page:  &page,
+			Limit: &limit,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get transfer history: %w", err)
+		}
+		for _, record := range response.Result {
+			if fmt.Sprintf("%d", record.TranId) != id {
+				continue
+			}
+			status := client.OperationStatusPending
+			switch record.Status {
+			case api.UniversalTransferStatusSuccess:
+				status = client.OperationStatusSuccess
+			case api.UniversalTransferStatusFailure:
+				status = client.OperationStatusFailed
+			}
+			return &client.TransferStatus{
+				ID:     id,
+				Status: status,
+			}, nil
+		}
+		if len(response.Result) < limit || page*limit >= response.TotalCount {
+			break
+		}
+	}
+	return nil, fmt.Errorf("transfer %s not found in recent transfer history", id)
+}
+
+func (c *Client) CreateWithdrawal(ctx context.Context, args client.WithdrawalArgs) (*client.WithdrawalResponse, error) {
 	req := api.WithdrawRequest{
 		Coin:    args.GetSymbol(),
 		Network: args.GetNetwork(),
@@ -101,7 +141,7 @@ func (c *Client) CreateWithdrawal(args client.WithdrawalArgs) (*client.Withdrawa
 		Amount:  args.GetAmount(),
 	}
 
-	response, err := c.api.Withdraw(&req)
+	response, err := c.api.Withdraw(ctx, &req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create withdrawal: %w", err)
 	}
@@ -111,22 +151,54 @@ func (c *Client) CreateWithdrawal(args client.WithdrawalArgs) (*client.Withdrawa
 	}, nil
 }
 
-func (c *Client) GetDepositAddress(args client.GetDepositAddressArgs) (oc.Address, error) {
+func (c *Client) EstimateWithdrawal(ctx context.Context, args client.WithdrawalArgs) (*client.WithdrawalQuote, error) {
+	response, err := c.api.GetAllCoinsInformation(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assets: %w", err)
+	}
+	for _, coin := range response {
+		if coin.Coin != args.GetSymbol() {
+			continue
+		}
+		for _, network := range coin.NetworkList {
+			if network.Network != args.GetNetwork() {
+				continue
+			}
+			fee, err := oc.NewAmountFromString(network.WithdrawFee)
+			if err != nil {
+				return nil, fmt.Errorf("invalid withdrawal fee %s: %w", network.WithdrawFee, err)
+			}
+			// binance deducts the fee from the withdrawal amount
+			return client.NewWithdrawalQuote(args, fee, coin.Coin, true), nil
+		}
+	}
+	return nil, fmt.Errorf("no withdrawal fee found for %s on network %s", args.GetSymbol(), args.GetNetwork())
+}
+
+func (c *Client) GetDepositAddress(ctx context.Context, args client.GetDepositAddressArgs) (oc.Address, error) {
 	request := api.DepositAddressRequest{
 		Coin:    args.GetSymbol(),
 		Network: args.GetNetwork(),
 		Amount:  nil,
 	}
 
-	response, err := c.api.GetDepositAddress(&request)
+	response, err := c.api.GetDepositAddress(ctx, &request)
 	if err != nil {
 		return "", fmt.Errorf("failed to get deposit address: %w", err)
 	}
 	return response.Address, nil
 }
 
-func (c *Client) ListWithdrawalHistory(args client.WithdrawalHistoryArgs) ([]*client.WithdrawalHistory, error) {
-	response, err := c.api.GetWithdrawalHistory(&api.WithdrawalHistoryRequest{})
+func (c *Client) ListWithdrawalHistory(ctx context.Context, args client.WithdrawalHistoryArgs) ([]*client.WithdrawalHistory, error) {
+	request := &api.WithdrawalHistoryRequest{}
+	if args.GetId() != "" {
+		request.IdList = []string{args.GetId()}
+	}
+	if args.GetStartTime() != nil {
+		startTime := args.GetStartTime().UnixMilli()
+		request.StartTime = &startTime
+	}
+	response, err := c.api.GetWithdrawalHistory(ctx, request)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get withdrawal history: %w", err)
 	}
@@ -156,3 +228,219 @@ func (c *Client) ListWithdrawalHistory(args client.WithdrawalHistoryArgs) ([]*cl
 	}
 	return history, nil
 }
+
+// binance lists universal transfers within 30 days of the end time, and keeps them for 6 months
+const (
+	transferHistoryWindow   = 30 * 24 * time.Hour
+	transferHistoryLookback = 180 * 24 * time.Hour
+)
+
+func (c *Client) ListTransferHistory(ctx context.Context, args client.TransferHistoryArgs) ([]*client.TransferHistory, error) {
+	cursor, err := args.GetCursor()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UnixMilli()
+	end := now
+	if cursor != nil {
+		end = cursor.Before
+	}
+
+	// search back a window at a time until the page is full, as the windows may be empty
+	records := []api.UniversalTransferRecord{}
+	for end > now-transferHistoryLookback.Milliseconds() && len(records) < args.GetLimit()+cursor.SeenCount() {
+		start := end - transferHistoryWindow.Milliseconds() + 1
+		for page := 1; ; page++ {
+			limit := 500
+			request := api.UniversalTransferHistoryRequest{
+				StartTime: &start,
+				EndTime:   &end,
+				Page:      &page,
+				Limit:     &limit,
+			}
+			response, err := c.api.GetUniversalTransferHistory(ctx, &request)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get transfer history: %w", err)
+			}
+			records = append(records, response.Result...)
+			if len(response.Result) < limit {
+				break
+			}
+		}
+		end = start - 1
+	}
+
+	history := []*client.TransferHistory{}
+	for _, record := range records {
+		status := client.OperationStatusPending
+		switch record.Status {
+		case api.UniversalTransferStatusSuccess:
+			status = client.OperationStatusSuccess
+		case api.UniversalTransferStatusFailure:
+			status = client.OperationStatusFailed
+		}
+		notes := map[string]string{}
+		if record.ClientTranId != "" {
+			notes["clientTranId"] = record.ClientTranId
+		}
+		history = append(history, &client.TransferHistory{
+			ID:        fmt.Sprintf("%d", record.TranId),
+			Status:    status,
+			Symbol:    record.Asset,
+			Amount:    record.Amount,
+			From:      record.FromEmail,
+			To:        record.ToEmail,
+			FromType:  oc.AccountType(record.FromAccountType),
+			ToType:    oc.AccountType(record.ToAccountType),
+			Timestamp: record.CreateTimeStamp,
+			Comment:   string(record.Status),
+			Notes:     notes,
+		})
+	}
+	return client.PageTransferHistory(history, cursor, args.GetLimit()), nil
+}
+
+func (c *Client) GetApiKeyInfo(ctx context.Context) (*client.ApiKeyInfo, error) {
+	restrictions, err := c.api.GetApiRestrictions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	permissions := []string{}
+	for name, enabled := range map[string]bool{
+		"reading":            restrictions.EnableReading,
+		"withdrawals":        restrictions.EnableWithdrawals,
+		"internal_transfer":  restrictions.EnableInternalTransfer,
+		"universal_transfer": restrictions.PermitsUniversalTransfer,
+		"spot_and_margin":    restrictions.EnableSpotAndMarginTrading,
+		"margin":             restrictions.EnableMargin,
+		"futures":            restrictions.EnableFutures,
+		"vanilla_options":    restrictions.EnableVanillaOptions,
+		"portfolio_margin":   restrictions.EnablePortfolioMarginTrading,
+	} {
+		if enabled {
+			permissions = append(permissions, name)
+		}
+	}
+	slices.Sort(permissions)
+	info := &client.ApiKeyInfo{
+		Permissions:  permissions,
+		ReadOnly:     slices.Equal(permissions, []string{"reading"}),
+		Withdraw:     restrictions.EnableWithdrawals,
+		IpRestricted: restrictions.IpRestrict,
+	}
+	if restrictions.TradingAuthorityExpirationTime > 0 {
+		expires := time.UnixMilli(restrictions.TradingAuthorityExpirationTime).UTC()
+		info.ExpiresAt = &expires
+	}
+	if restrictions.CreateTime > 0 {
+		created := time.UnixMilli(restrictions.CreateTime).UTC()
+		info.CreatedAt = &created
+	}
+	return info, nil
+}
+
+// brokerSubAccountId returns the id of a broker sub-account, which may be configured by email.
+func (c *Client) brokerSubAccountId(ctx context.Context, subaccount oc.AccountId) (string, error) {
+	if _, err := strconv.ParseUint(string(subaccount), 10, 64); err == nil {
+		return string(subaccount), nil
+	}
+	for page := 1; ; page++ {
+		subaccounts, err := c.api.GetBrokerSubAccounts(ctx, page)
+		if err != nil {
+			return "", fmt.Errorf("api keys can only be managed for broker sub-accounts: %w", err)
+		}
+		for _, sub := range subaccounts {
+			if sub.Email == string(subaccount) {
+				return sub.SubAccountId, nil
+			}
+		}
+		if len(subaccounts) == 0 {
+			return "", fmt.Errorf("broker sub-account %s not found", subaccount)
+		}
+	}
+}
+
+// The permissions reported by GetApiKeyInfo that broker sub-account keys can be created with
+var brokerKeyPermissions = []string{"reading", "spot_and_margin", "margin", "futures"}
+
+func (c *Client) GetSubAccountApiKeyInfo(ctx context.Context, subaccount oc.AccountId, apiKey string) (*client.ApiKeyInfo, error) {
+	subAccountId, err := c.brokerSubAccountId(ctx, subaccount)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := c.api.GetBrokerSubAccountApiKey(ctx, subAccountId, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("api key not found for sub-account %s", subaccount)
+	}
+	restriction, err := c.api.GetBrokerSubAccountApiIpRestriction(ctx, subAccountId, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	permissions := []string{"reading"}
+	for name, enabled := range map[string]bool{
+		"spot_and_margin": keys[0].CanTrade,
+		"margin":          keys[0].MarginTrade,
+		"futures":         keys[0].FuturesTrade,
+	} {
+		if enabled {
+			permissions = append(permissions, name)
+		}
+	}
+	slices.Sort(permissions)
+	return &client.ApiKeyInfo{
+		Permissions:  permissions,
+		ReadOnly:     len(permissions) == 1,
+		IpRestricted: restriction.IpRestrict == "true",
+		Ips:          restriction.IpList,
+	}, nil
+}
+
+func (c *Client) CreateSubAccountApiKey(ctx context.Context, args client.CreateApiKeyArgs) (*client.SubAccountApiKey, error) {
+	permissions := args.GetPermissions()
+	for _, permission := range permissions {
+		if !slices.Contains(brokerKeyPermissions, permission) {
+			return nil, fmt.Errorf("broker sub-account api keys can't be created with the %s permission", permission)
+		}
+	}
+	subAccountId, err := c.brokerSubAccountId(ctx, args.GetSubAccount())
+	if err != nil {
+		return nil, err
+	}
+	key, err := c.api.CreateBrokerSubAccountApiKey(ctx, &api.CreateBrokerSubAccountApiKeyRequest{
+		SubAccountId: subAccountId,
+		CanTrade:     slices.Contains(permissions, "spot_and_margin"),
+		MarginTrade:  slices.Contains(permissions, "margin"),
+		FuturesTrade: slices.Contains(permissions, "futures"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if key.ApiKey == "" {
+		return nil, fmt.Errorf("no api key returned")
+	}
+	// the key shouldn't be used if it can't be restricted like the previous one
+	if len(args.GetIps()) > 0 {
+		err := c.api.SetBrokerSubAccountApiIpRestriction(ctx, subAccountId, key.ApiKey, args.GetIps())
+		if err != nil {
+			if deleteErr := c.api.DeleteBrokerSubAccountApiKey(ctx, subAccountId, key.ApiKey); deleteErr != nil {
+				return nil, fmt.Errorf("%w (and failed to delete the new api key %s: %v)", err, key.ApiKey, deleteErr)
+			}
+			return nil, err
+		}
+	}
+	return &client.SubAccountApiKey{
+		ApiKey:    key.ApiKey,
+		SecretKey: key.SecretKey,
+	}, nil
+}
+
+func (c *Client) DeleteSubAccountApiKey(ctx context.Context, subaccount oc.AccountId, apiKey string) error {
+	subAccountId, err := c.brokerSubAccountId(ctx, subaccount)
+	if err != nil {
+		return err
+	}
+	return c.api.DeleteBrokerSubAccountApiKey(ctx, subAccountId, apiKey)
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
@@ -14,12 +15,20 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/cordialsys/offchain/pkg/logging"
+	"github.com/cordialsys/offchain/pkg/transport"
 )
 
 const (
 	BaseURL = "https://api.binance.us"
 )
 
+// rate limits and retries shared by all clients of the venue
+var transportOptions = transport.DefaultOptions().
+	WithRate(10, 20).
+	WithThrottle(transport.UsedWeightThrottle("X-Mbx-Used-Weight-1m", 1200, time.Minute))
+
 type Client struct {
 	apiKey     string
 	secretKey  string
@@ -31,7 +40,7 @@ func NewClient(apiKey, secretKey string) *Client {
 	return &Client{
 		apiKey:     apiKey,
 		secretKey:  secretKey,
-		httpClient: &http.Client{},
+		httpClient: transport.NewClient("binanceus", transportOptions),
 		baseURL:    BaseURL,
 	}
 }
@@ -40,8 +49,6 @@ func NewClient(apiKey, secretKey string) *Client {
 func (c *Client) signRequest(queryString string, body []byte) string {
 	// Create HMAC SHA256 signature
 	h := hmac.New(sha256.New, []byte(c.secretKey))
-	fmt.Println("queryString", queryString)
-	fmt.Println("body", string(body))
 	h.Write([]byte(queryString))
 	h.Write(body)
 	signature := hex.EncodeToString(h.Sum(nil))
@@ -79,7 +86,7 @@ func (c *Client) createQueryString(query url.Values) string {
 	return builder.String()
 }
 
-func (c *Client) Request(method, path string, body interface{}, output interface{}, query url.Values) ([]byte, error) {
+func (c *Client) Request(ctx context.Context, method, path string, body interface{}, output interface{}, query url.Values) ([]byte, error) {
 	endpoint := c.baseURL + path
 	var reqBody []byte
 	var err error
@@ -92,27 +99,31 @@ func (c *Client) Request(method, path string, body interface{}, output interface
 		}
 	}
 
-	// Create query string and signature
-	queryString := c.createQueryString(query)
-	signature := c.signRequest(queryString, reqBody)
-
-	// Add signature to query parameters
-	if query == nil {
-		query = url.Values{}
-	}
-	query.Set("signature", signature)
-
 	// Create full URL with query parameters
 	fullURL := endpoint
 	if len(query) > 0 {
 		fullURL += "?" + query.Encode()
 	}
 
+	// Sign with the current timestamp, again on every retry
+	sign := func(req *http.Request) error {
+		query := req.URL.Query()
+		query.Del("signature")
+		query.Del("timestamp")
+		queryString := c.createQueryString(query)
+		query.Set("signature", c.signRequest(queryString, reqBody))
+		req.URL.RawQuery = query.Encode()
+		return nil
+	}
+
 	// Create request
-	req, err := http.NewRequest(method, fullURL, strings.NewReader(string(reqBody)))
+	req, err := http.NewRequestWithContext(transport.WithSigner(ctx, sign), method, fullURL, strings.NewReader(string(reqBody)))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	if err := sign(req); err != nil {
+		return nil, err
+	}
 
 	// Set headers
 	req.Header.Set("X-MBX-APIKEY", c.apiKey)
@@ -121,10 +132,10 @@ func (c *Client) Request(method, path string, body interface{}, output interface
 	}
 
 	// Execute request
-	slog.Debug("sending request",
+	slog.DebugContext(ctx, "sending request",
 		"method", method,
-		"url", fullURL,
-		"body", string(reqBody),
+		"url", req.URL,
+		"body", logging.Body(reqBody),
 	)
 
 	resp, err := c.httpClient.Do(req)
@@ -139,9 +150,9 @@ func (c *Client) Request(method, path string, body interface{}, output interface
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	slog.Debug("received response",
+	slog.DebugContext(ctx, "received response",
 		"status", resp.StatusCode,
-		"body", string(respBody),
+		"body", logging.Body(respBody),
 	)
 
 	// Check response status
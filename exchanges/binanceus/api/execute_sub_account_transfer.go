@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"strconv"
@@ -24,7 +25,7 @@ type SubAccountTransferResponse struct {
 
 // SubAccountTransfer executes an asset transfer between the master account and a sub-account
 // https://docs.binance.us/#execute-sub-account-transfer
-func (c *Client) SubAccountTransfer(args *SubAccountTransferRequest) (*SubAccountTransferResponse, error) {
+func (c *Client) SubAccountTransfer(ctx context.Context, args *SubAccountTransferRequest) (*SubAccountTransferResponse, error) {
 	if args == nil {
 		return nil, fmt.Errorf("args cannot be nil")
 	}
@@ -39,7 +40,7 @@ func (c *Client) SubAccountTransfer(args *SubAccountTransferRequest) (*SubAccoun
 	query.Set("amount", args.Amount.String())
 	query.Set("timestamp", strconv.FormatInt(args.TimestampMillis, 10))
 
-	_, err := c.Request("POST", "/sapi/v3/sub-account/transfer", nil, &response, query)
+	_, err := c.Request(ctx, "POST", "/sapi/v3/sub-account/transfer", nil, &response, query)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"strconv"
@@ -52,7 +53,7 @@ type GetAssetConfigRequest struct {
 }
 
 // https://docs.binance.us/#asset-fees-amp-wallet-status
-func (c *Client) GetAssetConfig(args *GetAssetConfigRequest) ([]AssetConfig, error) {
+func (c *Client) GetAssetConfig(ctx context.Context, args *GetAssetConfigRequest) ([]AssetConfig, error) {
 	var response []AssetConfig
 	query := url.Values{}
 	query.Set("timestamp", strconv.FormatInt(args.TimestampMillis, 10))
@@ -64,7 +65,7 @@ func (c *Client) GetAssetConfig(args *GetAssetConfigRequest) ([]AssetConfig, err
 		query.Set("recvWindow", strconv.FormatInt(*args.RecvWindow, 10))
 	}
 
-	_, err := c.Request("GET", "/sapi/v1/capital/config/getall", nil, &response, query)
+	_, err := c.Request(ctx, "GET", "/sapi/v1/capital/config/getall", nil, &response, query)
 	if err != nil {
 		return nil, err
 	}
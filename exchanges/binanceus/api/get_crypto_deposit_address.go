@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"strconv"
@@ -23,7 +24,7 @@ type DepositAddressResponse struct {
 }
 
 // https://docs.binance.us/#get-crypto-deposit-address
-func (c *Client) GetDepositAddress(args *GetDepositAddressRequest) (*DepositAddressResponse, error) {
+func (c *Client) GetDepositAddress(ctx context.Context, args *GetDepositAddressRequest) (*DepositAddressResponse, error) {
 	if args == nil {
 		return nil, fmt.Errorf("args cannot be nil")
 	}
@@ -47,7 +48,7 @@ func (c *Client) GetDepositAddress(args *GetDepositAddressRequest) (*DepositAddr
 		query.Set("recvWindow", strconv.FormatInt(*args.RecvWindow, 10))
 	}
 
-	_, err := c.Request("GET", "/sapi/v1/capital/deposit/address", nil, &response, query)
+	_, err := c.Request(ctx, "GET", "/sapi/v1/capital/deposit/address", nil, &response, query)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"strconv"
@@ -94,7 +95,7 @@ type FiatWithdrawalHistoryResponse struct {
 }
 
 // GetCryptoWithdrawalHistory retrieves crypto withdrawal history
-func (c *Client) GetCryptoWithdrawalHistory(args *GetCryptoWithdrawalHistoryRequest) ([]CryptoWithdrawalRecord, error) {
+func (c *Client) GetCryptoWithdrawalHistory(ctx context.Context, args *GetCryptoWithdrawalHistoryRequest) ([]CryptoWithdrawalRecord, error) {
 	var response []CryptoWithdrawalRecord
 	query := url.Values{}
 	query.Set("timestamp", strconv.FormatInt(args.TimestampMillis, 10))
@@ -131,7 +132,7 @@ func (c *Client) GetCryptoWithdrawalHistory(args *GetCryptoWithdrawalHistoryRequ
 		}
 	}
 
-	_, err := c.Request("GET", "/sapi/v1/capital/withdraw/history", nil, &response, query)
+	_, err := c.Request(ctx, "GET", "/sapi/v1/capital/withdraw/history", nil, &response, query)
 	if err != nil {
 		return nil, err
 	}
@@ -140,7 +141,7 @@ func (c *Client) GetCryptoWithdrawalHistory(args *GetCryptoWithdrawalHistoryRequ
 }
 
 // GetFiatWithdrawalHistory retrieves fiat withdrawal history
-func (c *Client) GetFiatWithdrawalHistory(args *FiatWithdrawalHistoryRequest) (*FiatWithdrawalHistoryResponse, error) {
+func (c *Client) GetFiatWithdrawalHistory(ctx context.Context, args *FiatWithdrawalHistoryRequest) (*FiatWithdrawalHistoryResponse, error) {
 	var response FiatWithdrawalHistoryResponse
 	query := url.Values{}
 
@@ -174,7 +175,7 @@ func (c *Client) GetFiatWithdrawalHistory(args *FiatWithdrawalHistoryRequest) (*
 		}
 	}
 
-	_, err := c.Request("GET", "/sapi/v1/fiatpayment/query/withdraw/history", nil, &response, query)
+	_, err := c.Request(ctx, "GET", "/sapi/v1/fiatpayment/query/withdraw/history", nil, &response, query)
 	if err != nil {
 		return nil, err
 	}
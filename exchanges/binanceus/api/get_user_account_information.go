@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"net/url"
 	"strconv"
 
@@ -49,7 +50,7 @@ type GetAccountRequest struct {
 }
 
 // https://docs.binance.us/#get-current-account-information-user_data
-func (c *Client) GetAccount(args *GetAccountRequest) (*AccountResponse, error) {
+func (c *Client) GetAccount(ctx context.Context, args *GetAccountRequest) (*AccountResponse, error) {
 	var response AccountResponse
 	query := url.Values{}
 
@@ -59,7 +60,7 @@ func (c *Client) GetAccount(args *GetAccountRequest) (*AccountResponse, error) {
 		query.Set("recvWindow", strconv.FormatInt(*args.RecvWindow, 10))
 	}
 
-	_, err := c.Request("GET", "/api/v3/account", nil, &response, query)
+	_, err := c.Request(ctx, "GET", "/api/v3/account", nil, &response, query)
 	if err != nil {
 		return nil, err
 	}
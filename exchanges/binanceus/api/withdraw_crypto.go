@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"strconv"
@@ -24,7 +25,7 @@ type WithdrawResponse struct {
 }
 
 // https://docs.binance.us/#withdraw-crypto
-func (c *Client) WithdrawCrypto(args *WithdrawRequest) (*WithdrawResponse, error) {
+func (c *Client) WithdrawCrypto(ctx context.Context, args *WithdrawRequest) (*WithdrawResponse, error) {
 	if args == nil {
 		return nil, fmt.Errorf("args cannot be nil")
 	}
@@ -52,7 +53,7 @@ func (c *Client) WithdrawCrypto(args *WithdrawRequest) (*WithdrawResponse, error
 		query.Set("recvWindow", strconv.FormatInt(*args.RecvWindow, 10))
 	}
 
-	_, err := c.Request("POST", "/sapi/v1/capital/withdraw/apply", nil, &response, query)
+	_, err := c.Request(ctx, "POST", "/sapi/v1/capital/withdraw/apply", nil, &response, query)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package binanceus
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -15,12 +16,12 @@ type Client struct {
 
 var _ client.Client = &Client{}
 
-func NewClient(config *oc.ExchangeClientConfig, account *oc.Account) (*Client, error) {
-	apiKey, err := account.ApiKeyRef.Load()
+func NewClient(ctx context.Context, config *oc.ExchangeClientConfig, account *oc.Account) (*Client, error) {
+	apiKey, err := account.ApiKeyRef.Load(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load api key: %w", err)
 	}
-	secretKey, err := account.SecretKeyRef.Load()
+	secretKey, err := account.SecretKeyRef.Load(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load secret key: %w", err)
 	}
@@ -28,8 +29,8 @@ func NewClient(config *oc.ExchangeClientConfig, account *oc.Account) (*Client, e
 	return &Client{api: api}, nil
 }
 
-func (c *Client) ListAssets() ([]*oc.Asset, error) {
-	response, err := c.api.GetAssetConfig(&api.GetAssetConfigRequest{
+func (c *Client) ListAssets(ctx context.Context) ([]*oc.Asset, error) {
+	response, err := c.api.GetAssetConfig(ctx, &api.GetAssetConfigRequest{
 		TimestampMillis: time.Now().UnixMilli(),
 	})
 	if err != nil {
@@ -49,8 +50,8 @@ func (c *Client) ListAssets() ([]*oc.Asset, error) {
 	return assets, nil
 }
 
-func (c *Client) ListBalances(args client.GetBalanceArgs) ([]*client.BalanceDetail, error) {
-	response, err := c.api.GetAccount(&api.GetAccountRequest{
+func (c *Client) ListBalances(ctx context.Context, args client.GetBalanceArgs) ([]*client.BalanceDetail, error) {
+	response, err := c.api.GetAccount(ctx, &api.GetAccountRequest{
 		TimestampMillis: time.Now().UnixMilli(),
 	})
 	if err != nil {
@@ -70,8 +71,8 @@ func (c *Client) ListBalances(args client.GetBalanceArgs) ([]*client.BalanceDeta
 	return balances, nil
 }
 
-func (c *Client) CreateAccountTransfer(args client.AccountTransferArgs) (*client.TransferStatus, error) {
-	// response, err := c.api.SubAccountTransfer(&api.SubAccountTransferRequest{
+func (c *Client) CreateAccountTransfer(ctx context.Context, args client.AccountTransferArgs) (*client.TransferStatus, error) {
+	// response, err := c.api.SubAccountTransfer(ctx, &api.SubAccountTransferRequest{
 	// 	FromEmail:       args.GetFrom().Id(),
 	// 	ToEmail:         args.GetTo().Id(),
 	// 	Asset:           args.GetSymbol(),
@@ -88,8 +89,12 @@ func (c *Client) CreateAccountTransfer(args client.AccountTransferArgs) (*client
 	return nil, fmt.Errorf("not implemented")
 }
 
-func (c *Client) CreateWithdrawal(args client.WithdrawalArgs) (*client.WithdrawalResponse, error) {
-	response, err := c.api.WithdrawCrypto(&api.WithdrawRequest{
+func (c *Client) GetTransferStatus(ctx context.Context, id string) (*client.TransferStatus, error) {
+	return nil, fmt.Errorf("transfers not currently implemented for BinanceUS exchange")
+}
+
+func (c *Client) CreateWithdrawal(ctx context.Context, args client.WithdrawalArgs) (*client.WithdrawalResponse, error) {
+	response, err := c.api.WithdrawCrypto(ctx, &api.WithdrawRequest{
 		Coin:            args.GetSymbol(),
 		Network:         args.GetNetwork(),
 		Address:         args.GetAddress(),
@@ -105,8 +110,34 @@ func (c *Client) CreateWithdrawal(args client.WithdrawalArgs) (*client.Withdrawa
 	}, nil
 }
 
-func (c *Client) GetDepositAddress(args client.GetDepositAddressArgs) (oc.Address, error) {
-	response, err := c.api.GetDepositAddress(&api.GetDepositAddressRequest{
+func (c *Client) EstimateWithdrawal(ctx context.Context, args client.WithdrawalArgs) (*client.WithdrawalQuote, error) {
+	response, err := c.api.GetAssetConfig(ctx, &api.GetAssetConfigRequest{
+		TimestampMillis: time.Now().UnixMilli(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assets: %w", err)
+	}
+	for _, asset := range response {
+		if asset.Coin != args.GetSymbol() {
+			continue
+		}
+		for _, network := range asset.NetworkList {
+			if network.Network != args.GetNetwork() {
+				continue
+			}
+			fee, err := oc.NewAmountFromString(network.WithdrawFee)
+			if err != nil {
+				return nil, fmt.Errorf("invalid withdrawal fee %s: %w", network.WithdrawFee, err)
+			}
+			// binanceus deducts the fee from the withdrawal amount
+			return client.NewWithdrawalQuote(args, fee, asset.Coin, true), nil
+		}
+	}
+	return nil, fmt.Errorf("no withdrawal fee found for %s on network %s", args.GetSymbol(), args.GetNetwork())
+}
+
+func (c *Client) GetDepositAddress(ctx context.Context, args client.GetDepositAddressArgs) (oc.Address, error) {
+	response, err := c.api.GetDepositAddress(ctx, &api.GetDepositAddressRequest{
 		Coin:            args.GetSymbol(),
 		Network:         args.GetNetwork(),
 		TimestampMillis: time.Now().UnixMilli(),
@@ -117,10 +148,15 @@ func (c *Client) GetDepositAddress(args client.GetDepositAddressArgs) (oc.Addres
 	return response.Address, nil
 }
 
-func (c *Client) ListWithdrawalHistory(args client.WithdrawalHistoryArgs) ([]*client.WithdrawalHistory, error) {
-	response, err := c.api.GetCryptoWithdrawalHistory(&api.GetCryptoWithdrawalHistoryRequest{
+func (c *Client) ListWithdrawalHistory(ctx context.Context, args client.WithdrawalHistoryArgs) ([]*client.WithdrawalHistory, error) {
+	request := &api.GetCryptoWithdrawalHistoryRequest{
 		TimestampMillis: time.Now().UnixMilli(),
-	})
+	}
+	if args.GetStartTime() != nil {
+		startTime := args.GetStartTime().UnixMilli()
+		request.StartTime = &startTime
+	}
+	response, err := c.api.GetCryptoWithdrawalHistory(ctx, request)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get withdrawal history: %w", err)
 	}
@@ -147,3 +183,7 @@ func (c *Client) ListWithdrawalHistory(args client.WithdrawalHistoryArgs) ([]*cl
 	}
 	return history, nil
 }
+
+func (c *Client) ListTransferHistory(ctx context.Context, args client.TransferHistoryArgs) ([]*client.TransferHistory, error) {
+	return nil, fmt.Errorf("transfer history not currently implemented for BinanceUS exchange")
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/json"
@@ -11,8 +12,14 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/cordialsys/offchain/pkg/logging"
+	"github.com/cordialsys/offchain/pkg/transport"
 )
 
+// rate limits and retries shared by all clients of the venue
+var transportOptions = transport.DefaultOptions().WithThrottle(throttle)
+
 type Client struct {
 	apiKey     string
 	secretKey  string
@@ -27,7 +34,7 @@ func NewClient(apiKey, secretKey string) (*Client, error) {
 		apiKey:     apiKey,
 		secretKey:  secretKey,
 		baseURL:    "https://api.bybit.com",
-		httpClient: &http.Client{},
+		httpClient: transport.NewClient("bybit", transportOptions),
 		recvWindow: time.Second * 5,
 	}, nil
 }
@@ -53,15 +60,14 @@ func (c *Client) sign(timestamp int64, method, queryString, body string) string
 }
 
 // Request makes an authenticated HTTP request to the OKX API
-func (c *Client) Request(method, path string, input interface{}, output interface{}, query url.Values) ([]byte, error) {
+func (c *Client) Request(ctx context.Context, method, path string, input interface{}, output interface{}, query url.Values) ([]byte, error) {
 	method = strings.ToUpper(method)
+	log := slog.With("method", method, "url", c.baseURL+path, "query", query)
 	if len(query) > 0 {
 		path += "?" + query.Encode()
 	}
 	apiUrl := c.baseURL + path
 
-	log := slog.With("method", method, "url", apiUrl, "query", query)
-
 	var bodyStr string
 	if input != nil {
 		jsonBody, err := json.Marshal(input)
@@ -70,13 +76,18 @@ func (c *Client) Request(method, path string, input interface{}, output interfac
 		}
 		bodyStr = string(jsonBody)
 	}
-	log.Debug("request", "body", bodyStr)
+	log.DebugContext(ctx, "request", "body", logging.Body(bodyStr))
 
-	timestamp := time.Now().UnixMilli()
+	// Sign with the current timestamp, again on every retry
 	queryStr := query.Encode()
-	signature := c.sign(timestamp, method, queryStr, bodyStr)
+	sign := func(req *http.Request) error {
+		timestamp := time.Now().UnixMilli()
+		req.Header.Set("X-BAPI-SIGN", c.sign(timestamp, method, queryStr, bodyStr))
+		req.Header.Set("X-BAPI-TIMESTAMP", fmt.Sprintf("%d", timestamp))
+		return nil
+	}
 
-	req, err := http.NewRequest(method, apiUrl, strings.NewReader(bodyStr))
+	req, err := http.NewRequestWithContext(transport.WithSigner(ctx, sign), method, apiUrl, strings.NewReader(bodyStr))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -84,9 +95,10 @@ func (c *Client) Request(method, path string, input interface{}, output interfac
 	// Set headers for Bybit
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-BAPI-API-KEY", c.apiKey)
-	req.Header.Set("X-BAPI-SIGN", signature)
-	req.Header.Set("X-BAPI-TIMESTAMP", fmt.Sprintf("%d", timestamp))
 	req.Header.Set("X-BAPI-RECV-WINDOW", fmt.Sprintf("%d", c.recvWindow))
+	if err := sign(req); err != nil {
+		return nil, err
+	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -99,7 +111,7 @@ func (c *Client) Request(method, path string, input interface{}, output interfac
 		return nil, fmt.Errorf("failed to read response body: %w (status = %d)", err, resp.StatusCode)
 	}
 
-	log.Debug("response", "status", resp.StatusCode, "body", string(respBody))
+	log.DebugContext(ctx, "response", "status", resp.StatusCode, "body", logging.Body(respBody))
 
 	if output != nil {
 		err = json.Unmarshal(respBody, output)
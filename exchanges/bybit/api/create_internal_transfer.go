@@ -1,6 +1,8 @@
 package api
 
 import (
+	"context"
+
 	oc "github.com/cordialsys/offchain"
 	"github.com/google/uuid"
 )
@@ -15,7 +17,11 @@ type TransferRequest struct {
 
 type TransferStatus string
 
-const TransferStateSuccess TransferStatus = "SUCCESS"
+const (
+	TransferStateSuccess TransferStatus = "SUCCESS"
+	TransferStatePending TransferStatus = "PENDING"
+	TransferStateFailed  TransferStatus = "FAILED"
+)
 
 type TransferResult struct {
 	TransferID string         `json:"transferId"`
@@ -24,7 +30,7 @@ type TransferResult struct {
 type TransferResponse = Response[TransferResult]
 
 // https://bybit-exchange.github.io/docs/v5/asset/transfer/create-inter-transfer
-func (c *Client) CreateInternalTransfer(coin oc.SymbolId, amount oc.Amount, fromAccount oc.AccountType, toAccount oc.AccountType) (*TransferResponse, error) {
+func (c *Client) CreateInternalTransfer(ctx context.Context, coin oc.SymbolId, amount oc.Amount, fromAccount oc.AccountType, toAccount oc.AccountType) (*TransferResponse, error) {
 	uid := uuid.New().String()
 	request := TransferRequest{
 		TransferID:      uid,
@@ -34,7 +40,7 @@ func (c *Client) CreateInternalTransfer(coin oc.SymbolId, amount oc.Amount, from
 		ToAccountType:   toAccount,
 	}
 	var response TransferResponse
-	_, err := c.Request("POST", "/v5/asset/transfer/inter-transfer", &request, &response, nil)
+	_, err := c.Request(ctx, "POST", "/v5/asset/transfer/inter-transfer", &request, &response, nil)
 	if err != nil {
 		return nil, err
 	}
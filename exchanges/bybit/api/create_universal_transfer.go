@@ -1,6 +1,8 @@
 package api
 
 import (
+	"context"
+
 	oc "github.com/cordialsys/offchain"
 	"github.com/google/uuid"
 )
@@ -16,7 +18,7 @@ type UniversalTransferRequest struct {
 }
 
 // https://bybit-exchange.github.io/docs/v5/asset/transfer/unitransfer
-func (c *Client) CreateUniversalTransfer(coin oc.SymbolId, amount oc.Amount, fromMemberId int, toMemberId int, fromAccountType oc.AccountType, toAccountType oc.AccountType) (*TransferResponse, error) {
+func (c *Client) CreateUniversalTransfer(ctx context.Context, coin oc.SymbolId, amount oc.Amount, fromMemberId int, toMemberId int, fromAccountType oc.AccountType, toAccountType oc.AccountType) (*TransferResponse, error) {
 	uid := uuid.New().String()
 	request := UniversalTransferRequest{
 		TransferID:      uid,
@@ -28,7 +30,7 @@ func (c *Client) CreateUniversalTransfer(coin oc.SymbolId, amount oc.Amount, fro
 		ToAccountType:   toAccountType,
 	}
 	var response TransferResponse
-	_, err := c.Request("POST", "/v5/asset/transfer/universal-transfer", &request, &response, nil)
+	_, err := c.Request(ctx, "POST", "/v5/asset/transfer/universal-transfer", &request, &response, nil)
 	if err != nil {
 		return nil, err
 	}
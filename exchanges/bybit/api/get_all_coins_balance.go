@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"net/url"
 
 	oc "github.com/cordialsys/offchain"
@@ -32,7 +33,7 @@ const (
 )
 
 // https://bybit-exchange.github.io/docs/v5/asset/balance/all-balance
-func (c *Client) GetAllCoinsBalance(accountType oc.AccountType, coinMaybe oc.SymbolId) (*GetAllCoinsBalanceResponse, error) {
+func (c *Client) GetAllCoinsBalance(ctx context.Context, accountType oc.AccountType, coinMaybe oc.SymbolId) (*GetAllCoinsBalanceResponse, error) {
 
 	params := url.Values{}
 	params.Set("accountType", string(accountType))
@@ -40,7 +41,7 @@ func (c *Client) GetAllCoinsBalance(accountType oc.AccountType, coinMaybe oc.Sym
 		params.Set("coin", string(coinMaybe))
 	}
 	var response GetAllCoinsBalanceResponse
-	_, err := c.Request("GET", "/v5/asset/transfer/query-account-coins-balance", nil, &response, params)
+	_, err := c.Request(ctx, "GET", "/v5/asset/transfer/query-account-coins-balance", nil, &response, params)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"net/url"
 
 	oc "github.com/cordialsys/offchain"
@@ -34,13 +35,13 @@ type Chain struct {
 }
 
 // https://bybit-exchange.github.io/docs/v5/asset/coin-info/
-func (c *Client) GetCoinInfo(coinMaybe oc.SymbolId) (*GetCoinInfoResponse, error) {
+func (c *Client) GetCoinInfo(ctx context.Context, coinMaybe oc.SymbolId) (*GetCoinInfoResponse, error) {
 	params := url.Values{}
 	if coinMaybe != "" {
 		params.Set("coin", string(coinMaybe))
 	}
 	var response GetCoinInfoResponse
-	_, err := c.Request("GET", "/v5/asset/coin/query-info", nil, &response, params)
+	_, err := c.Request(ctx, "GET", "/v5/asset/coin/query-info", nil, &response, params)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"net/url"
 
 	oc "github.com/cordialsys/offchain"
@@ -23,12 +24,12 @@ type GetDepositAddressResult struct {
 type GetDepositAddressResponse = Response[GetDepositAddressResult]
 
 // https://bybit-exchange.github.io/docs/v5/asset/deposit/master-deposit-addr
-func (c *Client) GetMasterDepositAddress(coin oc.SymbolId, network oc.NetworkId) (*GetDepositAddressResponse, error) {
+func (c *Client) GetMasterDepositAddress(ctx context.Context, coin oc.SymbolId, network oc.NetworkId) (*GetDepositAddressResponse, error) {
 	params := url.Values{}
 	params.Set("coin", string(coin))
 	params.Set("chainType", string(network))
 	var result GetDepositAddressResponse
-	_, err := c.Request("GET", "/v5/asset/deposit/query-address", nil, &result, params)
+	_, err := c.Request(ctx, "GET", "/v5/asset/deposit/query-address", nil, &result, params)
 	if err != nil {
 		return nil, err
 	}
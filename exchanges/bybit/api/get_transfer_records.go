@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+
+	oc "github.com/cordialsys/offchain"
+)
+
+type TransferRecordsRequest struct {
+	TransferID string         `json:"transferId,omitempty"`
+	Coin       *oc.SymbolId   `json:"coin,omitempty"`
+	Status     TransferStatus `json:"status,omitempty"`
+	StartTime  *int64         `json:"startTime,omitempty"`
+	EndTime    *int64         `json:"endTime,omitempty"`
+	Limit      *int           `json:"limit,omitempty"`
+	Cursor     string         `json:"cursor,omitempty"`
+}
+
+type TransferRecord struct {
+	TransferID      string         `json:"transferId"`
+	Coin            oc.SymbolId    `json:"coin"`
+	Amount          oc.Amount      `json:"amount"`
+	FromMemberId    string         `json:"fromMemberId,omitempty"`
+	ToMemberId      string         `json:"toMemberId,omitempty"`
+	FromAccountType oc.AccountType `json:"fromAccountType"`
+	ToAccountType   oc.AccountType `json:"toAccountType"`
+	Timestamp       string         `json:"timestamp"`
+	Status          TransferStatus `json:"status"`
+}
+
+type TransferRecordsResult struct {
+	List           []TransferRecord `json:"list"`
+	NextPageCursor string           `json:"nextPageCursor"`
+}
+
+type TransferRecordsResponse = Response[TransferRecordsResult]
+
+// https://bybit-exchange.github.io/docs/v5/asset/transfer/inter-transfer-list
+func (c *Client) GetInternalTransferRecords(ctx context.Context, args *TransferRecordsRequest) (*TransferRecordsResponse, error) {
+	var response TransferRecordsResponse
+	_, err := c.Request(ctx, "GET", "/v5/asset/transfer/query-inter-transfer-list", nil, &response, args.query())
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// https://bybit-exchange.github.io/docs/v5/asset/transfer/unitransfer-list
+func (c *Client) GetUniversalTransferRecords(ctx context.Context, args *TransferRecordsRequest) (*TransferRecordsResponse, error) {
+	var response TransferRecordsResponse
+	_, err := c.Request(ctx, "GET", "/v5/asset/transfer/query-universal-transfer-list", nil, &response, args.query())
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (args *TransferRecordsRequest) query() url.Values {
+	query := url.Values{}
+	if args == nil {
+		return query
+	}
+	if args.TransferID != "" {
+		query.Set("transferId", args.TransferID)
+	}
+	if args.Coin != nil {
+		query.Set("coin", string(*args.Coin))
+	}
+	if args.Status != "" {
+		query.Set("status", string(args.Status))
+	}
+	if args.StartTime != nil {
+		query.Set("startTime", strconv.FormatInt(*args.StartTime, 10))
+	}
+	if args.EndTime != nil {
+		query.Set("endTime", strconv.FormatInt(*args.EndTime, 10))
+	}
+	if args.Limit != nil {
+		query.Set("limit", strconv.Itoa(*args.Limit))
+	}
+	if args.Cursor != "" {
+		query.Set("cursor", args.Cursor)
+	}
+	return query
+}
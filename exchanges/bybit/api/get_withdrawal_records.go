@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"net/url"
 	"strconv"
 
@@ -57,7 +58,7 @@ type WithdrawalRecordsResult struct {
 type WithdrawalRecordsResponse = Response[WithdrawalRecordsResult]
 
 // https://bybit-exchange.github.io/docs/v5/asset/withdraw/withdraw-record
-func (c *Client) GetWithdrawalRecords(args *WithdrawalRecordsRequest) (*WithdrawalRecordsResponse, error) {
+func (c *Client) GetWithdrawalRecords(ctx context.Context, args *WithdrawalRecordsRequest) (*WithdrawalRecordsResponse, error) {
 	var response WithdrawalRecordsResponse
 	query := url.Values{}
 
@@ -88,7 +89,7 @@ func (c *Client) GetWithdrawalRecords(args *WithdrawalRecordsRequest) (*Withdraw
 		}
 	}
 
-	_, err := c.Request("GET", "/v5/asset/withdraw/query-record", nil, &response, query)
+	_, err := c.Request(ctx, "GET", "/v5/asset/withdraw/query-record", nil, &response, query)
 	if err != nil {
 		return nil, err
 	}
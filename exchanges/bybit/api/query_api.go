@@ -0,0 +1,33 @@
+package api
+
+import (
+	"context"
+)
+
+type QueryApiResponse = Response[ApiKeyInfo]
+
+type ApiKeyInfo struct {
+	Id       string `json:"id"`
+	Note     string `json:"note"`
+	ApiKey   string `json:"apiKey"`
+	ReadOnly int    `json:"readOnly"`
+	// Permissions by product, e.g. {"Wallet": ["AccountTransfer", "Withdraw"]}
+	Permissions map[string][]string `json:"permissions"`
+	// IP addresses bound to the key, ["*"] if not bound
+	Ips []string `json:"ips"`
+	// RFC3339, empty if the key does not expire
+	ExpiredAt string `json:"expiredAt"`
+	CreatedAt string `json:"createdAt"`
+	IsMaster  bool   `json:"isMaster"`
+	ParentUid string `json:"parentUid"`
+}
+
+// https://bybit-exchange.github.io/docs/v5/user/apikey-info
+func (c *Client) QueryApi(ctx context.Context) (*QueryApiResponse, error) {
+	var response QueryApiResponse
+	_, err := c.Request(ctx, "GET", "/v5/user/query-api", nil, &response, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
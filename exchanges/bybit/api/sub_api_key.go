@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"net/url"
+)
+
+type CreateSubApiKeyRequest struct {
+	SubUid   int64  `json:"subuid"`
+	Note     string `json:"note,omitempty"`
+	ReadOnly int    `json:"readOnly"`
+	// Comma separated IP addresses, or "*" to not bind the key
+	Ips string `json:"ips,omitempty"`
+	// Permissions by product, e.g. {"Wallet": ["AccountTransfer"]}
+	Permissions map[string][]string `json:"permissions"`
+}
+
+type CreateSubApiKeyResult struct {
+	Id          string              `json:"id"`
+	Note        string              `json:"note"`
+	ApiKey      string              `json:"apiKey"`
+	ReadOnly    int                 `json:"readOnly"`
+	Secret      string              `json:"secret"`
+	Permissions map[string][]string `json:"permissions"`
+}
+
+type CreateSubApiKeyResponse = Response[CreateSubApiKeyResult]
+
+// https://bybit-exchange.github.io/docs/v5/user/create-subuid-apikey
+func (c *Client) CreateSubApiKey(ctx context.Context, args *CreateSubApiKeyRequest) (*CreateSubApiKeyResponse, error) {
+	var response CreateSubApiKeyResponse
+	_, err := c.Request(ctx, "POST", "/v5/user/create-sub-api", args, &response, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+type SubApiKeysResult struct {
+	Result         []ApiKeyInfo `json:"result"`
+	NextPageCursor string       `json:"nextPageCursor"`
+}
+
+type SubApiKeysResponse = Response[SubApiKeysResult]
+
+// https://bybit-exchange.github.io/docs/v5/user/list-sub-apikeys
+func (c *Client) GetSubApiKeys(ctx context.Context, subUid string, cursor string) (*SubApiKeysResponse, error) {
+	var response SubApiKeysResponse
+	query := url.Values{}
+	query.Set("subMemberId", subUid)
+	query.Set("limit", "20")
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	_, err := c.Request(ctx, "GET", "/v5/user/sub-apikeys", nil, &response, query)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+type DeleteSubApiKeyRequest struct {
+	ApiKey string `json:"apikey"`
+}
+
+// Deletes a key of a sub-account, when called with the key of the main account.
+// https://bybit-exchange.github.io/docs/v5/user/rm-sub-apikey
+func (c *Client) DeleteSubApiKey(ctx context.Context, apiKey string) (*Response[struct{}], error) {
+	var response Response[struct{}]
+	_, err := c.Request(ctx, "POST", "/v5/user/del-sub-api", &DeleteSubApiKeyRequest{ApiKey: apiKey}, &response, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
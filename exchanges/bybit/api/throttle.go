@@ -0,0 +1,22 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Bybit reports the requests remaining for the endpoint in the current window, and when the window resets.
+// Limits are per endpoint, but the whole venue is paused to keep it simple.
+// https://bybit-exchange.github.io/docs/v5/rate-limit
+func throttle(h http.Header, now time.Time) time.Duration {
+	remaining, err := strconv.Atoi(h.Get("X-Bapi-Limit-Status"))
+	if err != nil || remaining > 0 {
+		return 0
+	}
+	resetMillis, err := strconv.ParseInt(h.Get("X-Bapi-Limit-Reset-Timestamp"), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return max(time.UnixMilli(resetMillis).Sub(now), 0)
+}
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"context"
+
 	oc "github.com/cordialsys/offchain"
 )
 
@@ -28,9 +30,9 @@ type WithdrawResult struct {
 type WithdrawResponse = Response[WithdrawResult]
 
 // https://bybit-exchange.github.io/docs/v5/asset/withdraw
-func (c *Client) Withdraw(request *WithdrawRequest) (*WithdrawResponse, error) {
+func (c *Client) Withdraw(ctx context.Context, request *WithdrawRequest) (*WithdrawResponse, error) {
 	var response WithdrawResponse
-	_, err := c.Request("POST", "/v5/asset/withdraw/create", request, &response, nil)
+	_, err := c.Request(ctx, "POST", "/v5/asset/withdraw/create", request, &response, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -1,9 +1,12 @@
 package bybit
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	oc "github.com/cordialsys/offchain"
@@ -17,13 +20,15 @@ type Client struct {
 }
 
 var _ client.Client = &Client{}
+var _ client.ApiKeyInspector = &Client{}
+var _ client.ApiKeyManager = &Client{}
 
-func NewClient(config *oc.ExchangeClientConfig, secrets *oc.Account) (*Client, error) {
-	apiKey, err := secrets.ApiKeyRef.Load()
+func NewClient(ctx context.Context, config *oc.ExchangeClientConfig, secrets *oc.Account) (*Client, error) {
+	apiKey, err := secrets.ApiKeyRef.Load(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("could not load api key: %v", err)
 	}
-	secretKey, err := secrets.SecretKeyRef.Load()
+	secretKey, err := secrets.SecretKeyRef.Load(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("could not load secret key: %v", err)
 	}
@@ -41,8 +46,8 @@ func NewClient(config *oc.ExchangeClientConfig, secrets *oc.Account) (*Client, e
 	}, nil
 }
 
-func (c *Client) ListAssets() ([]*oc.Asset, error) {
-	response, err := c.api.GetCoinInfo("")
+func (c *Client) ListAssets(ctx context.Context) ([]*oc.Asset, error) {
+	response, err := c.api.GetCoinInfo(ctx, "")
 	if err != nil {
 		return nil, err
 	}
@@ -59,11 +64,11 @@ func (c *Client) ListAssets() ([]*oc.Asset, error) {
 	return assets, nil
 }
 
-func (c *Client) ListBalances(args client.GetBalanceArgs) ([]*client.BalanceDetail, error) {
+func (c *Client) ListBalances(ctx context.Context, args client.GetBalanceArgs) ([]*client.BalanceDetail, error) {
 
 	accountType := args.GetAccountType()
 
-	response, err := c.api.GetAllCoinsBalance(accountType, "")
+	response, err := c.api.GetAllCoinsBalance(ctx, accountType, "")
 	if err != nil {
 		return nil, err
 	}
@@ -85,7 +90,7 @@ func (c *Client) ListBalances(args client.GetBalanceArgs) ([]*client.BalanceDeta
 	return balances, nil
 }
 
-func (c *Client) CreateAccountTransfer(args client.AccountTransferArgs) (*client.TransferStatus, error) {
+func (c *Client) CreateAccountTransfer(ctx context.Context, args client.AccountTransferArgs) (*client.TransferStatus, error) {
 	var err error
 	var response *api.TransferResponse
 
@@ -93,7 +98,7 @@ func (c *Client) CreateAccountTransfer(args client.AccountTransferArgs) (*client
 		// apparently the 'universal' transfer doesn't work for transfers in the same account
 		_, fromType := args.GetFrom()
 		_, toType := args.GetTo()
-		response, err = c.api.CreateInternalTransfer(args.GetSymbol(), args.GetAmount(), fromType, toType)
+		response, err = c.api.CreateInternalTransfer(ctx, args.GetSymbol(), args.GetAmount(), fromType, toType)
 		if err != nil {
 			return nil, err
 		}
@@ -120,6 +125,7 @@ func (c *Client) CreateAccountTransfer(args client.AccountTransferArgs) (*client
 		}
 
 		response, err = c.api.CreateUniversalTransfer(
+			ctx,
 			args.GetSymbol(),
 			args.GetAmount(),
 			fromId,
@@ -144,7 +150,41 @@ func (c *Client) CreateAccountTransfer(args client.AccountTransferArgs) (*client
 	}, nil
 }
 
-func (c *Client) CreateWithdrawal(args client.WithdrawalArgs) (*client.WithdrawalResponse, error) {
+func (c *Client) GetTransferStatus(ctx context.Context, id string) (*client.TransferStatus, error) {
+	request := &api.TransferRecordsRequest{
+		TransferID: id,
+	}
+	// the transfer could have been created with either the internal or universal endpoint
+	internal, err := c.api.GetInternalTransferRecords(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	records := internal.Result.List
+	if len(records) == 0 {
+		universal, err := c.api.GetUniversalTransferRecords(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		records = universal.Result.List
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("transfer %s not found", id)
+	}
+
+	status := client.OperationStatusPending
+	switch records[0].Status {
+	case api.TransferStateSuccess:
+		status = client.OperationStatusSuccess
+	case api.TransferStateFailed:
+		status = client.OperationStatusFailed
+	}
+	return &client.TransferStatus{
+		ID:     records[0].TransferID,
+		Status: status,
+	}, nil
+}
+
+func (c *Client) CreateWithdrawal(ctx context.Context, args client.WithdrawalArgs) (*client.WithdrawalResponse, error) {
 
 	request := api.WithdrawRequest{
 		Coin:        args.GetSymbol(),
@@ -155,7 +195,7 @@ func (c *Client) CreateWithdrawal(args client.WithdrawalArgs) (*client.Withdrawa
 		AccountType: api.AccountTypeFund,
 	}
 
-	response, err := c.api.Withdraw(&request)
+	response, err := c.api.Withdraw(ctx, &request)
 	if err != nil {
 		return nil, err
 	}
@@ -165,13 +205,46 @@ func (c *Client) CreateWithdrawal(args client.WithdrawalArgs) (*client.Withdrawa
 	}, nil
 }
 
-func (c *Client) GetDepositAddress(args client.GetDepositAddressArgs) (oc.Address, error) {
+func (c *Client) EstimateWithdrawal(ctx context.Context, args client.WithdrawalArgs) (*client.WithdrawalQuote, error) {
+	response, err := c.api.GetCoinInfo(ctx, args.GetSymbol())
+	if err != nil {
+		return nil, err
+	}
+	for _, coin := range response.Result.Rows {
+		if oc.SymbolId(coin.Coin) != args.GetSymbol() {
+			continue
+		}
+		for _, chain := range coin.Chains {
+			if oc.NetworkId(chain.Chain) != args.GetNetwork() {
+				continue
+			}
+			fee, err := oc.NewAmountFromString(chain.WithdrawFee)
+			if err != nil {
+				return nil, fmt.Errorf("invalid withdrawal fee %s: %w", chain.WithdrawFee, err)
+			}
+			if chain.WithdrawPercentageFee != "" {
+				percentage, err := oc.NewAmountFromString(chain.WithdrawPercentageFee)
+				if err != nil {
+					return nil, fmt.Errorf("invalid withdrawal percentage fee %s: %w", chain.WithdrawPercentageFee, err)
+				}
+				// the percentage fee is a ratio (e.g. 0.022 is 2.2%)
+				variable := args.GetAmount().Decimal().Mul(percentage.Decimal())
+				fee = oc.Amount(fee.Decimal().Add(variable))
+			}
+			// we do not set feeType on withdrawals, so the fee is charged on top of the amount
+			return client.NewWithdrawalQuote(args, fee, args.GetSymbol(), false), nil
+		}
+	}
+	return nil, fmt.Errorf("no withdrawal fee found for %s on network %s", args.GetSymbol(), args.GetNetwork())
+}
+
+func (c *Client) GetDepositAddress(ctx context.Context, args client.GetDepositAddressArgs) (oc.Address, error) {
 	var response *api.GetDepositAddressResponse
 	var err error
 	if accountType, ok := args.GetSubaccount(); ok {
-		response, err = c.api.GetSubDepositAddress(accountType, args.GetSymbol(), args.GetNetwork())
+		response, err = c.api.GetSubDepositAddress(ctx, accountType, args.GetSymbol(), args.GetNetwork())
 	} else {
-		response, err = c.api.GetMasterDepositAddress(args.GetSymbol(), args.GetNetwork())
+		response, err = c.api.GetMasterDepositAddress(ctx, args.GetSymbol(), args.GetNetwork())
 	}
 
 	if err != nil {
@@ -185,8 +258,13 @@ func (c *Client) GetDepositAddress(args client.GetDepositAddressArgs) (oc.Addres
 	return "", fmt.Errorf("no deposit address found for network %s", args.GetNetwork())
 }
 
-func (c *Client) ListWithdrawalHistory(args client.WithdrawalHistoryArgs) ([]*client.WithdrawalHistory, error) {
-	response, err := c.api.GetWithdrawalRecords(&api.WithdrawalRecordsRequest{})
+func (c *Client) ListWithdrawalHistory(ctx context.Context, args client.WithdrawalHistoryArgs) ([]*client.WithdrawalHistory, error) {
+	request := &api.WithdrawalRecordsRequest{WithdrawID: args.GetId()}
+	if args.GetStartTime() != nil {
+		startTime := args.GetStartTime().UnixMilli()
+		request.StartTime = &startTime
+	}
+	response, err := c.api.GetWithdrawalRecords(ctx, request)
 	if err != nil {
 		return nil, err
 	}
@@ -218,3 +296,180 @@ func (c *Client) ListWithdrawalHistory(args client.WithdrawalHistoryArgs) ([]*cl
 	}
 	return history, nil
 }
+
+// bybit lists transfers within 7 days of the end time
+const (
+	transferHistoryWindow   = 7 * 24 * time.Hour
+	transferHistoryLookback = 180 * 24 * time.Hour
+)
+
+func (c *Client) ListTransferHistory(ctx context.Context, args client.TransferHistoryArgs) ([]*client.TransferHistory, error) {
+	cursor, err := args.GetCursor()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UnixMilli()
+	end := now
+	if cursor != nil {
+		end = cursor.Before
+	}
+
+	// search back a window at a time until the page is full, as the windows may be empty
+	records := []api.TransferRecord{}
+	for end > now-transferHistoryLookback.Milliseconds() && len(records) < args.GetLimit()+cursor.SeenCount() {
+		start := end - transferHistoryWindow.Milliseconds() + 1
+		// transfers within the same account and across accounts are recorded separately
+		for _, list := range []func(context.Context, *api.TransferRecordsRequest) (*api.TransferRecordsResponse, error){
+			c.api.GetInternalTransferRecords,
+			c.api.GetUniversalTransferRecords,
+		} {
+			for pageCursor := ""; ; {
+				// bybit limits transfer records to 50 per page
+				limit := 50
+				response, err := list(ctx, &api.TransferRecordsRequest{
+					StartTime: &start,
+					EndTime:   &end,
+					Limit:     &limit,
+					Cursor:    pageCursor,
+				})
+				if err != nil {
+					return nil, err
+				}
+				records = append(records, response.Result.List...)
+				pageCursor = response.Result.NextPageCursor
+				if pageCursor == "" || len(response.Result.List) == 0 {
+					break
+				}
+			}
+		}
+		end = start - 1
+	}
+
+	history := []*client.TransferHistory{}
+	for _, record := range records {
+		status := client.OperationStatusPending
+		switch record.Status {
+		case api.TransferStateSuccess:
+			status = client.OperationStatusSuccess
+		case api.TransferStateFailed:
+			status = client.OperationStatusFailed
+		}
+		timestamp, _ := strconv.ParseInt(record.Timestamp, 10, 64)
+		history = append(history, &client.TransferHistory{
+			ID:        record.TransferID,
+			Status:    status,
+			Symbol:    record.Coin,
+			Amount:    record.Amount,
+			From:      oc.AccountId(record.FromMemberId),
+			To:        oc.AccountId(record.ToMemberId),
+			FromType:  record.FromAccountType,
+			ToType:    record.ToAccountType,
+			Timestamp: timestamp,
+			Comment:   string(record.Status),
+			Notes:     map[string]string{},
+		})
+	}
+	return client.PageTransferHistory(history, cursor, args.GetLimit()), nil
+}
+
+func (c *Client) GetApiKeyInfo(ctx context.Context) (*client.ApiKeyInfo, error) {
+	response, err := c.api.QueryApi(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return apiKeyInfo(&response.Result), nil
+}
+
+func apiKeyInfo(key *api.ApiKeyInfo) *client.ApiKeyInfo {
+	permissions := []string{}
+	for product, names := range key.Permissions {
+		for _, name := range names {
+			permissions = append(permissions, product+"."+name)
+		}
+	}
+	slices.Sort(permissions)
+	ips := slices.DeleteFunc(slices.Clone(key.Ips), func(ip string) bool { return ip == "*" })
+	info := &client.ApiKeyInfo{
+		Permissions:  permissions,
+		ReadOnly:     key.ReadOnly == 1,
+		Withdraw:     slices.Contains(key.Permissions["Wallet"], "Withdraw"),
+		IpRestricted: len(ips) > 0,
+		Ips:          ips,
+	}
+	if key.ExpiredAt != "" {
+		if expires, err := time.Parse(time.RFC3339, key.ExpiredAt); err == nil {
+			info.ExpiresAt = &expires
+		}
+	}
+	if key.CreatedAt != "" {
+		if created, err := time.Parse(time.RFC3339, key.CreatedAt); err == nil {
+			info.CreatedAt = &created
+		}
+	}
+	return info
+}
+
+func (c *Client) GetSubAccountApiKeyInfo(ctx context.Context, subaccount oc.AccountId, apiKey string) (*client.ApiKeyInfo, error) {
+	cursor := ""
+	for {
+		response, err := c.api.GetSubApiKeys(ctx, string(subaccount), cursor)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range response.Result.Result {
+			if key.ApiKey == apiKey {
+				return apiKeyInfo(&key), nil
+			}
+		}
+		cursor = response.Result.NextPageCursor
+		if cursor == "" || len(response.Result.Result) == 0 {
+			return nil, fmt.Errorf("api key not found for sub-account %s", subaccount)
+		}
+	}
+}
+
+func (c *Client) CreateSubAccountApiKey(ctx context.Context, args client.CreateApiKeyArgs) (*client.SubAccountApiKey, error) {
+	subUid, err := strconv.ParseInt(string(args.GetSubAccount()), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("bybit subaccount id must be numeric (not %s)", args.GetSubAccount())
+	}
+	// permissions are reported as "<product>.<permission>"
+	permissions := map[string][]string{}
+	for _, permission := range args.GetPermissions() {
+		product, name, ok := strings.Cut(permission, ".")
+		if !ok {
+			return nil, fmt.Errorf("invalid bybit permission: %s", permission)
+		}
+		permissions[product] = append(permissions[product], name)
+	}
+	ips := "*"
+	if len(args.GetIps()) > 0 {
+		ips = strings.Join(args.GetIps(), ",")
+	}
+	readOnly := 0
+	if args.IsReadOnly() {
+		readOnly = 1
+	}
+	response, err := c.api.CreateSubApiKey(ctx, &api.CreateSubApiKeyRequest{
+		SubUid:      subUid,
+		Note:        args.GetLabel(),
+		ReadOnly:    readOnly,
+		Ips:         ips,
+		Permissions: permissions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if response.Result.ApiKey == "" {
+		return nil, fmt.Errorf("no api key returned")
+	}
+	return &client.SubAccountApiKey{
+		ApiKey:    response.Result.ApiKey,
+		SecretKey: response.Result.Secret,
+	}, nil
+}
+
+func (c *Client) DeleteSubAccountApiKey(ctx context.Context, subaccount oc.AccountId, apiKey string) error {
+	_, err := c.api.DeleteSubApiKey(ctx, apiKey)
+	return err
+}
@@ -1,6 +1,7 @@
 package offchain
 
 import (
+	"context"
 	"fmt"
 
 	oc "github.com/cordialsys/offchain"
@@ -24,8 +25,8 @@ func NewClient(serverClient *serverclient.Client, exchange oc.ExchangeId) (*Clie
 	}, nil
 }
 
-func (c *Client) ListAssets() ([]*oc.Asset, error) {
-	assets, err := c.cli.GetAssets(c.exchange)
+func (c *Client) ListAssets(ctx context.Context) ([]*oc.Asset, error) {
+	assets, err := c.cli.GetAssets(ctx, c.exchange)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get assets: %w", err)
 	}
@@ -46,14 +47,14 @@ func (c *Client) ListAssets() ([]*oc.Asset, error) {
 	return result, nil
 }
 
-func (c *Client) ListBalances(args client.GetBalanceArgs) ([]*client.BalanceDetail, error) {
+func (c *Client) ListBalances(ctx context.Context, args client.GetBalanceArgs) ([]*client.BalanceDetail, error) {
 	// Get account type from args if specified
 	accountType := ""
 	if args.GetAccountType() != "" {
 		accountType = string(args.GetAccountType())
 	}
 
-	balances, err := c.cli.GetBalances(c.exchange, accountType)
+	balances, err := c.cli.GetBalances(ctx, c.exchange, accountType)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get balances: %w", err)
 	}
@@ -82,7 +83,7 @@ func (c *Client) ListBalances(args client.GetBalanceArgs) ([]*client.BalanceDeta
 	return result, nil
 }
 
-func (c *Client) CreateAccountTransfer(args client.AccountTransferArgs) (*client.TransferStatus, error) {
+func (c *Client) CreateAccountTransfer(ctx context.Context, args client.AccountTransferArgs) (*client.TransferStatus, error) {
 	// Create transfer request
 	transfer := api.NewTransfer(
 		string(args.GetSymbol()),
@@ -108,7 +109,7 @@ func (c *Client) CreateAccountTransfer(args client.AccountTransferArgs) (*client
 	}
 
 	// Execute the transfer
-	response, err := c.cli.CreateAccountTransfer(c.exchange, transfer)
+	response, err := c.cli.CreateAccountTransfer(ctx, c.exchange, transfer)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create account transfer: %w", err)
 	}
@@ -119,7 +120,19 @@ func (c *Client) CreateAccountTransfer(args client.AccountTransferArgs) (*client
 	}, nil
 }
 
-func (c *Client) CreateWithdrawal(args client.WithdrawalArgs) (*client.WithdrawalResponse, error) {
+func (c *Client) GetTransferStatus(ctx context.Context, id string) (*client.TransferStatus, error) {
+	response, err := c.cli.GetTransferStatus(ctx, c.exchange, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfer status: %w", err)
+	}
+
+	return &client.TransferStatus{
+		ID:     response.Id,
+		Status: client.OperationStatus(response.Status),
+	}, nil
+}
+
+func (c *Client) CreateWithdrawal(ctx context.Context, args client.WithdrawalArgs) (*client.WithdrawalResponse, error) {
 	// Create withdrawal request
 	withdrawal := api.NewWithdrawal(
 		string(args.GetAddress()),
@@ -129,21 +142,60 @@ func (c *Client) CreateWithdrawal(args client.WithdrawalArgs) (*client.Withdrawa
 	)
 
 	// Execute the withdrawal
-	response, err := c.cli.CreateWithdrawal(c.exchange, withdrawal)
+	response, err := c.cli.CreateWithdrawal(ctx, c.exchange, withdrawal)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create withdrawal: %w", err)
 	}
 
 	return &client.WithdrawalResponse{
-		ID:     response.Id,
-		Status: client.OperationStatus(response.Status),
+		ID:       response.Id,
+		Status:   client.OperationStatus(response.Status),
+		IntentId: api.DerefOrZero(response.IntentId),
 	}, nil
 }
 
-func (c *Client) GetDepositAddress(args client.GetDepositAddressArgs) (oc.Address, error) {
+func (c *Client) EstimateWithdrawal(ctx context.Context, args client.WithdrawalArgs) (*client.WithdrawalQuote, error) {
+	withdrawal := api.NewWithdrawal(
+		string(args.GetAddress()),
+		string(args.GetSymbol()),
+		string(args.GetNetwork()),
+		args.GetAmount(),
+	)
+
+	response, err := c.cli.EstimateWithdrawal(ctx, c.exchange, withdrawal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate withdrawal: %w", err)
+	}
+
+	amount, err := oc.NewAmountFromString(response.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("invalid amount: %w", err)
+	}
+	fee, err := oc.NewAmountFromString(response.Fee)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fee: %w", err)
+	}
+	netAmount, err := oc.NewAmountFromString(response.NetAmount)
+	if err != nil {
+		return nil, fmt.Errorf("invalid net amount: %w", err)
+	}
+
+	return &client.WithdrawalQuote{
+		Symbol:      oc.SymbolId(response.Symbol),
+		Network:     oc.NetworkId(response.Network),
+		Amount:      amount,
+		Fee:         fee,
+		FeeSymbol:   oc.SymbolId(response.FeeSymbol),
+		NetAmount:   netAmount,
+		FeeDeducted: response.FeeDeducted,
+	}, nil
+}
+
+func (c *Client) GetDepositAddress(ctx context.Context, args client.GetDepositAddressArgs) (oc.Address, error) {
 
 	forMaybe, _ := args.GetSubaccount()
 	address, err := c.cli.GetDepositAddress(
+		ctx,
 		c.exchange,
 		(args.GetSymbol()),
 		(args.GetNetwork()),
@@ -156,7 +208,7 @@ func (c *Client) GetDepositAddress(args client.GetDepositAddressArgs) (oc.Addres
 	return oc.Address(address), nil
 }
 
-func (c *Client) ListWithdrawalHistory(args client.WithdrawalHistoryArgs) ([]*client.WithdrawalHistory, error) {
+func (c *Client) ListWithdrawalHistory(ctx context.Context, args client.WithdrawalHistoryArgs) ([]*client.WithdrawalHistory, error) {
 	limit := 0
 	if args.GetLimit() > 0 {
 		limit = args.GetLimit()
@@ -167,7 +219,7 @@ func (c *Client) ListWithdrawalHistory(args client.WithdrawalHistoryArgs) ([]*cl
 		pageToken = args.GetPageToken()
 	}
 
-	withdrawals, err := c.cli.ListWithdrawalHistory(c.exchange, limit, pageToken)
+	withdrawals, err := c.cli.ListWithdrawalHistory(ctx, c.exchange, limit, pageToken)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get withdrawal history: %w", err)
 	}
@@ -217,8 +269,47 @@ func (c *Client) ListWithdrawalHistory(args client.WithdrawalHistoryArgs) ([]*cl
 	return result, nil
 }
 
-func (c *Client) ListSubaccounts() ([]*oc.SubAccountHeader, error) {
-	subaccounts, err := c.cli.ListSubaccounts(c.exchange)
+func (c *Client) ListTransferHistory(ctx context.Context, args client.TransferHistoryArgs) ([]*client.TransferHistory, error) {
+	transfers, err := c.cli.ListTransferHistory(ctx, c.exchange, args.GetLimit(), args.GetPageToken())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfer history: %w", err)
+	}
+
+	result := make([]*client.TransferHistory, len(transfers))
+	for i, transfer := range transfers {
+		amount, err := oc.NewAmountFromString(transfer.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("invalid amount: %w", err)
+		}
+
+		notes := make(map[string]string)
+		if transfer.Notes != nil {
+			for k, v := range *transfer.Notes {
+				notes[k] = v
+			}
+		}
+
+		result[i] = &client.TransferHistory{
+			ID:        transfer.Id,
+			Status:    client.OperationStatus(transfer.Status),
+			Symbol:    oc.SymbolId(transfer.Symbol),
+			Amount:    amount,
+			From:      oc.AccountId(api.DerefOrZero(transfer.From)),
+			To:        oc.AccountId(api.DerefOrZero(transfer.To)),
+			FromType:  oc.AccountType(api.DerefOrZero(transfer.FromType)),
+			ToType:    oc.AccountType(api.DerefOrZero(transfer.ToType)),
+			Timestamp: transfer.Timestamp,
+			Comment:   api.DerefOrZero(transfer.Comment),
+			Notes:     notes,
+			PageToken: api.DerefOrZero(transfer.PageToken),
+		}
+	}
+
+	return result, nil
+}
+
+func (c *Client) ListSubaccounts(ctx context.Context) ([]*oc.SubAccountHeader, error) {
+	subaccounts, err := c.cli.ListSubaccounts(ctx, c.exchange)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list subaccounts: %w", err)
 	}
@@ -236,8 +327,8 @@ func (c *Client) ListSubaccounts() ([]*oc.SubAccountHeader, error) {
 	return result, nil
 }
 
-func (c *Client) ListAccountTypes() ([]*oc.AccountTypeConfig, error) {
-	accountTypes, err := c.cli.GetAccountTypes(c.exchange)
+func (c *Client) ListAccountTypes(ctx context.Context) ([]*oc.AccountTypeConfig, error) {
+	accountTypes, err := c.cli.GetAccountTypes(ctx, c.exchange)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get account types: %w", err)
 	}
@@ -255,3 +346,13 @@ func (c *Client) ListAccountTypes() ([]*oc.AccountTypeConfig, error) {
 
 	return result, nil
 }
+
+// GetApiKeyInfo is not available through the server; use the /health/exchanges endpoint instead.
+func (c *Client) GetApiKeyInfo(ctx context.Context) (*client.ApiKeyInfo, bool, error) {
+	return nil, false, nil
+}
+
+// ApiKeyManager is not available through the server; keys are rotated with `oc secret rotate`.
+func (c *Client) ApiKeyManager() (client.ApiKeyManager, bool) {
+	return nil, false
+}
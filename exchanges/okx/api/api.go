@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
@@ -12,8 +13,14 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/cordialsys/offchain/pkg/logging"
+	"github.com/cordialsys/offchain/pkg/transport"
 )
 
+// rate limits and retries shared by all clients of the venue
+var transportOptions = transport.DefaultOptions()
+
 type Client struct {
 	apiKey     string
 	secretKey  string
@@ -29,7 +36,7 @@ func NewClient(apiKey, secretKey, passphrase string) (*Client, error) {
 		secretKey:  secretKey,
 		passphrase: passphrase,
 		baseURL:    "https://www.okx.com",
-		httpClient: &http.Client{},
+		httpClient: transport.NewClient("okx", transportOptions),
 	}, nil
 }
 
@@ -46,15 +53,14 @@ func (c *Client) sign(timestamp, method, requestPath, body string) string {
 }
 
 // Request makes an authenticated HTTP request to the OKX API
-func (c *Client) Request(method, path string, input interface{}, output interface{}, query url.Values) ([]byte, error) {
+func (c *Client) Request(ctx context.Context, method, path string, input interface{}, output interface{}, query url.Values) ([]byte, error) {
 	method = strings.ToUpper(method)
+	log := slog.With("method", method, "url", c.baseURL+path, "query", query)
 	if len(query) > 0 {
 		path += "?" + query.Encode()
 	}
 	apiUrl := c.baseURL + path
 
-	log := slog.With("method", method, "url", apiUrl, "query", query)
-
 	var bodyStr string
 	if input != nil {
 		jsonBody, err := json.Marshal(input)
@@ -63,12 +69,17 @@ func (c *Client) Request(method, path string, input interface{}, output interfac
 		}
 		bodyStr = string(jsonBody)
 	}
-	log.Debug("request", "body", bodyStr)
-
-	timestamp := time.Now().UTC().Format("2006-01-02T15:04:05.999Z")
-	signature := c.sign(timestamp, method, path, bodyStr)
+	log.DebugContext(ctx, "request", "body", logging.Body(bodyStr))
+
+	// Sign with the current timestamp, again on every retry
+	sign := func(req *http.Request) error {
+		timestamp := time.Now().UTC().Format("2006-01-02T15:04:05.999Z")
+		req.Header.Set("OK-ACCESS-SIGN", c.sign(timestamp, method, path, bodyStr))
+		req.Header.Set("OK-ACCESS-TIMESTAMP", timestamp)
+		return nil
+	}
 
-	req, err := http.NewRequest(method, apiUrl, strings.NewReader(bodyStr))
+	req, err := http.NewRequestWithContext(transport.WithSigner(ctx, sign), method, apiUrl, strings.NewReader(bodyStr))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -76,9 +87,10 @@ func (c *Client) Request(method, path string, input interface{}, output interfac
 	// Set headers
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("OK-ACCESS-KEY", c.apiKey)
-	req.Header.Set("OK-ACCESS-SIGN", signature)
-	req.Header.Set("OK-ACCESS-TIMESTAMP", timestamp)
 	req.Header.Set("OK-ACCESS-PASSPHRASE", c.passphrase)
+	if err := sign(req); err != nil {
+		return nil, err
+	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -90,7 +102,7 @@ func (c *Client) Request(method, path string, input interface{}, output interfac
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
-	log.Debug("response", "status", resp.StatusCode, "body", string(respBody))
+	log.DebugContext(ctx, "response", "status", resp.StatusCode, "body", logging.Body(respBody))
 
 	if output != nil {
 		err = json.Unmarshal(respBody, output)
@@ -1,6 +1,10 @@
 package api
 
-import oc "github.com/cordialsys/offchain"
+import (
+	"context"
+
+	oc "github.com/cordialsys/offchain"
+)
 
 type AccountTransferType string
 
@@ -31,9 +35,9 @@ type AccountTransferResult struct {
 }
 
 // https://www.okx.com/docs-v5/en/#funding-account-rest-api-funds-transfer
-func (c *Client) FundsTransfer(args *AccountTransferRequest) (*AccountTransferResponse, error) {
+func (c *Client) FundsTransfer(ctx context.Context, args *AccountTransferRequest) (*AccountTransferResponse, error) {
 	var response AccountTransferResponse
-	_, err := c.Request("POST", "/api/v5/asset/transfer", args, &response, nil)
+	_, err := c.Request(ctx, "POST", "/api/v5/asset/transfer", args, &response, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"context"
+	"fmt"
+)
+
+type GetAccountConfigResponse = Response[[]AccountConfig]
+
+type AccountConfig struct {
+	Uid     string `json:"uid"`
+	MainUid string `json:"mainUid"`
+	AcctLv  string `json:"acctLv"`
+	Label   string `json:"label"`
+	// Comma separated permissions of the API key, e.g. "read_only,withdraw,trade"
+	Perm string `json:"perm"`
+	// Comma separated IP addresses bound to the API key, empty if not bound
+	Ip string `json:"ip"`
+}
+
+// https://www.okx.com/docs-v5/en/#trading-account-rest-api-get-account-configuration
+func (c *Client) GetAccountConfig(ctx context.Context) (*GetAccountConfigResponse, error) {
+	var response GetAccountConfigResponse
+	_, err := c.Request(ctx, "GET", "/api/v5/account/config", nil, &response, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account config: %w", err)
+	}
+	return &response, nil
+}
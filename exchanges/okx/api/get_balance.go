@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 
 	oc "github.com/cordialsys/offchain"
@@ -17,9 +18,9 @@ type BalanceInfo struct {
 }
 
 // https://www.okx.com/docs-v5/en/#funding-account-rest-api-get-balance
-func (c *Client) GetBalance(account client.GetBalanceArgs) (*BalanceResponse, error) {
+func (c *Client) GetBalance(ctx context.Context, account client.GetBalanceArgs) (*BalanceResponse, error) {
 	var response BalanceResponse
-	_, err := c.Request("GET", "/api/v5/asset/balances", nil, &response, nil)
+	_, err := c.Request(ctx, "GET", "/api/v5/asset/balances", nil, &response, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get balance: %w", err)
 	}
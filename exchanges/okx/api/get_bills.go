@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	oc "github.com/cordialsys/offchain"
+)
+
+type BillType string
+
+const (
+	BillTypeTransferToSubAccount       BillType = "20"
+	BillTypeTransferFromSubAccount     BillType = "21"
+	BillTypeTransferOutFromSubToMain   BillType = "22"
+	BillTypeTransferInFromMainToSub    BillType = "23"
+	BillTypeTransferFromTradingAccount BillType = "130"
+	BillTypeTransferToTradingAccount   BillType = "131"
+)
+
+// String returns a description of the bill type
+func (t BillType) String() string {
+	switch t {
+	case BillTypeTransferToSubAccount:
+		return "Transfer to sub account"
+	case BillTypeTransferFromSubAccount:
+		return "Transfer from sub account"
+	case BillTypeTransferOutFromSubToMain:
+		return "Transfer out from sub to master account"
+	case BillTypeTransferInFromMainToSub:
+		return "Transfer in from master to sub account"
+	case BillTypeTransferFromTradingAccount:
+		return "Transferred from Trading account"
+	case BillTypeTransferToTradingAccount:
+		return "Transferred to Trading account"
+	default:
+		return string(t)
+	}
+}
+
+// TransferBillTypes are the bill types that record a transfer between accounts
+var TransferBillTypes = []BillType{
+	BillTypeTransferToSubAccount, BillTypeTransferFromSubAccount,
+	BillTypeTransferOutFromSubToMain, BillTypeTransferInFromMainToSub,
+	BillTypeTransferFromTradingAccount, BillTypeTransferToTradingAccount,
+}
+
+type BillsRequest struct {
+	Currency *oc.SymbolId `json:"ccy,omitempty"`
+	Type     BillType     `json:"type,omitempty"`
+	ClientId string       `json:"clientId,omitempty"`
+	// Unix milliseconds; return records earlier than this timestamp
+	After int64 `json:"after,omitempty"`
+	// Unix milliseconds; return records newer than this timestamp
+	Before int64 `json:"before,omitempty"`
+	Limit  int   `json:"limit,omitempty"`
+}
+
+type BillRecord struct {
+	BillId        string      `json:"billId"`
+	Currency      oc.SymbolId `json:"ccy"`
+	ClientId      string      `json:"clientId"`
+	BalanceChange oc.Amount   `json:"balChg"`
+	Balance       oc.Amount   `json:"bal"`
+	Type          BillType    `json:"type"`
+	Timestamp     string      `json:"ts"`
+	Notes         string      `json:"notes"`
+}
+
+type BillsResponse = Response[[]BillRecord]
+
+// https://www.okx.com/docs-v5/en/#funding-account-rest-api-asset-bills-details
+func (c *Client) GetBills(ctx context.Context, args *BillsRequest) (*BillsResponse, error) {
+	var response BillsResponse
+	query := url.Values{}
+
+	if args != nil {
+		if args.Currency != nil {
+			query.Set("ccy", string(*args.Currency))
+		}
+		if args.Type != "" {
+			query.Set("type", string(args.Type))
+		}
+		if args.ClientId != "" {
+			query.Set("clientId", args.ClientId)
+		}
+		if args.After != 0 {
+			query.Set("after", fmt.Sprintf("%d", args.After))
+		}
+		if args.Before != 0 {
+			query.Set("before", fmt.Sprintf("%d", args.Before))
+		}
+		if args.Limit != 0 {
+			query.Set("limit", fmt.Sprintf("%d", args.Limit))
+		}
+	}
+
+	_, err := c.Request(ctx, "GET", "/api/v5/asset/bills", nil, &response, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 
 	oc "github.com/cordialsys/offchain"
@@ -43,9 +44,9 @@ type CurrencyInfo struct {
 }
 
 // https://www.okx.com/docs-v5/en/#funding-account-rest-api-get-currencies
-func (c *Client) GetCurrencies() (*GetCurrenciesResponse, error) {
+func (c *Client) GetCurrencies(ctx context.Context) (*GetCurrenciesResponse, error) {
 	var response GetCurrenciesResponse
-	_, err := c.Request("GET", "/api/v5/asset/currencies", nil, &response, nil)
+	_, err := c.Request(ctx, "GET", "/api/v5/asset/currencies", nil, &response, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get assets: %w", err)
 	}
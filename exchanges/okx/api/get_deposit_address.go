@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"net/url"
 
 	oc "github.com/cordialsys/offchain"
@@ -21,12 +22,12 @@ type DepositAddressData struct {
 type GetDepositAddressResponse = Response[[]DepositAddressData]
 
 // https://www.okx.com/docs-v5/en/#funding-account-rest-api-asset-bills-details
-func (c *Client) GetDepositAddress(coin oc.SymbolId) (*GetDepositAddressResponse, error) {
+func (c *Client) GetDepositAddress(ctx context.Context, coin oc.SymbolId) (*GetDepositAddressResponse, error) {
 	params := url.Values{}
 	params.Set("ccy", string(coin))
 
 	var response GetDepositAddressResponse
-	_, err := c.Request("GET", "/api/v5/asset/deposit-address", nil, &response, params)
+	_, err := c.Request(ctx, "GET", "/api/v5/asset/deposit-address", nil, &response, params)
 	if err != nil {
 		return nil, err
 	}
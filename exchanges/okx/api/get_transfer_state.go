@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+	"net/url"
+
+	oc "github.com/cordialsys/offchain"
+)
+
+type TransferState string
+
+const (
+	TransferStateSuccess TransferState = "success"
+	TransferStatePending TransferState = "pending"
+	TransferStateFailed  TransferState = "failed"
+)
+
+type TransferStateResult struct {
+	TransferID string              `json:"transId"`
+	ClientId   string              `json:"clientId"`
+	Currency   oc.SymbolId         `json:"ccy"`
+	Amount     oc.Amount           `json:"amt"`
+	Type       AccountTransferType `json:"type"`
+	From       oc.AccountType      `json:"from"`
+	To         oc.AccountType      `json:"to"`
+	SubAccount oc.AccountId        `json:"subAcct"`
+	State      TransferState       `json:"state"`
+}
+
+type TransferStateResponse = Response[[]TransferStateResult]
+
+// https://www.okx.com/docs-v5/en/#funding-account-rest-api-get-funds-transfer-state
+func (c *Client) GetTransferState(ctx context.Context, transferId string, transferType AccountTransferType) (*TransferStateResponse, error) {
+	var response TransferStateResponse
+	query := url.Values{}
+	query.Set("transId", transferId)
+	if transferType != "" {
+		query.Set("type", string(transferType))
+	}
+
+	_, err := c.Request(ctx, "GET", "/api/v5/asset/transfer-state", nil, &response, query)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
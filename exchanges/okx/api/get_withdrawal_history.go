@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 
@@ -58,7 +59,7 @@ const (
 type WithdrawalHistoryResponse = Response[[]WithdrawalRecord]
 
 // https://www.okx.com/docs-v5/en/#funding-account-rest-api-cancel-withdrawal
-func (c *Client) GetWithdrawalHistory(args *WithdrawalHistoryRequest) (*WithdrawalHistoryResponse, error) {
+func (c *Client) GetWithdrawalHistory(ctx context.Context, args *WithdrawalHistoryRequest) (*WithdrawalHistoryResponse, error) {
 	var response WithdrawalHistoryResponse
 	query := url.Values{}
 
@@ -92,7 +93,7 @@ func (c *Client) GetWithdrawalHistory(args *WithdrawalHistoryRequest) (*Withdraw
 		}
 	}
 
-	_, err := c.Request("GET", "/api/v5/asset/withdrawal-history", nil, &response, query)
+	_, err := c.Request(ctx, "GET", "/api/v5/asset/withdrawal-history", nil, &response, query)
 	if err != nil {
 		return nil, err
 	}
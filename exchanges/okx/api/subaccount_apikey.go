@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	oc "github.com/cordialsys/offchain"
+)
+
+type SubaccountApiKey struct {
+	SubAccount oc.AccountId `json:"subAcct,omitempty"`
+	Label      string       `json:"label"`
+	ApiKey     string       `json:"apiKey"`
+	// Only returned when the key is created
+	SecretKey  string `json:"secretKey,omitempty"`
+	Passphrase string `json:"passphrase,omitempty"`
+	// Comma separated permissions, e.g. "read_only,trade"
+	Perm string `json:"perm"`
+	// Comma separated IP addresses bound to the API key
+	Ip string `json:"ip"`
+	// Unix milliseconds of when the key was created
+	Ts string `json:"ts"`
+}
+
+type SubaccountApiKeyResponse = Response[[]SubaccountApiKey]
+
+// https://www.okx.com/docs-v5/en/#sub-account-rest-api-query-the-apikey-of-a-sub-account
+func (c *Client) GetSubaccountApiKey(ctx context.Context, subaccount oc.AccountId, apiKey string) (*SubaccountApiKeyResponse, error) {
+	var response SubaccountApiKeyResponse
+	query := url.Values{}
+	query.Set("subAcct", string(subaccount))
+	if apiKey != "" {
+		query.Set("apiKey", apiKey)
+	}
+	_, err := c.Request(ctx, "GET", "/api/v5/users/subaccount/apikey", nil, &response, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sub-account api key: %w", err)
+	}
+	return &response, nil
+}
+
+type CreateSubaccountApiKeyRequest struct {
+	SubAccount oc.AccountId `json:"subAcct"`
+	Label      string       `json:"label"`
+	Passphrase string       `json:"passphrase"`
+	Perm       string       `json:"perm"`
+	Ip         string       `json:"ip,omitempty"`
+}
+
+// https://www.okx.com/docs-v5/en/#sub-account-rest-api-create-an-apikey-for-a-sub-account
+func (c *Client) CreateSubaccountApiKey(ctx context.Context, args *CreateSubaccountApiKeyRequest) (*SubaccountApiKeyResponse, error) {
+	var response SubaccountApiKeyResponse
+	_, err := c.Request(ctx, "POST", "/api/v5/users/subaccount/apikey", args, &response, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sub-account api key: %w", err)
+	}
+	return &response, nil
+}
+
+type DeleteSubaccountApiKeyRequest struct {
+	SubAccount oc.AccountId `json:"subAcct"`
+	ApiKey     string       `json:"apiKey"`
+}
+
+// https://www.okx.com/docs-v5/en/#sub-account-rest-api-delete-the-apikey-of-sub-accounts
+func (c *Client) DeleteSubaccountApiKey(ctx context.Context, args *DeleteSubaccountApiKeyRequest) (*Response[[]SubaccountApiKey], error) {
+	var response Response[[]SubaccountApiKey]
+	_, err := c.Request(ctx, "POST", "/api/v5/users/subaccount/delete-apikey", args, &response, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete sub-account api key: %w", err)
+	}
+	return &response, nil
+}
@@ -1,6 +1,10 @@
 package api
 
-import oc "github.com/cordialsys/offchain"
+import (
+	"context"
+
+	oc "github.com/cordialsys/offchain"
+)
 
 type SubaccountTransferRequest struct {
 	Currency       oc.SymbolId    `json:"ccy"`
@@ -20,9 +24,9 @@ type SubaccountTransferResult struct {
 
 // Rate limit: 1 request per second
 // https://www.okx.com/docs-v5/en/#sub-account-rest-api-get-history-of-managed-sub-account-transfer
-func (c *Client) SubaccountTransfer(args *SubaccountTransferRequest) (*SubaccountTransferResponse, error) {
+func (c *Client) SubaccountTransfer(ctx context.Context, args *SubaccountTransferRequest) (*SubaccountTransferResponse, error) {
 	var response SubaccountTransferResponse
-	_, err := c.Request("POST", "/api/v5/asset/subaccount/transfer", args, &response, nil)
+	_, err := c.Request(ctx, "POST", "/api/v5/asset/subaccount/transfer", args, &response, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,10 @@
 package api
 
-import oc "github.com/cordialsys/offchain"
+import (
+	"context"
+
+	oc "github.com/cordialsys/offchain"
+)
 
 type WithdrawalRequest struct {
 	Amount         string         `json:"amt"`
@@ -23,9 +27,9 @@ type WithdrawalResult struct {
 }
 
 // https://www.okx.com/docs-v5/en/#funding-account-rest-api-withdrawal
-func (c *Client) Withdrawal(args *WithdrawalRequest) (*WithdrawalResponse, error) {
+func (c *Client) Withdrawal(ctx context.Context, args *WithdrawalRequest) (*WithdrawalResponse, error) {
 	var response WithdrawalResponse
-	_, err := c.Request("POST", "/api/v5/asset/withdrawal", args, &response, nil)
+	_, err := c.Request(ctx, "POST", "/api/v5/asset/withdrawal", args, &response, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -1,8 +1,14 @@
 package okx
 
 import (
+	"context"
+	"crypto/rand"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	oc "github.com/cordialsys/offchain"
 	"github.com/cordialsys/offchain/client"
@@ -15,17 +21,19 @@ type Client struct {
 }
 
 var _ client.Client = &Client{}
+var _ client.ApiKeyInspector = &Client{}
+var _ client.ApiKeyManager = &Client{}
 
-func NewClient(config *oc.ExchangeClientConfig, secrets *oc.Account) (*Client, error) {
-	apiKey, err := secrets.ApiKeyRef.Load()
+func NewClient(ctx context.Context, config *oc.ExchangeClientConfig, secrets *oc.Account) (*Client, error) {
+	apiKey, err := secrets.ApiKeyRef.Load(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("could not load api key: %v", err)
 	}
-	secretKey, err := secrets.SecretKeyRef.Load()
+	secretKey, err := secrets.SecretKeyRef.Load(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("could not load secret key: %v", err)
 	}
-	passphrase, err := secrets.PassphraseRef.Load()
+	passphrase, err := secrets.PassphraseRef.Load(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("could not load passphrase: %v", err)
 	}
@@ -45,8 +53,8 @@ func (c *Client) Api() *api.Client {
 	return c.api
 }
 
-func (c *Client) ListAssets() ([]*oc.Asset, error) {
-	response, err := c.api.GetCurrencies()
+func (c *Client) ListAssets(ctx context.Context) ([]*oc.Asset, error) {
+	response, err := c.api.GetCurrencies(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -62,8 +70,8 @@ func (c *Client) ListAssets() ([]*oc.Asset, error) {
 	return assets, nil
 }
 
-func (c *Client) ListBalances(args client.GetBalanceArgs) ([]*client.BalanceDetail, error) {
-	response, err := c.api.GetBalance(args)
+func (c *Client) ListBalances(ctx context.Context, args client.GetBalanceArgs) ([]*client.BalanceDetail, error) {
+	response, err := c.api.GetBalance(ctx, args)
 	if err != nil {
 		return nil, err
 	}
@@ -80,13 +88,13 @@ func (c *Client) ListBalances(args client.GetBalanceArgs) ([]*client.BalanceDeta
 	return balances, nil
 }
 
-func (c *Client) CreateAccountTransfer(args client.AccountTransferArgs) (*client.TransferStatus, error) {
+func (c *Client) CreateAccountTransfer(ctx context.Context, args client.AccountTransferArgs) (*client.TransferStatus, error) {
 	from, fromType := args.GetFrom()
 	to, toType := args.GetTo()
 
 	if c.account.IsMain() && from != "" && to != "" {
 		// used when transferring between subaccounts (the other endpoint doesnt work for this condition)
-		res, err := c.api.SubaccountTransfer(&api.SubaccountTransferRequest{
+		res, err := c.api.SubaccountTransfer(ctx, &api.SubaccountTransferRequest{
 			Currency:       args.GetSymbol(),
 			Amount:         args.GetAmount(),
 			From:           fromType,
@@ -137,7 +145,7 @@ func (c *Client) CreateAccountTransfer(args client.AccountTransferArgs) (*client
 			// "When type is 1/2/4, this parameter is required."
 			req.SubAccount = &subAccountInQuestion
 		}
-		res, err := c.api.FundsTransfer(req)
+		res, err := c.api.FundsTransfer(ctx, req)
 		if err != nil {
 			return nil, err
 		}
@@ -148,12 +156,41 @@ func (c *Client) CreateAccountTransfer(args client.AccountTransferArgs) (*client
 	}
 }
 
+func (c *Client) GetTransferStatus(ctx context.Context, id string) (*client.TransferStatus, error) {
+	res, err := c.api.GetTransferState(ctx, id, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Data) == 0 && c.account.IsMain() {
+		// "If you want to query the transfer state of sub-account to master account via master APIKey, you need to set type=2"
+		res, err = c.api.GetTransferState(ctx, id, api.SubToMainUsingMain)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(res.Data) == 0 {
+		return nil, fmt.Errorf("transfer %s not found", id)
+	}
+
+	status := client.OperationStatusPending
+	switch res.Data[0].State {
+	case api.TransferStateSuccess:
+		status = client.OperationStatusSuccess
+	case api.TransferStateFailed:
+		status = client.OperationStatusFailed
+	}
+	return &client.TransferStatus{
+		ID:     res.Data[0].TransferID,
+		Status: status,
+	}, nil
+}
+
 // more special static numbers okx used
 const InternalTransfer = "3"
 const OnChainTransfer = "4"
 
-func (c *Client) CreateWithdrawal(args client.WithdrawalArgs) (*client.WithdrawalResponse, error) {
-	response, err := c.api.Withdrawal(&api.WithdrawalRequest{
+func (c *Client) CreateWithdrawal(ctx context.Context, args client.WithdrawalArgs) (*client.WithdrawalResponse, error) {
+	response, err := c.api.Withdrawal(ctx, &api.WithdrawalRequest{
 		Amount:         args.GetAmount().String(),
 		Destination:    OnChainTransfer,
 		Currency:       args.GetSymbol(),
@@ -169,8 +206,33 @@ func (c *Client) CreateWithdrawal(args client.WithdrawalArgs) (*client.Withdrawa
 	}, nil
 }
 
-func (c *Client) GetDepositAddress(args client.GetDepositAddressArgs) (oc.Address, error) {
-	response, err := c.api.GetDepositAddress(args.GetSymbol())
+func (c *Client) EstimateWithdrawal(ctx context.Context, args client.WithdrawalArgs) (*client.WithdrawalQuote, error) {
+	response, err := c.api.GetCurrencies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	expectedSymbolAndChain := api.NewSymbolAndChain(args.GetSymbol(), args.GetNetwork())
+	for _, currency := range response.Data {
+		if currency.Chain != expectedSymbolAndChain {
+			continue
+		}
+		// okx has dropped the fixed fee in favor of a min/max range; the minimum fee is used by default
+		feeS := currency.Fee
+		if feeS == "" {
+			feeS = currency.MinFee
+		}
+		fee, err := oc.NewAmountFromString(feeS)
+		if err != nil {
+			return nil, fmt.Errorf("invalid withdrawal fee %s: %w", feeS, err)
+		}
+		// okx does not include the fee in the withdrawal amount
+		return client.NewWithdrawalQuote(args, fee, currency.Currency, false), nil
+	}
+	return nil, fmt.Errorf("no withdrawal fee found for %s on network %s", args.GetSymbol(), args.GetNetwork())
+}
+
+func (c *Client) GetDepositAddress(ctx context.Context, args client.GetDepositAddressArgs) (oc.Address, error) {
+	response, err := c.api.GetDepositAddress(ctx, args.GetSymbol())
 	if err != nil {
 		return "", err
 	}
@@ -183,8 +245,8 @@ func (c *Client) GetDepositAddress(args client.GetDepositAddressArgs) (oc.Addres
 	return "", fmt.Errorf("no deposit address found for network %s", args.GetNetwork())
 }
 
-func (c *Client) ListWithdrawalHistory(args client.WithdrawalHistoryArgs) ([]*client.WithdrawalHistory, error) {
-	response, err := c.api.GetWithdrawalHistory(&api.WithdrawalHistoryRequest{})
+func (c *Client) ListWithdrawalHistory(ctx context.Context, args client.WithdrawalHistoryArgs) ([]*client.WithdrawalHistory, error) {
+	response, err := c.api.GetWithdrawalHistory(ctx, &api.WithdrawalHistoryRequest{WdId: args.GetId()})
 	if err != nil {
 		return nil, err
 	}
@@ -212,3 +274,165 @@ func (c *Client) ListWithdrawalHistory(args client.WithdrawalHistoryArgs) ([]*cl
 	}
 	return history, nil
 }
+
+func (c *Client) ListTransferHistory(ctx context.Context, args client.TransferHistoryArgs) ([]*client.TransferHistory, error) {
+	cursor, err := args.GetCursor()
+	if err != nil {
+		return nil, err
+	}
+	// enough records to fill the page after skipping those already listed
+	limit := args.GetLimit() + cursor.SeenCount()
+	if limit <= 0 || limit > 100 {
+		// okx limits bills to 100 per page
+		limit = 100
+	}
+
+	// the bills include deposits, withdrawals, rewards, etc, and can only be filtered by one type at a time
+	records := []api.BillRecord{}
+	for _, billType := range api.TransferBillTypes {
+		request := &api.BillsRequest{
+			Type:  billType,
+			Limit: limit,
+		}
+		if cursor != nil {
+			// okx returns records earlier than the "after" timestamp
+			request.After = cursor.Before + 1
+		}
+		response, err := c.api.GetBills(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, response.Data...)
+	}
+
+	history := []*client.TransferHistory{}
+	for _, record := range records {
+		timestamp, _ := strconv.ParseInt(record.Timestamp, 10, 64)
+		transfer := &client.TransferHistory{
+			ID:        record.BillId,
+			Status:    client.OperationStatusSuccess,
+			Symbol:    record.Currency,
+			Amount:    oc.Amount(record.BalanceChange.Decimal().Abs()),
+			Timestamp: timestamp,
+			Comment:   record.Type.String(),
+			Notes: map[string]string{
+				"type": string(record.Type),
+			},
+		}
+		switch record.Type {
+		case api.BillTypeTransferFromTradingAccount:
+			transfer.FromType = "18"
+			transfer.ToType = "6"
+		case api.BillTypeTransferToTradingAccount:
+			transfer.FromType = "6"
+			transfer.ToType = "18"
+		}
+		if record.Notes != "" {
+			transfer.Notes["notes"] = record.Notes
+		}
+		if record.ClientId != "" {
+			transfer.Notes["clientId"] = record.ClientId
+		}
+		history = append(history, transfer)
+	}
+	return client.PageTransferHistory(history, cursor, args.GetLimit()), nil
+}
+
+func (c *Client) GetApiKeyInfo(ctx context.Context) (*client.ApiKeyInfo, error) {
+	response, err := c.api.GetAccountConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Data) == 0 {
+		return nil, fmt.Errorf("no account config returned")
+	}
+	config := response.Data[0]
+	info := &client.ApiKeyInfo{
+		Permissions: splitList(config.Perm),
+		Ips:         splitList(config.Ip),
+	}
+	info.ReadOnly = slices.Equal(info.Permissions, []string{"read_only"})
+	info.Withdraw = slices.Contains(info.Permissions, "withdraw")
+	info.IpRestricted = len(info.Ips) > 0
+	return info, nil
+}
+
+func (c *Client) GetSubAccountApiKeyInfo(ctx context.Context, subaccount oc.AccountId, apiKey string) (*client.ApiKeyInfo, error) {
+	response, err := c.api.GetSubaccountApiKey(ctx, subaccount, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Data) == 0 {
+		return nil, fmt.Errorf("api key not found for sub-account %s", subaccount)
+	}
+	key := response.Data[0]
+	info := &client.ApiKeyInfo{
+		Permissions: splitList(key.Perm),
+		Ips:         splitList(key.Ip),
+	}
+	info.ReadOnly = slices.Equal(info.Permissions, []string{"read_only"})
+	info.Withdraw = slices.Contains(info.Permissions, "withdraw")
+	info.IpRestricted = len(info.Ips) > 0
+	if ts, err := strconv.ParseInt(key.Ts, 10, 64); err == nil && ts > 0 {
+		created := time.UnixMilli(ts).UTC()
+		info.CreatedAt = &created
+	}
+	return info, nil
+}
+
+func (c *Client) CreateSubAccountApiKey(ctx context.Context, args client.CreateApiKeyArgs) (*client.SubAccountApiKey, error) {
+	passphrase, err := newPassphrase()
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.api.CreateSubaccountApiKey(ctx, &api.CreateSubaccountApiKeyRequest{
+		SubAccount: args.GetSubAccount(),
+		Label:      args.GetLabel(),
+		Passphrase: passphrase,
+		Perm:       strings.Join(args.GetPermissions(), ","),
+		Ip:         strings.Join(args.GetIps(), ","),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Data) == 0 || response.Data[0].ApiKey == "" {
+		return nil, fmt.Errorf("no api key returned")
+	}
+	return &client.SubAccountApiKey{
+		ApiKey:     response.Data[0].ApiKey,
+		SecretKey:  response.Data[0].SecretKey,
+		Passphrase: passphrase,
+	}, nil
+}
+
+func (c *Client) DeleteSubAccountApiKey(ctx context.Context, subaccount oc.AccountId, apiKey string) error {
+	_, err := c.api.DeleteSubaccountApiKey(ctx, &api.DeleteSubaccountApiKeyRequest{
+		SubAccount: subaccount,
+		ApiKey:     apiKey,
+	})
+	return err
+}
+
+// newPassphrase returns a random passphrase meeting the requirements of OKX: 8-32 characters, with
+// upper and lower case letters, numbers and special characters.
+func newPassphrase() (string, error) {
+	const chars = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	random := make([]byte, 24)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	for i, b := range random {
+		random[i] = chars[int(b)%len(chars)]
+	}
+	return "Oc1-" + string(random), nil
+}
+
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
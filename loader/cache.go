@@ -0,0 +1,135 @@
+package loader
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"sync"
+
+	oc "github.com/cordialsys/offchain"
+	"github.com/cordialsys/offchain/pkg/secret"
+)
+
+type clientKey struct {
+	exchange   *oc.ExchangeConfig
+	subaccount bool
+	account    oc.AccountId
+}
+
+type cachedClient struct {
+	client Client
+	// identifies the values of the secrets the client was created with
+	fingerprint string
+	refs        []secret.Secret
+}
+
+// ClientCache reuses a client for each account, so that connections to the exchange are reused.
+// A new client is created when the values of the account's secrets change, or when the exchange
+// rejects the client's credentials.
+type ClientCache struct {
+	lock    sync.Mutex
+	clients map[clientKey]*cachedClient
+}
+
+func NewClientCache() *ClientCache {
+	return &ClientCache{
+		clients: map[clientKey]*cachedClient{},
+	}
+}
+
+// InvalidateOnRotation drops clients using a secret when the secret cache sees it change.
+func (c *ClientCache) InvalidateOnRotation(cache *secret.Cache) {
+	cache.OnChange(c.Invalidate)
+}
+
+func secretRefs(account *oc.Account) []secret.Secret {
+	return []secret.Secret{account.ApiKeyRef, account.SecretKeyRef, account.PassphraseRef, account.SecretsRef}
+}
+
+// fingerprint hashes the values of the account's credentials.  They're loaded through the secret cache,
+// so that rotated secrets are seen once the cache refreshes them.
+func fingerprint(ctx context.Context, account *oc.Account) (string, error) {
+	h := sha256.New()
+	for _, ref := range []secret.Secret{account.ApiKeyRef, account.SecretKeyRef, account.PassphraseRef} {
+		if ref != "" {
+			value, err := ref.Load(ctx)
+			if err != nil {
+				return "", err
+			}
+			h.Write([]byte(value))
+		}
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Get returns the cached client for the account, or creates one.  The account's secrets should
+// already be loaded.
+func (c *ClientCache) Get(ctx context.Context, config *oc.ExchangeConfig, account *oc.Account) (Client, error) {
+	key := clientKey{config, account.SubAccount, account.Id}
+	refs := secretRefs(account)
+	fp, err := fingerprint(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	cached, ok := c.clients[key]
+	c.lock.Unlock()
+	if ok && cached.fingerprint == fp {
+		return cached.client, nil
+	}
+
+	cli, err := NewClient(ctx, config, account)
+	if err != nil {
+		return nil, err
+	}
+	entry := &cachedClient{
+		client:      cli,
+		fingerprint: fp,
+		refs:        refs,
+	}
+	if extra, ok := cli.(*ClientExtra); ok {
+		extra.onAuthFailure = func() { c.drop(key, entry) }
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.clients[key] = entry
+	return cli, nil
+}
+
+// drop removes a client whose credentials were rejected, and reloads its secrets on next use in case
+// they've been rotated.
+func (c *ClientCache) drop(key clientKey, entry *cachedClient) {
+	c.lock.Lock()
+	if c.clients[key] == entry {
+		delete(c.clients, key)
+	}
+	c.lock.Unlock()
+	if cache, ok := secret.DefaultCache(); ok {
+		for _, ref := range entry.refs {
+			if ref != "" {
+				cache.Invalidate(ref)
+			}
+		}
+	}
+}
+
+// Invalidate drops the clients that use the secret.
+func (c *ClientCache) Invalidate(s secret.Secret) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for key, cached := range c.clients {
+		if slices.Contains(cached.refs, s) {
+			delete(c.clients, key)
+		}
+	}
+}
+
+// Clear drops every client, e.g. after the configuration has been reloaded.
+func (c *ClientCache) Clear() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.clients = map[clientKey]*cachedClient{}
+}
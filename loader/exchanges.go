@@ -1,6 +1,7 @@
 package loader
 
 import (
+	"context"
 	"fmt"
 
 	oc "github.com/cordialsys/offchain"
@@ -10,34 +11,146 @@ import (
 	"github.com/cordialsys/offchain/exchanges/binanceus"
 	"github.com/cordialsys/offchain/exchanges/bybit"
 	"github.com/cordialsys/offchain/exchanges/okx"
+	"github.com/cordialsys/offchain/pkg/transport"
 )
 
 type Client interface {
 	client.Client
 	// These methods basically just read the config
-	ListAccountTypes() ([]*oc.AccountTypeConfig, error)
-	ListSubaccounts() ([]*oc.SubAccountHeader, error)
+	ListAccountTypes(ctx context.Context) ([]*oc.AccountTypeConfig, error)
+	ListSubaccounts(ctx context.Context) ([]*oc.SubAccountHeader, error)
+	// The permissions of the API key, if the exchange reports them (ok is false otherwise)
+	GetApiKeyInfo(ctx context.Context) (info *client.ApiKeyInfo, ok bool, err error)
+	// Manages the API keys of sub-accounts, if the exchange lets the main account do so (ok is false otherwise)
+	ApiKeyManager() (manager client.ApiKeyManager, ok bool)
 }
 
+// ClientExtra adds the config based methods, and applies the configured deadline to each operation.
 type ClientExtra struct {
-	client.Client
-	cfg *oc.ExchangeConfig
+	client client.Client
+	cfg    *oc.ExchangeConfig
+	// Called when the exchange rejects the credentials of the client
+	onAuthFailure func()
 }
 
 var _ Client = &ClientExtra{}
 
 func newClient(cfg *oc.ExchangeConfig, client client.Client) Client {
 	return &ClientExtra{
-		Client: client,
+		client: client,
 		cfg:    cfg,
 	}
 }
 
-func (c *ClientExtra) ListAccountTypes() ([]*oc.AccountTypeConfig, error) {
+func (c *ClientExtra) withTimeout(ctx context.Context, op oc.Operation) (context.Context, context.CancelFunc) {
+	if c.onAuthFailure != nil {
+		ctx = transport.WithAuthFailureHandler(ctx, c.onAuthFailure)
+	}
+	return context.WithTimeout(ctx, c.cfg.Timeouts.For(op))
+}
+
+func (c *ClientExtra) ListAssets(ctx context.Context) ([]*oc.Asset, error) {
+	ctx, cancel := c.withTimeout(ctx, oc.OperationListAssets)
+	defer cancel()
+	return c.client.ListAssets(ctx)
+}
+
+func (c *ClientExtra) ListBalances(ctx context.Context, args client.GetBalanceArgs) ([]*client.BalanceDetail, error) {
+	ctx, cancel := c.withTimeout(ctx, oc.OperationListBalances)
+	defer cancel()
+	return c.client.ListBalances(ctx, args)
+}
+
+func (c *ClientExtra) CreateAccountTransfer(ctx context.Context, args client.AccountTransferArgs) (*client.TransferStatus, error) {
+	ctx, cancel := c.withTimeout(ctx, oc.OperationCreateAccountTransfer)
+	defer cancel()
+	return c.client.CreateAccountTransfer(ctx, args)
+}
+
+func (c *ClientExtra) GetTransferStatus(ctx context.Context, id string) (*client.TransferStatus, error) {
+	ctx, cancel := c.withTimeout(ctx, oc.OperationGetTransferStatus)
+	defer cancel()
+	return c.client.GetTransferStatus(ctx, id)
+}
+
+func (c *ClientExtra) CreateWithdrawal(ctx context.Context, args client.WithdrawalArgs) (*client.WithdrawalResponse, error) {
+	ctx, cancel := c.withTimeout(ctx, oc.OperationCreateWithdrawal)
+	defer cancel()
+	return c.client.CreateWithdrawal(ctx, args)
+}
+
+func (c *ClientExtra) EstimateWithdrawal(ctx context.Context, args client.WithdrawalArgs) (*client.WithdrawalQuote, error) {
+	ctx, cancel := c.withTimeout(ctx, oc.OperationEstimateWithdrawal)
+	defer cancel()
+	return c.client.EstimateWithdrawal(ctx, args)
+}
+
+func (c *ClientExtra) GetDepositAddress(ctx context.Context, args client.GetDepositAddressArgs) (oc.Address, error) {
+	ctx, cancel := c.withTimeout(ctx, oc.OperationGetDepositAddress)
+	defer cancel()
+	return c.client.GetDepositAddress(ctx, args)
+}
+
+func (c *ClientExtra) ListWithdrawalHistory(ctx context.Context, args client.WithdrawalHistoryArgs) ([]*client.WithdrawalHistory, error) {
+	ctx, cancel := c.withTimeout(ctx, oc.OperationListWithdrawalHistory)
+	defer cancel()
+	return c.client.ListWithdrawalHistory(ctx, args)
+}
+
+func (c *ClientExtra) ListTransferHistory(ctx context.Context, args client.TransferHistoryArgs) ([]*client.TransferHistory, error) {
+	ctx, cancel := c.withTimeout(ctx, oc.OperationListTransferHistory)
+	defer cancel()
+	return c.client.ListTransferHistory(ctx, args)
+}
+
+func (c *ClientExtra) GetApiKeyInfo(ctx context.Context) (*client.ApiKeyInfo, bool, error) {
+	inspector, ok := c.client.(client.ApiKeyInspector)
+	if !ok {
+		return nil, false, nil
+	}
+	ctx, cancel := c.withTimeout(ctx, oc.OperationGetApiKeyInfo)
+	defer cancel()
+	info, err := inspector.GetApiKeyInfo(ctx)
+	return info, true, err
+}
+
+func (c *ClientExtra) ApiKeyManager() (client.ApiKeyManager, bool) {
+	manager, ok := c.client.(client.ApiKeyManager)
+	if !ok {
+		return nil, false
+	}
+	return &apiKeyManager{manager, c}, true
+}
+
+// apiKeyManager applies the configured deadline to each operation.
+type apiKeyManager struct {
+	manager client.ApiKeyManager
+	c       *ClientExtra
+}
+
+func (m *apiKeyManager) GetSubAccountApiKeyInfo(ctx context.Context, subaccount oc.AccountId, apiKey string) (*client.ApiKeyInfo, error) {
+	ctx, cancel := m.c.withTimeout(ctx, oc.OperationManageApiKeys)
+	defer cancel()
+	return m.manager.GetSubAccountApiKeyInfo(ctx, subaccount, apiKey)
+}
+
+func (m *apiKeyManager) CreateSubAccountApiKey(ctx context.Context, args client.CreateApiKeyArgs) (*client.SubAccountApiKey, error) {
+	ctx, cancel := m.c.withTimeout(ctx, oc.OperationManageApiKeys)
+	defer cancel()
+	return m.manager.CreateSubAccountApiKey(ctx, args)
+}
+
+func (m *apiKeyManager) DeleteSubAccountApiKey(ctx context.Context, subaccount oc.AccountId, apiKey string) error {
+	ctx, cancel := m.c.withTimeout(ctx, oc.OperationManageApiKeys)
+	defer cancel()
+	return m.manager.DeleteSubAccountApiKey(ctx, subaccount, apiKey)
+}
+
+func (c *ClientExtra) ListAccountTypes(ctx context.Context) ([]*oc.AccountTypeConfig, error) {
 	return c.cfg.AccountTypes, nil
 }
 
-func (c *ClientExtra) ListSubaccounts() ([]*oc.SubAccountHeader, error) {
+func (c *ClientExtra) ListSubaccounts(ctx context.Context) ([]*oc.SubAccountHeader, error) {
 	subaccounts := []*oc.SubAccountHeader{}
 	for _, subaccount := range c.cfg.SubAccounts {
 		subaccounts = append(subaccounts, &subaccount.SubAccountHeader)
@@ -45,20 +158,20 @@ func (c *ClientExtra) ListSubaccounts() ([]*oc.SubAccountHeader, error) {
 	return subaccounts, nil
 }
 
-func NewClient(config *oc.ExchangeConfig, account *oc.Account) (Client, error) {
+func NewClient(ctx context.Context, config *oc.ExchangeConfig, account *oc.Account) (Client, error) {
 	var cli client.Client
 	var err error
 	switch config.ExchangeId {
 	case oc.Okx:
-		cli, err = okx.NewClient(&config.ExchangeClientConfig, account)
+		cli, err = okx.NewClient(ctx, &config.ExchangeClientConfig, account)
 	case oc.Bybit:
-		cli, err = bybit.NewClient(&config.ExchangeClientConfig, account)
+		cli, err = bybit.NewClient(ctx, &config.ExchangeClientConfig, account)
 	case oc.Binance:
-		cli, err = binance.NewClient(&config.ExchangeClientConfig, account)
+		cli, err = binance.NewClient(ctx, &config.ExchangeClientConfig, account)
 	case oc.BinanceUS:
-		cli, err = binanceus.NewClient(&config.ExchangeClientConfig, account)
+		cli, err = binanceus.NewClient(ctx, &config.ExchangeClientConfig, account)
 	case oc.Backpack:
-		cli, err = backpack.NewClient(&config.ExchangeClientConfig, account)
+		cli, err = backpack.NewClient(ctx, &config.ExchangeClientConfig, account)
 	default:
 		return nil, fmt.Errorf("unsupported exchange: %s", config.ExchangeId)
 	}
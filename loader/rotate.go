@@ -0,0 +1,238 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	oc "github.com/cordialsys/offchain"
+	"github.com/cordialsys/offchain/client"
+	"github.com/cordialsys/offchain/pkg/secret"
+)
+
+type RotateOptions struct {
+	// Label of the new key on the exchange
+	Label string
+	// How long to wait before revoking the old key, e.g. so running servers reload the secrets first
+	Grace time.Duration
+	// Don't revoke the old key
+	KeepOld bool
+	// How many times to try the new key, as exchanges may take a moment to accept it
+	VerifyAttempts int
+	VerifyInterval time.Duration
+}
+
+func DefaultRotateOptions() RotateOptions {
+	return RotateOptions{
+		Label:          "offchain-" + time.Now().UTC().Format("20060102-150405"),
+		VerifyAttempts: 5,
+		VerifyInterval: 2 * time.Second,
+	}
+}
+
+type RotateResult struct {
+	Exchange    oc.ExchangeId `json:"exchange"`
+	SubAccount  oc.AccountId  `json:"subaccount"`
+	OldApiKey   string        `json:"old_api_key"`
+	NewApiKey   string        `json:"new_api_key"`
+	Permissions []string      `json:"permissions"`
+	Ips         []string      `json:"ips,omitempty"`
+	Revoked     bool          `json:"revoked"`
+}
+
+// rotatedSecrets are the secrets of a sub-account that are written when its key is rotated.
+type rotatedSecrets struct {
+	oc.MultiSecret
+	// the values before rotation, to restore if the new key can't be used
+	original map[secret.Secret]string
+}
+
+func newRotatedSecrets(ctx context.Context, exchange oc.ExchangeId, secrets oc.MultiSecret) (*rotatedSecrets, error) {
+	refs := []secret.Secret{secrets.SecretsRef}
+	if secrets.SecretsRef == "" {
+		refs = []secret.Secret{secrets.ApiKeyRef, secrets.SecretKeyRef}
+		if exchange == oc.Okx {
+			if secrets.PassphraseRef == "" {
+				return nil, fmt.Errorf("a passphrase secret must be configured to rotate %s keys", exchange)
+			}
+			refs = append(refs, secrets.PassphraseRef)
+		}
+	}
+	original := map[secret.Secret]string{}
+	for _, ref := range refs {
+		if err := ref.CheckStore(); err != nil {
+			return nil, fmt.Errorf("cannot write the new key: %w", err)
+		}
+		value, err := ref.Load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		original[ref] = value
+	}
+	return &rotatedSecrets{secrets, original}, nil
+}
+
+func (s *rotatedSecrets) write(ctx context.Context, key *client.SubAccountApiKey) error {
+	if s.SecretsRef != "" {
+		bundle := map[string]string{
+			"api_key":    key.ApiKey,
+			"secret_key": key.SecretKey,
+		}
+		if key.Passphrase != "" {
+			bundle["passphrase"] = key.Passphrase
+		}
+		bz, err := json.Marshal(bundle)
+		if err != nil {
+			return err
+		}
+		return s.SecretsRef.Store(ctx, string(bz))
+	}
+	if err := s.ApiKeyRef.Store(ctx, key.ApiKey); err != nil {
+		return err
+	}
+	if err := s.SecretKeyRef.Store(ctx, key.SecretKey); err != nil {
+		return err
+	}
+	if key.Passphrase != "" {
+		return s.PassphraseRef.Store(ctx, key.Passphrase)
+	}
+	return nil
+}
+
+func (s *rotatedSecrets) restore(ctx context.Context) error {
+	errs := []error{}
+	for ref, value := range s.original {
+		errs = append(errs, ref.Store(ctx, value))
+	}
+	return errors.Join(errs...)
+}
+
+// RotateSubAccountKey replaces the API key of a sub-account, using the main account's key.  The new key has
+// the same permissions and IP addresses as the old one.  It's written to the sub-account's secrets, and
+// read back and used once before the old key is revoked.  If the new key can't be written or used, the
+// secrets are restored and the new key is deleted.
+func RotateSubAccountKey(ctx context.Context, exchangeCfg *oc.ExchangeConfig, subaccount *oc.SubAccount, opts RotateOptions) (*RotateResult, error) {
+	log := slog.With("exchange", exchangeCfg.ExchangeId, "subaccount", subaccount.Id)
+	secrets, err := newRotatedSecrets(ctx, exchangeCfg.ExchangeId, subaccount.MultiSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	mainAccount := exchangeCfg.AsAccount()
+	if err := mainAccount.LoadSecrets(ctx); err != nil {
+		return nil, fmt.Errorf("could not load secrets for the main account: %w", err)
+	}
+	mainClient, err := NewClient(ctx, exchangeCfg, mainAccount)
+	if err != nil {
+		return nil, err
+	}
+	manager, ok := mainClient.ApiKeyManager()
+	if !ok {
+		return nil, fmt.Errorf("api keys of %s sub-accounts can't be created programmatically", exchangeCfg.ExchangeId)
+	}
+
+	account := subaccount.AsAccount()
+	if err := account.LoadSecrets(ctx); err != nil {
+		return nil, fmt.Errorf("could not load secrets for the sub-account: %w", err)
+	}
+	oldApiKey, err := account.ApiKeyRef.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	info, err := manager.GetSubAccountApiKeyInfo(ctx, subaccount.Id, oldApiKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not look up the current api key: %w", err)
+	}
+
+	newKey, err := manager.CreateSubAccountApiKey(ctx, client.NewCreateApiKeyArgs(subaccount.Id, opts.Label, info))
+	if err != nil {
+		return nil, fmt.Errorf("could not create a new api key: %w", err)
+	}
+	log.Info("created api key", "api_key", newKey.ApiKey)
+	result := &RotateResult{
+		Exchange:    exchangeCfg.ExchangeId,
+		SubAccount:  subaccount.Id,
+		OldApiKey:   oldApiKey,
+		NewApiKey:   newKey.ApiKey,
+		Permissions: info.Permissions,
+		Ips:         info.Ips,
+	}
+
+	// undo the rotation, keeping the old key
+	abort := func(cause error) (*RotateResult, error) {
+		errs := []error{cause}
+		if err := secrets.restore(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to restore the previous secrets: %w", err))
+		}
+		if err := manager.DeleteSubAccountApiKey(ctx, subaccount.Id, newKey.ApiKey); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete the new api key %s: %w", newKey.ApiKey, err))
+		}
+		return nil, errors.Join(errs...)
+	}
+
+	if err := secrets.write(ctx, newKey); err != nil {
+		return abort(fmt.Errorf("could not write the new api key: %w", err))
+	}
+	log.Info("wrote api key", "api_key", newKey.ApiKey)
+
+	if err := verifyApiKey(ctx, exchangeCfg, subaccount, newKey.ApiKey, opts); err != nil {
+		return abort(fmt.Errorf("could not verify the new api key: %w", err))
+	}
+	log.Info("verified api key", "api_key", newKey.ApiKey)
+
+	if opts.KeepOld {
+		return result, nil
+	}
+	if opts.Grace > 0 {
+		log.Info("waiting before revoking the old api key", "grace", opts.Grace)
+		select {
+		case <-time.After(opts.Grace):
+		case <-ctx.Done():
+			return result, fmt.Errorf("old api key %s was not revoked: %w", oldApiKey, ctx.Err())
+		}
+	}
+	if err := manager.DeleteSubAccountApiKey(ctx, subaccount.Id, oldApiKey); err != nil {
+		return result, fmt.Errorf("could not revoke the old api key %s: %w", oldApiKey, err)
+	}
+	result.Revoked = true
+	log.Info("revoked api key", "api_key", oldApiKey)
+	return result, nil
+}
+
+// verifyApiKey reloads the sub-account's secrets, and checks that the exchange accepts the new key.
+func verifyApiKey(ctx context.Context, exchangeCfg *oc.ExchangeConfig, subaccount *oc.SubAccount, apiKey string, opts RotateOptions) error {
+	account := subaccount.AsAccount()
+	if err := account.LoadSecrets(ctx); err != nil {
+		return err
+	}
+	loaded, err := account.ApiKeyRef.Load(ctx)
+	if err != nil {
+		return err
+	}
+	if loaded != apiKey {
+		return fmt.Errorf("the secrets did not return the new api key")
+	}
+	cli, err := NewClient(ctx, exchangeCfg, account)
+	if err != nil {
+		return err
+	}
+	attempts := max(opts.VerifyAttempts, 1)
+	for attempt := 1; ; attempt++ {
+		_, ok, err := cli.GetApiKeyInfo(ctx)
+		if err == nil && !ok {
+			accountType, _ := exchangeCfg.FirstAccountType()
+			_, err = cli.ListBalances(ctx, client.NewGetBalanceArgs(accountType.Type))
+		}
+		if err == nil || attempt >= attempts {
+			return err
+		}
+		select {
+		case <-time.After(opts.VerifyInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
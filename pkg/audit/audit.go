@@ -0,0 +1,302 @@
+// Package audit implements an append-only, hash-chained log of the requests made to the server.
+//
+// Each entry is written as a single JSON line and includes the hash of the entry before it.  The
+// hash of an entry covers all of its fields (except the hash itself), so modifying, removing or
+// reordering any entry breaks the chain from that point on, which Verify will detect.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Hash of the "previous" entry for the first entry in a log.
+var GenesisHash = hex.EncodeToString(make([]byte, sha256.Size))
+
+// Method of the entries for withdrawals the server makes itself, once a withdrawal intent is ready.
+const MethodExecute = "EXECUTE"
+
+type Entry struct {
+	Seq      uint64    `json:"seq"`
+	Time     time.Time `json:"time"`
+	PrevHash string    `json:"prev_hash"`
+
+	// The HTTP method, or MethodExecute
+	Method   string `json:"method"`
+	Path     string `json:"path"`
+	Exchange string `json:"exchange,omitempty"`
+
+	// How the request was authenticated, e.g. "http-signature" or "bearer".
+	Auth  string `json:"auth"`
+	KeyId string `json:"key_id,omitempty"`
+	// The signature and signature-input headers, if the request was signed.
+	Signature      string `json:"signature,omitempty"`
+	SignatureInput string `json:"signature_input,omitempty"`
+	SubAccount     string `json:"sub_account,omitempty"`
+
+	// Normalized request arguments (path params, query and body)
+	Args json.RawMessage `json:"args,omitempty"`
+
+	Status    int             `json:"status"`
+	Response  json.RawMessage `json:"response,omitempty"`
+	LatencyMs int64           `json:"latency_ms"`
+	Error     string          `json:"error,omitempty"`
+
+	Hash string `json:"hash"`
+}
+
+// ComputeHash returns the hash of the entry, which covers every field besides Hash.
+func (e *Entry) ComputeHash() (string, error) {
+	cpy := *e
+	cpy.Hash = ""
+	bz, err := json.Marshal(&cpy)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256(bz)
+	return hex.EncodeToString(digest[:]), nil
+}
+
+// Normalize returns the compact JSON encoding of a value, with object keys sorted,
+// so that equivalent arguments are always recorded the same way.
+func Normalize(value any) (json.RawMessage, error) {
+	bz, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return NormalizeJSON(bz)
+}
+
+// NormalizeJSON re-encodes a JSON document compactly with sorted object keys.
+// Documents that aren't valid JSON are recorded as a JSON string.
+func NormalizeJSON(bz []byte) (json.RawMessage, error) {
+	if len(bytes.TrimSpace(bz)) == 0 {
+		return nil, nil
+	}
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(bz))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		value = string(bz)
+	}
+	return json.Marshal(value)
+}
+
+type Config struct {
+	// Path to the file to append the log to.
+	File string `yaml:"file"`
+	// Write the log to stdout.
+	Stdout bool `yaml:"stdout"`
+}
+
+func (cfg Config) Enabled() bool {
+	return cfg.File != "" || cfg.Stdout
+}
+
+// Log appends entries to one or more sinks, continuing the chain of any existing log file.
+type Log struct {
+	lock     sync.Mutex
+	seq      uint64
+	prevHash string
+	sinks    []io.Writer
+	closers  []io.Closer
+	// Set once a write fails, as a sink may be left with part of an entry
+	failed error
+}
+
+func New(sinks ...io.Writer) *Log {
+	return &Log{
+		prevHash: GenesisHash,
+		sinks:    sinks,
+	}
+}
+
+// Open creates a log from the configuration.  An existing log file is verified before
+// appending to it, so that new entries are never chained onto a tampered log.
+func Open(cfg Config) (*Log, error) {
+	log := New()
+	if cfg.File != "" {
+		if f, err := os.Open(cfg.File); err == nil {
+			last, err := Verify(f)
+			f.Close()
+			if err != nil {
+				return nil, fmt.Errorf("existing audit log %s is invalid: %w", cfg.File, err)
+			}
+			if last != nil {
+				log.seq = last.Seq + 1
+				log.prevHash = last.Hash
+			}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+
+		f, err := os.OpenFile(cfg.File, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("could not open audit log: %w", err)
+		}
+		log.sinks = append(log.sinks, f)
+		log.closers = append(log.closers, f)
+	}
+	if cfg.Stdout {
+		log.sinks = append(log.sinks, os.Stdout)
+	}
+	return log, nil
+}
+
+// Append chains the entry onto the log and writes it to every sink.  If any sink can't be written, the
+// chain isn't advanced and the log fails: later entries are refused, as the sinks may no longer agree
+// on what the last entry is.
+func (l *Log) Append(entry *Entry) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.failed != nil {
+		return fmt.Errorf("audit log failed: %w", l.failed)
+	}
+
+	entry.Seq = l.seq
+	entry.PrevHash = l.prevHash
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	entry.Time = entry.Time.UTC()
+	hash, err := entry.ComputeHash()
+	if err != nil {
+		return err
+	}
+	entry.Hash = hash
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	var errs []error
+	for _, sink := range l.sinks {
+		if _, err := sink.Write(line); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if f, ok := sink.(*os.File); ok && f != os.Stdout {
+			if err := f.Sync(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		l.failed = errors.Join(errs...)
+		return l.failed
+	}
+	l.seq++
+	l.prevHash = hash
+	return nil
+}
+
+// Err returns the error that failed the log, or nil if entries can still be appended.
+func (l *Log) Err() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.failed
+}
+
+func (l *Log) Close() error {
+	var errs []error
+	for _, closer := range l.closers {
+		errs = append(errs, closer.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Read calls fn for each entry in the log, in order.
+func Read(r io.Reader, fn func(entry *Entry) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		entry := &Entry{}
+		if err := json.Unmarshal(scanner.Bytes(), entry); err != nil {
+			return fmt.Errorf("line %d: invalid entry: %w", line, err)
+		}
+		if err := fn(entry); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// Verify checks that every entry in the log is intact and chained to the one before it.
+// It returns the last entry, or nil if the log is empty.
+func Verify(r io.Reader) (*Entry, error) {
+	var last *Entry
+	err := Read(r, func(entry *Entry) error {
+		expectedSeq := uint64(0)
+		expectedPrev := GenesisHash
+		if last != nil {
+			expectedSeq = last.Seq + 1
+			expectedPrev = last.Hash
+		}
+		if entry.Seq != expectedSeq {
+			return fmt.Errorf("expected seq %d, got %d", expectedSeq, entry.Seq)
+		}
+		if entry.PrevHash != expectedPrev {
+			return fmt.Errorf("entry %d does not chain to the previous entry", entry.Seq)
+		}
+		hash, err := entry.ComputeHash()
+		if err != nil {
+			return err
+		}
+		if hash != entry.Hash {
+			return fmt.Errorf("entry %d has been modified, expected hash %s", entry.Seq, hash)
+		}
+		last = entry
+		return nil
+	})
+	return last, err
+}
+
+type Filter struct {
+	Exchange string
+	KeyId    string
+	Since    time.Time
+	Until    time.Time
+}
+
+func (f *Filter) Matches(entry *Entry) bool {
+	if f.Exchange != "" && entry.Exchange != f.Exchange {
+		return false
+	}
+	if f.KeyId != "" && entry.KeyId != f.KeyId {
+		return false
+	}
+	if !f.Since.IsZero() && entry.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !entry.Time.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Query returns the entries of the log matching the filter.
+func Query(r io.Reader, filter Filter) ([]*Entry, error) {
+	entries := []*Entry{}
+	err := Read(r, func(entry *Entry) error {
+		if filter.Matches(entry) {
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	return entries, err
+}
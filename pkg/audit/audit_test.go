@@ -0,0 +1,155 @@
+package audit_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cordialsys/offchain/pkg/audit"
+	"github.com/stretchr/testify/require"
+)
+
+func appendEntries(t *testing.T, log *audit.Log, start time.Time) {
+	for i, exchange := range []string{"binance", "okx", "binance"} {
+		args, err := audit.Normalize(map[string]string{"symbol": "USDC", "amount": "1"})
+		require.NoError(t, err)
+		err = log.Append(&audit.Entry{
+			Time:     start.Add(time.Duration(i) * time.Hour),
+			Method:   "POST",
+			Path:     "/v1/exchanges/" + exchange + "/withdrawal",
+			Exchange: exchange,
+			Auth:     "http-signature",
+			KeyId:    []string{"key1", "key2", "key2"}[i],
+			Args:     args,
+			Status:   200,
+		})
+		require.NoError(t, err)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	bz, err := audit.NormalizeJSON([]byte(`{ "b": 1.10, "a": {"d": true, "c": null} }`))
+	require.NoError(t, err)
+	require.Equal(t, `{"a":{"c":null,"d":true},"b":1.10}`, string(bz))
+
+	bz, err = audit.NormalizeJSON([]byte(`not json`))
+	require.NoError(t, err)
+	require.Equal(t, `"not json"`, string(bz))
+
+	bz, err = audit.NormalizeJSON(nil)
+	require.NoError(t, err)
+	require.Nil(t, bz)
+}
+
+func TestVerify(t *testing.T) {
+	buf := &bytes.Buffer{}
+	appendEntries(t, audit.New(buf), time.Now())
+	lines := strings.SplitAfter(buf.String(), "\n")
+
+	last, err := audit.Verify(strings.NewReader(buf.String()))
+	require.NoError(t, err)
+	require.EqualValues(t, 2, last.Seq)
+
+	last, err = audit.Verify(strings.NewReader(""))
+	require.NoError(t, err)
+	require.Nil(t, last)
+
+	// modified entry
+	tampered := strings.Replace(buf.String(), `"okx"`, `"bybit"`, 1)
+	_, err = audit.Verify(strings.NewReader(tampered))
+	require.ErrorContains(t, err, "entry 1 has been modified")
+
+	// removed entry
+	_, err = audit.Verify(strings.NewReader(lines[0] + lines[2]))
+	require.ErrorContains(t, err, "expected seq 1, got 2")
+
+	// reordered entries
+	_, err = audit.Verify(strings.NewReader(lines[1] + lines[0]))
+	require.ErrorContains(t, err, "expected seq 0, got 1")
+}
+
+func TestOpenContinuesChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	log, err := audit.Open(audit.Config{File: path})
+	require.NoError(t, err)
+	appendEntries(t, log, time.Now())
+	require.NoError(t, log.Close())
+
+	log, err = audit.Open(audit.Config{File: path})
+	require.NoError(t, err)
+	appendEntries(t, log, time.Now())
+	require.NoError(t, log.Close())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	last, err := audit.Verify(f)
+	require.NoError(t, err)
+	require.EqualValues(t, 5, last.Seq)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// refuse to append to a tampered log
+	bz, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, bytes.Replace(bz, []byte(`"key1"`), []byte(`"key3"`), 1), 0600))
+	_, err = audit.Open(audit.Config{File: path})
+	require.ErrorContains(t, err, "is invalid")
+}
+
+type failingWriter struct {
+	fail bool
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.fail {
+		return 0, fmt.Errorf("disk full")
+	}
+	return len(p), nil
+}
+
+func TestAppendFails(t *testing.T) {
+	buf := &bytes.Buffer{}
+	sink := &failingWriter{}
+	log := audit.New(buf, sink)
+	appendEntries(t, log, time.Now())
+	require.NoError(t, log.Err())
+
+	sink.fail = true
+	require.ErrorContains(t, log.Append(&audit.Entry{Method: "POST"}), "disk full")
+	require.ErrorContains(t, log.Err(), "disk full")
+
+	// the log stays failed, rather than chaining entries that some sinks are missing
+	sink.fail = false
+	require.ErrorContains(t, log.Append(&audit.Entry{Method: "POST"}), "audit log failed: disk full")
+	last, err := audit.Verify(strings.NewReader(buf.String()))
+	require.NoError(t, err)
+	require.EqualValues(t, 3, last.Seq)
+}
+
+func TestQuery(t *testing.T) {
+	buf := &bytes.Buffer{}
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	appendEntries(t, audit.New(buf), start)
+
+	entries, err := audit.Query(bytes.NewReader(buf.Bytes()), audit.Filter{Exchange: "binance"})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	entries, err = audit.Query(bytes.NewReader(buf.Bytes()), audit.Filter{KeyId: "key2", Exchange: "binance"})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.EqualValues(t, 2, entries[0].Seq)
+
+	entries, err = audit.Query(bytes.NewReader(buf.Bytes()), audit.Filter{Since: start.Add(time.Hour), Until: start.Add(2 * time.Hour)})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.EqualValues(t, 1, entries[0].Seq)
+}
@@ -0,0 +1,170 @@
+// Package ecc encodes and decodes the ECDSA keys and signatures used for http signatures.
+//
+// Public keys are SEC1 encoded points, and signatures are the fixed size r || s, as in RFC 9421.
+package ecc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"golang.org/x/crypto/cryptobyte"
+	cbasn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+type Curve string
+
+const (
+	P256 Curve = "p256"
+	// secp256k1
+	K256 Curve = "k256"
+)
+
+var (
+	oidEcPublicKey = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidP256        = asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}
+	oidK256        = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+)
+
+func (c Curve) Elliptic() (elliptic.Curve, error) {
+	switch c {
+	case P256:
+		return elliptic.P256(), nil
+	case K256:
+		return secp256k1.S256(), nil
+	default:
+		return nil, fmt.Errorf("unsupported curve: %s", c)
+	}
+}
+
+func (c Curve) size() int {
+	// both supported curves have 256 bit coordinates
+	return 32
+}
+
+func CurveFromOid(oid asn1.ObjectIdentifier) (Curve, error) {
+	switch {
+	case oid.Equal(oidP256):
+		return P256, nil
+	case oid.Equal(oidK256):
+		return K256, nil
+	default:
+		return "", fmt.Errorf("unsupported curve oid: %s", oid)
+	}
+}
+
+// ParseCurveOid parses a DER encoded curve OID, as in the parameters of a key.
+func ParseCurveOid(der []byte) (Curve, error) {
+	var oid asn1.ObjectIdentifier
+	rest, err := asn1.Unmarshal(der, &oid)
+	if err != nil {
+		return "", fmt.Errorf("invalid curve parameters: %w", err)
+	}
+	if len(rest) > 0 {
+		return "", fmt.Errorf("invalid curve parameters: trailing data")
+	}
+	return CurveFromOid(oid)
+}
+
+// ParsePublicKey parses a compressed or uncompressed SEC1 encoded point.
+func ParsePublicKey(curve Curve, bz []byte) (*ecdsa.PublicKey, error) {
+	c, err := curve.Elliptic()
+	if err != nil {
+		return nil, err
+	}
+	size := curve.size()
+	uncompressed := len(bz) == 1+2*size && bz[0] == 4
+	compressed := len(bz) == 1+size && (bz[0] == 2 || bz[0] == 3)
+	if !uncompressed && !compressed {
+		return nil, fmt.Errorf("invalid %s public key: expected a %d or %d byte SEC1 point", curve, 1+size, 1+2*size)
+	}
+	if curve == K256 {
+		key, err := secp256k1.ParsePubKey(bz)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s public key: not on the curve", curve)
+		}
+		return key.ToECDSA(), nil
+	}
+	var x, y *big.Int
+	if compressed {
+		x, y = elliptic.UnmarshalCompressed(c, bz)
+	} else {
+		x, y = elliptic.Unmarshal(c, bz)
+	}
+	if x == nil {
+		return nil, fmt.Errorf("invalid %s public key: not on the curve", curve)
+	}
+	return &ecdsa.PublicKey{Curve: c, X: x, Y: y}, nil
+}
+
+// MarshalPublicKey returns the uncompressed SEC1 encoding of the key.
+func MarshalPublicKey(pub *ecdsa.PublicKey) []byte {
+	size := (pub.Curve.Params().BitSize + 7) / 8
+	bz := make([]byte, 1+2*size)
+	bz[0] = 4
+	pub.X.FillBytes(bz[1 : 1+size])
+	pub.Y.FillBytes(bz[1+size:])
+	return bz
+}
+
+// ParsePKIXPublicKey parses a DER encoded SubjectPublicKeyInfo, as returned by cloud KMS services.  Unlike
+// x509.ParsePKIXPublicKey, it supports secp256k1.
+func ParsePKIXPublicKey(der []byte) (Curve, *ecdsa.PublicKey, error) {
+	var spki struct {
+		Algorithm struct {
+			Algorithm  asn1.ObjectIdentifier
+			Parameters asn1.RawValue `asn1:"optional"`
+		}
+		PublicKey asn1.BitString
+	}
+	rest, err := asn1.Unmarshal(der, &spki)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	if len(rest) > 0 {
+		return "", nil, fmt.Errorf("invalid public key: trailing data")
+	}
+	if !spki.Algorithm.Algorithm.Equal(oidEcPublicKey) {
+		return "", nil, fmt.Errorf("not an ECDSA public key: %s", spki.Algorithm.Algorithm)
+	}
+	curve, err := ParseCurveOid(spki.Algorithm.Parameters.FullBytes)
+	if err != nil {
+		return "", nil, err
+	}
+	pub, err := ParsePublicKey(curve, spki.PublicKey.RightAlign())
+	if err != nil {
+		return "", nil, err
+	}
+	return curve, pub, nil
+}
+
+// SignatureFromASN1 converts a DER encoded ECDSA signature to r || s.
+func SignatureFromASN1(curve Curve, der []byte) ([]byte, error) {
+	var r, s big.Int
+	input := cryptobyte.String(der)
+	var inner cryptobyte.String
+	if !input.ReadASN1(&inner, cbasn1.SEQUENCE) || !input.Empty() ||
+		!inner.ReadASN1Integer(&r) || !inner.ReadASN1Integer(&s) || !inner.Empty() {
+		return nil, fmt.Errorf("invalid ASN.1 signature")
+	}
+	size := curve.size()
+	if r.Sign() <= 0 || s.Sign() <= 0 || r.BitLen() > 8*size || s.BitLen() > 8*size {
+		return nil, fmt.Errorf("invalid ASN.1 signature")
+	}
+	sig := make([]byte, 2*size)
+	r.FillBytes(sig[:size])
+	s.FillBytes(sig[size:])
+	return sig, nil
+}
+
+// ParseSignature splits an r || s signature.
+func ParseSignature(curve Curve, sig []byte) (r *big.Int, s *big.Int, err error) {
+	size := curve.size()
+	if len(sig) != 2*size {
+		return nil, nil, fmt.Errorf("invalid %s signature length %d, expected %d", curve, len(sig), 2*size)
+	}
+	return new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:]), nil
+}
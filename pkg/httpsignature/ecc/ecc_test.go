@@ -0,0 +1,93 @@
+package ecc_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"testing"
+
+	"github.com/cordialsys/offchain/pkg/httpsignature/ecc"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePublicKey(t *testing.T) {
+	for _, curve := range []ecc.Curve{ecc.P256, ecc.K256} {
+		t.Run(string(curve), func(t *testing.T) {
+			c, err := curve.Elliptic()
+			require.NoError(t, err)
+			key, err := ecdsa.GenerateKey(c, rand.Reader)
+			require.NoError(t, err)
+
+			uncompressed := ecc.MarshalPublicKey(&key.PublicKey)
+			require.Len(t, uncompressed, 65)
+			parsed, err := ecc.ParsePublicKey(curve, uncompressed)
+			require.NoError(t, err)
+			require.True(t, parsed.Equal(&key.PublicKey))
+
+			compressed := append([]byte{2 + byte(key.Y.Bit(0))}, key.X.FillBytes(make([]byte, 32))...)
+			parsed, err = ecc.ParsePublicKey(curve, compressed)
+			require.NoError(t, err)
+			require.Equal(t, key.Y, parsed.Y)
+
+			_, err = ecc.ParsePublicKey(curve, uncompressed[:64])
+			require.ErrorContains(t, err, "expected a 33 or 65 byte SEC1 point")
+			uncompressed[64] ^= 1
+			_, err = ecc.ParsePublicKey(curve, uncompressed)
+			require.ErrorContains(t, err, "not on the curve")
+		})
+	}
+}
+
+func TestParsePKIXPublicKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	curve, pub, err := ecc.ParsePKIXPublicKey(der)
+	require.NoError(t, err)
+	require.Equal(t, ecc.P256, curve)
+	require.True(t, pub.Equal(&key.PublicKey))
+
+	// secp256k1 key from AWS KMS
+	der, err = hex.DecodeString("3056301006072a8648ce3d020106052b8104000a03420004" +
+		"79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798" +
+		"483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8")
+	require.NoError(t, err)
+	curve, pub, err = ecc.ParsePKIXPublicKey(der)
+	require.NoError(t, err)
+	require.Equal(t, ecc.K256, curve)
+	require.Equal(t, secp256k1.S256().Params().Gx, pub.X)
+
+	ed25519Der, err := hex.DecodeString("302a300506032b6570032100d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a")
+	require.NoError(t, err)
+	_, _, err = ecc.ParsePKIXPublicKey(ed25519Der)
+	require.ErrorContains(t, err, "not an ECDSA public key")
+}
+
+func TestSignatureFromASN1(t *testing.T) {
+	for _, curve := range []ecc.Curve{ecc.P256, ecc.K256} {
+		t.Run(string(curve), func(t *testing.T) {
+			c, err := curve.Elliptic()
+			require.NoError(t, err)
+			key, err := ecdsa.GenerateKey(c, rand.Reader)
+			require.NoError(t, err)
+			digest := sha256.Sum256([]byte("message"))
+			der, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+			require.NoError(t, err)
+
+			sig, err := ecc.SignatureFromASN1(curve, der)
+			require.NoError(t, err)
+			require.Len(t, sig, 64)
+			r, s, err := ecc.ParseSignature(curve, sig)
+			require.NoError(t, err)
+			require.True(t, ecdsa.Verify(&key.PublicKey, digest[:], r, s))
+
+			_, err = ecc.SignatureFromASN1(curve, sig)
+			require.ErrorContains(t, err, "invalid ASN.1 signature")
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -83,6 +84,55 @@ func NewSigParams(Created int64, headers ...string) *SigParams {
 	}
 }
 
+// The keyid parameter identifies the key that made the signature, so the verifier can look it up directly
+const SigParamKeyId = "keyid"
+
+// ValidKeyId checks that the key id can be serialized as a signature-input parameter.
+func ValidKeyId(keyId string) error {
+	if keyId == "" {
+		return fmt.Errorf("key id must not be empty")
+	}
+	for _, r := range keyId {
+		if r < 0x20 || r > 0x7e || strings.ContainsRune(`"\;=,()`, r) {
+			return fmt.Errorf("invalid character %q in key id %s", r, keyId)
+		}
+	}
+	return nil
+}
+
+// SetKeyId sets the keyid parameter, replacing any existing one.
+func (p *SigParams) SetKeyId(keyId string) {
+	value := fmt.Sprintf("\"%s\"", keyId)
+	for i, attr := range p.Attributes {
+		if attr.Key == SigParamKeyId {
+			p.Attributes[i].Value = value
+			return
+		}
+	}
+	p.Attributes = append(p.Attributes, SigParamKV{Key: SigParamKeyId, Value: value})
+}
+
+// KeyId returns the keyid parameter, if set.
+func (p *SigParams) KeyId() (string, bool) {
+	for _, attr := range p.Attributes {
+		if attr.Key == SigParamKeyId {
+			return strings.Trim(attr.Value, "\""), true
+		}
+	}
+	return "", false
+}
+
+// Created returns the created parameter, in unix seconds, if set.
+func (p *SigParams) Created() (int64, bool) {
+	for _, attr := range p.Attributes {
+		if attr.Key == "created" {
+			created, err := strconv.ParseInt(strings.Trim(attr.Value, "\""), 10, 64)
+			return created, err == nil
+		}
+	}
+	return 0, false
+}
+
 func (p *SigParams) Serialize() string {
 	attributes := []string{}
 	for _, attr := range p.Attributes {
@@ -230,3 +230,23 @@ func TestParseSignatureErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestSigParamsKeyId(t *testing.T) {
+	params := httpsignature.NewSigParams(1700000000)
+	_, ok := params.KeyId()
+	require.False(t, ok)
+
+	params.SetKeyId("k1")
+	params.SetKeyId("k2")
+	require.Equal(t, `(@method @path @query content-digest);created="1700000000";keyid="k2"`, params.Serialize())
+
+	parsed, err := httpsignature.ParseSigParams(params.Header()[1])
+	require.NoError(t, err)
+	keyId, ok := parsed.KeyId()
+	require.True(t, ok)
+	require.Equal(t, "k2", keyId)
+
+	require.NoError(t, httpsignature.ValidKeyId("client-keys.treasury_1"))
+	require.ErrorContains(t, httpsignature.ValidKeyId(""), "must not be empty")
+	require.ErrorContains(t, httpsignature.ValidKeyId("a=b"), "invalid character")
+}
@@ -0,0 +1,255 @@
+package httpsignature
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/cordialsys/offchain/pkg/httpsignature/signer"
+	"github.com/cordialsys/offchain/pkg/httpsignature/verifier"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Components of response signatures.  Those marked ;req are taken from the request, binding the response
+// to the request it answers (RFC 9421 section 2.4).
+const (
+	ComponentStatus           = "@status"
+	ComponentRequestMethod    = "@method;req"
+	ComponentRequestPath      = "@path;req"
+	ComponentRequestQuery     = "@query;req"
+	ComponentRequestSignature = "signature;req"
+)
+
+// The label of response signatures
+const ResponseSignatureName = "res"
+
+// Responses are checked as they're received, so their signatures are rejected once they're older than this,
+// or were created further than this ahead of our clock.
+const MaxResponseAge = 5 * time.Minute
+
+// ResponseArgs are the parts of a response, and of the request it answers, that are signed.
+type ResponseArgs struct {
+	Status int
+	Body   []byte
+	// the request
+	Method string
+	Path   string
+	Query  string
+	// The signature header of the request, if it was signed
+	RequestSignature string
+}
+
+// NewResponseSigParams covers the status, body and request.  The request's signature is covered if it was signed.
+func NewResponseSigParams(created int64, requestSigned bool) *SigParams {
+	components := []string{ComponentStatus, HeaderContentDigest, ComponentRequestMethod, ComponentRequestPath, ComponentRequestQuery}
+	if requestSigned {
+		components = append(components, ComponentRequestSignature)
+	}
+	return &SigParams{
+		Name:       ResponseSignatureName,
+		Components: components,
+		Attributes: []SigParamKV{
+			{
+				Key:   "created",
+				Value: fmt.Sprintf("\"%d\"", created),
+			},
+		},
+	}
+}
+
+type ResponseSigBase struct {
+	*SigParams
+	ResponseArgs
+	ContentDigest *ContentDigest
+}
+
+func (s *ResponseSigBase) Serialize() (string, error) {
+	signBase := ""
+	for _, component := range s.Components {
+		switch component {
+		case ComponentStatus:
+			signBase += fmt.Sprintf(`"%s": %d`, component, s.Status)
+		case HeaderContentDigest:
+			signBase += fmt.Sprintf(`"%s": %s`, component, s.ContentDigest.Base64())
+		case ComponentRequestMethod:
+			signBase += fmt.Sprintf(`"%s": %s`, component, s.Method)
+		case ComponentRequestPath:
+			signBase += fmt.Sprintf(`"%s": %s`, component, s.Path)
+		case ComponentRequestQuery:
+			signBase += fmt.Sprintf(`"%s": %s`, component, s.Query)
+		case ComponentRequestSignature:
+			if s.RequestSignature == "" {
+				return "", fmt.Errorf("the request was not signed")
+			}
+			signBase += fmt.Sprintf(`"%s": %s`, component, s.RequestSignature)
+		default:
+			return "", fmt.Errorf("unsupported response component: %s", component)
+		}
+		// each line has '\n' newline
+		signBase += "\n"
+	}
+	// the parameters are always covered, so created and keyid can't be changed (RFC 9421 section 2.5)
+	signBase += fmt.Sprintf(`"@signature-params": %s`, s.SigParams.Serialize())
+	return signBase, nil
+}
+
+// SignResponse signs the response, returning the headers to send with it.  If the signer is a KeyedSigner,
+// its id is included as the keyid parameter.
+func SignResponse(args ResponseArgs, s signer.SignerI) (http.Header, error) {
+	params := NewResponseSigParams(Now().Unix(), args.RequestSignature != "")
+	if keyed, ok := s.(signer.KeyedSigner); ok {
+		if err := ValidKeyId(keyed.KeyId()); err != nil {
+			return nil, err
+		}
+		params.SetKeyId(keyed.KeyId())
+	}
+	sigBase := &ResponseSigBase{
+		SigParams:     params,
+		ResponseArgs:  args,
+		ContentDigest: NewContentDigest(args.Body),
+	}
+	message, err := sigBase.Serialize()
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize signature base: %w", err)
+	}
+	rawSig, err := s.Sign([]byte(message))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+	signature := &Signature{Name: ResponseSignatureName, Signature: rawSig}
+
+	headers := http.Header{}
+	for _, header := range [][]string{params.Header(), sigBase.ContentDigest.Header(), signature.Header()} {
+		headers.Set(header[0], header[1])
+	}
+	return headers, nil
+}
+
+// SignResponseFiber signs the response that has been written to the fiber context.
+func SignResponseFiber(c *fiber.Ctx, s signer.SignerI) error {
+	headers, err := SignResponse(ResponseArgs{
+		Status:           c.Response().StatusCode(),
+		Body:             c.Response().Body(),
+		Method:           c.Method(),
+		Path:             c.Path(),
+		Query:            string(c.Request().URI().QueryString()),
+		RequestSignature: c.Get(HeaderSignature),
+	}, s)
+	if err != nil {
+		return err
+	}
+	for key := range headers {
+		c.Set(key, headers.Get(key))
+	}
+	return nil
+}
+
+// VerifyResponseRaw checks the signature of a response.  The status, body and request must all be covered,
+// including the request's signature if it was signed, and the signature must have been created within
+// MaxResponseAge.  If the verifier is a KeyedVerifier, the keyid of the signature must be its id.
+func VerifyResponseRaw(args ResponseArgs, signatureInput string, signatureHeader string, contentDigestHeader string, responseVerifier verifier.VerifierI) (*SigParams, error) {
+	if signatureInput == "" {
+		return nil, fmt.Errorf("missing signature-input header")
+	}
+	if signatureHeader == "" {
+		return nil, fmt.Errorf("missing signature header")
+	}
+	if contentDigestHeader == "" {
+		return nil, fmt.Errorf("missing content-digest header")
+	}
+	contentDigest, err := ParseContentDigest(contentDigestHeader)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := ParseSignature(signatureHeader)
+	if err != nil {
+		return nil, err
+	}
+	params, err := ParseSigParams(signatureInput)
+	if err != nil {
+		return nil, err
+	}
+
+	required := NewResponseSigParams(0, args.RequestSignature != "").Components
+	for _, component := range required {
+		covered := false
+		for _, c := range params.Components {
+			if c == component {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return nil, fmt.Errorf("missing %s in response signature input", component)
+		}
+	}
+
+	if keyed, ok := responseVerifier.(verifier.KeyedVerifier); ok {
+		if keyId, _ := params.KeyId(); keyId != keyed.KeyId() {
+			return nil, fmt.Errorf("response signed by key %q, expected %q", keyId, keyed.KeyId())
+		}
+	}
+	created, ok := params.Created()
+	if !ok {
+		return nil, fmt.Errorf("missing created in response signature input")
+	}
+	age := Now().Sub(time.Unix(created, 0))
+	if age > MaxResponseAge {
+		return nil, fmt.Errorf("response signature is too old, created %s ago", age.Truncate(time.Second))
+	}
+	if age < -MaxResponseAge {
+		return nil, fmt.Errorf("response signature was created %s in the future", (-age).Truncate(time.Second))
+	}
+
+	if !bytes.Equal(NewContentDigest(args.Body).Digest, contentDigest.Digest) {
+		return nil, fmt.Errorf("content-digest mismatch")
+	}
+
+	sigBase := &ResponseSigBase{
+		SigParams:     params,
+		ResponseArgs:  args,
+		ContentDigest: contentDigest,
+	}
+	message, err := sigBase.Serialize()
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize signature base: %w", err)
+	}
+	if !responseVerifier.Verify([]byte(message), signature.Signature) {
+		return nil, fmt.Errorf("response signature invalid")
+	}
+	return params, nil
+}
+
+// VerifyResponse checks the signature of a response to a request made by an http.Client.  The body is
+// read and replaced, so it can still be read after.
+func VerifyResponse(resp *http.Response, verifier verifier.VerifierI) (*SigParams, error) {
+	if resp.Request == nil {
+		return nil, fmt.Errorf("response has no request")
+	}
+	var body []byte
+	if resp.Body != nil {
+		var err error
+		body, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewBuffer(body))
+	}
+	args := ResponseArgs{
+		Status:           resp.StatusCode,
+		Body:             body,
+		Method:           resp.Request.Method,
+		Path:             resp.Request.URL.Path,
+		Query:            resp.Request.URL.RawQuery,
+		RequestSignature: resp.Request.Header.Get(HeaderSignature),
+	}
+	return VerifyResponseRaw(
+		args,
+		resp.Header.Get(HeaderSignatureInput),
+		resp.Header.Get(HeaderSignature),
+		resp.Header.Get(HeaderContentDigest),
+		verifier,
+	)
+}
@@ -0,0 +1,188 @@
+package httpsignature_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cordialsys/offchain/pkg/httpsignature"
+	"github.com/cordialsys/offchain/pkg/httpsignature/signer"
+	"github.com/cordialsys/offchain/pkg/httpsignature/verifier"
+	"github.com/stretchr/testify/require"
+)
+
+// signedResponse returns the response the server would send to the request, signed by the signer
+func signedResponse(t *testing.T, req *http.Request, status int, body string, s signer.SignerI) *http.Response {
+	headers, err := httpsignature.SignResponse(httpsignature.ResponseArgs{
+		Status:           status,
+		Body:             []byte(body),
+		Method:           req.Method,
+		Path:             req.URL.Path,
+		Query:            req.URL.RawQuery,
+		RequestSignature: req.Header.Get(httpsignature.HeaderSignature),
+	}, s)
+	require.NoError(t, err)
+	return &http.Response{
+		StatusCode: status,
+		Header:     headers,
+		Body:       io.NopCloser(bytes.NewReader([]byte(body))),
+		Request:    req,
+	}
+}
+
+func TestSignResponse(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	serverSigner := signer.WithKeyId(&signer.Ed25519Signer{Key: privKey}, "offchain")
+	serverVerifier, err := verifier.NewEd25519Verifier(pubKey)
+	require.NoError(t, err)
+	_, clientKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	newRequest := func(url string) *http.Request {
+		req, err := http.NewRequest("GET", url, nil)
+		require.NoError(t, err)
+		return req
+	}
+
+	t.Run("unsigned request", func(t *testing.T) {
+		req := newRequest("https://example.com/v1/exchanges/okx/deposit-address?network=BTC&symbol=BTC")
+		resp := signedResponse(t, req, 200, `"bc1qaddress"`, serverSigner)
+		require.True(t, strings.HasPrefix(resp.Header.Get(httpsignature.HeaderSignatureInput),
+			`res=(@status content-digest @method;req @path;req @query;req);created="`))
+
+		params, err := httpsignature.VerifyResponse(resp, serverVerifier)
+		require.NoError(t, err)
+		keyId, ok := params.KeyId()
+		require.True(t, ok)
+		require.Equal(t, "offchain", keyId)
+
+		// the body can still be read
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, `"bc1qaddress"`, string(body))
+	})
+
+	t.Run("signed request", func(t *testing.T) {
+		req := newRequest("https://example.com/v1/exchanges/okx/deposit-address?network=BTC&symbol=BTC")
+		require.NoError(t, httpsignature.Sign(req, &signer.Ed25519Signer{Key: clientKey}))
+		resp := signedResponse(t, req, 200, `"bc1qaddress"`, serverSigner)
+		require.Contains(t, resp.Header.Get(httpsignature.HeaderSignatureInput), "signature;req")
+		_, err := httpsignature.VerifyResponse(resp, serverVerifier)
+		require.NoError(t, err)
+
+		// a response to a different signature of the same request
+		replayed := newRequest(req.URL.String())
+		require.NoError(t, httpsignature.Sign(replayed, &signer.Ed25519Signer{Key: clientKey}))
+		replayed.Header.Set(httpsignature.HeaderSignature, "iam=:AAAA:")
+		resp = signedResponse(t, req, 200, `"bc1qaddress"`, serverSigner)
+		resp.Request = replayed
+		_, err = httpsignature.VerifyResponse(resp, serverVerifier)
+		require.ErrorContains(t, err, "response signature invalid")
+	})
+
+	t.Run("modified body", func(t *testing.T) {
+		req := newRequest("https://example.com/v1/exchanges/okx/deposit-address?network=BTC&symbol=BTC")
+		resp := signedResponse(t, req, 200, `"bc1qaddress"`, serverSigner)
+		resp.Body = io.NopCloser(bytes.NewReader([]byte(`"bc1qattacker"`)))
+		_, err := httpsignature.VerifyResponse(resp, serverVerifier)
+		require.ErrorContains(t, err, "content-digest mismatch")
+
+		// with a recalculated digest
+		resp = signedResponse(t, req, 200, `"bc1qaddress"`, serverSigner)
+		resp.Body = io.NopCloser(bytes.NewReader([]byte(`"bc1qattacker"`)))
+		digest := httpsignature.NewContentDigest([]byte(`"bc1qattacker"`)).Header()
+		resp.Header.Set(digest[0], digest[1])
+		_, err = httpsignature.VerifyResponse(resp, serverVerifier)
+		require.ErrorContains(t, err, "response signature invalid")
+	})
+
+	t.Run("response to another request", func(t *testing.T) {
+		req := newRequest("https://example.com/v1/exchanges/okx/deposit-address?network=ETH&symbol=USDC")
+		resp := signedResponse(t, req, 200, `"0xaddress"`, serverSigner)
+		resp.Request = newRequest("https://example.com/v1/exchanges/okx/deposit-address?network=BTC&symbol=BTC")
+		_, err := httpsignature.VerifyResponse(resp, serverVerifier)
+		require.ErrorContains(t, err, "response signature invalid")
+	})
+
+	t.Run("modified status", func(t *testing.T) {
+		req := newRequest("https://example.com/v1/exchanges/okx/deposit-address?network=BTC&symbol=BTC")
+		resp := signedResponse(t, req, 500, `{"message":"failed"}`, serverSigner)
+		resp.StatusCode = 200
+		_, err := httpsignature.VerifyResponse(resp, serverVerifier)
+		require.ErrorContains(t, err, "response signature invalid")
+	})
+
+	t.Run("unsigned response", func(t *testing.T) {
+		req := newRequest("https://example.com/v1/exchanges/okx/deposit-address?network=BTC&symbol=BTC")
+		resp := &http.Response{StatusCode: 200, Header: http.Header{}, Body: io.NopCloser(bytes.NewReader(nil)), Request: req}
+		_, err := httpsignature.VerifyResponse(resp, serverVerifier)
+		require.ErrorContains(t, err, "missing signature-input header")
+	})
+
+	t.Run("request signature not covered", func(t *testing.T) {
+		unsigned := newRequest("https://example.com/v1/exchanges/okx/deposit-address?network=BTC&symbol=BTC")
+		resp := signedResponse(t, unsigned, 200, `"bc1qaddress"`, serverSigner)
+		signed := newRequest(unsigned.URL.String())
+		require.NoError(t, httpsignature.Sign(signed, &signer.Ed25519Signer{Key: clientKey}))
+		resp.Request = signed
+		_, err := httpsignature.VerifyResponse(resp, serverVerifier)
+		require.ErrorContains(t, err, "missing signature;req in response signature input")
+	})
+
+	t.Run("stale response", func(t *testing.T) {
+		req := newRequest("https://example.com/v1/exchanges/okx/deposit-address?network=BTC&symbol=BTC")
+		resp := signedResponse(t, req, 200, `"bc1qaddress"`, serverSigner)
+		originalNow := httpsignature.Now
+		defer func() { httpsignature.Now = originalNow }()
+
+		httpsignature.Now = func() time.Time { return originalNow().Add(httpsignature.MaxResponseAge + time.Minute) }
+		_, err := httpsignature.VerifyResponse(resp, serverVerifier)
+		require.ErrorContains(t, err, "response signature is too old")
+
+		httpsignature.Now = func() time.Time { return originalNow().Add(-httpsignature.MaxResponseAge - time.Minute) }
+		_, err = httpsignature.VerifyResponse(resp, serverVerifier)
+		require.ErrorContains(t, err, "in the future")
+	})
+
+	t.Run("modified created", func(t *testing.T) {
+		req := newRequest("https://example.com/v1/exchanges/okx/deposit-address?network=BTC&symbol=BTC")
+		resp := signedResponse(t, req, 200, `"bc1qaddress"`, serverSigner)
+		input := resp.Header.Get(httpsignature.HeaderSignatureInput)
+		params, err := httpsignature.ParseSigParams(input)
+		require.NoError(t, err)
+		created, ok := params.Created()
+		require.True(t, ok)
+		resp.Header.Set(httpsignature.HeaderSignatureInput, strings.Replace(input,
+			fmt.Sprintf(`created="%d"`, created), fmt.Sprintf(`created="%d"`, created+1), 1))
+		_, err = httpsignature.VerifyResponse(resp, serverVerifier)
+		require.ErrorContains(t, err, "response signature invalid")
+	})
+
+	t.Run("key id", func(t *testing.T) {
+		req := newRequest("https://example.com/v1/exchanges/okx/deposit-address?network=BTC&symbol=BTC")
+		resp := signedResponse(t, req, 200, `"bc1qaddress"`, serverSigner)
+		_, err := httpsignature.VerifyResponse(resp, verifier.WithKeyId(serverVerifier, "offchain"))
+		require.NoError(t, err)
+
+		resp = signedResponse(t, req, 200, `"bc1qaddress"`, serverSigner)
+		_, err = httpsignature.VerifyResponse(resp, verifier.WithKeyId(serverVerifier, "other"))
+		require.ErrorContains(t, err, `response signed by key "offchain", expected "other"`)
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		otherPub, _, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		otherVerifier, err := verifier.NewEd25519Verifier(otherPub)
+		require.NoError(t, err)
+		req := newRequest("https://example.com/v1/exchanges/okx/deposit-address?network=BTC&symbol=BTC")
+		resp := signedResponse(t, req, 200, `"bc1qaddress"`, serverSigner)
+		_, err = httpsignature.VerifyResponse(resp, otherVerifier)
+		require.ErrorContains(t, err, "response signature invalid")
+	})
+}
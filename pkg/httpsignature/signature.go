@@ -12,7 +12,8 @@ import (
 
 var Now = time.Now
 
-func Sign(req *http.Request, signer signer.SignerI, additionalHeaders ...string) error {
+// Sign signs the request.  If the signer is a KeyedSigner, its id is included as the keyid parameter.
+func Sign(req *http.Request, s signer.SignerI, additionalHeaders ...string) error {
 	var bodyBytes []byte
 	var err error
 	if req.Body != nil {
@@ -24,13 +25,19 @@ func Sign(req *http.Request, signer signer.SignerI, additionalHeaders ...string)
 	}
 	created := Now().Unix()
 	params := NewSigParams(created, additionalHeaders...)
+	if keyed, ok := s.(signer.KeyedSigner); ok {
+		if err := ValidKeyId(keyed.KeyId()); err != nil {
+			return err
+		}
+		params.SetKeyId(keyed.KeyId())
+	}
 	signatureBase := NewSigBase(params, req.Method, req.URL.Path, req.URL.RawQuery, req.Header, bodyBytes)
 
 	sigBaseBz, err := signatureBase.Serialize()
 	if err != nil {
 		return fmt.Errorf("failed to serialize signature base: %w", err)
 	}
-	rawSig, err := signer.Sign([]byte(sigBaseBz))
+	rawSig, err := s.Sign([]byte(sigBaseBz))
 	if err != nil {
 		return fmt.Errorf("failed to sign: %w", err)
 	}
@@ -0,0 +1,90 @@
+package signer
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/cordialsys/offchain/pkg/httpsignature/ecc"
+)
+
+// Overrides the KMS endpoint, as with the AWS SDKs
+const ENV_AWS_ENDPOINT_URL_KMS = "AWS_ENDPOINT_URL_KMS"
+
+var _ SignerI = &AwsKmsSigner{}
+
+// AwsKmsSigner signs with an ECC_NIST_P256 or ECC_SECG_P256K1 key in AWS KMS.  Credentials are loaded the
+// same way as the AWS SDKs.
+type AwsKmsSigner struct {
+	keyId     string
+	client    *kms.Client
+	curve     ecc.Curve
+	publicKey []byte
+}
+
+func NewAwsKmsSigner(ctx context.Context, keyId string, region string) (*AwsKmsSigner, error) {
+	options := []func(*config.LoadOptions) error{}
+	if region != "" {
+		options = append(options, config.WithRegion(region))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Region == "" {
+		return nil, fmt.Errorf("no region is configured for aws kms")
+	}
+	client := kms.NewFromConfig(cfg, func(o *kms.Options) {
+		if endpoint := os.Getenv(ENV_AWS_ENDPOINT_URL_KMS); endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+	})
+
+	response, err := client.GetPublicKey(ctx, &kms.GetPublicKeyInput{KeyId: aws.String(keyId)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get public key of %s: %w", keyId, err)
+	}
+	if response.KeyUsage != types.KeyUsageTypeSignVerify {
+		return nil, fmt.Errorf("kms key %s is not a signing key", keyId)
+	}
+	curve, pub, err := ecc.ParsePKIXPublicKey(response.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported kms key %s (%s): %w", keyId, response.KeySpec, err)
+	}
+	return &AwsKmsSigner{
+		keyId:     keyId,
+		client:    client,
+		curve:     curve,
+		publicKey: ecc.MarshalPublicKey(pub),
+	}, nil
+}
+
+func (s *AwsKmsSigner) Sign(msg []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), RemoteTimeout)
+	defer cancel()
+	digest := sha256.Sum256(msg)
+	response, err := s.client.Sign(ctx, &kms.SignInput{
+		KeyId:            aws.String(s.keyId),
+		Message:          digest[:],
+		MessageType:      types.MessageTypeDigest,
+		SigningAlgorithm: types.SigningAlgorithmSpecEcdsaSha256,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign with kms key %s: %w", s.keyId, err)
+	}
+	return ecc.SignatureFromASN1(s.curve, response.Signature)
+}
+
+// Returns the uncompressed SEC1 encoded public key
+func (s *AwsKmsSigner) PublicKey() []byte {
+	return s.publicKey
+}
+
+func (s *AwsKmsSigner) Curve() ecc.Curve {
+	return s.curve
+}
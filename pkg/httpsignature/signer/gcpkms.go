@@ -0,0 +1,89 @@
+package signer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	kms "cloud.google.com/go/kms/apiv1"
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"github.com/cordialsys/offchain/pkg/httpsignature/ecc"
+	"google.golang.org/api/option"
+)
+
+// The Cloud KMS endpoint, may be changed for testing
+var GcpKmsEndpoint = "https://cloudkms.googleapis.com"
+
+var _ SignerI = &GcpKmsSigner{}
+
+// GcpKmsSigner signs with an EC_SIGN_P256_SHA256 or EC_SIGN_SECP256K1_SHA256 key version in GCP Cloud KMS.
+// Credentials are the application default credentials.
+type GcpKmsSigner struct {
+	// projects/*/locations/*/keyRings/*/cryptoKeys/*/cryptoKeyVersions/*
+	keyVersion string
+	client     *kms.KeyManagementClient
+	curve      ecc.Curve
+	publicKey  []byte
+}
+
+func NewGcpKmsSigner(ctx context.Context, keyVersion string) (*GcpKmsSigner, error) {
+	keyVersion = strings.Trim(keyVersion, "/")
+	if !strings.Contains(keyVersion, "/cryptoKeyVersions/") {
+		return nil, fmt.Errorf("expected a key version, projects/*/locations/*/keyRings/*/cryptoKeys/*/cryptoKeyVersions/*")
+	}
+	client, err := kms.NewKeyManagementRESTClient(context.WithoutCancel(ctx), option.WithEndpoint(GcpKmsEndpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gcp kms client: %w", err)
+	}
+
+	response, err := client.GetPublicKey(ctx, &kmspb.GetPublicKeyRequest{Name: keyVersion})
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to get public key of %s: %w", keyVersion, err)
+	}
+	block, _ := pem.Decode([]byte(response.Pem))
+	if block == nil {
+		client.Close()
+		return nil, fmt.Errorf("invalid public key of %s", keyVersion)
+	}
+	curve, pub, err := ecc.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("unsupported kms key %s (%s): %w", keyVersion, response.Algorithm, err)
+	}
+	return &GcpKmsSigner{
+		keyVersion: keyVersion,
+		client:     client,
+		curve:      curve,
+		publicKey:  ecc.MarshalPublicKey(pub),
+	}, nil
+}
+
+func (s *GcpKmsSigner) Sign(msg []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), RemoteTimeout)
+	defer cancel()
+	digest := sha256.Sum256(msg)
+	response, err := s.client.AsymmetricSign(ctx, &kmspb.AsymmetricSignRequest{
+		Name:   s.keyVersion,
+		Digest: &kmspb.Digest{Digest: &kmspb.Digest_Sha256{Sha256: digest[:]}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign with kms key %s: %w", s.keyVersion, err)
+	}
+	return ecc.SignatureFromASN1(s.curve, response.Signature)
+}
+
+// Returns the uncompressed SEC1 encoded public key
+func (s *GcpKmsSigner) PublicKey() []byte {
+	return s.publicKey
+}
+
+func (s *GcpKmsSigner) Curve() ecc.Curve {
+	return s.curve
+}
+
+func (s *GcpKmsSigner) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,182 @@
+//go:build cgo
+
+package signer
+
+import (
+	"crypto/sha256"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/cordialsys/offchain/pkg/httpsignature/ecc"
+	"github.com/miekg/pkcs11"
+)
+
+var _ SignerI = &Pkcs11Signer{}
+
+// Pkcs11Signer signs with an EC private key on a PKCS#11 token, such as an HSM or SoftHSM.  The public key
+// must be stored on the token with the same label.
+type Pkcs11Signer struct {
+	// a session can only be used for one operation at a time
+	lock      sync.Mutex
+	ctx       *pkcs11.Ctx
+	session   pkcs11.SessionHandle
+	key       pkcs11.ObjectHandle
+	curve     ecc.Curve
+	publicKey []byte
+}
+
+func pkcs11Err(function string, err error) error {
+	switch {
+	case errors.Is(err, pkcs11.Error(pkcs11.CKR_PIN_INCORRECT)):
+		return fmt.Errorf("%s: incorrect pin", function)
+	case errors.Is(err, pkcs11.Error(pkcs11.CKR_PIN_LOCKED)):
+		return fmt.Errorf("%s: pin is locked", function)
+	default:
+		return fmt.Errorf("%s: %w", function, err)
+	}
+}
+
+// NewPkcs11Signer loads the PKCS#11 module (a shared library) and logs in to the token with the label.
+func NewPkcs11Signer(module string, tokenLabel string, keyLabel string, pin string) (*Pkcs11Signer, error) {
+	ctx := pkcs11.New(module)
+	if ctx == nil {
+		return nil, fmt.Errorf("failed to load pkcs11 module %s", module)
+	}
+	err := ctx.Initialize()
+	if err != nil && !errors.Is(err, pkcs11.Error(pkcs11.CKR_CRYPTOKI_ALREADY_INITIALIZED)) {
+		return nil, pkcs11Err("C_Initialize", err)
+	}
+	s := &Pkcs11Signer{ctx: ctx}
+	slot, err := s.findSlot(tokenLabel)
+	if err != nil {
+		return nil, err
+	}
+	s.session, err = ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION)
+	if err != nil {
+		return nil, pkcs11Err("C_OpenSession", err)
+	}
+	err = ctx.Login(s.session, pkcs11.CKU_USER, pin)
+	if err != nil && !errors.Is(err, pkcs11.Error(pkcs11.CKR_USER_ALREADY_LOGGED_IN)) {
+		s.Close()
+		return nil, pkcs11Err("C_Login", err)
+	}
+	if err := s.loadKey(keyLabel); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Pkcs11Signer) findSlot(tokenLabel string) (uint, error) {
+	slots, err := s.ctx.GetSlotList(true)
+	if err != nil {
+		return 0, pkcs11Err("C_GetSlotList", err)
+	}
+	if len(slots) == 0 {
+		return 0, fmt.Errorf("no pkcs11 tokens are present")
+	}
+	labels := []string{}
+	for _, slot := range slots {
+		info, err := s.ctx.GetTokenInfo(slot)
+		if err != nil {
+			return 0, pkcs11Err("C_GetTokenInfo", err)
+		}
+		if info.Label == tokenLabel {
+			return slot, nil
+		}
+		labels = append(labels, info.Label)
+	}
+	return 0, fmt.Errorf("pkcs11 token %s not found, tokens are %v", tokenLabel, labels)
+}
+
+func (s *Pkcs11Signer) findObject(class uint, label string) (pkcs11.ObjectHandle, error) {
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, class),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	}
+	if err := s.ctx.FindObjectsInit(s.session, template); err != nil {
+		return 0, pkcs11Err("C_FindObjectsInit", err)
+	}
+	objects, _, err := s.ctx.FindObjects(s.session, 1)
+	s.ctx.FindObjectsFinal(s.session)
+	if err != nil {
+		return 0, pkcs11Err("C_FindObjects", err)
+	}
+	if len(objects) == 0 {
+		return 0, fmt.Errorf("no pkcs11 key with label %s", label)
+	}
+	return objects[0], nil
+}
+
+func (s *Pkcs11Signer) loadKey(label string) error {
+	key, err := s.findObject(pkcs11.CKO_PRIVATE_KEY, label)
+	if err != nil {
+		return err
+	}
+	publicKey, err := s.findObject(pkcs11.CKO_PUBLIC_KEY, label)
+	if err != nil {
+		return fmt.Errorf("could not find the public key: %w", err)
+	}
+	attrs, err := s.ctx.GetAttributeValue(s.session, publicKey, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, nil),
+		pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, nil),
+	})
+	if err != nil {
+		return pkcs11Err("C_GetAttributeValue", err)
+	}
+	curve, err := ecc.ParseCurveOid(attrs[0].Value)
+	if err != nil {
+		return fmt.Errorf("unsupported pkcs11 key %s: %w", label, err)
+	}
+	// the point should be wrapped in an octet string, but some modules return it as is
+	point := attrs[1].Value
+	var unwrapped []byte
+	if rest, err := asn1.Unmarshal(point, &unwrapped); err == nil && len(rest) == 0 {
+		point = unwrapped
+	}
+	pub, err := ecc.ParsePublicKey(curve, point)
+	if err != nil {
+		return err
+	}
+	s.key = key
+	s.curve = curve
+	s.publicKey = ecc.MarshalPublicKey(pub)
+	return nil
+}
+
+func (s *Pkcs11Signer) Sign(msg []byte) ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	// CKM_ECDSA signs a digest, and returns r || s
+	digest := sha256.Sum256(msg)
+	mechanism := []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_ECDSA, nil)}
+	if err := s.ctx.SignInit(s.session, mechanism, s.key); err != nil {
+		return nil, pkcs11Err("C_SignInit", err)
+	}
+	sig, err := s.ctx.Sign(s.session, digest[:])
+	if err != nil {
+		return nil, pkcs11Err("C_Sign", err)
+	}
+	return sig, nil
+}
+
+// Returns the uncompressed SEC1 encoded public key
+func (s *Pkcs11Signer) PublicKey() []byte {
+	return s.publicKey
+}
+
+func (s *Pkcs11Signer) Curve() ecc.Curve {
+	return s.curve
+}
+
+// Close ends the session with the token.
+func (s *Pkcs11Signer) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.ctx.CloseSession(s.session); err != nil {
+		return pkcs11Err("C_CloseSession", err)
+	}
+	return nil
+}
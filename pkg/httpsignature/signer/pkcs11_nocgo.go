@@ -0,0 +1,34 @@
+//go:build !cgo
+
+package signer
+
+import (
+	"fmt"
+
+	"github.com/cordialsys/offchain/pkg/httpsignature/ecc"
+)
+
+var _ SignerI = &Pkcs11Signer{}
+
+// Pkcs11Signer requires cgo to load the PKCS#11 module.
+type Pkcs11Signer struct{}
+
+func NewPkcs11Signer(module string, tokenLabel string, keyLabel string, pin string) (*Pkcs11Signer, error) {
+	return nil, fmt.Errorf("pkcs11 signing is not supported by this build, as it requires cgo")
+}
+
+func (s *Pkcs11Signer) Sign(msg []byte) ([]byte, error) {
+	return nil, fmt.Errorf("pkcs11 signing is not supported by this build")
+}
+
+func (s *Pkcs11Signer) PublicKey() []byte {
+	return nil
+}
+
+func (s *Pkcs11Signer) Curve() ecc.Curve {
+	return ""
+}
+
+func (s *Pkcs11Signer) Close() error {
+	return nil
+}
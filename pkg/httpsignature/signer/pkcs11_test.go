@@ -0,0 +1,128 @@
+//go:build cgo
+
+package signer_test
+
+import (
+	"encoding/asn1"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cordialsys/offchain/pkg/httpsignature/ecc"
+	"github.com/cordialsys/offchain/pkg/httpsignature/signer"
+	"github.com/miekg/pkcs11"
+	"github.com/stretchr/testify/require"
+)
+
+// softhsmModule finds the SoftHSM library, which may also be set with SOFTHSM2_MODULE
+func softhsmModule(t *testing.T) string {
+	paths := []string{
+		os.Getenv("SOFTHSM2_MODULE"),
+		"/usr/lib/softhsm/libsofthsm2.so",
+		"/usr/lib/x86_64-linux-gnu/softhsm/libsofthsm2.so",
+		"/usr/lib/aarch64-linux-gnu/softhsm/libsofthsm2.so",
+		"/usr/local/lib/softhsm/libsofthsm2.so",
+		"/opt/homebrew/lib/softhsm/libsofthsm2.so",
+	}
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	t.Skip("SoftHSM is not installed")
+	return ""
+}
+
+// initSofthsmToken creates a SoftHSM token in a temporary directory, with a P-256 and a secp256k1 key pair
+// labelled by their curve.
+func initSofthsmToken(t *testing.T, module string, label string, pin string) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "softhsm2.conf")
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "tokens"), 0700))
+	require.NoError(t, os.WriteFile(conf, []byte("directories.tokendir = "+filepath.Join(dir, "tokens")+"\n"), 0600))
+	t.Setenv("SOFTHSM2_CONF", conf)
+
+	ctx := pkcs11.New(module)
+	require.NotNil(t, ctx)
+	require.NoError(t, ctx.Initialize())
+	t.Cleanup(func() {
+		ctx.Finalize()
+		ctx.Destroy()
+	})
+	slots, err := ctx.GetSlotList(true)
+	require.NoError(t, err)
+	require.NotEmpty(t, slots)
+	require.NoError(t, ctx.InitToken(slots[0], "0000", label))
+
+	// the initialized token is moved to a new slot
+	slots, err = ctx.GetSlotList(true)
+	require.NoError(t, err)
+	slot := -1
+	for _, s := range slots {
+		info, err := ctx.GetTokenInfo(s)
+		require.NoError(t, err)
+		if info.Label == label {
+			slot = int(s)
+		}
+	}
+	require.NotEqual(t, -1, slot)
+	session, err := ctx.OpenSession(uint(slot), pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	require.NoError(t, err)
+	defer ctx.CloseSession(session)
+	require.NoError(t, ctx.Login(session, pkcs11.CKU_SO, "0000"))
+	require.NoError(t, ctx.InitPIN(session, pin))
+	require.NoError(t, ctx.Logout(session))
+	require.NoError(t, ctx.Login(session, pkcs11.CKU_USER, pin))
+	defer ctx.Logout(session)
+
+	oids := map[ecc.Curve]asn1.ObjectIdentifier{
+		ecc.P256: {1, 2, 840, 10045, 3, 1, 7},
+		ecc.K256: {1, 3, 132, 0, 10},
+	}
+	for curve, oid := range oids {
+		params, err := asn1.Marshal(oid)
+		require.NoError(t, err)
+		_, _, err = ctx.GenerateKeyPair(session,
+			[]*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_EC_KEY_PAIR_GEN, nil)},
+			[]*pkcs11.Attribute{
+				pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+				pkcs11.NewAttribute(pkcs11.CKA_VERIFY, true),
+				pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, params),
+				pkcs11.NewAttribute(pkcs11.CKA_LABEL, string(curve)),
+			},
+			[]*pkcs11.Attribute{
+				pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+				pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+				pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+				pkcs11.NewAttribute(pkcs11.CKA_SIGN, true),
+				pkcs11.NewAttribute(pkcs11.CKA_LABEL, string(curve)),
+			},
+		)
+		require.NoError(t, err)
+	}
+}
+
+func TestPkcs11Signer(t *testing.T) {
+	module := softhsmModule(t)
+	initSofthsmToken(t, module, "offchain", "1234")
+
+	_, err := signer.NewPkcs11Signer(module, "offchain", string(ecc.P256), "wrong-pin")
+	require.ErrorContains(t, err, "incorrect pin")
+	_, err = signer.NewPkcs11Signer(module, "other", string(ecc.P256), "1234")
+	require.ErrorContains(t, err, "pkcs11 token other not found")
+	_, err = signer.NewPkcs11Signer(module, "offchain", "missing", "1234")
+	require.ErrorContains(t, err, "no pkcs11 key with label missing")
+
+	for _, curve := range []ecc.Curve{ecc.P256, ecc.K256} {
+		t.Run(string(curve), func(t *testing.T) {
+			s, err := signer.NewPkcs11Signer(module, "offchain", string(curve), "1234")
+			require.NoError(t, err)
+			defer s.Close()
+			require.Equal(t, curve, s.Curve())
+			requireSignsRequests(t, s, curve)
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package signer_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cordialsys/offchain/pkg/httpsignature"
+	"github.com/cordialsys/offchain/pkg/httpsignature/ecc"
+	"github.com/cordialsys/offchain/pkg/httpsignature/signer"
+	"github.com/cordialsys/offchain/pkg/httpsignature/verifier"
+	"github.com/stretchr/testify/require"
+)
+
+// marshalPKIX encodes the key as a SubjectPublicKeyInfo, including secp256k1 keys which x509 doesn't support
+func marshalPKIX(t *testing.T, curve ecc.Curve, key *ecdsa.PrivateKey) []byte {
+	oids := map[ecc.Curve]asn1.ObjectIdentifier{
+		ecc.P256: {1, 2, 840, 10045, 3, 1, 7},
+		ecc.K256: {1, 3, 132, 0, 10},
+	}
+	point := ecc.MarshalPublicKey(&key.PublicKey)
+	der, err := asn1.Marshal(struct {
+		Algorithm struct {
+			Algorithm  asn1.ObjectIdentifier
+			Parameters asn1.ObjectIdentifier
+		}
+		PublicKey asn1.BitString
+	}{
+		Algorithm: struct {
+			Algorithm  asn1.ObjectIdentifier
+			Parameters asn1.ObjectIdentifier
+		}{asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}, oids[curve]},
+		PublicKey: asn1.BitString{Bytes: point, BitLength: 8 * len(point)},
+	})
+	require.NoError(t, err)
+	return der
+}
+
+func generateKey(t *testing.T, curve ecc.Curve) *ecdsa.PrivateKey {
+	c, err := curve.Elliptic()
+	require.NoError(t, err)
+	key, err := ecdsa.GenerateKey(c, rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+// requireSignsRequests checks that requests signed by the signer are accepted by the verifier
+func requireSignsRequests(t *testing.T, s signer.SignerI, curve ecc.Curve) {
+	v, err := verifier.NewEcdsaVerifier(curve, s.PublicKey())
+	require.NoError(t, err)
+	req, err := http.NewRequest("POST", "https://example.com/v1/withdrawal?x=1", bytes.NewReader([]byte(`{"amount":"1"}`)))
+	require.NoError(t, err)
+	require.NoError(t, httpsignature.Sign(req, s))
+	_, err = httpsignature.Verify(req, v)
+	require.NoError(t, err)
+
+	other, err := verifier.NewEcdsaVerifier(curve, ecc.MarshalPublicKey(&generateKey(t, curve).PublicKey))
+	require.NoError(t, err)
+	_, err = httpsignature.Verify(req, other)
+	require.ErrorContains(t, err, "signature invalid")
+}
+
+func TestAwsKmsSigner(t *testing.T) {
+	for _, curve := range []ecc.Curve{ecc.P256, ecc.K256} {
+		t.Run(string(curve), func(t *testing.T) {
+			key := generateKey(t, curve)
+			keyId := "arn:aws:kms:us-east-1:111122223333:key/op-1"
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDTEST/"))
+				require.Contains(t, r.Header.Get("Authorization"), "/us-east-1/kms/aws4_request")
+				var request map[string]any
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+				if request["KeyId"] != keyId {
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte(`{"__type":"NotFoundException","message":"Key not found"}`))
+					return
+				}
+				switch r.Header.Get("X-Amz-Target") {
+				case "TrentService.GetPublicKey":
+					json.NewEncoder(w).Encode(map[string]any{
+						"KeyId":     keyId,
+						"KeySpec":   "ECC_NIST_P256",
+						"KeyUsage":  "SIGN_VERIFY",
+						"PublicKey": marshalPKIX(t, curve, key),
+					})
+				case "TrentService.Sign":
+					require.Equal(t, "DIGEST", request["MessageType"])
+					require.Equal(t, "ECDSA_SHA_256", request["SigningAlgorithm"])
+					var message []byte
+					require.NoError(t, json.Unmarshal([]byte(`"`+request["Message"].(string)+`"`), &message))
+					require.Len(t, message, 32)
+					sig, err := ecdsa.SignASN1(rand.Reader, key, message)
+					require.NoError(t, err)
+					json.NewEncoder(w).Encode(map[string]any{"Signature": sig})
+				}
+			}))
+			defer server.Close()
+			t.Setenv("AWS_ACCESS_KEY_ID", "AKIDTEST")
+			t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+			t.Setenv("AWS_REGION", "us-east-1")
+			t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "none"))
+			t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "none"))
+			t.Setenv(signer.ENV_AWS_ENDPOINT_URL_KMS, server.URL)
+
+			s, err := signer.NewAwsKmsSigner(context.Background(), keyId, "")
+			require.NoError(t, err)
+			require.Equal(t, curve, s.Curve())
+			require.Equal(t, ecc.MarshalPublicKey(&key.PublicKey), s.PublicKey())
+			requireSignsRequests(t, s, curve)
+
+			_, err = signer.NewAwsKmsSigner(context.Background(), "other", "")
+			require.ErrorContains(t, err, "NotFoundException: Key not found")
+		})
+	}
+}
+
+func TestGcpKmsSigner(t *testing.T) {
+	key := generateKey(t, ecc.K256)
+	keyVersion := "projects/p/locations/global/keyRings/offchain/cryptoKeys/operator/cryptoKeyVersions/1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			json.NewEncoder(w).Encode(map[string]any{"access_token": "gcp-token", "token_type": "Bearer", "expires_in": 3600})
+			return
+		}
+		require.Equal(t, "Bearer gcp-token", r.Header.Get("Authorization"))
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/v1/"+keyVersion+"/publicKey":
+			pemKey := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: marshalPKIX(t, ecc.K256, key)})
+			json.NewEncoder(w).Encode(map[string]any{"pem": string(pemKey), "algorithm": "EC_SIGN_SECP256K1_SHA256"})
+		case r.Method == "POST" && r.URL.Path == "/v1/"+keyVersion+":asymmetricSign":
+			var request struct {
+				Digest struct {
+					Sha256 []byte `json:"sha256"`
+				} `json:"digest"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+			sig, err := ecdsa.SignASN1(rand.Reader, key, request.Digest.Sha256)
+			require.NoError(t, err)
+			json.NewEncoder(w).Encode(map[string]any{"signature": sig})
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"code":404,"status":"NOT_FOUND","message":"key not found"}}`))
+		}
+	}))
+	defer server.Close()
+
+	// a service account with a token endpoint on the test server
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	credentials, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "offchain@p.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})),
+		"token_uri":    server.URL + "/token",
+	})
+	require.NoError(t, err)
+	credentialsFile := filepath.Join(t.TempDir(), "credentials.json")
+	require.NoError(t, os.WriteFile(credentialsFile, credentials, 0600))
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", credentialsFile)
+	defaultEndpoint := signer.GcpKmsEndpoint
+	signer.GcpKmsEndpoint = server.URL
+	defer func() { signer.GcpKmsEndpoint = defaultEndpoint }()
+
+	s, err := signer.NewGcpKmsSigner(context.Background(), keyVersion)
+	require.NoError(t, err)
+	require.Equal(t, ecc.K256, s.Curve())
+	requireSignsRequests(t, s, ecc.K256)
+
+	_, err = signer.NewGcpKmsSigner(context.Background(), "projects/p/locations/global/keyRings/offchain/cryptoKeys/other/cryptoKeyVersions/1")
+	require.ErrorContains(t, err, "Error 404")
+	_, err = signer.NewGcpKmsSigner(context.Background(), "projects/p/locations/global/keyRings/offchain/cryptoKeys/operator")
+	require.ErrorContains(t, err, "expected a key version")
+}
@@ -1,6 +1,32 @@
 package signer
 
+import "time"
+
 type SignerI interface {
 	Sign(data []byte) ([]byte, error)
 	PublicKey() []byte
 }
+
+// How long remote signers wait for a signature
+const RemoteTimeout = 30 * time.Second
+
+// KeyedSigner is a signer with the id that verifiers know its key by.  The id is sent as the keyid of
+// signatures, so the verifier can look up the key directly.
+type KeyedSigner interface {
+	SignerI
+	KeyId() string
+}
+
+type keyedSigner struct {
+	SignerI
+	keyId string
+}
+
+// WithKeyId returns the signer with the key id attached.
+func WithKeyId(signer SignerI, keyId string) KeyedSigner {
+	return &keyedSigner{signer, keyId}
+}
+
+func (s *keyedSigner) KeyId() string {
+	return s.keyId
+}
@@ -0,0 +1,51 @@
+package verifier
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+
+	"github.com/cordialsys/offchain/pkg/httpsignature/ecc"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	k256ecdsa "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// EcdsaVerifier verifies ECDSA signatures of the SHA-256 digest of the message, encoded as r || s.
+type EcdsaVerifier struct {
+	curve  ecc.Curve
+	pubKey *ecdsa.PublicKey
+}
+
+var _ PublicKeyVerifier = &EcdsaVerifier{}
+
+// NewEcdsaVerifier creates a verifier from a compressed or uncompressed SEC1 encoded public key.
+func NewEcdsaVerifier(curve ecc.Curve, pubKey []byte) (*EcdsaVerifier, error) {
+	key, err := ecc.ParsePublicKey(curve, pubKey)
+	if err != nil {
+		return nil, err
+	}
+	return &EcdsaVerifier{curve: curve, pubKey: key}, nil
+}
+
+// Returns the uncompressed SEC1 encoded public key
+func (v *EcdsaVerifier) PublicKey() []byte {
+	return ecc.MarshalPublicKey(v.pubKey)
+}
+
+func (v *EcdsaVerifier) Verify(message []byte, signature []byte) bool {
+	r, s, err := ecc.ParseSignature(v.curve, signature)
+	if err != nil {
+		return false
+	}
+	digest := sha256.Sum256(message)
+	if v.curve == ecc.K256 {
+		var rs, ss secp256k1.ModNScalar
+		if rs.SetByteSlice(signature[:32]) || ss.SetByteSlice(signature[32:]) || rs.IsZero() || ss.IsZero() {
+			return false
+		}
+		var x, y secp256k1.FieldVal
+		x.SetByteSlice(v.pubKey.X.Bytes())
+		y.SetByteSlice(v.pubKey.Y.Bytes())
+		return k256ecdsa.NewSignature(&rs, &ss).Verify(digest[:], secp256k1.NewPublicKey(&x, &y))
+	}
+	return ecdsa.Verify(v.pubKey, digest[:], r, s)
+}
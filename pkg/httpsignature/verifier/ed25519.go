@@ -9,6 +9,8 @@ type Ed25519Verifier struct {
 	pubKey []byte
 }
 
+var _ PublicKeyVerifier = &Ed25519Verifier{}
+
 func NewEd25519Verifier(pubKey []byte) (*Ed25519Verifier, error) {
 	if len(pubKey) != ed25519.PublicKeySize {
 		return nil, fmt.Errorf("invalid public key length")
@@ -16,6 +18,10 @@ func NewEd25519Verifier(pubKey []byte) (*Ed25519Verifier, error) {
 	return &Ed25519Verifier{pubKey: pubKey}, nil
 }
 
+func (v *Ed25519Verifier) PublicKey() []byte {
+	return v.pubKey
+}
+
 func (v *Ed25519Verifier) Verify(message []byte, signature []byte) bool {
 	return ed25519.Verify(v.pubKey, message, signature)
 }
@@ -3,3 +3,31 @@ package verifier
 type VerifierI interface {
 	Verify(message []byte, signature []byte) bool
 }
+
+// PublicKeyVerifier is a verifier that exposes its public key, so that keys can be compared however they
+// were encoded.
+type PublicKeyVerifier interface {
+	VerifierI
+	// Returns the raw ed25519 key, or the uncompressed SEC1 encoded ECDSA key
+	PublicKey() []byte
+}
+
+// KeyedVerifier is a verifier that only accepts signatures with the keyid of its key.
+type KeyedVerifier interface {
+	VerifierI
+	KeyId() string
+}
+
+type keyedVerifier struct {
+	VerifierI
+	keyId string
+}
+
+// WithKeyId returns the verifier with the key id attached.
+func WithKeyId(verifier VerifierI, keyId string) KeyedVerifier {
+	return &keyedVerifier{verifier, keyId}
+}
+
+func (v *keyedVerifier) KeyId() string {
+	return v.keyId
+}
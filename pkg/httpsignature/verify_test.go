@@ -226,3 +226,37 @@ func TestVerify(t *testing.T) {
 		})
 	}
 }
+
+func TestSignWithKeyId(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	testVerifier, err := verifier.NewEd25519Verifier(pubKey)
+	require.NoError(t, err)
+	keyed := signer.WithKeyId(&signer.Ed25519Signer{Key: privKey}, "treasury-1")
+
+	req, err := http.NewRequest("POST", "https://example.com/v1/withdrawal", bytes.NewReader([]byte("{}")))
+	require.NoError(t, err)
+	require.NoError(t, httpsignature.Sign(req, keyed))
+	require.Contains(t, req.Header.Get(httpsignature.HeaderSignatureInput), `;keyid="treasury-1"`)
+
+	params, err := httpsignature.Verify(req, testVerifier)
+	require.NoError(t, err)
+	keyId, ok := params.KeyId()
+	require.True(t, ok)
+	require.Equal(t, "treasury-1", keyId)
+
+	// without a key id
+	req, err = http.NewRequest("POST", "https://example.com/v1/withdrawal", bytes.NewReader([]byte("{}")))
+	require.NoError(t, err)
+	require.NoError(t, httpsignature.Sign(req, &signer.Ed25519Signer{Key: privKey}))
+	params, err = httpsignature.Verify(req, testVerifier)
+	require.NoError(t, err)
+	_, ok = params.KeyId()
+	require.False(t, ok)
+
+	// the key id must fit in the signature-input header
+	req, err = http.NewRequest("POST", "https://example.com/v1/withdrawal", nil)
+	require.NoError(t, err)
+	err = httpsignature.Sign(req, signer.WithKeyId(&signer.Ed25519Signer{Key: privKey}, `a";created=1`))
+	require.ErrorContains(t, err, "invalid character")
+}
@@ -0,0 +1,62 @@
+package intent
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	oc "github.com/cordialsys/offchain"
+)
+
+// A Delay holds withdrawals of at least the threshold for a cooling-off period before they're made.
+type Delay struct {
+	// Applies to every exchange if empty
+	Exchange string `yaml:"exchange"`
+	// Applies to every symbol if empty
+	Symbol    string        `yaml:"symbol"`
+	Threshold oc.Amount     `yaml:"threshold"`
+	Delay     time.Duration `yaml:"delay"`
+}
+
+func (d *Delay) Applies(w Withdrawal) (bool, error) {
+	if d.Exchange != "" && d.Exchange != w.Exchange {
+		return false, nil
+	}
+	if d.Symbol != "" && !strings.EqualFold(d.Symbol, w.Symbol) {
+		return false, nil
+	}
+	amount, err := oc.NewAmountFromString(w.Amount)
+	if err != nil {
+		return false, fmt.Errorf("invalid amount %s: %w", w.Amount, err)
+	}
+	return amount.Decimal().GreaterThanOrEqual(d.Threshold.Decimal()), nil
+}
+
+type Delays []Delay
+
+// For returns the longest of the delays that apply to the withdrawal, or 0 if none do.
+func (delays Delays) For(w Withdrawal) (time.Duration, error) {
+	longest := time.Duration(0)
+	for _, d := range delays {
+		applies, err := d.Applies(w)
+		if err != nil {
+			return 0, err
+		}
+		if applies && d.Delay > longest {
+			longest = d.Delay
+		}
+	}
+	return longest, nil
+}
+
+func (delays Delays) Validate() error {
+	for _, d := range delays {
+		if d.Delay <= 0 {
+			return fmt.Errorf("delay for a threshold of %s must be positive", d.Threshold)
+		}
+		if d.Threshold.Decimal().IsNegative() {
+			return fmt.Errorf("threshold must not be negative: %s", d.Threshold)
+		}
+	}
+	return nil
+}
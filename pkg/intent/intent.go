@@ -0,0 +1,287 @@
+// Package intent implements withdrawal intents, which hold a withdrawal until a quorum of approvers has
+// signed it, or until its cooling-off delay has passed.
+//
+// Approvers sign the digest of the intent, which covers the withdrawal and when the intent expires, so an
+// approval can't be used for a different withdrawal or after the intent has expired.
+package intent
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/cordialsys/offchain/pkg/httpsignature/verifier"
+)
+
+// Version of the canonical form of intents that approvers sign
+const DigestVersion = "offchain-withdrawal-intent-v2"
+
+type Status string
+
+const (
+	StatusPendingApproval Status = "pending-approval"
+	// Waiting for its delay to pass, and can still be cancelled
+	StatusScheduled Status = "scheduled"
+	// Approved, and the withdrawal is being made
+	StatusExecuting Status = "executing"
+	StatusExecuted  Status = "executed"
+	StatusRejected  Status = "rejected"
+	StatusExpired   Status = "expired"
+	StatusCancelled Status = "cancelled"
+	// The exchange did not accept the withdrawal
+	StatusFailed Status = "failed"
+)
+
+// Final returns true if the intent can't change anymore.
+func (s Status) Final() bool {
+	switch s {
+	case StatusExecuted, StatusRejected, StatusExpired, StatusCancelled, StatusFailed:
+		return true
+	}
+	return false
+}
+
+type Decision string
+
+const (
+	Approve Decision = "approve"
+	Reject  Decision = "reject"
+)
+
+type Withdrawal struct {
+	Exchange   string `json:"exchange"`
+	SubAccount string `json:"sub_account,omitempty"`
+	Address    string `json:"address"`
+	Symbol     string `json:"symbol"`
+	Network    string `json:"network"`
+	Amount     string `json:"amount"`
+}
+
+type Approval struct {
+	KeyId     string    `json:"key_id"`
+	Decision  Decision  `json:"decision"`
+	Signature []byte    `json:"signature"`
+	Time      time.Time `json:"time"`
+}
+
+type Intent struct {
+	Id string `json:"id"`
+	Withdrawal
+	// The key that requested the withdrawal
+	CreatedBy string `json:"created_by"`
+	// The public key that requested the withdrawal, if it was signed
+	CreatedByKey []byte     `json:"created_by_key,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	Status       Status     `json:"status"`
+	Approvals    []Approval `json:"approvals"`
+	// When the withdrawal is made, once it's approved
+	ScheduledAt time.Time `json:"scheduled_at"`
+	// The key that cancelled the intent
+	CancelledBy string `json:"cancelled_by,omitempty"`
+
+	// The id the exchange returned for the withdrawal, once executed
+	WithdrawalId string `json:"withdrawal_id,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// New creates a pending intent for the withdrawal, which expires after the expiry.
+func New(withdrawal Withdrawal, createdBy string, now time.Time, expiry time.Duration) *Intent {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	now = now.UTC()
+	return &Intent{
+		Id:         hex.EncodeToString(id),
+		Withdrawal: withdrawal,
+		CreatedBy:  createdBy,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(expiry),
+		Status:     StatusPendingApproval,
+		Approvals:  []Approval{},
+	}
+}
+
+// Canonical is the form of the intent that is hashed for approvers to sign.  It covers the withdrawal
+// and the expiry, one field per line.  Values are quoted, so a value with a newline or quote can't be
+// mistaken for the start of another field.
+func (i *Intent) Canonical() string {
+	return fmt.Sprintf(`%s
+id: %s
+exchange: %s
+sub-account: %s
+address: %s
+symbol: %s
+network: %s
+amount: %s
+expires-at: %d
+`,
+		DigestVersion,
+		strconv.Quote(i.Id),
+		strconv.Quote(i.Exchange),
+		strconv.Quote(i.SubAccount),
+		strconv.Quote(i.Address),
+		strconv.Quote(i.Symbol),
+		strconv.Quote(i.Network),
+		strconv.Quote(i.Amount),
+		i.ExpiresAt.Unix(),
+	)
+}
+
+// Digest is the SHA-256 of the canonical form of the intent.
+func (i *Intent) Digest() []byte {
+	digest := sha256.Sum256([]byte(i.Canonical()))
+	return digest[:]
+}
+
+// DecisionMessage is what an approver signs to approve or reject an intent.  The decision is included so
+// that a rejection can't be used as an approval.
+func DecisionMessage(decision Decision, digest []byte) []byte {
+	return []byte(fmt.Sprintf("%s %s", decision, hex.EncodeToString(digest)))
+}
+
+// ApprovalCount counts the approvals of the intent.
+func (i *Intent) ApprovalCount() int {
+	count := 0
+	for _, approval := range i.Approvals {
+		if approval.Decision == Approve {
+			count++
+		}
+	}
+	return count
+}
+
+// Expire marks a pending intent as expired if it's past its expiry.  It returns true if the intent expired.
+func (i *Intent) Expire(now time.Time) bool {
+	if i.Status == StatusPendingApproval && !now.Before(i.ExpiresAt) {
+		i.Status = StatusExpired
+		return true
+	}
+	return false
+}
+
+// Schedule moves an intent that is ready to be made to scheduled, to be made after the delay.  Intents
+// without a delay move straight to executing, and the caller should make the withdrawal.
+func (i *Intent) Schedule(now time.Time, delay time.Duration) {
+	i.ScheduledAt = now.UTC().Add(delay)
+	if delay > 0 {
+		i.Status = StatusScheduled
+	} else {
+		i.Status = StatusExecuting
+	}
+}
+
+// Due returns true if the intent is scheduled and its delay has passed.
+func (i *Intent) Due(now time.Time) bool {
+	return i.Status == StatusScheduled && !now.Before(i.ScheduledAt)
+}
+
+// Cancel cancels an intent that is waiting for approval or for its delay.
+func (i *Intent) Cancel(by string) error {
+	if i.Status != StatusPendingApproval && i.Status != StatusScheduled {
+		return fmt.Errorf("intent %s is %s", i.Id, i.Status)
+	}
+	i.Status = StatusCancelled
+	i.CancelledBy = by
+	return nil
+}
+
+// An approver, identified by the id of its key in the configuration.
+type Approver struct {
+	Id string
+	verifier.VerifierI
+}
+
+type Policy struct {
+	// Number of approvals needed to make a withdrawal.  Withdrawals are made immediately if 0.
+	Quorum    int
+	Approvers []Approver
+	// How long an intent can be approved for
+	Expiry time.Duration
+}
+
+// Enabled returns true if withdrawals need approval.
+func (p *Policy) Enabled() bool {
+	return p != nil && p.Quorum > 0
+}
+
+// Validate checks that the quorum can be reached.
+func (p *Policy) Validate() error {
+	if p.Quorum < 0 {
+		return fmt.Errorf("quorum must not be negative")
+	}
+	if p.Quorum > len(p.Approvers) {
+		return fmt.Errorf("quorum of %d is more than the %d approvers", p.Quorum, len(p.Approvers))
+	}
+	ids := map[string]bool{}
+	for _, approver := range p.Approvers {
+		if approver.Id == "" {
+			return fmt.Errorf("approvers must have an id")
+		}
+		if ids[approver.Id] {
+			return fmt.Errorf("approver %s is configured more than once", approver.Id)
+		}
+		ids[approver.Id] = true
+	}
+	if p.Quorum > 0 && p.Expiry <= 0 {
+		return fmt.Errorf("expiry must be positive")
+	}
+	return nil
+}
+
+func (p *Policy) Approver(id string) (Approver, bool) {
+	for _, approver := range p.Approvers {
+		if approver.Id == id {
+			return approver, true
+		}
+	}
+	return Approver{}, false
+}
+
+// Decide records an approver's decision on the intent.  The signature must be the approver's signature of
+// the DecisionMessage.  Once the quorum of approvals is reached, the intent moves to executing, and the
+// caller should make the withdrawal.  A single rejection rejects the intent.  The key that requested the
+// withdrawal can't decide on it, even if it's configured as an approver under another id.
+func (p *Policy) Decide(i *Intent, keyId string, decision Decision, signature []byte, now time.Time) error {
+	if decision != Approve && decision != Reject {
+		return fmt.Errorf("invalid decision: %s", decision)
+	}
+	approver, ok := p.Approver(keyId)
+	if !ok {
+		return fmt.Errorf("%s is not an approver", keyId)
+	}
+	if keyId == i.CreatedBy {
+		return fmt.Errorf("%s requested intent %s, so can't decide on it", keyId, i.Id)
+	}
+	if key, ok := approver.VerifierI.(verifier.PublicKeyVerifier); ok && len(i.CreatedByKey) > 0 && bytes.Equal(key.PublicKey(), i.CreatedByKey) {
+		return fmt.Errorf("%s has the key that requested intent %s, so can't decide on it", keyId, i.Id)
+	}
+	if i.Expire(now) {
+		return fmt.Errorf("intent %s expired at %s", i.Id, i.ExpiresAt.Format(time.RFC3339))
+	}
+	if i.Status != StatusPendingApproval {
+		return fmt.Errorf("intent %s is %s", i.Id, i.Status)
+	}
+	if slices.ContainsFunc(i.Approvals, func(a Approval) bool { return a.KeyId == keyId }) {
+		return fmt.Errorf("%s has already decided on intent %s", keyId, i.Id)
+	}
+	if !approver.Verify(DecisionMessage(decision, i.Digest()), signature) {
+		return fmt.Errorf("invalid %s signature by %s", decision, keyId)
+	}
+	i.Approvals = append(i.Approvals, Approval{
+		KeyId:     keyId,
+		Decision:  decision,
+		Signature: signature,
+		Time:      now.UTC(),
+	})
+	if decision == Reject {
+		i.Status = StatusRejected
+	} else if i.ApprovalCount() >= p.Quorum {
+		i.Status = StatusExecuting
+	}
+	return nil
+}
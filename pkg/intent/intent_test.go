@@ -0,0 +1,366 @@
+package intent_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	oc "github.com/cordialsys/offchain"
+	"github.com/cordialsys/offchain/pkg/httpsignature/ecc"
+	"github.com/cordialsys/offchain/pkg/httpsignature/verifier"
+	"github.com/cordialsys/offchain/pkg/intent"
+	"github.com/stretchr/testify/require"
+)
+
+var withdrawal = intent.Withdrawal{
+	Exchange: "okx",
+	Address:  "bc1qaddress",
+	Symbol:   "BTC",
+	Network:  "BTC",
+	Amount:   "1.5",
+}
+
+type approver struct {
+	id  string
+	key ed25519.PrivateKey
+}
+
+func (a approver) sign(i *intent.Intent, decision intent.Decision) []byte {
+	return ed25519.Sign(a.key, intent.DecisionMessage(decision, i.Digest()))
+}
+
+func newPolicy(t *testing.T, quorum int, ids ...string) (*intent.Policy, []approver) {
+	policy := &intent.Policy{Quorum: quorum, Expiry: time.Hour}
+	approvers := []approver{}
+	for _, id := range ids {
+		pub, priv, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		v, err := verifier.NewEd25519Verifier(pub)
+		require.NoError(t, err)
+		policy.Approvers = append(policy.Approvers, intent.Approver{Id: id, VerifierI: v})
+		approvers = append(approvers, approver{id, priv})
+	}
+	require.NoError(t, policy.Validate())
+	return policy, approvers
+}
+
+func TestDigest(t *testing.T) {
+	created := time.Unix(1700000000, 0)
+	i := intent.New(withdrawal, "operator", created, time.Hour)
+	i.Id = "0123456789abcdef0123456789abcdef"
+	i.SubAccount = "treasury"
+	require.Equal(t, `offchain-withdrawal-intent-v2
+id: "0123456789abcdef0123456789abcdef"
+exchange: "okx"
+sub-account: "treasury"
+address: "bc1qaddress"
+symbol: "BTC"
+network: "BTC"
+amount: "1.5"
+expires-at: 1700003600
+`, i.Canonical())
+
+	digest := i.Digest()
+	require.Len(t, digest, 32)
+	for _, change := range []func(i *intent.Intent){
+		func(i *intent.Intent) { i.Address = "bc1qother" },
+		func(i *intent.Intent) { i.Amount = "15" },
+		func(i *intent.Intent) { i.SubAccount = "sub" },
+		func(i *intent.Intent) { i.ExpiresAt = i.ExpiresAt.Add(time.Second) },
+		// a value can't spill into the next field
+		func(i *intent.Intent) { i.Address, i.Symbol = "bc1qaddress\"\nsymbol: \"BTC", "" },
+		func(i *intent.Intent) { i.Address, i.Symbol = "bc1qaddress\nsymbol: BTC", "" },
+	} {
+		cpy := *i
+		change(&cpy)
+		require.NotEqual(t, digest, cpy.Digest())
+	}
+	// the status and approvals aren't covered
+	i.Status = intent.StatusExecuted
+	require.Equal(t, digest, i.Digest())
+}
+
+func TestQuorum(t *testing.T) {
+	policy, approvers := newPolicy(t, 2, "alice", "bob", "carol")
+	now := time.Now()
+	i := intent.New(withdrawal, "operator", now, policy.Expiry)
+	alice, bob, carol := approvers[0], approvers[1], approvers[2]
+
+	// signatures over another intent, or of the other decision, aren't accepted
+	other := intent.New(withdrawal, "operator", now, policy.Expiry)
+	require.ErrorContains(t, policy.Decide(i, alice.id, intent.Approve, alice.sign(other, intent.Approve), now), "invalid approve signature by alice")
+	require.ErrorContains(t, policy.Decide(i, alice.id, intent.Approve, alice.sign(i, intent.Reject), now), "invalid approve signature by alice")
+	require.ErrorContains(t, policy.Decide(i, alice.id, intent.Approve, bob.sign(i, intent.Approve), now), "invalid approve signature by alice")
+	require.ErrorContains(t, policy.Decide(i, "mallory", intent.Approve, alice.sign(i, intent.Approve), now), "mallory is not an approver")
+	require.Empty(t, i.Approvals)
+
+	// approvers can't approve their own withdrawals
+	own := intent.New(withdrawal, alice.id, now, policy.Expiry)
+	require.ErrorContains(t, policy.Decide(own, alice.id, intent.Approve, alice.sign(own, intent.Approve), now), "alice requested intent")
+	require.Empty(t, own.Approvals)
+
+	require.NoError(t, policy.Decide(i, alice.id, intent.Approve, alice.sign(i, intent.Approve), now))
+	require.Equal(t, intent.StatusPendingApproval, i.Status)
+	require.ErrorContains(t, policy.Decide(i, alice.id, intent.Approve, alice.sign(i, intent.Approve), now), "alice has already decided")
+	require.Equal(t, 1, i.ApprovalCount())
+
+	require.NoError(t, policy.Decide(i, bob.id, intent.Approve, bob.sign(i, intent.Approve), now))
+	require.Equal(t, intent.StatusExecuting, i.Status)
+	require.Equal(t, 2, i.ApprovalCount())
+
+	require.ErrorContains(t, policy.Decide(i, carol.id, intent.Approve, carol.sign(i, intent.Approve), now), "is executing")
+}
+
+func TestRequesterKey(t *testing.T) {
+	policy, approvers := newPolicy(t, 1, "alice")
+	alice := approvers[0]
+	now := time.Now()
+
+	// alice's key also requests withdrawals, as "operator"
+	i := intent.New(withdrawal, "operator", now, policy.Expiry)
+	i.CreatedByKey = alice.key.Public().(ed25519.PublicKey)
+	require.ErrorContains(t, policy.Decide(i, alice.id, intent.Approve, alice.sign(i, intent.Approve), now), "alice has the key that requested intent")
+	require.Empty(t, i.Approvals)
+
+	// an ECDSA key is the same however it's encoded
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	compressed := elliptic.MarshalCompressed(elliptic.P256(), priv.X, priv.Y)
+	v, err := verifier.NewEcdsaVerifier(ecc.P256, compressed)
+	require.NoError(t, err)
+	policy.Approvers = append(policy.Approvers, intent.Approver{Id: "bob", VerifierI: v})
+	i.CreatedByKey = ecc.MarshalPublicKey(&priv.PublicKey)
+	require.ErrorContains(t, policy.Decide(i, "bob", intent.Approve, nil, now), "bob has the key that requested intent")
+
+	// another key can approve
+	i.CreatedByKey = compressed
+	require.NoError(t, policy.Decide(i, alice.id, intent.Approve, alice.sign(i, intent.Approve), now))
+	require.Equal(t, intent.StatusExecuting, i.Status)
+}
+
+func TestReject(t *testing.T) {
+	policy, approvers := newPolicy(t, 2, "alice", "bob")
+	now := time.Now()
+	i := intent.New(withdrawal, "operator", now, policy.Expiry)
+	alice, bob := approvers[0], approvers[1]
+
+	require.NoError(t, policy.Decide(i, alice.id, intent.Approve, alice.sign(i, intent.Approve), now))
+	require.ErrorContains(t, policy.Decide(i, bob.id, intent.Reject, bob.sign(i, intent.Approve), now), "invalid reject signature by bob")
+	require.NoError(t, policy.Decide(i, bob.id, intent.Reject, bob.sign(i, intent.Reject), now))
+	require.Equal(t, intent.StatusRejected, i.Status)
+	require.True(t, i.Status.Final())
+}
+
+func TestExpiry(t *testing.T) {
+	policy, approvers := newPolicy(t, 1, "alice")
+	now := time.Now()
+	i := intent.New(withdrawal, "operator", now, policy.Expiry)
+	alice := approvers[0]
+
+	err := policy.Decide(i, alice.id, intent.Approve, alice.sign(i, intent.Approve), now.Add(policy.Expiry))
+	require.ErrorContains(t, err, "expired")
+	require.Equal(t, intent.StatusExpired, i.Status)
+
+	store := intent.NewStore()
+	pending := intent.New(withdrawal, "operator", now, policy.Expiry)
+	require.NoError(t, store.Create(pending))
+	require.NoError(t, store.Expire(now.Add(time.Minute)))
+	got, ok := store.Get(pending.Id)
+	require.True(t, ok)
+	require.Equal(t, intent.StatusPendingApproval, got.Status)
+	require.NoError(t, store.Expire(now.Add(2*policy.Expiry)))
+	got, _ = store.Get(pending.Id)
+	require.Equal(t, intent.StatusExpired, got.Status)
+}
+
+func TestPolicyValidate(t *testing.T) {
+	policy, _ := newPolicy(t, 2, "alice", "bob")
+	policy.Quorum = 3
+	require.ErrorContains(t, policy.Validate(), "quorum of 3 is more than the 2 approvers")
+	policy.Quorum = 1
+	policy.Approvers[1].Id = "alice"
+	require.ErrorContains(t, policy.Validate(), "approver alice is configured more than once")
+	require.False(t, (*intent.Policy)(nil).Enabled())
+}
+
+func TestStore(t *testing.T) {
+	policy, approvers := newPolicy(t, 2, "alice", "bob", "carol")
+	store := intent.NewStore()
+	now := time.Now()
+	first := intent.New(withdrawal, "operator", now, policy.Expiry)
+	second := intent.New(withdrawal, "operator", now.Add(time.Second), policy.Expiry)
+	require.NoError(t, store.Create(first))
+	require.NoError(t, store.Create(second))
+	require.ErrorContains(t, store.Create(first), "already exists")
+
+	list := store.List()
+	require.Len(t, list, 2)
+	require.Equal(t, second.Id, list[0].Id)
+
+	// changes to returned intents aren't stored
+	list[0].Status = intent.StatusExecuted
+	got, ok := store.Get(second.Id)
+	require.True(t, ok)
+	require.Equal(t, intent.StatusPendingApproval, got.Status)
+
+	// failed changes are discarded
+	_, err := store.Update(first.Id, func(i *intent.Intent) error {
+		return policy.Decide(i, "alice", intent.Approve, []byte("bad"), now)
+	})
+	require.Error(t, err)
+	got, _ = store.Get(first.Id)
+	require.Empty(t, got.Approvals)
+
+	// only one of the approvals that race to the quorum moves the intent to executing
+	require.NoError(t, policy.Decide(first, "alice", intent.Approve, approvers[0].sign(first, intent.Approve), now))
+	_, err = store.Update(first.Id, func(i *intent.Intent) error {
+		*i = *first
+		return nil
+	})
+	require.NoError(t, err)
+	var wg sync.WaitGroup
+	executing := make(chan string, 2)
+	for _, a := range approvers[1:] {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			updated, err := store.Update(first.Id, func(i *intent.Intent) error {
+				return policy.Decide(i, a.id, intent.Approve, a.sign(i, intent.Approve), now)
+			})
+			if err == nil && updated.Status == intent.StatusExecuting {
+				executing <- a.id
+			}
+		}()
+	}
+	wg.Wait()
+	close(executing)
+	require.Len(t, executing, 1)
+
+	_, err = store.Update("missing", func(i *intent.Intent) error { return nil })
+	require.ErrorContains(t, err, "intent missing not found")
+}
+
+func amount(t *testing.T, s string) oc.Amount {
+	a, err := oc.NewAmountFromString(s)
+	require.NoError(t, err)
+	return a
+}
+
+func TestDelays(t *testing.T) {
+	delays := intent.Delays{
+		{Threshold: amount(t, "1"), Delay: time.Hour},
+		{Exchange: "okx", Symbol: "btc", Threshold: amount(t, "1.5"), Delay: 24 * time.Hour},
+		{Exchange: "binance", Threshold: amount(t, "0"), Delay: 48 * time.Hour},
+	}
+	require.NoError(t, delays.Validate())
+
+	for _, tc := range []struct {
+		exchange string
+		symbol   string
+		amount   string
+		delay    time.Duration
+	}{
+		{"okx", "BTC", "0.5", 0},
+		{"okx", "BTC", "1", time.Hour},
+		{"okx", "BTC", "1.5", 24 * time.Hour},
+		{"okx", "ETH", "100", time.Hour},
+		{"binance", "ETH", "0.01", 48 * time.Hour},
+	} {
+		w := withdrawal
+		w.Exchange, w.Symbol, w.Amount = tc.exchange, tc.symbol, tc.amount
+		delay, err := delays.For(w)
+		require.NoError(t, err)
+		require.Equal(t, tc.delay, delay, "%s %s %s", tc.exchange, tc.amount, tc.symbol)
+	}
+
+	w := withdrawal
+	w.Amount = "lots"
+	_, err := delays.For(w)
+	require.ErrorContains(t, err, "invalid amount lots")
+
+	require.ErrorContains(t, intent.Delays{{Threshold: amount(t, "1")}}.Validate(), "must be positive")
+	require.ErrorContains(t, intent.Delays{{Threshold: amount(t, "-1"), Delay: time.Hour}}.Validate(), "must not be negative")
+}
+
+func TestSchedule(t *testing.T) {
+	store := intent.NewStore()
+	now := time.Now()
+	scheduled := intent.New(withdrawal, "operator", now, 0)
+	scheduled.Schedule(now, time.Hour)
+	require.Equal(t, intent.StatusScheduled, scheduled.Status)
+	require.Equal(t, now.Add(time.Hour).Unix(), scheduled.ScheduledAt.Unix())
+	cancelled := intent.New(withdrawal, "operator", now, 0)
+	cancelled.Schedule(now, time.Hour)
+	require.NoError(t, store.Create(scheduled))
+	require.NoError(t, store.Create(cancelled))
+
+	immediate := intent.New(withdrawal, "operator", now, 0)
+	immediate.Schedule(now, 0)
+	require.Equal(t, intent.StatusExecuting, immediate.Status)
+
+	cancelled, err := store.Update(cancelled.Id, func(i *intent.Intent) error { return i.Cancel("ops") })
+	require.NoError(t, err)
+	require.Equal(t, intent.StatusCancelled, cancelled.Status)
+	require.Equal(t, "ops", cancelled.CancelledBy)
+	require.True(t, cancelled.Status.Final())
+
+	due, err := store.TakeDue(now.Add(time.Minute))
+	require.NoError(t, err)
+	require.Empty(t, due)
+	due, err = store.TakeDue(now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	require.Equal(t, scheduled.Id, due[0].Id)
+	require.Equal(t, intent.StatusExecuting, due[0].Status)
+	// only taken once
+	due, err = store.TakeDue(now.Add(2 * time.Hour))
+	require.NoError(t, err)
+	require.Empty(t, due)
+
+	// too late to cancel
+	_, err = store.Update(scheduled.Id, func(i *intent.Intent) error { return i.Cancel("ops") })
+	require.ErrorContains(t, err, "is executing")
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "intents.json")
+	store, err := intent.OpenStore(path)
+	require.NoError(t, err)
+	require.Empty(t, store.List())
+
+	now := time.Now()
+	scheduled := intent.New(withdrawal, "operator", now, time.Hour)
+	scheduled.Schedule(now, time.Hour)
+	executing := intent.New(withdrawal, "operator", now.Add(time.Second), time.Hour)
+	executing.Schedule(now, time.Minute)
+	require.NoError(t, store.Create(scheduled))
+	require.NoError(t, store.Create(executing))
+	_, err = store.TakeDue(now.Add(time.Minute))
+	require.NoError(t, err)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// the server stops while a withdrawal is being made
+	reopened, err := intent.OpenStore(path)
+	require.NoError(t, err)
+	got, ok := reopened.Get(scheduled.Id)
+	require.True(t, ok)
+	require.Equal(t, intent.StatusScheduled, got.Status)
+	require.Equal(t, scheduled.ScheduledAt.Unix(), got.ScheduledAt.Unix())
+	require.Equal(t, scheduled.Digest(), got.Digest())
+	got, _ = reopened.Get(executing.Id)
+	require.Equal(t, intent.StatusFailed, got.Status)
+	require.Contains(t, got.Error, "interrupted")
+
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0600))
+	_, err = intent.OpenStore(path)
+	require.ErrorContains(t, err, "invalid intents file")
+}
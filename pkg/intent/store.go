@@ -0,0 +1,210 @@
+package intent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Store holds the intents in memory, and optionally in a file so they survive restarts.  Intents are copied in
+// and out, so changes are only made through Update.
+type Store struct {
+	lock    sync.Mutex
+	intents map[string]*Intent
+	// Rewritten on every change if set
+	path string
+}
+
+func NewStore() *Store {
+	return &Store{
+		intents: map[string]*Intent{},
+	}
+}
+
+// OpenStore loads the intents from the file, creating it on the first change if it doesn't exist.  Intents that
+// were executing when the server stopped are marked as failed, as the withdrawal may or may not have been made.
+func OpenStore(path string) (*Store, error) {
+	s := NewStore()
+	s.path = path
+	bz, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	intents := []*Intent{}
+	if err := json.Unmarshal(bz, &intents); err != nil {
+		return nil, fmt.Errorf("invalid intents file %s: %w", path, err)
+	}
+	interrupted := false
+	for _, i := range intents {
+		if i.Status == StatusExecuting {
+			i.Status = StatusFailed
+			i.Error = "interrupted while the withdrawal was being made, check the withdrawal history of the exchange"
+			interrupted = true
+		}
+		s.intents[i.Id] = i
+	}
+	if interrupted {
+		if err := s.save(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// save replaces the file with the current intents.  The lock must be held.
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	intents := make([]*Intent, 0, len(s.intents))
+	for _, i := range s.intents {
+		intents = append(intents, i)
+	}
+	sort.Slice(intents, func(a, b int) bool {
+		return intents[a].CreatedAt.Before(intents[b].CreatedAt)
+	})
+	bz, err := json.MarshalIndent(intents, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), "."+filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("could not save intents: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(bz); err != nil {
+		tmp.Close()
+		return fmt.Errorf("could not save intents: %w", err)
+	}
+	// the intent must be on disk before the withdrawal is made
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("could not save intents: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("could not save intents: %w", err)
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+func clone(i *Intent) *Intent {
+	cpy := *i
+	cpy.Approvals = append([]Approval{}, i.Approvals...)
+	return &cpy
+}
+
+func (s *Store) Create(i *Intent) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.intents[i.Id]; ok {
+		return fmt.Errorf("intent %s already exists", i.Id)
+	}
+	s.intents[i.Id] = clone(i)
+	if err := s.save(); err != nil {
+		delete(s.intents, i.Id)
+		return err
+	}
+	return nil
+}
+
+func (s *Store) Get(id string) (*Intent, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	i, ok := s.intents[id]
+	if !ok {
+		return nil, false
+	}
+	return clone(i), true
+}
+
+// List returns the intents, newest first.
+func (s *Store) List() []*Intent {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	intents := make([]*Intent, 0, len(s.intents))
+	for _, i := range s.intents {
+		intents = append(intents, clone(i))
+	}
+	sort.Slice(intents, func(a, b int) bool {
+		return intents[a].CreatedAt.After(intents[b].CreatedAt)
+	})
+	return intents
+}
+
+// Update applies the change to the intent atomically.  The intent is left unchanged if the change returns
+// an error, or if it can't be saved.
+func (s *Store) Update(id string, change func(i *Intent) error) (*Intent, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	current, ok := s.intents[id]
+	if !ok {
+		return nil, fmt.Errorf("intent %s not found", id)
+	}
+	updated := clone(current)
+	if err := change(updated); err != nil {
+		return nil, err
+	}
+	s.intents[id] = updated
+	if err := s.save(); err != nil {
+		s.intents[id] = current
+		return nil, err
+	}
+	return clone(updated), nil
+}
+
+// Expire marks the pending intents that are past their expiry as expired.
+func (s *Store) Expire(now time.Time) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	expired := false
+	for _, i := range s.intents {
+		if i.Expire(now) {
+			expired = true
+		}
+	}
+	if expired {
+		return s.save()
+	}
+	return nil
+}
+
+// TakeDue moves the scheduled intents whose delay has passed to executing, and returns them for the caller
+// to make the withdrawals.  Each intent is only returned once.
+func (s *Store) TakeDue(now time.Time) ([]*Intent, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	due := []*Intent{}
+	for _, i := range s.intents {
+		if i.Due(now) {
+			due = append(due, i)
+		}
+	}
+	if len(due) == 0 {
+		return nil, nil
+	}
+	for _, i := range due {
+		i.Status = StatusExecuting
+	}
+	if err := s.save(); err != nil {
+		for _, i := range due {
+			i.Status = StatusScheduled
+		}
+		return nil, err
+	}
+	taken := make([]*Intent, len(due))
+	for j, i := range due {
+		taken[j] = clone(i)
+	}
+	sort.Slice(taken, func(a, b int) bool {
+		return taken[a].ScheduledAt.Before(taken[b].ScheduledAt)
+	})
+	return taken, nil
+}
@@ -0,0 +1,203 @@
+package keyring
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/term"
+)
+
+// Env var holding the passphrase of encrypted keys, used instead of prompting
+const ENV_KEYRING_PASSPHRASE = "OFFCHAIN_KEYRING_PASSPHRASE"
+
+const KdfArgon2id = "argon2id"
+
+// argon2id parameters for newly encrypted keys (RFC 9106 second recommendation)
+const (
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024
+	argon2Threads = 4
+)
+
+var ErrWrongPassphrase = errors.New("wrong passphrase, or the key file has been modified")
+
+// EncryptedSecret is a secret key encrypted with XChaCha20-Poly1305, using a key derived from a passphrase.
+type EncryptedSecret struct {
+	Kdf        string `toml:"kdf"`
+	Salt       Hex    `toml:"salt"`
+	Time       uint32 `toml:"time"`
+	Memory     uint32 `toml:"memory"`
+	Threads    uint8  `toml:"threads"`
+	Nonce      Hex    `toml:"nonce"`
+	Ciphertext Hex    `toml:"ciphertext"`
+}
+
+// PassphraseFunc returns the passphrase to unlock a key with.
+type PassphraseFunc func(name ClientKeyName) (string, error)
+
+// StaticPassphrase unlocks every key with the same passphrase, e.g. one loaded from a secret manager.
+func StaticPassphrase(passphrase string) PassphraseFunc {
+	return func(name ClientKeyName) (string, error) {
+		return passphrase, nil
+	}
+}
+
+// EnvOrPromptPassphrase uses $OFFCHAIN_KEYRING_PASSPHRASE if set, and otherwise prompts for the passphrase.
+func EnvOrPromptPassphrase(name ClientKeyName) (string, error) {
+	if passphrase, ok := os.LookupEnv(ENV_KEYRING_PASSPHRASE); ok {
+		return passphrase, nil
+	}
+	return PromptPassphrase(fmt.Sprintf("Passphrase for %s: ", name.Id()))
+}
+
+// PromptPassphrase reads a passphrase from the terminal without echoing it.
+func PromptPassphrase(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("cannot prompt for passphrase without a terminal (maybe set %s)", ENV_KEYRING_PASSPHRASE)
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	bz, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return string(bz), nil
+}
+
+// PromptNewPassphrase prompts for a new passphrase twice, to catch typos.
+func PromptNewPassphrase() (string, error) {
+	passphrase, err := PromptPassphrase("New passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("passphrase must not be empty")
+	}
+	confirmation, err := PromptPassphrase("Repeat passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase != confirmation {
+		return "", fmt.Errorf("passphrases do not match")
+	}
+	return passphrase, nil
+}
+
+// the name and algorithm are authenticated, so an encrypted secret can't be moved to another key
+func (ck *ClientKey) additionalData() []byte {
+	return []byte(string(ck.Name) + "\x00" + string(ck.Algorithm))
+}
+
+func deriveKey(passphrase string, enc *EncryptedSecret) ([]byte, error) {
+	if enc.Kdf != KdfArgon2id {
+		return nil, fmt.Errorf("unsupported kdf: %s", enc.Kdf)
+	}
+	salt, err := enc.Salt.Decode()
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt: %w", err)
+	}
+	return argon2.IDKey([]byte(passphrase), salt, enc.Time, enc.Memory, enc.Threads, chacha20poly1305.KeySize), nil
+}
+
+func (ck *ClientKey) IsEncrypted() bool {
+	return ck.Encrypted != nil
+}
+
+// Encrypt replaces the secret key with one encrypted using the passphrase.
+func (ck *ClientKey) Encrypt(passphrase string) error {
+	if ck.IsEncrypted() {
+		return fmt.Errorf("key %s is already encrypted", ck.Name)
+	}
+	if passphrase == "" {
+		return fmt.Errorf("passphrase must not be empty")
+	}
+	secretBz, err := ck.Secret.Decode()
+	if err != nil {
+		return fmt.Errorf("invalid secret key: %w", err)
+	}
+	// keep the public key readable without the passphrase
+	publicKey, err := ck.PublicKey()
+	if err != nil {
+		return err
+	}
+
+	salt := make([]byte, 16)
+	nonce := make([]byte, chacha20poly1305.NonceSizeX)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	enc := &EncryptedSecret{
+		Kdf:     KdfArgon2id,
+		Salt:    Hex(hex.EncodeToString(salt)),
+		Time:    argon2Time,
+		Memory:  argon2Memory,
+		Threads: argon2Threads,
+		Nonce:   Hex(hex.EncodeToString(nonce)),
+	}
+	key, err := deriveKey(passphrase, enc)
+	if err != nil {
+		return err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return err
+	}
+	enc.Ciphertext = Hex(hex.EncodeToString(aead.Seal(nil, nonce, secretBz, ck.additionalData())))
+
+	ck.Encrypted = enc
+	ck.Public = Hex(hex.EncodeToString(publicKey))
+	ck.Secret = ""
+	return nil
+}
+
+// Decrypt recovers the secret key using the passphrase.  The key is left encrypted, so it can be saved again.
+func (ck *ClientKey) Decrypt(passphrase string) error {
+	if !ck.IsEncrypted() {
+		return nil
+	}
+	enc := ck.Encrypted
+	key, err := deriveKey(passphrase, enc)
+	if err != nil {
+		return err
+	}
+	nonce, err := enc.Nonce.Decode()
+	if err != nil {
+		return fmt.Errorf("invalid nonce: %w", err)
+	}
+	ciphertext, err := enc.Ciphertext.Decode()
+	if err != nil {
+		return fmt.Errorf("invalid ciphertext: %w", err)
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return fmt.Errorf("invalid nonce length: %d", len(nonce))
+	}
+	secretBz, err := aead.Open(nil, nonce, ciphertext, ck.additionalData())
+	if err != nil {
+		return ErrWrongPassphrase
+	}
+	ck.Secret = Hex(hex.EncodeToString(secretBz))
+	return nil
+}
+
+// Decrypted returns a copy of the key with the secret in plaintext, for migrating away from encryption
+// or changing the passphrase.
+func (ck *ClientKey) Decrypted() *ClientKey {
+	return &ClientKey{
+		Name:      ck.Name,
+		Algorithm: ck.Algorithm,
+		Secret:    ck.Secret,
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
@@ -44,11 +45,18 @@ func (h Hex) Decode() ([]byte, error) {
 type ClientKey struct {
 	Name      ClientKeyName `toml:"name"`
 	Algorithm Algorithm     `toml:"algorithm"`
-	Secret    Hex           `toml:"secret_key"`
+	// Plaintext secret key, or the decrypted secret key once an encrypted key is unlocked
+	Secret Hex `toml:"secret_key,omitempty"`
+	// Public key, stored alongside encrypted keys so it can be read without the passphrase
+	Public    Hex              `toml:"public_key,omitempty"`
+	Encrypted *EncryptedSecret `toml:"encrypted,omitempty"`
 }
 
 // Returns the public key
 func (ck *ClientKey) PublicKey() ([]byte, error) {
+	if ck.Secret == "" && ck.Public != "" {
+		return ck.Public.Decode()
+	}
 	switch ck.Algorithm {
 	case Ed25519:
 		secretBz, err := ck.Secret.Decode()
@@ -63,23 +71,55 @@ func (ck *ClientKey) PublicKey() ([]byte, error) {
 }
 
 type Keyring struct {
-	dir string
+	dir        string
+	passphrase PassphraseFunc
 }
 
 const DefaultSubDir = "keyring"
 
 // Create a new keyring, passing the directory
-// that contains the .toml key files, typically $XDG_DATA_HOME/treasury/$TREASURY_ID or $TREASURY_HOME
+// that contains the .toml key files, typically $XDG_DATA_HOME/treasury/$TREASURY_ID or $TREASURY_HOME.
+// Encrypted keys are unlocked using $OFFCHAIN_KEYRING_PASSPHRASE or by prompting.
 func New(dir string) Keyring {
-	return Keyring{dir}
+	return Keyring{dir, EnvOrPromptPassphrase}
+}
+
+// SetPassphrase changes how the passphrase of encrypted keys is obtained.
+func (keyring *Keyring) SetPassphrase(passphrase PassphraseFunc) {
+	keyring.passphrase = passphrase
 }
 
+// Load reads a key, unlocking it if it's encrypted.
 func (keyring *Keyring) Load(filename string) (*ClientKey, error) {
+	ck, err := keyring.Read(filename)
+	if err != nil {
+		return ck, err
+	}
+	if ck.IsEncrypted() {
+		passphrase, err := keyring.passphrase(ck.Name)
+		if err != nil {
+			return ck, err
+		}
+		if err := ck.Decrypt(passphrase); err != nil {
+			return ck, fmt.Errorf("could not unlock key %s: %w", ck.Name.Id(), err)
+		}
+	}
+	return ck, nil
+}
+
+func (keyring *Keyring) path(name ClientKeyName) string {
+	return filepath.Join(keyring.dir, name.Id()+".toml")
+}
+
+// Read reads a key without unlocking it, e.g. to get the public key.
+func (keyring *Keyring) Read(filename string) (*ClientKey, error) {
 	ck := &ClientKey{}
-	bz, err := os.ReadFile(filepath.Join(keyring.dir, filename))
+	path := filepath.Join(keyring.dir, filename)
+	bz, err := os.ReadFile(path)
 	if errors.Is(err, os.ErrNotExist) {
 		// try with .toml
-		bz, err = os.ReadFile(filepath.Join(keyring.dir, filename+".toml"))
+		path = filepath.Join(keyring.dir, filename+".toml")
+		bz, err = os.ReadFile(path)
 	}
 	if err != nil {
 		return ck, err
@@ -88,6 +128,9 @@ func (keyring *Keyring) Load(filename string) (*ClientKey, error) {
 	if err != nil {
 		return ck, err
 	}
+	if info, err := os.Stat(path); err == nil && !ck.IsEncrypted() && info.Mode().Perm()&0o077 != 0 {
+		slog.Warn("plaintext key file is readable by other users, it should be encrypted (oc keys encrypt)", "path", path)
+	}
 	return ck, nil
 }
 
@@ -109,7 +152,7 @@ func (keyring *Keyring) List() ([]string, error) {
 	return ids, nil
 }
 
-func (keyring *Keyring) New(name ClientKeyName, alg Algorithm) (*ClientKey, error) {
+func (keyring *Keyring) New(name ClientKeyName, alg Algorithm, passphrase string) (*ClientKey, error) {
 	var secret []byte
 	switch alg {
 	case Ed25519, "ed255":
@@ -117,34 +160,58 @@ func (keyring *Keyring) New(name ClientKeyName, alg Algorithm) (*ClientKey, erro
 	default:
 		return nil, fmt.Errorf("unsupported alg: %s", alg)
 	}
-	return keyring.Import(name, alg, secret)
+	return keyring.Import(name, alg, secret, passphrase)
 }
 
-func (keyring *Keyring) Import(name ClientKeyName, alg Algorithm, secret []byte) (*ClientKey, error) {
+// Import stores a secret key.  The key is encrypted if a passphrase is given.
+func (keyring *Keyring) Import(name ClientKeyName, alg Algorithm, secret []byte, passphrase string) (*ClientKey, error) {
 	ck := &ClientKey{
 		Name:      name,
 		Algorithm: alg,
 		Secret:    Hex(hex.EncodeToString(secret)),
 	}
+	if passphrase != "" {
+		if err := ck.Encrypt(passphrase); err != nil {
+			return ck, err
+		}
+	}
+	return ck, keyring.Save(ck)
+}
+
+// Save writes the key, readable only by the current user.  Any existing file is replaced.
+func (keyring *Keyring) Save(ck *ClientKey) error {
+	if ck.IsEncrypted() {
+		// never write the unlocked secret next to the encrypted one
+		saved := *ck
+		saved.Secret = ""
+		ck = &saved
+	}
 	bz, err := toml.Marshal(ck)
 	if err != nil {
-		return ck, err
+		return err
 	}
-	// fmt.Println("opening", keyring.dir, "for", name)
-	err = os.MkdirAll(keyring.dir, os.ModePerm)
+	err = os.MkdirAll(keyring.dir, 0o700)
 	if err != nil {
-		return ck, err
+		return err
 	}
-	// fmt.Println("writing", filepath.Join(keyring.dir, name.Id()+".toml"))
-	err = os.WriteFile(
-		filepath.Join(keyring.dir, name.Id()+".toml"),
-		bz,
-		os.ModePerm,
-	)
+	// write to a temporary file first, so an existing key isn't lost if writing fails
+	tmp, err := os.CreateTemp(keyring.dir, "."+ck.Name.Id()+".*.tmp")
 	if err != nil {
-		return ck, err
+		return err
 	}
-	return ck, nil
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(bz); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), keyring.path(ck.Name))
 }
 
 func GenerateEd255Seed() []byte {
@@ -1,7 +1,10 @@
 package keyring_test
 
 import (
+	"crypto/ed25519"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/cordialsys/offchain/pkg/keyring"
@@ -32,3 +35,83 @@ func TestGetKeyringSecret(t *testing.T) {
 	dir = keyring.KeyringDirOrTreasuryHome("otherdir/id")
 	require.Equal(t, dir, "otherdir")
 }
+
+func TestEncryptedKey(t *testing.T) {
+	require := require.New(t)
+	dir := t.TempDir()
+	kr := keyring.New(dir)
+	kr.SetPassphrase(keyring.StaticPassphrase("hunter2"))
+
+	ck, err := kr.New(keyring.NewClientKeyName("enc"), keyring.Ed25519, "hunter2")
+	require.NoError(err)
+	require.True(ck.IsEncrypted())
+
+	info, err := os.Stat(filepath.Join(dir, "enc.toml"))
+	require.NoError(err)
+	require.EqualValues(0o600, info.Mode().Perm())
+	bz, err := os.ReadFile(filepath.Join(dir, "enc.toml"))
+	require.NoError(err)
+	require.NotContains(string(bz), "secret_key")
+
+	// the public key can be read while locked
+	locked, err := kr.Read("enc")
+	require.NoError(err)
+	require.Empty(locked.Secret)
+	pub, err := locked.PublicKey()
+	require.NoError(err)
+
+	unlocked, err := kr.Load("enc")
+	require.NoError(err)
+	require.Len(unlocked.Secret, 64)
+	seed, err := unlocked.Secret.Decode()
+	require.NoError(err)
+	require.EqualValues(ed25519.NewKeyFromSeed(seed).Public(), ed25519.PublicKey(pub))
+
+	kr.SetPassphrase(keyring.StaticPassphrase("wrong"))
+	_, err = kr.Load("enc")
+	require.ErrorIs(err, keyring.ErrWrongPassphrase)
+
+	// change the passphrase
+	changed := unlocked.Decrypted()
+	require.NoError(changed.Encrypt("correct horse"))
+	require.NoError(kr.Save(changed))
+	kr.SetPassphrase(keyring.StaticPassphrase("correct horse"))
+	reloaded, err := kr.Load("enc")
+	require.NoError(err)
+	require.Equal(unlocked.Secret, reloaded.Secret)
+}
+
+func TestEncryptPlaintextKey(t *testing.T) {
+	require := require.New(t)
+	dir := t.TempDir()
+	// keys used to be written readable by everyone
+	plaintext := "name = 'client-keys/old'\nalgorithm = 'ed25519'\nsecret_key = '" + strings.Repeat("ab", 32) + "'\n"
+	require.NoError(os.WriteFile(filepath.Join(dir, "old.toml"), []byte(plaintext), 0o644))
+
+	kr := keyring.New(dir)
+	ck, err := kr.Load("old")
+	require.NoError(err)
+	require.False(ck.IsEncrypted())
+
+	require.NoError(ck.Encrypt("pw"))
+	require.NoError(kr.Save(ck))
+	info, err := os.Stat(filepath.Join(dir, "old.toml"))
+	require.NoError(err)
+	require.EqualValues(0o600, info.Mode().Perm())
+
+	kr.SetPassphrase(keyring.StaticPassphrase("pw"))
+	ck, err = kr.Load("old")
+	require.NoError(err)
+	require.EqualValues(strings.Repeat("ab", 32), ck.Secret)
+}
+
+func TestEncryptedKeyIsBoundToName(t *testing.T) {
+	require := require.New(t)
+	kr := keyring.New(t.TempDir())
+	ck, err := kr.New(keyring.NewClientKeyName("a"), keyring.Ed25519, "pw")
+	require.NoError(err)
+
+	// the encrypted secret can't be reused under another name
+	ck.Name = keyring.NewClientKeyName("b")
+	require.ErrorIs(ck.Decrypt("pw"), keyring.ErrWrongPassphrase)
+}
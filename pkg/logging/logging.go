@@ -0,0 +1,201 @@
+// Package logging configures structured logging with slog.  Values of attributes that look like
+// credentials are redacted, and the id of the request being handled is added to each record.
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatJson Format = "json"
+	FormatText Format = "text"
+)
+
+const Redacted = "[REDACTED]"
+
+// Header identifying a request to the server, sent by the caller or generated by the server
+const RequestIdHeader = "X-Request-Id"
+
+// Level of the handlers created by New, so it can be changed after they're installed.
+var Level = new(slog.LevelVar)
+
+// Attribute, header and query parameter names containing any of these are redacted
+var sensitiveNames = []string{
+	"secret",
+	"passphrase",
+	"password",
+	"token",
+	"sign",
+	"apikey",
+	"api_key",
+	"api-key",
+	"access-key",
+	"access_key",
+	"authorization",
+	"private",
+	"mnemonic",
+	"cookie",
+}
+
+// IsSensitive reports whether a value with this name should be redacted.
+func IsSensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, sensitive := range sensitiveNames {
+		if strings.Contains(name, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+type requestIdKey struct{}
+
+// WithRequestId returns a context whose log records are tagged with the request id.
+func WithRequestId(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, requestId)
+}
+
+// RequestId returns the id of the request being handled, if any.
+func RequestId(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestId, _ := ctx.Value(requestIdKey{}).(string)
+	return requestId
+}
+
+// New creates a handler writing in the format, at the package Level.
+func New(w io.Writer, format Format) slog.Handler {
+	opts := &slog.HandlerOptions{
+		Level:       Level,
+		ReplaceAttr: redact,
+	}
+	var handler slog.Handler
+	if format == FormatJson {
+		handler = slog.NewJSONHandler(w, opts)
+	} else {
+		handler = slog.NewTextHandler(w, opts)
+	}
+	return &contextHandler{handler}
+}
+
+// Setup installs a handler as the default logger, including for the standard log package.
+func Setup(w io.Writer, format Format) {
+	slog.SetDefault(slog.New(New(w, format)))
+}
+
+func redact(groups []string, attr slog.Attr) slog.Attr {
+	if attr.Value.Kind() == slog.KindGroup {
+		return attr
+	}
+	if IsSensitive(attr.Key) {
+		return slog.String(attr.Key, Redacted)
+	}
+	if attr.Value.Kind() != slog.KindAny {
+		return attr
+	}
+	switch value := attr.Value.Any().(type) {
+	case url.Values:
+		return slog.String(attr.Key, RedactQuery(value).Encode())
+	case *url.URL:
+		if value != nil {
+			redacted := *value
+			redacted.RawQuery = RedactQuery(value.Query()).Encode()
+			redacted.User = nil
+			return slog.String(attr.Key, redacted.String())
+		}
+	case http.Header:
+		return slog.Any(attr.Key, RedactHeader(value))
+	}
+	return attr
+}
+
+// RedactQuery returns a copy of the query with sensitive parameters redacted.
+func RedactQuery(query url.Values) url.Values {
+	redacted := url.Values{}
+	for key, values := range query {
+		if IsSensitive(key) {
+			redacted[key] = []string{Redacted}
+		} else {
+			redacted[key] = values
+		}
+	}
+	return redacted
+}
+
+// RedactHeader returns a copy of the header with sensitive fields redacted.
+func RedactHeader(header http.Header) http.Header {
+	redacted := http.Header{}
+	for key, values := range header {
+		if IsSensitive(key) {
+			redacted[key] = []string{Redacted}
+		} else {
+			redacted[key] = values
+		}
+	}
+	return redacted
+}
+
+// Body is a request or response body to log.  Values of sensitive fields in JSON bodies are redacted at any
+// depth, e.g. the secret of a newly created API key.
+type Body []byte
+
+var _ slog.LogValuer = Body(nil)
+
+func (b Body) LogValue() slog.Value {
+	var value interface{}
+	if err := json.Unmarshal(b, &value); err != nil {
+		return slog.StringValue(string(b))
+	}
+	redacted, err := json.Marshal(redactJson(value))
+	if err != nil {
+		return slog.StringValue(Redacted)
+	}
+	return slog.StringValue(string(redacted))
+}
+
+func redactJson(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if IsSensitive(key) {
+				value[key] = Redacted
+			} else {
+				value[key] = redactJson(field)
+			}
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = redactJson(item)
+		}
+	}
+	return value
+}
+
+// contextHandler adds the request id from the context to each record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestId := RequestId(ctx); requestId != "" {
+		record.AddAttrs(slog.String("request_id", requestId))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{h.Handler.WithGroup(name)}
+}
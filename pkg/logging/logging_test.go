@@ -0,0 +1,92 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/cordialsys/offchain/pkg/logging"
+	"github.com/stretchr/testify/require"
+)
+
+func logJson(t *testing.T, log func(logger *slog.Logger)) map[string]any {
+	buf := &bytes.Buffer{}
+	log(slog.New(logging.New(buf, logging.FormatJson)))
+	record := map[string]any{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	return record
+}
+
+func TestRedactsSecrets(t *testing.T) {
+	require := require.New(t)
+	record := logJson(t, func(logger *slog.Logger) {
+		logger.Warn("request",
+			"api_key", "k",
+			"secretKey", "s",
+			"query", url.Values{"symbol": {"BTC"}, "signature": {"abcd"}},
+			"header", http.Header{"X-Mbx-Apikey": {"k"}, "Content-Type": {"application/json"}},
+			"url", &url.URL{Scheme: "https", Host: "example.com", Path: "/x", RawQuery: "a=1&sign=abcd"},
+		)
+	})
+	require.Equal(logging.Redacted, record["api_key"])
+	require.Equal(logging.Redacted, record["secretKey"])
+	require.Equal("signature=%5BREDACTED%5D&symbol=BTC", record["query"])
+	require.Equal(map[string]any{
+		"X-Mbx-Apikey": []any{logging.Redacted},
+		"Content-Type": []any{"application/json"},
+	}, record["header"])
+	require.Equal("https://example.com/x?a=1&sign=%5BREDACTED%5D", record["url"])
+}
+
+func TestRedactsBody(t *testing.T) {
+	require := require.New(t)
+	record := logJson(t, func(logger *slog.Logger) {
+		logger.Warn("response",
+			"body", logging.Body(`{"code":"0","data":[{"apiKey":"k","secretKey":"s","label":"main","perm":{"passphrase":"p"}}]}`),
+			"text", logging.Body("bad gateway"),
+		)
+	})
+	require.JSONEq(`{"code":"0","data":[{"apiKey":"[REDACTED]","secretKey":"[REDACTED]","label":"main","perm":{"passphrase":"[REDACTED]"}}]}`, record["body"].(string))
+	require.Equal("bad gateway", record["text"])
+}
+
+func TestRedactsAttrsAddedWith(t *testing.T) {
+	record := logJson(t, func(logger *slog.Logger) {
+		logger.With("passphrase", "p").Warn("request")
+	})
+	require.Equal(t, logging.Redacted, record["passphrase"])
+}
+
+func TestRequestId(t *testing.T) {
+	require := require.New(t)
+	ctx := logging.WithRequestId(context.Background(), "req-1")
+	require.Equal("req-1", logging.RequestId(ctx))
+	require.Equal("", logging.RequestId(context.Background()))
+
+	record := logJson(t, func(logger *slog.Logger) {
+		logger.With("exchange", "okx").WarnContext(ctx, "request")
+	})
+	require.Equal("req-1", record["request_id"])
+	require.Equal("okx", record["exchange"])
+
+	record = logJson(t, func(logger *slog.Logger) {
+		logger.Warn("request")
+	})
+	require.NotContains(record, "request_id")
+}
+
+func TestLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(logging.New(buf, logging.FormatJson))
+	logging.Level.Set(slog.LevelWarn)
+	defer logging.Level.Set(slog.LevelInfo)
+	logger.Info("hidden")
+	require.Empty(t, buf.String())
+	logging.Level.Set(slog.LevelInfo)
+	logger.Info("shown")
+	require.Contains(t, buf.String(), "shown")
+}
@@ -0,0 +1,104 @@
+// Package metrics defines the prometheus metrics exported by offchain.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "offchain"
+
+// Outcomes used to label requests
+const (
+	OutcomeSuccess      = "success"
+	OutcomeClientError  = "client_error"
+	OutcomeServerError  = "server_error"
+	OutcomeRateLimited  = "rate_limited"
+	OutcomeNetworkError = "network_error"
+)
+
+var Registry = prometheus.NewRegistry()
+
+var (
+	// Requests handled by the server
+	Requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Requests handled by the server.",
+	}, []string{"exchange", "endpoint", "subaccount", "outcome"})
+
+	RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to handle requests, including calls to the exchange.",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"exchange", "endpoint", "subaccount", "outcome"})
+
+	// Requests rejected by authentication
+	AuthRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_rejections_total",
+		Help:      "Requests rejected because of missing or invalid credentials.",
+	}, []string{"endpoint", "reason"})
+
+	// Requests made to the exchange APIs, counting each retry
+	ExchangeRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "exchange_requests_total",
+		Help:      "Requests made to exchange APIs, including retries.",
+	}, []string{"exchange", "endpoint", "outcome"})
+
+	ExchangeRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "exchange_request_duration_seconds",
+		Help:      "Latency of requests made to exchange APIs.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"exchange", "endpoint", "outcome"})
+
+	SecretLoads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "secret_loads_total",
+		Help:      "Secrets loaded, by secret backend.",
+	}, []string{"backend", "outcome"})
+
+	LastBalanceFetch = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_balance_fetch_timestamp_seconds",
+		Help:      "Unix time of the last successful balance fetch.",
+	}, []string{"exchange", "subaccount"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		Requests,
+		RequestDuration,
+		AuthRejections,
+		ExchangeRequests,
+		ExchangeRequestDuration,
+		SecretLoads,
+		LastBalanceFetch,
+	)
+}
+
+// OutcomeForStatus classifies an HTTP response status.
+func OutcomeForStatus(status int) string {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return OutcomeRateLimited
+	case status >= 500:
+		return OutcomeServerError
+	case status >= 400:
+		return OutcomeClientError
+	default:
+		return OutcomeSuccess
+	}
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
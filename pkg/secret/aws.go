@@ -0,0 +1,126 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	awssecretmanager "github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+type awsBackend struct{}
+
+func (awsBackend) Type() SecretType { return AwsSecretManager }
+func (awsBackend) Name() string     { return "AWS Secret Manager" }
+func (awsBackend) Usage() string    { return "<name[:key]>[,region][,version]" }
+func (awsBackend) Load(ctx context.Context, args []string) (string, error) {
+	if !slices.Contains([]int{1, 2, 3}, len(args)) {
+		return "", fmt.Errorf("%s secret has 1-3 comma separated arguments: %s", AwsSecretManager, AwsSecretManager.Usage())
+	}
+	secretName := args[0]
+	keyName := ""
+	nameParts := strings.Split(secretName, ":")
+	if len(nameParts) > 1 {
+		secretName = nameParts[0]
+		keyName = strings.Join(nameParts[1:], ":")
+	}
+	region := ""
+	version := "AWSCURRENT"
+	if len(args) > 1 {
+		region = args[1]
+	}
+	if len(args) > 2 {
+		version = args[2]
+	}
+	awsAwgs := []func(*config.LoadOptions) error{}
+	if region != "" {
+		awsAwgs = append(awsAwgs, config.WithRegion(region))
+	}
+
+	config, err := config.LoadDefaultConfig(ctx, awsAwgs...)
+	if err != nil {
+		return "", err
+	}
+	svc := awssecretmanager.NewFromConfig(config)
+	input := &awssecretmanager.GetSecretValueInput{
+		SecretId:     aws.String(secretName),
+		VersionStage: aws.String(version),
+	}
+	result, err := svc.GetSecretValue(ctx, input)
+	if err != nil {
+		// https://docs.aws.amazon.com/secretsmanager/latest/apireference/API_GetSecretValue.html
+		return "", err
+	}
+	secretBz := *result.SecretString
+	if keyName != "" {
+		// Another layer of nesting to unwrap, this time JSON
+		secretData := map[string]interface{}{}
+		err = json.Unmarshal([]byte(secretBz), &secretData)
+		if err != nil {
+			// do not omit internal error to guard from leaking anything sensitive
+			return "", fmt.Errorf("could not retrieve %s key because %s has invalid JSON", keyName, secretName)
+		}
+		secretValue, ok := secretData[keyName]
+		if !ok {
+			return "", fmt.Errorf("could not find %s key in %s JSON", keyName, secretName)
+		}
+		return fmt.Sprint(secretValue), nil
+	}
+	// return secretBz literally as the secret, no JSON nesting
+	return secretBz, nil
+}
+
+func (awsBackend) CheckStore(args []string) error {
+	if !slices.Contains([]int{1, 2, 3}, len(args)) {
+		return fmt.Errorf("%s secret has 1-3 comma separated arguments: %s", AwsSecretManager, AwsSecretManager.Usage())
+	}
+	if len(args) > 2 && args[2] != "AWSCURRENT" {
+		return fmt.Errorf("%s secret pins version %s, so a new version would not be used", AwsSecretManager, args[2])
+	}
+	return nil
+}
+
+// Store puts a new current version of the secret.  If the reference selects a key, the other keys of the
+// JSON secret are kept.
+func (awsBackend) Store(ctx context.Context, args []string, value string) error {
+	secretName, keyName, _ := strings.Cut(args[0], ":")
+	awsAwgs := []func(*config.LoadOptions) error{}
+	if len(args) > 1 && args[1] != "" {
+		awsAwgs = append(awsAwgs, config.WithRegion(args[1]))
+	}
+	config, err := config.LoadDefaultConfig(ctx, awsAwgs...)
+	if err != nil {
+		return err
+	}
+	svc := awssecretmanager.NewFromConfig(config)
+	if keyName != "" {
+		result, err := svc.GetSecretValue(ctx, &awssecretmanager.GetSecretValueInput{
+			SecretId:     aws.String(secretName),
+			VersionStage: aws.String("AWSCURRENT"),
+		})
+		if err != nil {
+			return err
+		}
+		secretData := map[string]interface{}{}
+		if result.SecretString != nil {
+			if err := json.Unmarshal([]byte(*result.SecretString), &secretData); err != nil {
+				return fmt.Errorf("could not set %s key because %s has invalid JSON", keyName, secretName)
+			}
+		}
+		secretData[keyName] = value
+		bz, err := json.Marshal(secretData)
+		if err != nil {
+			return err
+		}
+		value = string(bz)
+	}
+	_, err = svc.PutSecretValue(ctx, &awssecretmanager.PutSecretValueInput{
+		SecretId:     aws.String(secretName),
+		SecretString: aws.String(value),
+	})
+	return err
+}
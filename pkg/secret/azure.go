@@ -0,0 +1,130 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azsecrets"
+)
+
+// Env vars used by the Azure SDKs to configure credentials
+const (
+	ENV_AZURE_TENANT_ID            = "AZURE_TENANT_ID"
+	ENV_AZURE_CLIENT_ID            = "AZURE_CLIENT_ID"
+	ENV_AZURE_CLIENT_SECRET        = "AZURE_CLIENT_SECRET"
+	ENV_AZURE_FEDERATED_TOKEN_FILE = "AZURE_FEDERATED_TOKEN_FILE"
+	ENV_AZURE_AUTHORITY_HOST       = "AZURE_AUTHORITY_HOST"
+)
+
+// Options for the Azure credential and Key Vault clients, e.g. to use another cloud or transport.  They're
+// read when the first Azure secret is loaded.
+var (
+	AzureCredentialOptions = &azidentity.DefaultAzureCredentialOptions{}
+	AzureKeyVaultOptions   = &azsecrets.ClientOptions{}
+)
+
+// The credential caches its tokens, so it's shared by the clients for every vault.
+var azureClients = struct {
+	sync.Mutex
+	credential azcore.TokenCredential
+	vaults     map[string]*azsecrets.Client
+}{vaults: map[string]*azsecrets.Client{}}
+
+// azureBackend reads secrets from Azure Key Vault.  It authenticates with the Azure SDK's default
+// credential chain, e.g. a client secret or workload identity configured using the standard AZURE_* env
+// vars, the managed identity, or the Azure CLI.
+type azureBackend struct{}
+
+func (azureBackend) Type() SecretType { return AzureKeyVault }
+func (azureBackend) Name() string     { return "Azure Key Vault" }
+func (azureBackend) Usage() string    { return "<vault-name-or-url>,<name>[,version]" }
+func (azureBackend) Load(ctx context.Context, args []string) (string, error) {
+	if !slices.Contains([]int{2, 3}, len(args)) {
+		return "", fmt.Errorf("%s secret has 2-3 comma separated arguments: %s", AzureKeyVault, AzureKeyVault.Usage())
+	}
+	version := ""
+	if len(args) > 2 {
+		version = args[2]
+	}
+	client, err := azureVaultClient(args[0])
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.GetSecret(ctx, args[1], version, nil)
+	if err != nil {
+		return "", azureErr("get", args[1], err)
+	}
+	if resp.Value == nil {
+		return "", fmt.Errorf("azure secret %s has no value", args[1])
+	}
+	return strings.TrimSpace(*resp.Value), nil
+}
+
+func (azureBackend) CheckStore(args []string) error {
+	if !slices.Contains([]int{2, 3}, len(args)) {
+		return fmt.Errorf("%s secret has 2-3 comma separated arguments: %s", AzureKeyVault, AzureKeyVault.Usage())
+	}
+	if len(args) > 2 && args[2] != "" {
+		return fmt.Errorf("%s secret pins version %s, so a new version would not be used", AzureKeyVault, args[2])
+	}
+	return nil
+}
+
+// Store sets the secret, which adds a new version.
+func (azureBackend) Store(ctx context.Context, args []string, value string) error {
+	client, err := azureVaultClient(args[0])
+	if err != nil {
+		return err
+	}
+	_, err = client.SetSecret(ctx, args[1], azsecrets.SetSecretParameters{Value: &value}, nil)
+	if err != nil {
+		return azureErr("set", args[1], err)
+	}
+	return nil
+}
+
+// azureErr drops the body of an error response, as it may echo the request.
+func azureErr(operation string, name string, err error) error {
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) {
+		return fmt.Errorf("failed to %s azure secret %s: %d %s", operation, name, respErr.StatusCode, respErr.ErrorCode)
+	}
+	return fmt.Errorf("failed to %s azure secret %s: %w", operation, name, err)
+}
+
+// azureVaultClient returns the client for the vault, creating the credential on first use.
+func azureVaultClient(vault string) (*azsecrets.Client, error) {
+	vaultUrl := azureVaultUrl(vault)
+	azureClients.Lock()
+	defer azureClients.Unlock()
+	if client, ok := azureClients.vaults[vaultUrl]; ok {
+		return client, nil
+	}
+	if azureClients.credential == nil {
+		credential, err := azidentity.NewDefaultAzureCredential(AzureCredentialOptions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to authenticate to azure: %w", err)
+		}
+		azureClients.credential = credential
+	}
+	client, err := azsecrets.NewClient(vaultUrl, azureClients.credential, AzureKeyVaultOptions)
+	if err != nil {
+		return nil, err
+	}
+	azureClients.vaults[vaultUrl] = client
+	return client, nil
+}
+
+// azureVaultUrl returns the url of the vault, which may be given by name.
+func azureVaultUrl(vault string) string {
+	if !strings.Contains(vault, "://") {
+		vault = fmt.Sprintf("https://%s.vault.azure.net", vault)
+	}
+	return strings.TrimSuffix(vault, "/")
+}
@@ -0,0 +1,186 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cordialsys/offchain/pkg/keyring"
+)
+
+// Backend loads secrets from a secret manager.  A reference `<type>:<args>` is loaded by the backend
+// registered for the type, with the comma separated args.
+type Backend interface {
+	Type() SecretType
+	// Human readable name, e.g. "Hashicorp Vault"
+	Name() string
+	// The expected args, e.g. "<project>,<name>[,version]"
+	Usage() string
+	Load(ctx context.Context, args []string) (string, error)
+}
+
+var registry = struct {
+	sync.RWMutex
+	backends map[SecretType]Backend
+	// in order of registration, without aliases
+	types []SecretType
+}{backends: map[SecretType]Backend{}}
+
+// Types lists the registered backends, in order of registration.  Aliases and raw secrets aren't listed.
+func Types() []SecretType {
+	registry.RLock()
+	defer registry.RUnlock()
+	return slices.Clone(registry.types)
+}
+
+// Register adds a backend, replacing any backend already registered for the type.  It may also be
+// referenced by any of the aliases.
+func Register(backend Backend, aliases ...SecretType) {
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.backends[backend.Type()]; !ok && backend.Type() != Raw {
+		registry.types = append(registry.types, backend.Type())
+	}
+	registry.backends[backend.Type()] = backend
+	for _, alias := range aliases {
+		registry.backends[alias] = backend
+	}
+}
+
+func GetBackend(t SecretType) (Backend, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	backend, ok := registry.backends[t]
+	return backend, ok
+}
+
+func init() {
+	Register(envBackend{})
+	Register(vaultBackend{})
+	Register(fileBackend{})
+	Register(gcpBackend{}, "gsm")
+	Register(awsBackend{})
+	Register(keyringBackend{})
+	Register(azureBackend{})
+	Register(onePasswordBackend{})
+	Register(ageBackend{})
+	Register(sopsBackend{})
+	Register(rawBackend{})
+}
+
+type rawBackend struct{}
+
+func (rawBackend) Type() SecretType { return Raw }
+func (rawBackend) Name() string     { return "Raw value" }
+func (rawBackend) Usage() string    { return "<value>" }
+func (rawBackend) Load(ctx context.Context, args []string) (string, error) {
+	sec := args[0]
+	return strings.TrimSpace(sec), nil
+}
+
+type envBackend struct{}
+
+func (envBackend) Type() SecretType { return Env }
+func (envBackend) Name() string     { return "Environment variable" }
+func (envBackend) Usage() string    { return "<name>" }
+func (envBackend) Load(ctx context.Context, args []string) (string, error) {
+	path := args[0]
+	return strings.TrimSpace(os.Getenv(path)), nil
+}
+
+type fileBackend struct{}
+
+func (fileBackend) Type() SecretType { return File }
+func (fileBackend) Name() string     { return "File" }
+func (fileBackend) Usage() string    { return "<path>" }
+func (fileBackend) Load(ctx context.Context, args []string) (string, error) {
+	path := replaceTilda(args[0])
+	_, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	result, err := io.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(result)), nil
+}
+
+func (fileBackend) CheckStore(args []string) error {
+	return nil
+}
+
+// Store replaces the file, keeping its permissions if it already exists.
+func (fileBackend) Store(ctx context.Context, args []string, value string) error {
+	path := replaceTilda(args[0])
+	mode := os.FileMode(0600)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(value + "\n"); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+type keyringBackend struct{}
+
+func (keyringBackend) Type() SecretType { return Keyring }
+func (keyringBackend) Name() string     { return "Treasury Keyring" }
+func (keyringBackend) Usage() string    { return "<id-or-path>" }
+func (keyringBackend) Load(ctx context.Context, args []string) (string, error) {
+	dir := keyring.KeyringDirOrTreasuryHome(args[0])
+
+	kr := keyring.New(dir)
+	key, err := kr.Load(filepath.Base(args[0]))
+	if err != nil {
+		return "", err
+	}
+	return string(key.Secret), nil
+}
+
+// Client for backends that are called over plain HTTP APIs
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// requestJson sends the request and decodes the JSON response.  The body of an error response is not
+// returned, as it may echo the request.
+func requestJson(req *http.Request, output interface{}) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s failed: %s", req.Method, req.URL.Path, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(output); err != nil {
+		return fmt.Errorf("invalid response from %s: %w", req.URL.Path, err)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package secret_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cordialsys/offchain/pkg/secret"
+	"github.com/stretchr/testify/require"
+)
+
+type staticBackend struct {
+	values map[string]string
+}
+
+func (staticBackend) Type() secret.SecretType { return "static" }
+func (staticBackend) Name() string            { return "Static values" }
+func (staticBackend) Usage() string           { return "<name>" }
+func (b staticBackend) Load(ctx context.Context, args []string) (string, error) {
+	return b.values[args[0]], nil
+}
+
+func TestRegisterBackend(t *testing.T) {
+	require := require.New(t)
+	secret.Register(staticBackend{values: map[string]string{"a": "1"}}, "constant")
+
+	require.Contains(secret.Types(), secret.SecretType("static"))
+	require.NotContains(secret.Types(), secret.SecretType("constant"))
+	require.NotContains(secret.Types(), secret.Raw)
+	require.Equal("Static values", secret.SecretType("static").Name())
+	require.Equal("<name>", secret.SecretType("static").Usage())
+
+	value, err := GetSecret("static:a")
+	require.NoError(err)
+	require.Equal("1", value)
+	value, err = GetSecret("constant:a")
+	require.NoError(err)
+	require.Equal("1", value)
+
+	// the built in backends are registered
+	for _, t := range []secret.SecretType{secret.Env, secret.Vault, secret.File, secret.GcpSecretManager, secret.AwsSecretManager, secret.Keyring, secret.AzureKeyVault, secret.OnePassword} {
+		require.Contains(secret.Types(), t)
+		require.NotEmpty(t.Name())
+	}
+	require.Equal("GCP Secret Manager", secret.SecretType("gsm").Name())
+}
+
+func TestGetSecretAzure(t *testing.T) {
+	require := require.New(t)
+	tokens := &atomic.Int32{}
+	latest := "latest-key"
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		path := strings.TrimSuffix(r.URL.Path, "/")
+		switch {
+		case path == "/tenant1/v2.0/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"authorization_endpoint": server.URL + "/tenant1/oauth2/v2.0/authorize",
+				"token_endpoint":         server.URL + "/tenant1/oauth2/v2.0/token",
+				"issuer":                 server.URL + "/tenant1/v2.0",
+			})
+		case path == "/tenant1/oauth2/v2.0/token":
+			require.NoError(r.ParseForm())
+			if r.Form.Get("client_id") != "client1" || r.Form.Get("client_secret") != "s3cret" || !slices.Contains(strings.Fields(r.Form.Get("scope")), "https://vault.azure.net/.default") {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			tokens.Add(1)
+			json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "tok", "expires_in": 3600, "token_type": "Bearer"})
+		case r.Header.Get("Authorization") != "Bearer tok":
+			// Key Vault challenges for the tenant and resource
+			w.Header().Set("WWW-Authenticate", `Bearer authorization="`+server.URL+`/tenant1", resource="https://vault.azure.net"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Query().Get("api-version") == "":
+			w.WriteHeader(http.StatusBadRequest)
+		case path == "/secrets/api-key" && r.Method == http.MethodPut:
+			var body struct {
+				Value string `json:"value"`
+			}
+			require.NoError(json.NewDecoder(r.Body).Decode(&body))
+			latest = body.Value
+			json.NewEncoder(w).Encode(map[string]interface{}{"value": latest, "id": server.URL + "/secrets/api-key/v2"})
+		case path == "/secrets/api-key":
+			json.NewEncoder(w).Encode(map[string]interface{}{"value": latest})
+		case path == "/secrets/api-key/v1":
+			json.NewEncoder(w).Encode(map[string]interface{}{"value": "old-key"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]string{"code": "SecretNotFound", "message": "echoed " + path}})
+		}
+	}))
+	defer server.Close()
+	t.Setenv(secret.ENV_AZURE_AUTHORITY_HOST, server.URL)
+	t.Setenv(secret.ENV_AZURE_TENANT_ID, "tenant1")
+	t.Setenv(secret.ENV_AZURE_CLIENT_ID, "client1")
+	t.Setenv(secret.ENV_AZURE_CLIENT_SECRET, "s3cret")
+	secret.AzureCredentialOptions.Transport = server.Client()
+	// the test authority isn't known to Microsoft Entra, and the test vault isn't in an Azure domain
+	secret.AzureCredentialOptions.DisableInstanceDiscovery = true
+	secret.AzureKeyVaultOptions.Transport = server.Client()
+	secret.AzureKeyVaultOptions.DisableChallengeResourceVerification = true
+
+	value, err := GetSecret("azure:" + server.URL + ",api-key")
+	require.NoError(err)
+	require.Equal("latest-key", value)
+	value, err = GetSecret("azure:" + server.URL + ",api-key,v1")
+	require.NoError(err)
+	require.Equal("old-key", value)
+	// the token is reused
+	require.EqualValues(1, tokens.Load())
+
+	require.NoError(secret.Secret("azure:"+server.URL+",api-key").Store(context.Background(), "rotated-key"))
+	value, err = GetSecret("azure:" + server.URL + ",api-key")
+	require.NoError(err)
+	require.Equal("rotated-key", value)
+
+	_, err = GetSecret("azure:" + server.URL + ",missing")
+	require.ErrorContains(err, "404 SecretNotFound")
+	// the body of the response isn't included
+	require.NotContains(err.Error(), "echoed")
+	_, err = GetSecret("azure:" + server.URL)
+	require.ErrorContains(err, "2-3 comma separated arguments")
+}
+
+func TestGetSecretOnePassword(t *testing.T) {
+	require := require.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Header.Get("Authorization") != "Bearer op-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		filter := r.URL.Query().Get("filter")
+		switch r.URL.Path {
+		case "/v1/vaults":
+			if filter == `name eq "Treasury"` {
+				json.NewEncoder(w).Encode([]map[string]string{{"id": "vault1", "name": "Treasury"}})
+			} else {
+				json.NewEncoder(w).Encode([]map[string]string{})
+			}
+		case "/v1/vaults/vault1/items":
+			if filter == `title eq "Binance"` {
+				json.NewEncoder(w).Encode([]map[string]string{{"id": "item1", "title": "Binance"}})
+			} else {
+				json.NewEncoder(w).Encode([]map[string]string{})
+			}
+		case "/v1/vaults/vault1/items/item1":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"id": "item1",
+				"fields": []map[string]string{
+					{"id": "username", "label": "username", "value": "treasury"},
+					{"id": "f2", "label": "api_key", "value": "binance-key"},
+				},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	t.Setenv(secret.ENV_OP_CONNECT_HOST, server.URL)
+	t.Setenv(secret.ENV_OP_CONNECT_TOKEN, "op-token")
+
+	value, err := GetSecret("op:Treasury,Binance,api_key")
+	require.NoError(err)
+	require.Equal("binance-key", value)
+
+	// by id
+	value, err = GetSecret("op:vault1,item1,f2")
+	require.NoError(err)
+	require.Equal("binance-key", value)
+
+	_, err = GetSecret("op:Treasury,Binance,missing")
+	require.ErrorContains(err, "could not find field")
+	_, err = GetSecret("op:Treasury,Missing,api_key")
+	require.ErrorContains(err, "404")
+
+	t.Setenv(secret.ENV_OP_CONNECT_TOKEN, "wrong")
+	_, err = GetSecret("op:Treasury,Binance,api_key")
+	require.Error(err)
+	require.False(strings.Contains(err.Error(), "wrong"))
+}
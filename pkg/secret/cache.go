@@ -0,0 +1,159 @@
+package secret
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type CacheOptions struct {
+	// How long a loaded secret is used for before it's loaded again
+	Ttl time.Duration
+	// Secrets used within this long of expiring are reloaded in the background, so requests
+	// don't wait on the secret manager.
+	RefreshAhead time.Duration
+	// Deadline for loading a secret in the background
+	RefreshTimeout time.Duration
+}
+
+func DefaultCacheOptions() CacheOptions {
+	return CacheOptions{
+		Ttl:            5 * time.Minute,
+		RefreshAhead:   time.Minute,
+		RefreshTimeout: 30 * time.Second,
+	}
+}
+
+type cacheEntry struct {
+	value      string
+	expires    time.Time
+	refreshing bool
+}
+
+// Cache keeps loaded secrets in memory, so that using a secret doesn't call the secret manager
+// each time.  Secrets are reloaded once they expire, and callbacks are notified when a reloaded
+// value differs from the previous one (i.e. the secret was rotated).
+type Cache struct {
+	opts CacheOptions
+
+	lock     sync.Mutex
+	entries  map[Secret]*cacheEntry
+	onChange []func(Secret)
+}
+
+func NewCache(opts CacheOptions) *Cache {
+	return &Cache{
+		opts:    opts,
+		entries: map[Secret]*cacheEntry{},
+	}
+}
+
+// The cache used by Secret.Load, if enabled
+var defaultCache atomic.Pointer[Cache]
+
+// EnableCache makes Secret.Load use the cache.  Long running processes like the server should
+// enable it; short lived commands don't need to.
+func EnableCache(cache *Cache) {
+	defaultCache.Store(cache)
+}
+
+func DefaultCache() (*Cache, bool) {
+	cache := defaultCache.Load()
+	return cache, cache != nil
+}
+
+// OnChange registers a callback for when a secret is invalidated or reloaded with a different value.
+func (c *Cache) OnChange(callback func(Secret)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.onChange = append(c.onChange, callback)
+}
+
+func (c *Cache) notify(s Secret) {
+	c.lock.Lock()
+	callbacks := c.onChange
+	c.lock.Unlock()
+	for _, callback := range callbacks {
+		callback(s)
+	}
+}
+
+func (c *Cache) Load(ctx context.Context, s Secret) (string, error) {
+	c.lock.Lock()
+	entry, ok := c.entries[s]
+	if ok && time.Now().Before(entry.expires) {
+		if !entry.refreshing && time.Until(entry.expires) < c.opts.RefreshAhead {
+			entry.refreshing = true
+			go c.refresh(ctx, s)
+		}
+		c.lock.Unlock()
+		return entry.value, nil
+	}
+	c.lock.Unlock()
+
+	value, err := s.load(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.store(s, value)
+	return value, nil
+}
+
+func (c *Cache) refresh(ctx context.Context, s Secret) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.opts.RefreshTimeout)
+	defer cancel()
+	value, err := s.load(ctx)
+	if err != nil {
+		// keep using the cached value until it expires
+		slog.Warn("failed to refresh secret", "backend", s.backendLabel(), "error", err)
+		c.lock.Lock()
+		if entry, ok := c.entries[s]; ok {
+			entry.refreshing = false
+		}
+		c.lock.Unlock()
+		return
+	}
+	c.store(s, value)
+}
+
+func (c *Cache) store(s Secret, value string) {
+	c.lock.Lock()
+	previous, existed := c.entries[s]
+	c.entries[s] = &cacheEntry{
+		value:   value,
+		expires: time.Now().Add(c.opts.Ttl),
+	}
+	c.lock.Unlock()
+	if existed && previous.value != value {
+		slog.Info("secret was rotated", "backend", s.backendLabel())
+		c.notify(s)
+	}
+}
+
+// Invalidate drops the secret, so that it is loaded again on next use.  This should be called when
+// a secret is known to have been rotated, e.g. when an exchange rejects an API key.
+func (c *Cache) Invalidate(s Secret) {
+	c.lock.Lock()
+	_, existed := c.entries[s]
+	delete(c.entries, s)
+	c.lock.Unlock()
+	if existed {
+		c.notify(s)
+	}
+}
+
+// InvalidateAll drops every secret.
+func (c *Cache) InvalidateAll() {
+	c.lock.Lock()
+	secrets := make([]Secret, 0, len(c.entries))
+	for s := range c.entries {
+		secrets = append(secrets, s)
+	}
+	c.entries = map[Secret]*cacheEntry{}
+	c.lock.Unlock()
+	for _, s := range secrets {
+		c.notify(s)
+	}
+}
@@ -0,0 +1,105 @@
+package secret_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cordialsys/offchain/pkg/secret"
+	"github.com/stretchr/testify/require"
+)
+
+type changes struct {
+	lock    sync.Mutex
+	secrets []secret.Secret
+}
+
+func (c *changes) add(s secret.Secret) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.secrets = append(c.secrets, s)
+}
+
+func (c *changes) get() []secret.Secret {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]secret.Secret{}, c.secrets...)
+}
+
+func TestCacheInvalidate(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(path, []byte("first"), 0600))
+	s := secret.Secret("file:" + path)
+
+	cache := secret.NewCache(secret.DefaultCacheOptions())
+	changed := &changes{}
+	cache.OnChange(changed.add)
+
+	value, err := cache.Load(ctx, s)
+	require.NoError(t, err)
+	require.Equal(t, "first", value)
+
+	// the cached value is used until it's invalidated
+	require.NoError(t, os.WriteFile(path, []byte("second"), 0600))
+	value, err = cache.Load(ctx, s)
+	require.NoError(t, err)
+	require.Equal(t, "first", value)
+	require.Empty(t, changed.get())
+
+	cache.Invalidate(s)
+	require.Equal(t, []secret.Secret{s}, changed.get())
+	value, err = cache.Load(ctx, s)
+	require.NoError(t, err)
+	require.Equal(t, "second", value)
+}
+
+func TestCacheRefresh(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(path, []byte("first"), 0600))
+	s := secret.Secret("file:" + path)
+
+	opts := secret.DefaultCacheOptions()
+	opts.Ttl = time.Hour
+	// always refresh in the background
+	opts.RefreshAhead = 2 * time.Hour
+	cache := secret.NewCache(opts)
+	changed := &changes{}
+	cache.OnChange(changed.add)
+
+	value, err := cache.Load(ctx, s)
+	require.NoError(t, err)
+	require.Equal(t, "first", value)
+
+	// the rotated secret is picked up in the background, while the cached value is returned
+	require.NoError(t, os.WriteFile(path, []byte("rotated"), 0600))
+	value, err = cache.Load(ctx, s)
+	require.NoError(t, err)
+	require.Equal(t, "first", value)
+	require.Eventually(t, func() bool {
+		return len(changed.get()) == 1
+	}, time.Second, time.Millisecond)
+
+	value, err = cache.Load(ctx, s)
+	require.NoError(t, err)
+	require.Equal(t, "rotated", value)
+}
+
+func TestCacheErrorNotCached(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "secret")
+	s := secret.Secret("file:" + path)
+	cache := secret.NewCache(secret.DefaultCacheOptions())
+
+	_, err := cache.Load(ctx, s)
+	require.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte("created"), 0600))
+	value, err := cache.Load(ctx, s)
+	require.NoError(t, err)
+	require.Equal(t, "created", value)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -15,7 +16,7 @@ func main() {
 		fmt.Println("example: ./main gsm:myproject,mysecret")
 		return
 	}
-	sec, err := secret.GetSecret(os.Args[1])
+	sec, err := secret.GetSecret(context.Background(), os.Args[1])
 	if err != nil {
 		log.Fatalln(err)
 	}
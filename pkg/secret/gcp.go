@@ -0,0 +1,97 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"google.golang.org/api/iterator"
+)
+
+type gcpBackend struct{}
+
+func (gcpBackend) Type() SecretType { return GcpSecretManager }
+func (gcpBackend) Name() string     { return "GCP Secret Manager" }
+func (gcpBackend) Usage() string    { return "<project>,<name>[,version]" }
+func (gcpBackend) Load(ctx context.Context, args []string) (string, error) {
+	if !slices.Contains([]int{2, 3}, len(args)) {
+		return "", fmt.Errorf("%s secret has 2-3 comma separated arguments: %s", GcpSecretManager, GcpSecretManager.Usage())
+	}
+	project := args[0]
+	if len(strings.Split(project, "/")) == 1 {
+		// should have /projects/ prefix
+		project = filepath.Join("projects", project)
+	}
+	name := args[1]
+	version := "latest"
+	if len(args) > 2 {
+		version = args[2]
+		version = strings.TrimPrefix(version, "versions/")
+	}
+
+	client, err := secretmanager.NewClient(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	it := client.ListSecrets(ctx, &secretmanagerpb.ListSecretsRequest{
+		Parent: project,
+		Filter: name,
+	})
+	for {
+		resp, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		_, secretName := filepath.Split(resp.Name)
+		if secretName == name {
+			// access the specific version
+			latest := filepath.Join(resp.Name, "versions/"+version)
+			latestSecret, err := client.AccessSecretVersion(ctx, &secretmanagerpb.AccessSecretVersionRequest{
+				Name: latest,
+			})
+			if err != nil {
+				return "", err
+			}
+			return string(latestSecret.Payload.Data), nil
+		}
+	}
+	return "", fmt.Errorf("could not find a gsm secret by name %s", name)
+}
+
+func (gcpBackend) CheckStore(args []string) error {
+	if !slices.Contains([]int{2, 3}, len(args)) {
+		return fmt.Errorf("%s secret has 2-3 comma separated arguments: %s", GcpSecretManager, GcpSecretManager.Usage())
+	}
+	if len(args) > 2 && args[2] != "latest" {
+		return fmt.Errorf("%s secret pins version %s, so a new version would not be used", GcpSecretManager, args[2])
+	}
+	return nil
+}
+
+// Store adds a version to the secret, which must already exist.
+func (gcpBackend) Store(ctx context.Context, args []string, value string) error {
+	project := args[0]
+	if len(strings.Split(project, "/")) == 1 {
+		project = filepath.Join("projects", project)
+	}
+	client, err := secretmanager.NewClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	_, err = client.AddSecretVersion(ctx, &secretmanagerpb.AddSecretVersionRequest{
+		Parent:  filepath.Join(project, "secrets", args[1]),
+		Payload: &secretmanagerpb.SecretPayload{Data: []byte(value)},
+	})
+	return err
+}
@@ -0,0 +1,98 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Env vars used by 1Password Connect clients
+const (
+	ENV_OP_CONNECT_HOST  = "OP_CONNECT_HOST"
+	ENV_OP_CONNECT_TOKEN = "OP_CONNECT_TOKEN"
+)
+
+// onePasswordBackend reads a field of an item from a 1Password Connect server.  Vaults and items may be
+// referenced by name or id, and fields by label or id.
+type onePasswordBackend struct{}
+
+func (onePasswordBackend) Type() SecretType { return OnePassword }
+func (onePasswordBackend) Name() string     { return "1Password Connect" }
+func (onePasswordBackend) Usage() string    { return "<vault>,<item>,<field>" }
+func (onePasswordBackend) Load(ctx context.Context, args []string) (string, error) {
+	if len(args) != 3 {
+		return "", fmt.Errorf("%s secret has 3 comma separated arguments: %s", OnePassword, OnePassword.Usage())
+	}
+	host := os.Getenv(ENV_OP_CONNECT_HOST)
+	token := os.Getenv(ENV_OP_CONNECT_TOKEN)
+	if host == "" || token == "" {
+		return "", fmt.Errorf("%s and %s must be set to use 1password", ENV_OP_CONNECT_HOST, ENV_OP_CONNECT_TOKEN)
+	}
+	connect := &onePasswordConnect{host: strings.TrimSuffix(host, "/"), token: token}
+
+	vaultId, err := connect.resolve(ctx, "/v1/vaults", "name", args[0])
+	if err != nil {
+		return "", err
+	}
+	itemsPath := "/v1/vaults/" + url.PathEscape(vaultId) + "/items"
+	itemId, err := connect.resolve(ctx, itemsPath, "title", args[1])
+	if err != nil {
+		return "", err
+	}
+	var item struct {
+		Fields []struct {
+			Id    string `json:"id"`
+			Label string `json:"label"`
+			Value string `json:"value"`
+		} `json:"fields"`
+	}
+	if err := connect.get(ctx, itemsPath+"/"+url.PathEscape(itemId), nil, &item); err != nil {
+		return "", err
+	}
+	for _, field := range item.Fields {
+		if field.Label == args[2] || field.Id == args[2] {
+			return strings.TrimSpace(field.Value), nil
+		}
+	}
+	return "", fmt.Errorf("could not find field %s in 1password item %s", args[2], args[1])
+}
+
+type onePasswordConnect struct {
+	host  string
+	token string
+}
+
+func (c *onePasswordConnect) get(ctx context.Context, path string, query url.Values, output interface{}) error {
+	reqUrl := c.host + path
+	if len(query) > 0 {
+		reqUrl += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	return requestJson(req, output)
+}
+
+// resolve returns the id of the vault or item with the name, or the name itself if there is none,
+// since it may already be an id.
+func (c *onePasswordConnect) resolve(ctx context.Context, path string, attribute string, name string) (string, error) {
+	var matches []struct {
+		Id string `json:"id"`
+	}
+	query := url.Values{"filter": {fmt.Sprintf("%s eq %q", attribute, name)}}
+	if err := c.get(ctx, path, query, &matches); err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return name, nil
+	}
+	if len(matches) > 1 {
+		return "", fmt.Errorf("more than one 1password %s named %s", attribute, name)
+	}
+	return matches[0].Id, nil
+}
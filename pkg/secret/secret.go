@@ -2,29 +2,45 @@ package secret
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"os"
-	"path/filepath"
-	"slices"
 	"strings"
 
-	secretmanager "cloud.google.com/go/secretmanager/apiv1"
-	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	awssecretmanager "github.com/aws/aws-sdk-go-v2/service/secretsmanager"
-	"github.com/cordialsys/offchain/pkg/keyring"
-	vault "github.com/hashicorp/vault/api"
-	"google.golang.org/api/iterator"
+	"github.com/cordialsys/offchain/pkg/metrics"
+	"github.com/cordialsys/offchain/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Secret string
 
-func (s Secret) Load() (string, error) {
-	return GetSecret(string(s))
+// Load returns the value of the secret, from the cache if it's enabled.
+func (s Secret) Load(ctx context.Context) (string, error) {
+	if cache, ok := DefaultCache(); ok && !s.IsType(Raw) {
+		return cache.Load(ctx, s)
+	}
+	return s.load(ctx)
+}
+
+func (s Secret) load(ctx context.Context) (string, error) {
+	value, err := GetSecret(ctx, string(s))
+	outcome := metrics.OutcomeSuccess
+	if err != nil {
+		outcome = metrics.OutcomeServerError
+	}
+	metrics.SecretLoads.WithLabelValues(s.backendLabel(), outcome).Inc()
+	return value, err
+}
+
+// backendLabel names the secret backend in metrics, without including any part of the reference.
+func (s Secret) backendLabel() string {
+	prefix, _, _ := strings.Cut(string(s), ":")
+	t := SecretType(strings.ToLower(prefix))
+	if t == Raw || t.Name() != "" {
+		return string(t)
+	}
+	return "unknown"
 }
 
 func (s Secret) IsType(t SecretType) bool {
@@ -47,48 +63,27 @@ const (
 	Keyring          SecretType = "keyring"
 	GcpSecretManager SecretType = "gcp"
 	AwsSecretManager SecretType = "aws"
+	AzureKeyVault    SecretType = "azure"
+	OnePassword      SecretType = "op"
+	Age              SecretType = "age"
+	Sops             SecretType = "sops"
 	Raw              SecretType = "raw"
 )
 
+// Name of the backend, or empty if no backend is registered for the type.
 func (t SecretType) Name() string {
-	switch t {
-	case Env:
-		return "Environment variable"
-	case Vault:
-		return "Hashicorp Vault"
-	case File:
-		return "File"
-	case GcpSecretManager, "gsm":
-		return "GCP Secret Manager"
-	case AwsSecretManager:
-		return "AWS Secret Manager"
-	case Keyring:
-		return "Treasury Keyring"
+	if backend, ok := GetBackend(t); ok {
+		return backend.Name()
 	}
 	return ""
 }
 func (t SecretType) Usage() string {
-	switch t {
-	case Env:
-		return "<name>"
-	case Vault:
-		return "<server-url>,<path>"
-	case File:
-		return "<path>"
-	case GcpSecretManager, "gsm":
-		return "<project>,<name>[,version]"
-	case AwsSecretManager:
-		return "<name[:key]>[,region][,version]"
-	case Keyring:
-		return "<id-or-path>"
+	if backend, ok := GetBackend(t); ok {
+		return backend.Usage()
 	}
 	return ""
 }
 
-var Types = []SecretType{
-	Env, Vault, File, GcpSecretManager, AwsSecretManager, Keyring,
-}
-
 // Raw secrets should only be used for internal configuration, not external.
 func NewRawSecret(value string) Secret {
 	return Secret(fmt.Sprintf("%s:%s", Raw, value))
@@ -102,190 +97,29 @@ func replaceTilda(path string) string {
 	return path
 }
 
-// GetSecret returns a secret, e.g. from env variable. Extend as needed.
-func GetSecret(uri string) (secret string, err error) {
+// GetSecret returns a secret, e.g. from env variable.  New secret managers are added by registering a Backend.
+func GetSecret(ctx context.Context, uri string) (secret string, err error) {
+	// only the backend is recorded, never the reference or the value
+	ctx, span := tracing.Start(ctx, "secret.GetSecret", trace.WithAttributes(
+		attribute.String("secret.backend", Secret(uri).backendLabel()),
+	))
+	defer func() { tracing.End(span, err) }()
+
 	value := uri
 
 	splits := strings.Split(value, ":")
 	if len(splits) < 2 {
 		return "", fmt.Errorf(
 			"could not load secret, missing prefix; secret should be in '<prefix>:<path>' format, where prefix is one of %v",
-			Types,
+			Types(),
 		)
 	}
 
 	secretType := strings.ToLower(splits[0])
 	args := strings.Split(strings.Join(splits[1:], ":"), ",")
-	switch SecretType(secretType) {
-	case Raw:
-		sec := args[0]
-		return strings.TrimSpace(sec), nil
-	case Env:
-		path := args[0]
-		return strings.TrimSpace(os.Getenv(path)), nil
-	case File:
-		path := replaceTilda(args[0])
-		_, err := os.Stat(path)
-		if err != nil {
-			return "", err
-		}
-		file, err := os.Open(path)
-		defer file.Close()
-		if err != nil {
-			return "", err
-		}
-		result, err := io.ReadAll(file)
-		if err != nil {
-			return "", err
-		}
-		return strings.TrimSpace(string(result)), nil
-	case Vault:
-		if len(args) != 2 {
-			return "", errors.New("vault secret has 2 comma separated arguments (url,path)")
-		}
-		vaultUrl := args[0]
-		vaultFullPath := args[1]
-
-		cfg := &vault.Config{Address: vaultUrl}
-		// just check the error
-		_, err := vault.NewClient(cfg)
-		if err != nil {
-			return "", err
-		}
-		client, err := NewVaultClient(cfg)
-		if err != nil {
-			return "", err
-		}
-
-		idx := strings.LastIndex(vaultFullPath, "/")
-		if idx == -1 || idx == len(vaultFullPath) { // idx shouldn't be the last char
-			return "", errors.New("malformed vault secret in config file")
-		}
-		vaultKey := vaultFullPath[idx+1:]
-		vaultPath := vaultFullPath[:idx]
-
-		secret, err := client.LoadSecretData(vaultPath)
-		if err != nil {
-			return "", err
-		}
-		data, _ := secret.Data["data"].(map[string]interface{})
-		result, _ := data[vaultKey].(string)
-		return strings.TrimSpace(result), nil
-	case GcpSecretManager, "gsm":
-		// google secret manager
-		if !slices.Contains([]int{2, 3}, len(args)) {
-			return "", fmt.Errorf("%s secret has 2-3 comma separated arguments: %s", GcpSecretManager, GcpSecretManager.Usage())
-		}
-		project := args[0]
-		if len(strings.Split(project, "/")) == 1 {
-			// should have /projects/ prefix
-			project = filepath.Join("projects", project)
-		}
-		name := args[1]
-		version := "latest"
-		if len(args) > 2 {
-			version = args[2]
-			version = strings.TrimPrefix(version, "versions/")
-		}
-
-		client, err := secretmanager.NewClient(context.Background())
-		if err != nil {
-			return "", err
-		}
-
-		it := client.ListSecrets(context.Background(), &secretmanagerpb.ListSecretsRequest{
-			Parent: project,
-			Filter: name,
-		})
-		for {
-			resp, err := it.Next()
-			if err == iterator.Done {
-				break
-			}
-
-			if err != nil {
-				return "", err
-			}
-
-			_, secretName := filepath.Split(resp.Name)
-			if secretName == name {
-				// access the specific version
-				latest := filepath.Join(resp.Name, "versions/"+version)
-				latestSecret, err := client.AccessSecretVersion(context.Background(), &secretmanagerpb.AccessSecretVersionRequest{
-					Name: latest,
-				})
-				if err != nil {
-					return "", err
-				}
-				return string(latestSecret.Payload.Data), nil
-			}
-		}
-		return "", fmt.Errorf("could not find a gsm secret by name %s", name)
-	case Keyring:
-		dir := keyring.KeyringDirOrTreasuryHome(args[0])
-
-		kr := keyring.New(dir)
-		key, err := kr.Load(filepath.Base(args[0]))
-		if err != nil {
-			return "", err
-		}
-		return string(key.Secret), nil
-	case AwsSecretManager:
-		if !slices.Contains([]int{1, 2, 3}, len(args)) {
-			return "", fmt.Errorf("%s secret has 1-3 comma separated arguments: %s", AwsSecretManager, AwsSecretManager.Usage())
-		}
-		secretName := args[0]
-		keyName := ""
-		nameParts := strings.Split(secretName, ":")
-		if len(nameParts) > 1 {
-			secretName = nameParts[0]
-			keyName = strings.Join(nameParts[1:], ":")
-		}
-		region := ""
-		version := "AWSCURRENT"
-		if len(args) > 1 {
-			region = args[1]
-		}
-		if len(args) > 2 {
-			version = args[2]
-		}
-		awsAwgs := []func(*config.LoadOptions) error{}
-		if region != "" {
-			awsAwgs = append(awsAwgs, config.WithRegion(region))
-		}
-
-		config, err := config.LoadDefaultConfig(context.Background(), awsAwgs...)
-		if err != nil {
-			return "", err
-		}
-		svc := awssecretmanager.NewFromConfig(config)
-		input := &awssecretmanager.GetSecretValueInput{
-			SecretId:     aws.String(secretName),
-			VersionStage: aws.String(version),
-		}
-		result, err := svc.GetSecretValue(context.Background(), input)
-		if err != nil {
-			// https://docs.aws.amazon.com/secretsmanager/latest/apireference/API_GetSecretValue.html
-			return "", err
-		}
-		secretBz := *result.SecretString
-		if keyName != "" {
-			// Another layer of nesting to unwrap, this time JSON
-			secretData := map[string]interface{}{}
-			err = json.Unmarshal([]byte(secretBz), &secretData)
-			if err != nil {
-				// do not omit internal error to guard from leaking anything sensitive
-				return "", fmt.Errorf("could not retrieve %s key because %s has invalid JSON", keyName, secretName)
-			}
-			secretValue, ok := secretData[keyName]
-			if !ok {
-				return "", fmt.Errorf("could not find %s key in %s JSON", keyName, secretName)
-			}
-			return fmt.Sprint(secretValue), nil
-		}
-		// return secretBz literally as the secret, no JSON nesting
-		return secretBz, nil
-
+	backend, ok := GetBackend(SecretType(secretType))
+	if !ok {
+		return "", errors.New("invalid secret source for: ***")
 	}
-	return "", errors.New("invalid secret source for: ***")
+	return backend.Load(ctx, args)
 }
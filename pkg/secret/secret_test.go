@@ -1,9 +1,12 @@
 package secret_test
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/cordialsys/offchain/pkg/secret"
@@ -11,7 +14,9 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-var GetSecret = secret.GetSecret
+func GetSecret(uri string) (string, error) {
+	return secret.GetSecret(context.Background(), uri)
+}
 
 func TestGetSecretEnv(t *testing.T) {
 	os.Setenv("XCTEST", "mysecret")
@@ -58,12 +63,46 @@ func TestGetSecretErrInvalidType(t *testing.T) {
 }
 
 type MockedVaultLoaded struct {
-	data map[string]interface{}
+	data   map[string]interface{}
+	mounts map[string]*secret.KvMount
+	logins []secret.VaultAuth
+	// path@version of the versioned reads
+	versionReads []string
 }
 
 var _ secret.VaultLoader = &MockedVaultLoaded{}
 
-func (l *MockedVaultLoaded) LoadSecretData(path string) (*vault.Secret, error) {
+func (l *MockedVaultLoaded) LoadSecretVersion(ctx context.Context, path string, version int) (*vault.Secret, error) {
+	l.versionReads = append(l.versionReads, fmt.Sprintf("%s@%d", path, version))
+	return l.LoadSecretData(ctx, path)
+}
+
+func (l *MockedVaultLoaded) KvMount(ctx context.Context, path string) (*secret.KvMount, error) {
+	for prefix, mount := range l.mounts {
+		if strings.HasPrefix(path, prefix) {
+			return mount, nil
+		}
+	}
+	return nil, nil
+}
+
+func (l *MockedVaultLoaded) Login(ctx context.Context, auth secret.VaultAuth) error {
+	l.logins = append(l.logins, auth)
+	return nil
+}
+
+func (l *MockedVaultLoaded) Logout(auth secret.VaultAuth) {
+}
+
+func (l *MockedVaultLoaded) WriteSecretData(ctx context.Context, path string, data map[string]interface{}) error {
+	if l.data == nil {
+		l.data = map[string]interface{}{}
+	}
+	l.data[path] = data
+	return nil
+}
+
+func (l *MockedVaultLoaded) LoadSecretData(ctx context.Context, path string) (*vault.Secret, error) {
 	data, ok := l.data[path]
 	if !ok {
 		return &vault.Secret{}, errors.New("path not found")
@@ -74,6 +113,9 @@ func (l *MockedVaultLoaded) LoadSecretData(path string) (*vault.Secret, error) {
 }
 
 func TestGetSecretVault(t *testing.T) {
+	defer func(newVaultClient func(*vault.Config) (secret.VaultLoader, error)) {
+		secret.NewVaultClient = newVaultClient
+	}(secret.NewVaultClient)
 	secret.NewVaultClient = func(cfg *vault.Config) (secret.VaultLoader, error) {
 		vaultRes := `{
 			"path1/to": {
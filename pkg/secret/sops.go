@@ -0,0 +1,479 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"gopkg.in/yaml.v3"
+)
+
+// Env vars used by sops to locate age identities
+const (
+	ENV_SOPS_AGE_KEY      = "SOPS_AGE_KEY"
+	ENV_SOPS_AGE_KEY_FILE = "SOPS_AGE_KEY_FILE"
+)
+
+const sopsUnencryptedSuffix = "_unencrypted"
+
+// ageBackend reads a value from a YAML or JSON file that is encrypted as a whole with age.
+type ageBackend struct{}
+
+func (ageBackend) Type() SecretType { return Age }
+func (ageBackend) Name() string     { return "age encrypted file" }
+func (ageBackend) Usage() string    { return "<path>[,<key.path>[,<identity-secret>]]" }
+func (ageBackend) Load(ctx context.Context, args []string) (string, error) {
+	path, keyPath, identities, err := parseEncryptedFileArgs(ctx, Age, args)
+	if err != nil {
+		return "", err
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := ageDecrypt(contents, identities)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt %s: %w", path, err)
+	}
+	var document interface{}
+	if err := yaml.Unmarshal(plaintext, &document); err != nil {
+		return "", fmt.Errorf("%s is not a YAML or JSON document: %w", path, err)
+	}
+	value, _, err := selectKeyPath(document, keyPath)
+	if err != nil {
+		return "", err
+	}
+	return formatSecretValue(value)
+}
+
+// sopsBackend reads a value from a YAML or JSON file encrypted by sops, using an age identity to
+// decrypt the data key.  The whole file is decrypted and checked against its MAC, so values can't be
+// added, removed or replaced by anyone without the data key.
+type sopsBackend struct{}
+
+func (sopsBackend) Type() SecretType { return Sops }
+func (sopsBackend) Name() string     { return "sops encrypted file" }
+func (sopsBackend) Usage() string    { return "<path>[,<key.path>[,<identity-secret>]]" }
+func (sopsBackend) Load(ctx context.Context, args []string) (string, error) {
+	path, keyPath, identities, err := parseEncryptedFileArgs(ctx, Sops, args)
+	if err != nil {
+		return "", err
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	document, err := sopsDecryptFile(contents, identities)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt %s: %w", path, err)
+	}
+	value, _, err := selectKeyPath(document, keyPath)
+	if err != nil {
+		return "", err
+	}
+	return formatSecretValue(value)
+}
+
+// parseEncryptedFileArgs splits `<path>[,<key.path>[,<identity-secret>]]`.  The identity is a secret
+// reference which may itself contain commas, so it takes the remaining args.
+func parseEncryptedFileArgs(ctx context.Context, t SecretType, args []string) (path string, keyPath string, identities []age.Identity, err error) {
+	if args[0] == "" {
+		return "", "", nil, fmt.Errorf("%s secret requires a path: %s", t, t.Usage())
+	}
+	path = replaceTilda(args[0])
+	if len(args) > 1 {
+		keyPath = args[1]
+	}
+	identityRef := ""
+	if len(args) > 2 {
+		identityRef = strings.Join(args[2:], ",")
+	}
+	identities, err = loadAgeIdentities(ctx, identityRef)
+	return path, keyPath, identities, err
+}
+
+// loadAgeIdentities parses the identities from the secret, or from the same places sops looks if there is none.
+func loadAgeIdentities(ctx context.Context, identityRef string) ([]age.Identity, error) {
+	var value string
+	var err error
+	switch {
+	case identityRef != "":
+		value, err = Secret(identityRef).Load(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load age identity: %w", err)
+		}
+	case os.Getenv(ENV_SOPS_AGE_KEY) != "":
+		value = os.Getenv(ENV_SOPS_AGE_KEY)
+	default:
+		keyFile := os.Getenv(ENV_SOPS_AGE_KEY_FILE)
+		if keyFile == "" {
+			configDir, err := os.UserConfigDir()
+			if err != nil {
+				return nil, fmt.Errorf("no age identity configured: %w", err)
+			}
+			keyFile = filepath.Join(configDir, "sops", "age", "keys.txt")
+		}
+		contents, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("no age identity configured: %w", err)
+		}
+		value = string(contents)
+	}
+	identities, err := age.ParseIdentities(strings.NewReader(value))
+	if err != nil {
+		// do not wrap, the error may include part of the identity
+		return nil, errors.New("invalid age identity")
+	}
+	return identities, nil
+}
+
+func ageDecrypt(ciphertext []byte, identities []age.Identity) ([]byte, error) {
+	var reader io.Reader = bytes.NewReader(ciphertext)
+	if bytes.HasPrefix(bytes.TrimSpace(ciphertext), []byte(armor.Header)) {
+		reader = armor.NewReader(bytes.NewReader(bytes.TrimSpace(ciphertext)))
+	}
+	decrypted, err := age.Decrypt(reader, identities...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(decrypted)
+}
+
+// sopsDataKey decrypts the data key from any of the age recipients in the sops metadata.
+func sopsDataKey(metadata sopsMetadata, identities []age.Identity) ([]byte, error) {
+	var lastErr error
+	for _, recipient := range metadata.Age {
+		if recipient.Enc == "" {
+			continue
+		}
+		dataKey, err := ageDecrypt([]byte(recipient.Enc), identities)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return dataKey, nil
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("the file has no age recipients")
+	}
+	return nil, lastErr
+}
+
+type sopsMetadata struct {
+	Age []struct {
+		Enc string `yaml:"enc"`
+	} `yaml:"age"`
+	LastModified      string `yaml:"lastmodified"`
+	Mac               string `yaml:"mac"`
+	MacOnlyEncrypted  bool   `yaml:"mac_only_encrypted"`
+	UnencryptedSuffix string `yaml:"unencrypted_suffix"`
+	EncryptedSuffix   string `yaml:"encrypted_suffix"`
+	UnencryptedRegex  string `yaml:"unencrypted_regex"`
+	EncryptedRegex    string `yaml:"encrypted_regex"`
+}
+
+// sopsTree decrypts a document the way sops does, hashing the values in document order for the MAC.
+type sopsTree struct {
+	metadata         sopsMetadata
+	unencryptedRegex *regexp.Regexp
+	encryptedRegex   *regexp.Regexp
+	dataKey          []byte
+	mac              hash.Hash
+}
+
+// sopsDecryptFile decrypts every value of the document and verifies the MAC over them.
+func sopsDecryptFile(contents []byte, identities []age.Identity) (map[string]interface{}, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(contents, &root); err != nil {
+		return nil, fmt.Errorf("not a YAML or JSON document: %w", err)
+	}
+	if root.Kind != yaml.DocumentNode || len(root.Content) != 1 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("not encrypted with sops")
+	}
+	mapping := root.Content[0]
+
+	tree := &sopsTree{mac: sha512.New()}
+	branches := &yaml.Node{Kind: yaml.MappingNode}
+	found := false
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == "sops" {
+			if err := mapping.Content[i+1].Decode(&tree.metadata); err != nil {
+				return nil, fmt.Errorf("invalid sops metadata: %w", err)
+			}
+			found = true
+			continue
+		}
+		branches.Content = append(branches.Content, mapping.Content[i], mapping.Content[i+1])
+	}
+	if !found {
+		return nil, fmt.Errorf("not encrypted with sops")
+	}
+	var err error
+	if tree.metadata.UnencryptedRegex != "" {
+		if tree.unencryptedRegex, err = regexp.Compile(tree.metadata.UnencryptedRegex); err != nil {
+			return nil, fmt.Errorf("invalid unencrypted_regex: %w", err)
+		}
+	}
+	if tree.metadata.EncryptedRegex != "" {
+		if tree.encryptedRegex, err = regexp.Compile(tree.metadata.EncryptedRegex); err != nil {
+			return nil, fmt.Errorf("invalid encrypted_regex: %w", err)
+		}
+	}
+	if tree.metadata.UnencryptedSuffix == "" && tree.metadata.EncryptedSuffix == "" &&
+		tree.unencryptedRegex == nil && tree.encryptedRegex == nil {
+		tree.metadata.UnencryptedSuffix = sopsUnencryptedSuffix
+	}
+	if tree.dataKey, err = sopsDataKey(tree.metadata, identities); err != nil {
+		return nil, err
+	}
+
+	document, err := tree.decrypt(branches, []string{})
+	if err != nil {
+		return nil, err
+	}
+	if err := tree.verifyMac(); err != nil {
+		return nil, err
+	}
+	return document.(map[string]interface{}), nil
+}
+
+// encrypted returns true if sops encrypts values with the path, following the rules in the metadata.
+func (tree *sopsTree) encrypted(path []string) bool {
+	encrypted := true
+	if suffix := tree.metadata.UnencryptedSuffix; suffix != "" {
+		if slices.ContainsFunc(path, func(key string) bool { return strings.HasSuffix(key, suffix) }) {
+			encrypted = false
+		}
+	}
+	if suffix := tree.metadata.EncryptedSuffix; suffix != "" {
+		encrypted = slices.ContainsFunc(path, func(key string) bool { return strings.HasSuffix(key, suffix) })
+	}
+	if tree.unencryptedRegex != nil && slices.ContainsFunc(path, tree.unencryptedRegex.MatchString) {
+		encrypted = false
+	}
+	if tree.encryptedRegex != nil {
+		encrypted = slices.ContainsFunc(path, tree.encryptedRegex.MatchString)
+	}
+	return encrypted
+}
+
+func (tree *sopsTree) decrypt(node *yaml.Node, path []string) (interface{}, error) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		decrypted := make(map[string]interface{}, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			value, err := tree.decrypt(node.Content[i+1], append(path[:len(path):len(path)], key))
+			if err != nil {
+				return nil, err
+			}
+			decrypted[key] = value
+		}
+		return decrypted, nil
+	case yaml.SequenceNode:
+		// list items are not part of the path
+		decrypted := make([]interface{}, len(node.Content))
+		for i, item := range node.Content {
+			value, err := tree.decrypt(item, path)
+			if err != nil {
+				return nil, err
+			}
+			decrypted[i] = value
+		}
+		return decrypted, nil
+	case yaml.AliasNode:
+		return tree.decrypt(node.Alias, path)
+	case yaml.ScalarNode:
+		encrypted := tree.encrypted(path)
+		var value interface{}
+		switch {
+		case encrypted && (node.Tag == "!!null" || node.Tag == "!!str" && node.Value == ""):
+			// sops leaves nulls and empty strings as they are, and they add nothing to the MAC
+		case encrypted:
+			if node.Tag != "!!str" || !strings.HasPrefix(node.Value, "ENC[") {
+				return nil, fmt.Errorf("value of %s is not encrypted", strings.Join(path, ":"))
+			}
+			var err error
+			if value, err = sopsDecryptValue(node.Value, strings.Join(path, ":")+":", tree.dataKey); err != nil {
+				return nil, err
+			}
+		default:
+			if err := node.Decode(&value); err != nil {
+				return nil, err
+			}
+		}
+		if encrypted || !tree.metadata.MacOnlyEncrypted {
+			bz, err := sopsMacBytes(value)
+			if err != nil {
+				return nil, fmt.Errorf("value of %s: %w", strings.Join(path, ":"), err)
+			}
+			tree.mac.Write(bz)
+		}
+		return value, nil
+	default:
+		return nil, fmt.Errorf("unsupported YAML node at %s", strings.Join(path, ":"))
+	}
+}
+
+// sopsMacBytes is how sops hashes a value for the MAC.
+func sopsMacBytes(value interface{}) ([]byte, error) {
+	switch value := value.(type) {
+	case nil:
+		return []byte{}, nil
+	case string:
+		return []byte(value), nil
+	case int:
+		return []byte(strconv.Itoa(value)), nil
+	case float64:
+		return []byte(strconv.FormatFloat(value, 'f', -1, 64)), nil
+	case bool:
+		// sops keeps the python spelling
+		if value {
+			return []byte("True"), nil
+		}
+		return []byte("False"), nil
+	case time.Time:
+		return value.MarshalText()
+	default:
+		return nil, fmt.Errorf("unsupported type %T", value)
+	}
+}
+
+// verifyMac checks the MAC of the decrypted values against the MAC in the metadata, which is encrypted with
+// the last modified time as additional data.
+func (tree *sopsTree) verifyMac() error {
+	if tree.metadata.Mac == "" {
+		return fmt.Errorf("the file has no MAC")
+	}
+	lastModified, err := time.Parse(time.RFC3339, tree.metadata.LastModified)
+	if err != nil {
+		return fmt.Errorf("invalid lastmodified: %w", err)
+	}
+	expected, err := sopsDecryptValue(tree.metadata.Mac, lastModified.Format(time.RFC3339), tree.dataKey)
+	if err != nil {
+		return fmt.Errorf("could not decrypt the MAC")
+	}
+	expectedMac, ok := expected.(string)
+	if !ok || subtle.ConstantTimeCompare([]byte(expectedMac), []byte(fmt.Sprintf("%X", tree.mac.Sum(nil)))) != 1 {
+		return fmt.Errorf("MAC mismatch, the file has been modified")
+	}
+	return nil
+}
+
+// sopsDecryptValue decrypts `ENC[AES256_GCM,data:<b64>,iv:<b64>,tag:<b64>,type:<type>]`.
+func sopsDecryptValue(value string, additionalData string, dataKey []byte) (interface{}, error) {
+	if !strings.HasPrefix(value, "ENC[AES256_GCM,") || !strings.HasSuffix(value, "]") {
+		return nil, fmt.Errorf("unsupported sops encrypted value")
+	}
+	fields := map[string]string{}
+	for _, field := range strings.Split(strings.TrimSuffix(strings.TrimPrefix(value, "ENC[AES256_GCM,"), "]"), ",") {
+		name, fieldValue, _ := strings.Cut(field, ":")
+		fields[name] = fieldValue
+	}
+	data, err := base64.StdEncoding.DecodeString(fields["data"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid sops encrypted value: %w", err)
+	}
+	iv, err := base64.StdEncoding.DecodeString(fields["iv"])
+	if err != nil || len(iv) == 0 {
+		return nil, fmt.Errorf("invalid sops encrypted value iv")
+	}
+	tag, err := base64.StdEncoding.DecodeString(fields["tag"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid sops encrypted value tag")
+	}
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, iv, append(data, tag...), []byte(additionalData))
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt value of %s: %w", strings.TrimSuffix(additionalData, ":"), err)
+	}
+	switch fields["type"] {
+	case "int":
+		return strconv.Atoi(string(plaintext))
+	case "float":
+		return strconv.ParseFloat(string(plaintext), 64)
+	case "bool":
+		return strconv.ParseBool(string(plaintext))
+	case "time":
+		var t time.Time
+		err := t.UnmarshalText(plaintext)
+		return t, err
+	default:
+		return string(plaintext), nil
+	}
+}
+
+// selectKeyPath returns the value at the dot separated path, e.g. `exchanges.binance.api_key` or
+// `accounts.0.secret_key`, and the keys leading to it.  An empty path selects the whole document.
+func selectKeyPath(document interface{}, keyPath string) (interface{}, []string, error) {
+	node := document
+	path := []string{}
+	if keyPath == "" {
+		return node, path, nil
+	}
+	for _, part := range strings.Split(keyPath, ".") {
+		switch current := node.(type) {
+		case map[string]interface{}:
+			value, ok := current[part]
+			if !ok {
+				return nil, nil, fmt.Errorf("could not find key %s", keyPath)
+			}
+			node = value
+			path = append(path, part)
+		case []interface{}:
+			index, err := strconv.Atoi(part)
+			if err != nil || index < 0 || index >= len(current) {
+				return nil, nil, fmt.Errorf("could not find key %s", keyPath)
+			}
+			node = current[index]
+		default:
+			return nil, nil, fmt.Errorf("could not find key %s", keyPath)
+		}
+	}
+	return node, path, nil
+}
+
+// formatSecretValue returns scalars as is, and objects as JSON so they can be used for MultiSecret.SecretsRef.
+func formatSecretValue(value interface{}) (string, error) {
+	switch value := value.(type) {
+	case string:
+		return strings.TrimSpace(value), nil
+	case map[string]interface{}, []interface{}:
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		return string(encoded), nil
+	case nil:
+		return "", nil
+	case time.Time:
+		return value.Format(time.RFC3339Nano), nil
+	default:
+		return fmt.Sprint(value), nil
+	}
+}
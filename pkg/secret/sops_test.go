@@ -0,0 +1,310 @@
+package secret_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	oc "github.com/cordialsys/offchain"
+	"github.com/cordialsys/offchain/pkg/secret"
+	"github.com/stretchr/testify/require"
+)
+
+func ageEncrypt(t *testing.T, plaintext []byte, armored bool, recipient age.Recipient) []byte {
+	buf := &bytes.Buffer{}
+	var out io.Writer = buf
+	var armorWriter io.WriteCloser
+	if armored {
+		armorWriter = armor.NewWriter(buf)
+		out = armorWriter
+	}
+	w, err := age.Encrypt(out, recipient)
+	require.NoError(t, err)
+	_, err = w.Write(plaintext)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	if armorWriter != nil {
+		require.NoError(t, armorWriter.Close())
+	}
+	return buf.Bytes()
+}
+
+// sopsEncryptValue encrypts a value the same way sops does.
+func sopsEncryptValue(t *testing.T, dataKey []byte, value string, valueType string, path ...string) string {
+	return sopsSeal(t, dataKey, value, valueType, strings.Join(path, ":")+":")
+}
+
+// sopsEncryptMac hashes the values in document order and encrypts the MAC the same way sops does.
+func sopsEncryptMac(t *testing.T, dataKey []byte, lastModified string, values ...string) string {
+	mac := sha512.Sum512([]byte(strings.Join(values, "")))
+	return sopsSeal(t, dataKey, fmt.Sprintf("%X", mac), "str", lastModified)
+}
+
+func sopsSeal(t *testing.T, dataKey []byte, value string, valueType string, additionalData string) string {
+	block, err := aes.NewCipher(dataKey)
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCMWithNonceSize(block, 32)
+	require.NoError(t, err)
+	iv := make([]byte, 32)
+	_, err = rand.Read(iv)
+	require.NoError(t, err)
+	sealed := gcm.Seal(nil, iv, []byte(value), []byte(additionalData))
+	data, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:%s]",
+		base64.StdEncoding.EncodeToString(data),
+		base64.StdEncoding.EncodeToString(iv),
+		base64.StdEncoding.EncodeToString(tag),
+		valueType,
+	)
+}
+
+func TestGetSecretAge(t *testing.T) {
+	require := require.New(t)
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(err)
+	dir := t.TempDir()
+	identityPath := filepath.Join(dir, "keys.txt")
+	require.NoError(os.WriteFile(identityPath, []byte("# created: test\n"+identity.String()+"\n"), 0600))
+
+	document := []byte("binance:\n  api_key: key1\n  secret_key: secret1\naccounts:\n  - id: sub1\n    api_key: key2\n")
+	for _, armored := range []bool{false, true} {
+		path := filepath.Join(dir, fmt.Sprintf("secrets-%v.yaml.age", armored))
+		require.NoError(os.WriteFile(path, ageEncrypt(t, document, armored, identity.Recipient()), 0600))
+
+		value, err := GetSecret(fmt.Sprintf("age:%s,binance.api_key,file:%s", path, identityPath))
+		require.NoError(err)
+		require.Equal("key1", value)
+
+		value, err = GetSecret(fmt.Sprintf("age:%s,accounts.0.api_key,file:%s", path, identityPath))
+		require.NoError(err)
+		require.Equal("key2", value)
+
+		value, err = GetSecret(fmt.Sprintf("age:%s,binance,file:%s", path, identityPath))
+		require.NoError(err)
+		require.JSONEq(`{"api_key":"key1","secret_key":"secret1"}`, value)
+
+		_, err = GetSecret(fmt.Sprintf("age:%s,binance.missing,file:%s", path, identityPath))
+		require.ErrorContains(err, "could not find key binance.missing")
+	}
+
+	// default identity from env
+	path := filepath.Join(dir, "secrets-false.yaml.age")
+	t.Setenv(secret.ENV_SOPS_AGE_KEY, identity.String())
+	value, err := GetSecret(fmt.Sprintf("age:%s,binance.secret_key", path))
+	require.NoError(err)
+	require.Equal("secret1", value)
+
+	other, err := age.GenerateX25519Identity()
+	require.NoError(err)
+	t.Setenv(secret.ENV_SOPS_AGE_KEY, other.String())
+	_, err = GetSecret(fmt.Sprintf("age:%s,binance.secret_key", path))
+	require.ErrorContains(err, "failed to decrypt")
+
+	t.Setenv(secret.ENV_SOPS_AGE_KEY, "not-an-identity")
+	_, err = GetSecret(fmt.Sprintf("age:%s,binance.secret_key", path))
+	require.ErrorContains(err, "invalid age identity")
+	require.NotContains(err.Error(), "not-an-identity")
+}
+
+func TestGetSecretSops(t *testing.T) {
+	require := require.New(t)
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(err)
+	dir := t.TempDir()
+	identityPath := filepath.Join(dir, "keys.txt")
+	require.NoError(os.WriteFile(identityPath, []byte(identity.String()+"\n"), 0600))
+	other, err := age.GenerateX25519Identity()
+	require.NoError(err)
+
+	dataKey := make([]byte, 32)
+	_, err = rand.Read(dataKey)
+	require.NoError(err)
+	enc := func(value string, valueType string, path ...string) string {
+		return sopsEncryptValue(t, dataKey, value, valueType, path...)
+	}
+	encryptedKey := func(recipient age.Recipient) string {
+		return string(ageEncrypt(t, dataKey, true, recipient))
+	}
+
+	lastModified := "2025-01-01T00:00:00Z"
+	mac := sopsEncryptMac(t, dataKey, lastModified, "key1", "secret1", "pass1", "main", "sub1", "3")
+	sopsDocument := func(passphrase string, label string, mac string) string {
+		return fmt.Sprintf(`{
+	"okx": {
+		"api_key": %q,
+		"secret_key": %q,
+		"passphrase": %q,
+		"label_unencrypted": %q
+	},
+	"accounts": [{"id": %q}],
+	"retries": %q,
+	"sops": {
+		"age": [
+			{"recipient": %q, "enc": %q},
+			{"recipient": %q, "enc": %q}
+		],
+		"lastmodified": %q,
+		"mac": %q,
+		"unencrypted_suffix": "_unencrypted",
+		"version": "3.9.0"
+	}
+}`,
+			enc("key1", "str", "okx", "api_key"),
+			enc("secret1", "str", "okx", "secret_key"),
+			passphrase,
+			label,
+			enc("sub1", "str", "accounts", "id"),
+			enc("3", "int", "retries"),
+			other.Recipient().String(), encryptedKey(other.Recipient()),
+			identity.Recipient().String(), encryptedKey(identity.Recipient()),
+			lastModified, mac,
+		)
+	}
+	document := sopsDocument(enc("pass1", "str", "okx", "passphrase"), "main", mac)
+	path := filepath.Join(dir, "secrets.enc.json")
+	require.NoError(os.WriteFile(path, []byte(document), 0600))
+
+	ref := func(keyPath string) string {
+		return fmt.Sprintf("sops:%s,%s,file:%s", path, keyPath, identityPath)
+	}
+	value, err := GetSecret(ref("okx.api_key"))
+	require.NoError(err)
+	require.Equal("key1", value)
+
+	value, err = GetSecret(ref("okx.label_unencrypted"))
+	require.NoError(err)
+	require.Equal("main", value)
+
+	value, err = GetSecret(ref("accounts.0.id"))
+	require.NoError(err)
+	require.Equal("sub1", value)
+
+	value, err = GetSecret(ref("retries"))
+	require.NoError(err)
+	require.Equal("3", value)
+
+	// whole credential bundles
+	multi := oc.MultiSecret{SecretsRef: secret.Secret(ref("okx"))}
+	require.NoError(multi.LoadSecrets(context.Background()))
+	apiKey, err := multi.ApiKeyRef.Load(context.Background())
+	require.NoError(err)
+	require.Equal("key1", apiKey)
+	passphrase, err := multi.PassphraseRef.Load(context.Background())
+	require.NoError(err)
+	require.Equal("pass1", passphrase)
+
+	tampered := func(document string, keyPath string) error {
+		tamperedPath := filepath.Join(dir, "tampered.enc.json")
+		require.NoError(os.WriteFile(tamperedPath, []byte(document), 0600))
+		_, err := GetSecret(fmt.Sprintf("sops:%s,%s,file:%s", tamperedPath, keyPath, identityPath))
+		return err
+	}
+	// values are bound to their path
+	err = tampered(sopsDocument(enc("secret1", "str", "okx", "secret_key"), "main", mac), "okx.passphrase")
+	require.ErrorContains(err, "could not decrypt value of okx:passphrase")
+	// plaintext can't be swapped in for an encrypted value, even one that isn't selected
+	err = tampered(sopsDocument("pass2", "main", mac), "okx.api_key")
+	require.ErrorContains(err, "value of okx:passphrase is not encrypted")
+	// unencrypted values are covered by the MAC
+	err = tampered(sopsDocument(enc("pass1", "str", "okx", "passphrase"), "other", mac), "okx.label_unencrypted")
+	require.ErrorContains(err, "MAC mismatch")
+	err = tampered(sopsDocument(enc("pass1", "str", "okx", "passphrase"), "main", enc("0000", "str", "okx")), "okx.api_key")
+	require.ErrorContains(err, "could not decrypt the MAC")
+	err = tampered(strings.Replace(document, `"mac"`, `"mac_removed"`, 1), "okx.api_key")
+	require.ErrorContains(err, "the file has no MAC")
+
+	// only keys matching encrypted_regex are encrypted
+	regexDocument := fmt.Sprintf(`okx:
+  api_key: %s
+  secret_key: %s
+  label: main
+  limits:
+    - 1.5
+    - true
+sops:
+  age:
+    - recipient: %s
+      enc: |
+%s
+  lastmodified: "%s"
+  mac: %s
+  encrypted_regex: ^(api|secret)_key$
+  version: 3.9.0
+`,
+		enc("key1", "str", "okx", "api_key"),
+		enc("secret1", "str", "okx", "secret_key"),
+		identity.Recipient().String(),
+		"        "+strings.ReplaceAll(strings.TrimSpace(encryptedKey(identity.Recipient())), "\n", "\n        "),
+		lastModified,
+		sopsEncryptMac(t, dataKey, lastModified, "key1", "secret1", "main", "1.5", "True"),
+	)
+	regexPath := filepath.Join(dir, "regex.enc.yaml")
+	require.NoError(os.WriteFile(regexPath, []byte(regexDocument), 0600))
+	value, err = GetSecret(fmt.Sprintf("sops:%s,okx.secret_key,file:%s", regexPath, identityPath))
+	require.NoError(err)
+	require.Equal("secret1", value)
+	value, err = GetSecret(fmt.Sprintf("sops:%s,okx.label,file:%s", regexPath, identityPath))
+	require.NoError(err)
+	require.Equal("main", value)
+	err = tampered(strings.Replace(regexDocument, "label: main", "label: other", 1), "okx.api_key")
+	require.ErrorContains(err, "MAC mismatch")
+
+	third, err := age.GenerateX25519Identity()
+	require.NoError(err)
+	t.Setenv(secret.ENV_SOPS_AGE_KEY, third.String())
+	_, err = GetSecret(fmt.Sprintf("sops:%s,okx.api_key", path))
+	require.ErrorContains(err, "failed to decrypt")
+
+	_, err = GetSecret(fmt.Sprintf("sops:%s,okx.api_key,file:%s", identityPath, identityPath))
+	require.Error(err)
+}
+
+// The fixture is in the layout sops writes, with a comment, an empty string, a null and timestamps, which
+// sops leaves unencrypted or encrypts with their own types.
+func TestGetSecretSopsFixture(t *testing.T) {
+	require := require.New(t)
+	ref := func(keyPath string) string {
+		return fmt.Sprintf("sops:testdata/secrets.enc.yaml,%s,file:testdata/age-keys.txt", keyPath)
+	}
+	for keyPath, expected := range map[string]string{
+		"okx.api_key":             "key1",
+		"okx.secret_key":          "secret1",
+		"okx.passphrase":          "",
+		"okx.label_unencrypted":   "main",
+		"okx.rotated_at":          "2025-01-01T10:00:00Z",
+		"okx.expires_unencrypted": "2026-01-01T00:00:00Z",
+		"okx.sub_account":         "",
+		"accounts.0.id":           "sub1",
+		"accounts.0.retries":      "3",
+		"accounts.0.enabled":      "true",
+		"accounts.0.weight":       "1.5",
+	} {
+		value, err := GetSecret(ref(keyPath))
+		require.NoError(err, keyPath)
+		require.Equal(expected, value, keyPath)
+	}
+
+	contents, err := os.ReadFile("testdata/secrets.enc.yaml")
+	require.NoError(err)
+	tampered := func(old string, new string) error {
+		path := filepath.Join(t.TempDir(), "tampered.enc.yaml")
+		require.NoError(os.WriteFile(path, bytes.Replace(contents, []byte(old), []byte(new), 1), 0600))
+		_, err := GetSecret(fmt.Sprintf("sops:%s,okx.api_key,file:testdata/age-keys.txt", path))
+		return err
+	}
+	require.ErrorContains(tampered("expires_unencrypted: 2026-01-01", "expires_unencrypted: 2027-01-01"), "MAC mismatch")
+	require.ErrorContains(tampered(`passphrase: ""`, `passphrase: "pass2"`), "value of okx:passphrase is not encrypted")
+	require.ErrorContains(tampered("sub_account: null", "sub_account: 2026-01-01"), "value of okx:sub_account is not encrypted")
+}
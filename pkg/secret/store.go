@@ -0,0 +1,75 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Storer is implemented by backends that can write a new version of a secret, e.g. when an exchange API
+// key is rotated.
+type Storer interface {
+	// CheckStore returns an error if a new version can't be written for the reference, e.g. because it
+	// pins a version.  It doesn't make any requests.
+	CheckStore(args []string) error
+	// Store writes a new version of the secret.  Other keys of the same secret are kept.
+	Store(ctx context.Context, args []string, value string) error
+}
+
+func (s Secret) storer() (Storer, []string, error) {
+	prefix, rest, ok := strings.Cut(string(s), ":")
+	if !ok {
+		return nil, nil, errors.New("secret reference is missing a prefix")
+	}
+	t := SecretType(strings.ToLower(prefix))
+	backend, ok := GetBackend(t)
+	if !ok {
+		return nil, nil, errors.New("invalid secret source for: ***")
+	}
+	storer, ok := backend.(Storer)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s secrets can't be written, use one of %v", t, StorableTypes())
+	}
+	return storer, strings.Split(rest, ","), nil
+}
+
+// CheckStore returns an error if a new version of the secret can't be written.
+func (s Secret) CheckStore() error {
+	storer, args, err := s.storer()
+	if err != nil {
+		return err
+	}
+	return storer.CheckStore(args)
+}
+
+// Store writes a new version of the secret to its backend, and drops any cached value.
+func (s Secret) Store(ctx context.Context, value string) error {
+	storer, args, err := s.storer()
+	if err != nil {
+		return err
+	}
+	if err := storer.CheckStore(args); err != nil {
+		return err
+	}
+	if err := storer.Store(ctx, args, value); err != nil {
+		return err
+	}
+	if cache, ok := DefaultCache(); ok {
+		cache.Invalidate(s)
+	}
+	return nil
+}
+
+// StorableTypes lists the registered backends that can write secrets.
+func StorableTypes() []SecretType {
+	types := []SecretType{}
+	for _, t := range Types() {
+		if backend, ok := GetBackend(t); ok {
+			if _, ok := backend.(Storer); ok {
+				types = append(types, t)
+			}
+		}
+	}
+	return types
+}
@@ -0,0 +1,75 @@
+package secret_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cordialsys/offchain/pkg/secret"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreFile(t *testing.T) {
+	require := require.New(t)
+	path := filepath.Join(t.TempDir(), "api_key")
+	require.NoError(os.WriteFile(path, []byte("old\n"), 0640))
+
+	ref := secret.Secret("file:" + path)
+	require.NoError(ref.CheckStore())
+	require.NoError(ref.Store(context.Background(), "new"))
+	value, err := ref.Load(context.Background())
+	require.NoError(err)
+	require.Equal("new", value)
+	info, err := os.Stat(path)
+	require.NoError(err)
+	require.Equal(os.FileMode(0640), info.Mode().Perm())
+}
+
+func TestCheckStore(t *testing.T) {
+	require := require.New(t)
+	require.Contains(secret.StorableTypes(), secret.Vault)
+	require.Contains(secret.StorableTypes(), secret.GcpSecretManager)
+	require.Contains(secret.StorableTypes(), secret.AwsSecretManager)
+	require.NotContains(secret.StorableTypes(), secret.Env)
+
+	require.ErrorContains(secret.Secret("env:API_KEY").CheckStore(), "env secrets can't be written")
+	require.ErrorContains(secret.NewRawSecret("key").CheckStore(), "raw secrets can't be written")
+	require.ErrorContains(secret.Secret("gcp:project,API_KEY,3").CheckStore(), "pins version 3")
+	require.NoError(secret.Secret("gcp:project,API_KEY,latest").CheckStore())
+	require.ErrorContains(secret.Secret("aws:exchange:api_key,us-east-1,v1").CheckStore(), "pins version v1")
+	require.NoError(secret.Secret("aws:exchange:api_key,us-east-1").CheckStore())
+	require.ErrorContains(secret.Secret("vault:https://example.com,kv2/exchange#api_key@3").CheckStore(), "pins version 3")
+	require.NoError(secret.Secret("vault:https://example.com,kv2/exchange#api_key").CheckStore())
+	require.ErrorContains(secret.Secret("azure:vault,api-key,abc").CheckStore(), "pins version abc")
+}
+
+func TestStoreVault(t *testing.T) {
+	require := require.New(t)
+	mock := &MockedVaultLoaded{
+		data: map[string]interface{}{
+			"kv1/exchange": map[string]interface{}{"api_key": "old-key", "secret_key": "old-secret"},
+			"kv2/data/exchange": map[string]interface{}{
+				"data":     map[string]interface{}{"api_key": "old-key", "secret_key": "old-secret"},
+				"metadata": map[string]interface{}{"version": "4"},
+			},
+		},
+		mounts: map[string]*secret.KvMount{
+			"kv1/": {Path: "kv1/", Version: 1},
+			"kv2/": {Path: "kv2/", Version: 2},
+		},
+	}
+	mockVault(t, mock)
+	ctx := context.Background()
+
+	require.NoError(secret.Secret("vault:https://example.com,kv1/exchange#api_key").Store(ctx, "new-key"))
+	require.Equal(map[string]interface{}{"api_key": "new-key", "secret_key": "old-secret"}, mock.data["kv1/exchange"])
+
+	require.NoError(secret.Secret("vault:https://example.com,kv2/exchange#api_key").Store(ctx, "new-key"))
+	require.Equal(map[string]interface{}{
+		"data":    map[string]interface{}{"api_key": "new-key", "secret_key": "old-secret"},
+		"options": map[string]interface{}{"cas": 4},
+	}, mock.data["kv2/data/exchange"])
+
+	require.ErrorContains(secret.Secret("vault:https://example.com,kv2/exchange#api_key@4").Store(ctx, "new-key"), "pins version 4")
+}
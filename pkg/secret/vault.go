@@ -1,6 +1,18 @@
 package secret
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
 	vault "github.com/hashicorp/vault/api"
 )
 
@@ -14,20 +26,505 @@ func newVaultClient(cfg *vault.Config) (VaultLoader, error) {
 
 var NewVaultClient = newVaultClient
 
+type VaultAuthMethod string
+
+const (
+	// Use $VAULT_TOKEN
+	VaultAuthToken      VaultAuthMethod = ""
+	VaultAuthAppRole    VaultAuthMethod = "approle"
+	VaultAuthKubernetes VaultAuthMethod = "kubernetes"
+	VaultAuthJwt        VaultAuthMethod = "jwt"
+)
+
+// Where kubernetes mounts the service account token in pods
+const KubernetesServiceAccountToken = "file:/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// VaultAuth is how to log in to Vault.  It can be set per reference using query parameters of the
+// server url (e.g. `vault:https://vault:8200?auth=approle&role_id=...&secret_id=env:SECRET_ID,path#key`),
+// or globally using OFFCHAIN_VAULT_* env vars.
+type VaultAuth struct {
+	Method VaultAuthMethod
+	// Path the auth method is mounted at, defaults to the name of the method
+	Mount string
+	// Role to log in as (kubernetes and jwt)
+	Role string
+	// AppRole credentials
+	RoleId   string
+	SecretId Secret
+	// Token to log in with (kubernetes and jwt).  Defaults to the pod's service account token for kubernetes.
+	Jwt Secret
+}
+
+const (
+	ENV_VAULT_AUTH       = "OFFCHAIN_VAULT_AUTH"
+	ENV_VAULT_AUTH_MOUNT = "OFFCHAIN_VAULT_AUTH_MOUNT"
+	ENV_VAULT_ROLE       = "OFFCHAIN_VAULT_ROLE"
+	ENV_VAULT_ROLE_ID    = "OFFCHAIN_VAULT_ROLE_ID"
+	ENV_VAULT_SECRET_ID  = "OFFCHAIN_VAULT_SECRET_ID"
+	ENV_VAULT_JWT        = "OFFCHAIN_VAULT_JWT"
+)
+
+// vaultAuthFromEnv returns the global vault auth.  Credentials are secret references.
+func vaultAuthFromEnv() VaultAuth {
+	return VaultAuth{
+		Method:   VaultAuthMethod(os.Getenv(ENV_VAULT_AUTH)),
+		Mount:    os.Getenv(ENV_VAULT_AUTH_MOUNT),
+		Role:     os.Getenv(ENV_VAULT_ROLE),
+		RoleId:   os.Getenv(ENV_VAULT_ROLE_ID),
+		SecretId: Secret(os.Getenv(ENV_VAULT_SECRET_ID)),
+		Jwt:      Secret(os.Getenv(ENV_VAULT_JWT)),
+	}
+}
+
+// parseVaultUrl separates the auth query parameters from the server url.  The global auth is used if
+// the url doesn't set one.
+func parseVaultUrl(vaultUrl string) (string, VaultAuth, error) {
+	u, err := url.Parse(vaultUrl)
+	if err != nil {
+		return "", VaultAuth{}, fmt.Errorf("invalid vault url: %w", err)
+	}
+	query := u.Query()
+	u.RawQuery = ""
+	if !query.Has("auth") {
+		return u.String(), vaultAuthFromEnv(), nil
+	}
+	return u.String(), VaultAuth{
+		Method:   VaultAuthMethod(query.Get("auth")),
+		Mount:    query.Get("mount"),
+		Role:     query.Get("role"),
+		RoleId:   query.Get("role_id"),
+		SecretId: Secret(query.Get("secret_id")),
+		Jwt:      Secret(query.Get("jwt")),
+	}, nil
+}
+
+type vaultRef struct {
+	path string
+	key  string
+	// 0 for the latest version
+	version int
+}
+
+// parseVaultPath parses `path#key@version`, or the original `path/key` format.
+func parseVaultPath(fullPath string) (vaultRef, error) {
+	ref := vaultRef{}
+	if path, key, ok := strings.Cut(fullPath, "#"); ok {
+		ref.path, ref.key = path, key
+	} else {
+		idx := strings.LastIndex(fullPath, "/")
+		if idx == -1 || idx == len(fullPath) { // idx shouldn't be the last char
+			return ref, fmt.Errorf("malformed vault secret in config file")
+		}
+		ref.path, ref.key = fullPath[:idx], fullPath[idx+1:]
+	}
+	if idx := strings.LastIndex(ref.key, "@"); idx != -1 {
+		version, err := strconv.Atoi(ref.key[idx+1:])
+		if err != nil || version <= 0 {
+			return ref, fmt.Errorf("invalid vault secret version: %s", ref.key[idx+1:])
+		}
+		ref.key, ref.version = ref.key[:idx], version
+	}
+	if ref.path == "" || ref.key == "" {
+		return ref, fmt.Errorf("malformed vault secret in config file")
+	}
+	return ref, nil
+}
+
+// KvMount is the KV secrets engine that a path is in.
+type KvMount struct {
+	// e.g. "secret/"
+	Path    string
+	Version int
+}
+
+// dataPath returns the path to read, which for KV v2 includes "data/" after the mount.
+func (m *KvMount) dataPath(path string) string {
+	if m.Version != 2 {
+		return path
+	}
+	mount := strings.TrimSuffix(m.Path, "/")
+	rest := strings.TrimPrefix(strings.TrimPrefix(path, mount), "/")
+	if strings.HasPrefix(rest, "data/") {
+		// already the full api path
+		return path
+	}
+	return mount + "/data/" + rest
+}
+
+// withVaultLogin logs in and runs the operation.  If vault denies it, e.g. because the token was revoked, the
+// operation is retried once with a new token.
+func withVaultLogin(ctx context.Context, client VaultLoader, auth VaultAuth, operation func() error) error {
+	if err := client.Login(ctx, auth); err != nil {
+		return fmt.Errorf("failed to log in to vault: %w", err)
+	}
+	err := operation()
+	var responseErr *vault.ResponseError
+	if auth.Method == VaultAuthToken || !errors.As(err, &responseErr) || responseErr.StatusCode != http.StatusForbidden {
+		return err
+	}
+	slog.Debug("vault denied the request, logging in again", "auth", auth.Method)
+	client.Logout(auth)
+	if err := client.Login(ctx, auth); err != nil {
+		return fmt.Errorf("failed to log in to vault: %w", err)
+	}
+	return operation()
+}
+
+func loadVaultSecret(ctx context.Context, client VaultLoader, auth VaultAuth, ref vaultRef) (string, error) {
+	var value string
+	err := withVaultLogin(ctx, client, auth, func() error {
+		var err error
+		value, err = readVaultSecret(ctx, client, ref)
+		return err
+	})
+	return value, err
+}
+
+func readVaultSecret(ctx context.Context, client VaultLoader, ref vaultRef) (string, error) {
+	mount, err := client.KvMount(ctx, ref.path)
+	if err != nil {
+		return "", err
+	}
+
+	var data map[string]interface{}
+	switch {
+	case mount != nil && mount.Version == 1:
+		if ref.version != 0 {
+			return "", fmt.Errorf("vault kv v1 secrets are not versioned")
+		}
+		secret, err := client.LoadSecretData(ctx, ref.path)
+		if err != nil {
+			return "", err
+		}
+		data = secret.Data
+	case mount != nil && mount.Version == 2:
+		secret, err := client.LoadSecretVersion(ctx, mount.dataPath(ref.path), ref.version)
+		if err != nil {
+			return "", err
+		}
+		data, _ = secret.Data["data"].(map[string]interface{})
+	default:
+		// the engine couldn't be detected, so expect the full kv v2 api path
+		if ref.version != 0 {
+			secret, err := client.LoadSecretVersion(ctx, ref.path, ref.version)
+			if err != nil {
+				return "", err
+			}
+			data, _ = secret.Data["data"].(map[string]interface{})
+		} else {
+			secret, err := client.LoadSecretData(ctx, ref.path)
+			if err != nil {
+				return "", err
+			}
+			data, _ = secret.Data["data"].(map[string]interface{})
+		}
+	}
+	result, _ := data[ref.key].(string)
+	return strings.TrimSpace(result), nil
+}
+
+// storeVaultSecret sets the key of the secret, keeping its other keys.  KV v2 writes are checked against the
+// version that was read, so that concurrent changes to other keys aren't lost.
+func storeVaultSecret(ctx context.Context, client VaultLoader, auth VaultAuth, ref vaultRef, value string) error {
+	return withVaultLogin(ctx, client, auth, func() error {
+		return writeVaultSecret(ctx, client, ref, value)
+	})
+}
+
+func writeVaultSecret(ctx context.Context, client VaultLoader, ref vaultRef, value string) error {
+	mount, err := client.KvMount(ctx, ref.path)
+	if err != nil {
+		return err
+	}
+	if mount != nil && mount.Version == 1 {
+		current, err := client.LoadSecretData(ctx, ref.path)
+		if err != nil {
+			return err
+		}
+		data := map[string]interface{}{}
+		for key, existing := range current.Data {
+			data[key] = existing
+		}
+		data[ref.key] = value
+		return client.WriteSecretData(ctx, ref.path, data)
+	}
+
+	path := ref.path
+	if mount != nil {
+		path = mount.dataPath(ref.path)
+	}
+	current, err := client.LoadSecretData(ctx, path)
+	if err != nil {
+		return err
+	}
+	data := map[string]interface{}{}
+	if existing, ok := current.Data["data"].(map[string]interface{}); ok {
+		for key, existing := range existing {
+			data[key] = existing
+		}
+	}
+	data[ref.key] = value
+	cas := 0
+	if metadata, ok := current.Data["metadata"].(map[string]interface{}); ok {
+		cas, _ = strconv.Atoi(fmt.Sprint(metadata["version"]))
+	}
+	return client.WriteSecretData(ctx, path, map[string]interface{}{
+		"data":    data,
+		"options": map[string]interface{}{"cas": cas},
+	})
+}
+
 type DefaultVaultLoader struct {
 	*vault.Client
 }
 
 var _ VaultLoader = &DefaultVaultLoader{}
 
-func (v *DefaultVaultLoader) LoadSecretData(vaultPath string) (*vault.Secret, error) {
-	secret, err := v.Logical().Read(vaultPath)
+func (v *DefaultVaultLoader) LoadSecretData(ctx context.Context, vaultPath string) (*vault.Secret, error) {
+	secret, err := v.Logical().ReadWithContext(ctx, vaultPath)
 	if err != nil || secret == nil { // yes, secret can be nil
 		return &vault.Secret{}, err
 	}
 	return secret, nil
 }
 
+func (v *DefaultVaultLoader) LoadSecretVersion(ctx context.Context, vaultPath string, version int) (*vault.Secret, error) {
+	if version == 0 {
+		return v.LoadSecretData(ctx, vaultPath)
+	}
+	secret, err := v.Logical().ReadWithDataWithContext(ctx, vaultPath, map[string][]string{
+		"version": {strconv.Itoa(version)},
+	})
+	if err != nil || secret == nil {
+		return &vault.Secret{}, err
+	}
+	return secret, nil
+}
+
+func (v *DefaultVaultLoader) WriteSecretData(ctx context.Context, vaultPath string, data map[string]interface{}) error {
+	_, err := v.Logical().WriteWithContext(ctx, vaultPath, data)
+	return err
+}
+
+func (v *DefaultVaultLoader) KvMount(ctx context.Context, vaultPath string) (*KvMount, error) {
+	secret, err := v.Logical().ReadWithContext(ctx, "sys/internal/ui/mounts/"+vaultPath)
+	if err != nil || secret == nil {
+		// older servers, or tokens not allowed to look up mounts
+		slog.Debug("could not detect vault kv version", "error", err)
+		return nil, nil
+	}
+	mountPath, _ := secret.Data["path"].(string)
+	options, _ := secret.Data["options"].(map[string]interface{})
+	version := 1
+	if options["version"] == "2" {
+		version = 2
+	}
+	return &KvMount{Path: mountPath, Version: version}, nil
+}
+
+type vaultTokenKey struct {
+	address string
+	auth    VaultAuth
+}
+
+type vaultToken struct {
+	token string
+	// when to log in again, shortly before the token expires; zero if it doesn't expire
+	refreshAt time.Time
+}
+
+// Tokens from logging in are reused until shortly before they expire, and renewed in the background
+// until they reach their max ttl.
+var vaultTokens = struct {
+	sync.Mutex
+	tokens map[vaultTokenKey]vaultToken
+}{tokens: map[vaultTokenKey]vaultToken{}}
+
+// vaultTokenRefreshAt leaves a tenth of the lease, so that a token isn't used as it expires.
+func vaultTokenRefreshAt(now time.Time, leaseSeconds int) time.Time {
+	if leaseSeconds <= 0 {
+		return time.Time{}
+	}
+	lease := time.Duration(leaseSeconds) * time.Second
+	return now.Add(lease - lease/10)
+}
+
+func (v *DefaultVaultLoader) Logout(auth VaultAuth) {
+	vaultTokens.Lock()
+	defer vaultTokens.Unlock()
+	delete(vaultTokens.tokens, vaultTokenKey{v.Address(), auth})
+}
+
+func (v *DefaultVaultLoader) Login(ctx context.Context, auth VaultAuth) error {
+	if auth.Method == VaultAuthToken {
+		return nil
+	}
+	key := vaultTokenKey{v.Address(), auth}
+	vaultTokens.Lock()
+	cached, ok := vaultTokens.tokens[key]
+	vaultTokens.Unlock()
+	if ok && (cached.refreshAt.IsZero() || time.Now().Before(cached.refreshAt)) {
+		v.SetToken(cached.token)
+		return nil
+	}
+
+	mount := auth.Mount
+	if mount == "" {
+		mount = string(auth.Method)
+	}
+	data := map[string]interface{}{}
+	switch auth.Method {
+	case VaultAuthAppRole:
+		data["role_id"] = auth.RoleId
+		if auth.SecretId != "" {
+			secretId, err := auth.SecretId.Load(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to load approle secret id: %w", err)
+			}
+			data["secret_id"] = secretId
+		}
+	case VaultAuthKubernetes, VaultAuthJwt:
+		jwtRef := auth.Jwt
+		if jwtRef == "" && auth.Method == VaultAuthKubernetes {
+			jwtRef = KubernetesServiceAccountToken
+		}
+		if jwtRef == "" {
+			return fmt.Errorf("vault %s auth requires a jwt", auth.Method)
+		}
+		jwt, err := jwtRef.Load(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to load jwt: %w", err)
+		}
+		data["role"] = auth.Role
+		data["jwt"] = jwt
+	default:
+		return fmt.Errorf("unsupported vault auth method: %s", auth.Method)
+	}
+
+	// log in without any token that may be set in the env
+	v.ClearToken()
+	secret, err := v.Logical().WriteWithContext(ctx, fmt.Sprintf("auth/%s/login", mount), data)
+	if err != nil {
+		return err
+	}
+	if secret == nil || secret.Auth == nil || secret.Auth.ClientToken == "" {
+		return fmt.Errorf("vault login returned no token")
+	}
+	token := secret.Auth.ClientToken
+	v.SetToken(token)
+
+	vaultTokens.Lock()
+	vaultTokens.tokens[key] = vaultToken{token, vaultTokenRefreshAt(time.Now(), secret.Auth.LeaseDuration)}
+	vaultTokens.Unlock()
+	if secret.Auth.Renewable {
+		watcher, err := v.NewLifetimeWatcher(&vault.LifetimeWatcherInput{Secret: secret})
+		if err != nil {
+			return err
+		}
+		go watcher.Start()
+		go func() {
+			defer watcher.Stop()
+			for {
+				select {
+				case err := <-watcher.DoneCh():
+					// the token can't be renewed any more, so log in again on next use
+					if err != nil {
+						slog.Warn("failed to renew vault token", "auth", auth.Method, "error", err)
+					}
+					vaultTokens.Lock()
+					if vaultTokens.tokens[key].token == token {
+						delete(vaultTokens.tokens, key)
+					}
+					vaultTokens.Unlock()
+					return
+				case renewal := <-watcher.RenewCh():
+					slog.Debug("renewed vault token", "auth", auth.Method)
+					if renewal.Secret != nil && renewal.Secret.Auth != nil {
+						vaultTokens.Lock()
+						if vaultTokens.tokens[key].token == token {
+							vaultTokens.tokens[key] = vaultToken{token, vaultTokenRefreshAt(renewal.RenewedAt, renewal.Secret.Auth.LeaseDuration)}
+						}
+						vaultTokens.Unlock()
+					}
+				}
+			}
+		}()
+	}
+	return nil
+}
+
 type VaultLoader interface {
-	LoadSecretData(path string) (*vault.Secret, error)
+	LoadSecretData(ctx context.Context, path string) (*vault.Secret, error)
+	// LoadSecretVersion reads a version of a KV v2 secret, or the latest if the version is 0.
+	LoadSecretVersion(ctx context.Context, path string, version int) (*vault.Secret, error)
+	// KvMount returns the KV engine that the path is in, or nil if it can't be detected.
+	KvMount(ctx context.Context, path string) (*KvMount, error)
+	// Login authenticates using the auth method, if any.
+	Login(ctx context.Context, auth VaultAuth) error
+	// Logout forgets the token from logging in with the auth method, so that the next Login logs in again.
+	Logout(auth VaultAuth)
+	WriteSecretData(ctx context.Context, path string, data map[string]interface{}) error
+}
+
+type vaultBackend struct{}
+
+func (vaultBackend) Type() SecretType { return Vault }
+func (vaultBackend) Name() string     { return "Hashicorp Vault" }
+func (vaultBackend) Usage() string {
+	return "<server-url>[?auth=approle|kubernetes|jwt&...],<path>[#key][@version]"
+}
+func (vaultBackend) Load(ctx context.Context, args []string) (string, error) {
+	client, auth, ref, err := vaultClient(args)
+	if err != nil {
+		return "", err
+	}
+	return loadVaultSecret(ctx, client, auth, ref)
+}
+
+func (vaultBackend) CheckStore(args []string) error {
+	if len(args) != 2 {
+		return errors.New("vault secret has 2 comma separated arguments (url,path)")
+	}
+	ref, err := parseVaultPath(args[1])
+	if err != nil {
+		return err
+	}
+	if ref.version != 0 {
+		return fmt.Errorf("%s secret pins version %d, so a new version would not be used", Vault, ref.version)
+	}
+	return nil
+}
+
+func (vaultBackend) Store(ctx context.Context, args []string, value string) error {
+	client, auth, ref, err := vaultClient(args)
+	if err != nil {
+		return err
+	}
+	return storeVaultSecret(ctx, client, auth, ref, value)
+}
+
+func vaultClient(args []string) (VaultLoader, VaultAuth, vaultRef, error) {
+	if len(args) != 2 {
+		return nil, VaultAuth{}, vaultRef{}, errors.New("vault secret has 2 comma separated arguments (url,path)")
+	}
+	vaultUrl, auth, err := parseVaultUrl(args[0])
+	if err != nil {
+		return nil, VaultAuth{}, vaultRef{}, err
+	}
+	cfg := &vault.Config{Address: vaultUrl}
+	// just check the error
+	_, err = vault.NewClient(cfg)
+	if err != nil {
+		return nil, VaultAuth{}, vaultRef{}, err
+	}
+	client, err := NewVaultClient(cfg)
+	if err != nil {
+		return nil, VaultAuth{}, vaultRef{}, err
+	}
+
+	ref, err := parseVaultPath(args[1])
+	if err != nil {
+		return nil, VaultAuth{}, vaultRef{}, err
+	}
+	return client, auth, ref, nil
 }
@@ -0,0 +1,184 @@
+package secret_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cordialsys/offchain/pkg/secret"
+	vault "github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/require"
+)
+
+func mockVault(t *testing.T, mock *MockedVaultLoaded) {
+	newVaultClient := secret.NewVaultClient
+	t.Cleanup(func() { secret.NewVaultClient = newVaultClient })
+	secret.NewVaultClient = func(cfg *vault.Config) (secret.VaultLoader, error) {
+		// auth parameters aren't part of the address
+		require.NotContains(t, cfg.Address, "?")
+		return mock, nil
+	}
+}
+
+func TestGetSecretVaultKvVersions(t *testing.T) {
+	require := require.New(t)
+	mock := &MockedVaultLoaded{
+		data: map[string]interface{}{
+			"kv1/exchange":      map[string]interface{}{"api_key": "v1-key"},
+			"kv2/data/exchange": map[string]interface{}{"data": map[string]interface{}{"api_key": "v2-key"}},
+		},
+		mounts: map[string]*secret.KvMount{
+			"kv1/": {Path: "kv1/", Version: 1},
+			"kv2/": {Path: "kv2/", Version: 2},
+		},
+	}
+	mockVault(t, mock)
+
+	value, err := GetSecret("vault:https://example.com,kv1/exchange#api_key")
+	require.NoError(err)
+	require.Equal("v1-key", value)
+	// the original format still works
+	value, err = GetSecret("vault:https://example.com,kv1/exchange/api_key")
+	require.NoError(err)
+	require.Equal("v1-key", value)
+
+	_, err = GetSecret("vault:https://example.com,kv1/exchange#api_key@2")
+	require.ErrorContains(err, "not versioned")
+
+	// the data path is inserted for kv v2
+	value, err = GetSecret("vault:https://example.com,kv2/exchange#api_key")
+	require.NoError(err)
+	require.Equal("v2-key", value)
+	value, err = GetSecret("vault:https://example.com,kv2/data/exchange#api_key@3")
+	require.NoError(err)
+	require.Equal("v2-key", value)
+	require.Equal([]string{"kv2/data/exchange@0", "kv2/data/exchange@3"}, mock.versionReads)
+
+	_, err = GetSecret("vault:https://example.com,kv2/exchange#api_key@latest")
+	require.ErrorContains(err, "invalid vault secret version")
+}
+
+func TestGetSecretVaultAuth(t *testing.T) {
+	require := require.New(t)
+	mock := &MockedVaultLoaded{
+		data: map[string]interface{}{
+			"secret/data/exchange": map[string]interface{}{"data": map[string]interface{}{"api_key": "key"}},
+		},
+	}
+	mockVault(t, mock)
+
+	_, err := GetSecret("vault:https://example.com?auth=approle&role_id=r1&secret_id=env:SECRET_ID,secret/data/exchange#api_key")
+	require.NoError(err)
+	_, err = GetSecret("vault:https://example.com?auth=kubernetes&role=offchain&mount=k8s,secret/data/exchange#api_key")
+	require.NoError(err)
+
+	// configured globally
+	t.Setenv(secret.ENV_VAULT_AUTH, "jwt")
+	t.Setenv(secret.ENV_VAULT_ROLE, "ci")
+	t.Setenv(secret.ENV_VAULT_JWT, "env:CI_JWT")
+	_, err = GetSecret("vault:https://example.com,secret/data/exchange#api_key")
+	require.NoError(err)
+
+	require.Equal([]secret.VaultAuth{
+		{Method: secret.VaultAuthAppRole, RoleId: "r1", SecretId: "env:SECRET_ID"},
+		{Method: secret.VaultAuthKubernetes, Role: "offchain", Mount: "k8s"},
+		{Method: secret.VaultAuthJwt, Role: "ci", Jwt: "env:CI_JWT"},
+	}, mock.logins)
+}
+
+// fakeVault serves approle login, mount lookup and a kv v2 secret with two versions.  Only the token from
+// the last login is valid.
+func fakeVault(t *testing.T, logins *atomic.Int32, leaseSeconds int) *httptest.Server {
+	respond := func(w http.ResponseWriter, body map[string]interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(body))
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/auth/approle/login":
+			body := map[string]string{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			if body["role_id"] != "role" || body["secret_id"] != "s3cret" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			respond(w, map[string]interface{}{
+				"auth": map[string]interface{}{"client_token": fmt.Sprintf("tok%d", logins.Add(1)), "lease_duration": leaseSeconds, "renewable": false},
+			})
+		case r.Header.Get("X-Vault-Token") != fmt.Sprintf("tok%d", logins.Load()):
+			w.WriteHeader(http.StatusForbidden)
+		case strings.HasPrefix(r.URL.Path, "/v1/sys/internal/ui/mounts/"):
+			respond(w, map[string]interface{}{
+				"data": map[string]interface{}{"path": "secret/", "type": "kv", "options": map[string]interface{}{"version": "2"}},
+			})
+		case r.URL.Path == "/v1/secret/data/exchange":
+			value := "new-key"
+			if r.URL.Query().Get("version") == "1" {
+				value = "old-key"
+			}
+			respond(w, map[string]interface{}{
+				"data": map[string]interface{}{"data": map[string]interface{}{"api_key": value}},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestVaultLoaderAppRole(t *testing.T) {
+	require := require.New(t)
+	logins := &atomic.Int32{}
+	server := fakeVault(t, logins, 3600)
+	defer server.Close()
+	t.Setenv("VAULT_TOKEN", "")
+	t.Setenv("SECRET_ID", "s3cret")
+
+	ref := "vault:" + server.URL + "?auth=approle&role_id=role&secret_id=env:SECRET_ID,secret/exchange#api_key"
+	value, err := GetSecret(ref)
+	require.NoError(err)
+	require.Equal("new-key", value)
+
+	value, err = GetSecret(ref + "@1")
+	require.NoError(err)
+	require.Equal("old-key", value)
+
+	// the token is reused
+	require.EqualValues(1, logins.Load())
+
+	_, err = GetSecret("vault:" + server.URL + "?auth=approle&role_id=other,secret/exchange#api_key")
+	require.ErrorContains(err, "failed to log in to vault")
+
+	// the token is revoked by logging in elsewhere, so vault denies it and we log in again
+	logins.Add(1)
+	value, err = GetSecret(ref)
+	require.NoError(err)
+	require.Equal("new-key", value)
+	require.EqualValues(3, logins.Load())
+}
+
+func TestVaultLoaderTokenExpiry(t *testing.T) {
+	require := require.New(t)
+	logins := &atomic.Int32{}
+	server := fakeVault(t, logins, 1)
+	defer server.Close()
+	t.Setenv("VAULT_TOKEN", "")
+	t.Setenv("SECRET_ID", "s3cret")
+
+	ref := "vault:" + server.URL + "?auth=approle&role_id=role&secret_id=env:SECRET_ID,secret/exchange#api_key"
+	_, err := GetSecret(ref)
+	require.NoError(err)
+	_, err = GetSecret(ref)
+	require.NoError(err)
+	require.EqualValues(1, logins.Load())
+
+	// logs in again as the lease runs out, rather than using the token until vault rejects it
+	time.Sleep(time.Second)
+	_, err = GetSecret(ref)
+	require.NoError(err)
+	require.EqualValues(2, logins.Load())
+}
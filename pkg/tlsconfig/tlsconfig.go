@@ -0,0 +1,101 @@
+// Package tlsconfig loads TLS certificates and CA bundles from secrets, and identifies the
+// holders of client certificates.
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/cordialsys/offchain/pkg/secret"
+)
+
+// LoadCertificate loads a PEM encoded certificate (chain) and private key.
+func LoadCertificate(ctx context.Context, certRef, keyRef secret.Secret) (tls.Certificate, error) {
+	certPem, err := certRef.Load(ctx)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to load certificate: %w", err)
+	}
+	keyPem, err := keyRef.Load(ctx)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to load private key: %w", err)
+	}
+	cert, err := tls.X509KeyPair([]byte(certPem), []byte(keyPem))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("invalid certificate or private key: %w", err)
+	}
+	return cert, nil
+}
+
+// LoadCertPool loads a bundle of PEM encoded CA certificates.
+func LoadCertPool(ctx context.Context, bundleRef secret.Secret) (*x509.CertPool, error) {
+	bundle, err := bundleRef.Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(bundle)) {
+		return nil, fmt.Errorf("no certificates found in CA bundle")
+	}
+	return pool, nil
+}
+
+// Server returns the TLS config for a server.  If clientCa is set, clients must present a
+// certificate signed by one of the CAs in the bundle.
+func Server(ctx context.Context, certRef, keyRef, clientCaRef secret.Secret) (*tls.Config, error) {
+	cert, err := LoadCertificate(ctx, certRef, keyRef)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+	if clientCaRef != "" {
+		config.ClientCAs, err = LoadCertPool(ctx, clientCaRef)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// Client returns the TLS config for a client.  The certificate is only used if the server
+// requires one, and the CA bundle replaces the system roots if set.  Each is optional.
+func Client(ctx context.Context, certRef, keyRef, caRef secret.Secret) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if certRef != "" || keyRef != "" {
+		cert, err := LoadCertificate(ctx, certRef, keyRef)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if caRef != "" {
+		pool, err := LoadCertPool(ctx, caRef)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
+
+// Identities lists the names a certificate identifies its holder by: the subject common name,
+// and any DNS, email and URI (e.g. SPIFFE) subject alternative names.
+func Identities(cert *x509.Certificate) []string {
+	identities := []string{}
+	if cert.Subject.CommonName != "" {
+		identities = append(identities, cert.Subject.CommonName)
+	}
+	identities = append(identities, cert.DNSNames...)
+	identities = append(identities, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		identities = append(identities, uri.String())
+	}
+	return identities
+}
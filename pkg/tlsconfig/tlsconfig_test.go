@@ -0,0 +1,111 @@
+package tlsconfig_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/cordialsys/offchain/pkg/secret"
+	"github.com/cordialsys/offchain/pkg/tlsconfig"
+	"github.com/stretchr/testify/require"
+)
+
+type issued struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPem string
+	keyPem  string
+}
+
+func issue(t *testing.T, template *x509.Certificate, parent *issued) *issued {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	parentCert, parentKey := template, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCert, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return &issued{
+		cert:    cert,
+		key:     key,
+		certPem: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		keyPem:  string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})),
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	ctx := context.Background()
+	ca := issue(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "test ca"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+	server := issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "offchain"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca)
+	spiffe, _ := url.Parse("spiffe://example.org/treasury")
+	client := issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "treasury"},
+		URIs:        []*url.URL{spiffe},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca)
+	require.Equal(t, []string{"treasury", "spiffe://example.org/treasury"}, tlsconfig.Identities(client.cert))
+
+	caRef := secret.NewRawSecret(ca.certPem)
+	serverConfig, err := tlsconfig.Server(ctx, secret.NewRawSecret(server.certPem), secret.NewRawSecret(server.keyPem), caRef)
+	require.NoError(t, err)
+	require.Equal(t, tls.RequireAndVerifyClientCert, serverConfig.ClientAuth)
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	require.NoError(t, err)
+	defer ln.Close()
+	go http.Serve(ln, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, tlsconfig.Identities(r.TLS.VerifiedChains[0][0])[0])
+	}))
+	url := "https://" + ln.Addr().String()
+
+	clientConfig, err := tlsconfig.Client(ctx, secret.NewRawSecret(client.certPem), secret.NewRawSecret(client.keyPem), caRef)
+	require.NoError(t, err)
+	resp, err := (&http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig}}).Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	require.Equal(t, "treasury", string(body))
+
+	// without a client certificate
+	clientConfig, err = tlsconfig.Client(ctx, "", "", caRef)
+	require.NoError(t, err)
+	_, err = (&http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig}}).Get(url)
+	require.Error(t, err)
+}
+
+func TestLoadErrors(t *testing.T) {
+	ctx := context.Background()
+	_, err := tlsconfig.LoadCertPool(ctx, secret.NewRawSecret("not a certificate"))
+	require.ErrorContains(t, err, "no certificates")
+	_, err = tlsconfig.LoadCertificate(ctx, secret.NewRawSecret("bad"), secret.NewRawSecret("bad"))
+	require.Error(t, err)
+}
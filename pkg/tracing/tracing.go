@@ -0,0 +1,121 @@
+// Package tracing sets up OpenTelemetry tracing for offchain.
+//
+// Spans are created using the global tracer provider, so they are no-ops unless Setup configures
+// an exporter.  Span attributes must never include secrets: URLs are recorded without their query
+// (which is where some exchanges put API keys and signatures), and headers are not recorded.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/cordialsys/offchain"
+
+type Config struct {
+	// OTLP/HTTP endpoint to export spans to, e.g. "localhost:4318".  The standard OTEL_EXPORTER_OTLP_*
+	// environment variables are also respected.
+	Endpoint string `yaml:"otlp_endpoint"`
+	// Export over plain http instead of https
+	Insecure bool `yaml:"insecure"`
+	// Fraction of new traces to sample, between 0 and 1 (default 1).  Traces started by callers
+	// follow the caller's sampling decision.
+	SampleRatio *float64 `yaml:"sample_ratio"`
+	// Defaults to "offchain"
+	ServiceName string `yaml:"service_name"`
+}
+
+func (cfg Config) Enabled() bool {
+	return cfg.Endpoint != "" ||
+		os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" ||
+		os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+func init() {
+	// propagate W3C trace context, whether or not spans are exported
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+}
+
+// Setup installs a tracer provider exporting to the configured OTLP endpoint.  The returned
+// function flushes any remaining spans and should be called on shutdown.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	if !cfg.Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+	opts := []otlptracehttp.Option{}
+	if cfg.Endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+	}
+	if cfg.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otlp exporter: %w", err)
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = "offchain"
+	}
+	ratio := 1.0
+	if cfg.SampleRatio != nil {
+		ratio = *cfg.SampleRatio
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts a span using the global tracer provider.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// End records the error, if any, on the span before ending it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// RedactURL returns the URL without any credentials, query or fragment.
+func RedactURL(u *url.URL) string {
+	redacted := url.URL{
+		Scheme: u.Scheme,
+		Host:   u.Host,
+		Path:   u.Path,
+	}
+	return redacted.String()
+}
+
+// Attributes common to outbound HTTP requests, excluding the query and headers.
+func RequestAttributes(method string, u *url.URL) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(method),
+		semconv.ServerAddress(u.Hostname()),
+		semconv.URLFull(RedactURL(u)),
+		semconv.URLPath(u.Path),
+	}
+}
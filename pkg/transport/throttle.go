@@ -0,0 +1,31 @@
+package transport
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// UsedWeightThrottle pauses until the end of the current window once the weight reported
+// in the header reaches 90% of the limit.  Binance reports the weight used in headers like
+// X-MBX-USED-WEIGHT-1M.
+func UsedWeightThrottle(header string, limit int, window time.Duration) Throttle {
+	return func(h http.Header, now time.Time) time.Duration {
+		used, err := strconv.Atoi(h.Get(header))
+		if err != nil || used < limit*9/10 {
+			return 0
+		}
+		return now.Truncate(window).Add(window).Sub(now)
+	}
+}
+
+// Throttles combines throttles, pausing for the longest of them.
+func Throttles(throttles ...Throttle) Throttle {
+	return func(h http.Header, now time.Time) time.Duration {
+		var pause time.Duration
+		for _, throttle := range throttles {
+			pause = max(pause, throttle(h, now))
+		}
+		return pause
+	}
+}
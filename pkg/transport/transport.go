@@ -0,0 +1,351 @@
+// Package transport provides the http.RoundTripper shared by the exchange API clients.
+//
+// Each venue gets a single token bucket, shared by every client of that venue, so that
+// many accounts on the same venue don't exceed the venue's rate limit together.  Idempotent
+// requests are retried with jitter on network errors, 5xx and 429 responses.  Other requests
+// (e.g. withdrawals) are never retried, as they may have been executed by the venue.
+//
+// Venues reject signatures that are too old, so the api clients pass a Sign function in the
+// request context (see WithSigner), which the transport calls before every attempt.  Waits
+// are also capped at MaxWait, so that a request isn't held long after it was made.
+package transport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cordialsys/offchain/pkg/metrics"
+	"github.com/cordialsys/offchain/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
+)
+
+// Throttle inspects venue specific rate limit headers, returning how long new requests
+// to the venue should be paused for.
+type Throttle func(header http.Header, now time.Time) time.Duration
+
+type Options struct {
+	// Steady-state requests per second allowed to the venue, and the burst above that rate.
+	RequestsPerSecond float64
+	Burst             int
+	// Number of retries after the first attempt.  Only idempotent requests are retried.
+	MaxRetries int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Longest time a request will wait before being sent, whether for the token bucket,
+	// a Retry-After, or a throttle pause.
+	MaxWait time.Duration
+	// Overall timeout for the http.Client returned by NewClient.
+	Timeout  time.Duration
+	Throttle Throttle
+}
+
+func DefaultOptions() Options {
+	return Options{
+		RequestsPerSecond: 5,
+		Burst:             10,
+		MaxRetries:        3,
+		MinBackoff:        200 * time.Millisecond,
+		MaxBackoff:        2 * time.Second,
+		MaxWait:           3 * time.Second,
+		Timeout:           30 * time.Second,
+	}
+}
+
+func (opts Options) WithRate(requestsPerSecond float64, burst int) Options {
+	opts.RequestsPerSecond = requestsPerSecond
+	opts.Burst = burst
+	return opts
+}
+
+func (opts Options) WithThrottle(throttle Throttle) Options {
+	opts.Throttle = throttle
+	return opts
+}
+
+// state shared by all clients of a venue
+type venue struct {
+	name        string
+	limiter     *rate.Limiter
+	lock        sync.Mutex
+	pausedUntil time.Time
+}
+
+var venuesLock sync.Mutex
+var venues = map[string]*venue{}
+
+func getVenue(name string, opts Options) *venue {
+	venuesLock.Lock()
+	defer venuesLock.Unlock()
+	v, ok := venues[name]
+	if !ok {
+		v = &venue{
+			name:    name,
+			limiter: rate.NewLimiter(rate.Limit(opts.RequestsPerSecond), opts.Burst),
+		}
+		venues[name] = v
+	}
+	return v
+}
+
+func (v *venue) pause(until time.Time) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if until.After(v.pausedUntil) {
+		v.pausedUntil = until
+	}
+}
+
+// wait blocks until a request may be sent, or fails if that would take longer than maxWait.
+func (v *venue) wait(ctx context.Context, maxWait time.Duration) error {
+	v.lock.Lock()
+	paused := time.Until(v.pausedUntil)
+	v.lock.Unlock()
+	if paused > maxWait {
+		return fmt.Errorf("rate limited by %s, try again in %s", v.name, paused.Round(time.Second))
+	}
+
+	reservation := v.limiter.Reserve()
+	delay := max(paused, reservation.Delay())
+	if delay > maxWait {
+		reservation.Cancel()
+		return fmt.Errorf("too many requests to %s, try again in %s", v.name, delay.Round(time.Millisecond))
+	}
+	if err := sleep(ctx, delay); err != nil {
+		reservation.Cancel()
+		return err
+	}
+	return nil
+}
+
+type authFailureKey struct{}
+
+// WithAuthFailureHandler returns a context whose requests call the handler when the venue rejects their
+// credentials, e.g. because the API key was rotated or revoked.
+func WithAuthFailureHandler(ctx context.Context, handler func()) context.Context {
+	return context.WithValue(ctx, authFailureKey{}, handler)
+}
+
+type signerKey struct{}
+
+// Sign authenticates a request, e.g. with a signature over the current time.
+type Sign func(req *http.Request) error
+
+// WithSigner returns a context whose requests are signed again just before every attempt, so that
+// retries are not rejected for carrying a stale timestamp.
+func WithSigner(ctx context.Context, sign Sign) context.Context {
+	return context.WithValue(ctx, signerKey{}, sign)
+}
+
+type Transport struct {
+	base  http.RoundTripper
+	venue *venue
+	opts  Options
+}
+
+var _ http.RoundTripper = &Transport{}
+
+// New returns a round tripper that shares its rate limit with every other transport of the same venue.
+// The rate options of the first transport created for a venue are used.
+func New(venueName string, opts Options) *Transport {
+	return &Transport{
+		base:  http.DefaultTransport,
+		venue: getVenue(venueName, opts),
+		opts:  opts,
+	}
+}
+
+// NewClient returns an http.Client for a venue using New.
+func NewClient(venueName string, opts Options) *http.Client {
+	return &http.Client{
+		Transport: New(venueName, opts),
+		Timeout:   opts.Timeout,
+	}
+}
+
+func (t *Transport) SetBase(base http.RoundTripper) {
+	t.base = base
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	// the trace context is not propagated to the venue
+	_, span := tracing.Start(req.Context(), fmt.Sprintf("%s %s %s", t.venue.name, req.Method, req.URL.Path),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(tracing.RequestAttributes(req.Method, req.URL)...),
+		trace.WithAttributes(attribute.String("offchain.venue", t.venue.name)),
+	)
+	defer func() {
+		if resp != nil {
+			span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+		}
+		tracing.End(span, err)
+	}()
+
+	ctx := req.Context()
+	retryable := isIdempotent(req.Method) && (req.Body == nil || req.Body == http.NoBody || req.GetBody != nil)
+	for attempt := 0; ; attempt++ {
+		if err := t.venue.wait(ctx, t.opts.MaxWait); err != nil {
+			return nil, err
+		}
+		attemptReq := req
+		if attempt > 0 {
+			var err error
+			attemptReq, err = rewind(req)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if sign, ok := ctx.Value(signerKey{}).(Sign); ok {
+			if attemptReq == req {
+				// the request passed to a round tripper must not be modified
+				attemptReq = req.Clone(ctx)
+			}
+			if err := sign(attemptReq); err != nil {
+				return nil, fmt.Errorf("failed to sign request: %w", err)
+			}
+		}
+
+		start := time.Now()
+		resp, err := t.base.RoundTrip(attemptReq)
+		t.record(req, resp, err, time.Since(start))
+		if resp != nil {
+			t.observe(req, resp)
+			if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+				if onAuthFailure, ok := ctx.Value(authFailureKey{}).(func()); ok {
+					onAuthFailure()
+				}
+			}
+		}
+		if !retryable || attempt >= t.opts.MaxRetries || ctx.Err() != nil {
+			return resp, err
+		}
+		delay, retry := t.retryDelay(resp, err, attempt)
+		if !retry {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		slog.DebugContext(req.Context(), "retrying request", "venue", t.venue.name, "method", req.Method, "path", req.URL.Path, "attempt", attempt+1, "delay", delay, "error", err)
+		span.AddEvent("retry", trace.WithAttributes(
+			attribute.Int("attempt", attempt+1),
+			attribute.String("delay", delay.String()),
+		))
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// record updates the metrics for a single attempt of a request to the venue.
+func (t *Transport) record(req *http.Request, resp *http.Response, err error, duration time.Duration) {
+	outcome := metrics.OutcomeNetworkError
+	if err == nil {
+		outcome = metrics.OutcomeForStatus(resp.StatusCode)
+	}
+	metrics.ExchangeRequests.WithLabelValues(t.venue.name, req.URL.Path, outcome).Inc()
+	metrics.ExchangeRequestDuration.WithLabelValues(t.venue.name, req.URL.Path, outcome).Observe(duration.Seconds())
+}
+
+// observe pauses the venue on rate limit responses, including those to requests that won't be retried.
+func (t *Transport) observe(req *http.Request, resp *http.Response) {
+	now := time.Now()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if retryAfter, ok := ParseRetryAfter(resp.Header, now); ok {
+			t.venue.pause(now.Add(retryAfter))
+		}
+	}
+	if t.opts.Throttle != nil {
+		if pause := t.opts.Throttle(resp.Header, now); pause > 0 {
+			slog.DebugContext(req.Context(), "throttling venue", "venue", t.venue.name, "pause", pause)
+			t.venue.pause(now.Add(pause))
+		}
+	}
+}
+
+func (t *Transport) retryDelay(resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if err != nil {
+		return t.backoff(attempt), true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+	default:
+		return 0, false
+	}
+	if retryAfter, ok := ParseRetryAfter(resp.Header, time.Now()); ok {
+		if retryAfter > t.opts.MaxWait {
+			return 0, false
+		}
+		return retryAfter, true
+	}
+	return t.backoff(attempt), true
+}
+
+// backoff is exponential with jitter over the upper half of the interval
+func (t *Transport) backoff(attempt int) time.Duration {
+	delay := t.opts.MinBackoff << attempt
+	if delay > t.opts.MaxBackoff || delay <= 0 {
+		delay = t.opts.MaxBackoff
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// ParseRetryAfter parses a Retry-After header given in either seconds or as an HTTP date.
+func ParseRetryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0), true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+func rewind(req *http.Request) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to rewind request body: %w", err)
+		}
+		clone.Body = body
+	}
+	return clone, nil
+}
+
+func sleep(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
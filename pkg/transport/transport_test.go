@@ -0,0 +1,243 @@
+package transport_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cordialsys/offchain/pkg/metrics"
+	"github.com/cordialsys/offchain/pkg/transport"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func testOptions() transport.Options {
+	opts := transport.DefaultOptions().WithRate(1000, 1000)
+	opts.MinBackoff = time.Millisecond
+	opts.MaxBackoff = 5 * time.Millisecond
+	opts.MaxWait = time.Second
+	return opts
+}
+
+func failingServer(failures int32, status int, header http.Header) (*httptest.Server, *atomic.Int32) {
+	calls := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(status)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	return server, calls
+}
+
+func TestRetryIdempotent(t *testing.T) {
+	for _, status := range []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusBadGateway} {
+		server, calls := failingServer(2, status, nil)
+		defer server.Close()
+		cli := transport.NewClient("test-retry-"+http.StatusText(status), testOptions())
+
+		resp, err := cli.Get(server.URL)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.EqualValues(t, 3, calls.Load())
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	server, calls := failingServer(10, http.StatusServiceUnavailable, nil)
+	defer server.Close()
+	cli := transport.NewClient("test-gives-up", testOptions())
+
+	resp, err := cli.Get(server.URL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	require.EqualValues(t, 4, calls.Load())
+}
+
+func TestNoRetryClientError(t *testing.T) {
+	server, calls := failingServer(1, http.StatusBadRequest, nil)
+	defer server.Close()
+	cli := transport.NewClient("test-client-error", testOptions())
+
+	resp, err := cli.Get(server.URL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	require.EqualValues(t, 1, calls.Load())
+}
+
+func TestNoRetryPost(t *testing.T) {
+	server, calls := failingServer(1, http.StatusServiceUnavailable, nil)
+	defer server.Close()
+	cli := transport.NewClient("test-post", testOptions())
+
+	resp, err := cli.Post(server.URL, "application/json", strings.NewReader(`{"amount":"1"}`))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	require.EqualValues(t, 1, calls.Load())
+}
+
+func TestRetryAfter(t *testing.T) {
+	// within the max wait
+	server, calls := failingServer(1, http.StatusTooManyRequests, http.Header{"Retry-After": []string{"0"}})
+	defer server.Close()
+	cli := transport.NewClient("test-retry-after", testOptions())
+	resp, err := cli.Get(server.URL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.EqualValues(t, 2, calls.Load())
+
+	// exceeds the max wait, so the response is returned and the venue is paused
+	server, calls = failingServer(1, http.StatusTooManyRequests, http.Header{"Retry-After": []string{"60"}})
+	defer server.Close()
+	cli = transport.NewClient("test-retry-after-long", testOptions())
+	resp, err = cli.Get(server.URL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	require.EqualValues(t, 1, calls.Load())
+
+	_, err = cli.Get(server.URL)
+	require.ErrorContains(t, err, "rate limited by test-retry-after-long")
+	require.EqualValues(t, 1, calls.Load())
+}
+
+func TestThrottleSharedByVenue(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Mbx-Used-Weight-1m", "5999")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	opts := testOptions().WithThrottle(transport.UsedWeightThrottle("X-Mbx-Used-Weight-1m", 6000, time.Hour))
+
+	resp, err := transport.NewClient("test-throttle", opts).Get(server.URL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// a separate client for the same venue is paused too
+	_, err = transport.NewClient("test-throttle", opts).Get(server.URL)
+	require.ErrorContains(t, err, "rate limited by test-throttle")
+
+	// other venues are unaffected
+	resp, err = transport.NewClient("test-throttle-other", testOptions()).Get(server.URL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	d, ok := transport.ParseRetryAfter(http.Header{"Retry-After": []string{"3"}}, now)
+	require.True(t, ok)
+	require.Equal(t, 3*time.Second, d)
+
+	d, ok = transport.ParseRetryAfter(http.Header{"Retry-After": []string{now.Add(time.Minute).Format(http.TimeFormat)}}, now)
+	require.True(t, ok)
+	require.Equal(t, time.Minute, d)
+
+	_, ok = transport.ParseRetryAfter(http.Header{}, now)
+	require.False(t, ok)
+
+	_, ok = transport.ParseRetryAfter(http.Header{"Retry-After": []string{"soon"}}, now)
+	require.False(t, ok)
+}
+
+func TestMetrics(t *testing.T) {
+	server, _ := failingServer(1, http.StatusServiceUnavailable, nil)
+	defer server.Close()
+	cli := transport.NewClient("test-metrics", testOptions())
+
+	resp, err := cli.Get(server.URL + "/api/v1/balances")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// each attempt is counted
+	require.EqualValues(t, 1, testutil.ToFloat64(metrics.ExchangeRequests.WithLabelValues("test-metrics", "/api/v1/balances", metrics.OutcomeServerError)))
+	require.EqualValues(t, 1, testutil.ToFloat64(metrics.ExchangeRequests.WithLabelValues("test-metrics", "/api/v1/balances", metrics.OutcomeSuccess)))
+}
+
+func TestTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	server, _ := failingServer(1, http.StatusServiceUnavailable, nil)
+	defer server.Close()
+	cli := transport.NewClient("test-tracing", testOptions())
+
+	resp, err := cli.Get(server.URL + "/api/v5/account/balance?apiKey=secret-key&signature=abcd")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	require.Equal(t, "test-tracing GET /api/v5/account/balance", span.Name)
+	require.Len(t, span.Events, 1)
+	require.Equal(t, "retry", span.Events[0].Name)
+	for _, attr := range span.Attributes {
+		require.NotContains(t, attr.Value.Emit(), "secret-key", string(attr.Key))
+		require.NotContains(t, attr.Value.Emit(), "signature", string(attr.Key))
+	}
+	require.Contains(t, span.Attributes, attribute.Int("http.response.status_code", http.StatusOK))
+}
+
+func TestAuthFailureHandler(t *testing.T) {
+	server, _ := failingServer(1, http.StatusUnauthorized, nil)
+	defer server.Close()
+	cli := transport.NewClient("test-auth-failure", testOptions())
+
+	failures := 0
+	ctx := transport.WithAuthFailureHandler(context.Background(), func() { failures++ })
+	for range 2 {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		resp, err := cli.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+	require.Equal(t, 1, failures)
+}
+
+func TestSignEachAttempt(t *testing.T) {
+	timestamps := []string{}
+	attempts := atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timestamps = append(timestamps, r.Header.Get("X-Timestamp"))
+		if attempts.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	cli := transport.NewClient("test-sign", testOptions())
+
+	signed := 0
+	ctx := transport.WithSigner(context.Background(), func(req *http.Request) error {
+		signed++
+		req.Header.Set("X-Timestamp", strconv.Itoa(signed))
+		return nil
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	resp, err := cli.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, []string{"1", "2", "3"}, timestamps)
+	require.Empty(t, req.Header.Get("X-Timestamp"))
+}
@@ -0,0 +1,326 @@
+// Package webhook delivers signed event notifications to configured URLs.
+//
+// Events are signed following the Standard Webhooks scheme (https://www.standardwebhooks.com):
+// the signed content is "<webhook-id>.<webhook-timestamp>.<body>", and the signature is sent in the
+// webhook-signature header as "v1,<base64 hmac-sha256>" or "v1a,<base64 ed25519>".
+//
+// Deliveries are retried with backoff.  Events that can't be delivered are appended to a
+// dead-letter file, so they can be inspected and replayed.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cordialsys/offchain/pkg/httpsignature/signer"
+	"github.com/google/uuid"
+)
+
+const (
+	HeaderId        = "webhook-id"
+	HeaderTimestamp = "webhook-timestamp"
+	HeaderSignature = "webhook-signature"
+)
+
+type Event struct {
+	Id        string          `json:"id"`
+	Type      string          `json:"type"`
+	Timestamp time.Time       `json:"timestamp"`
+	Data      json.RawMessage `json:"data"`
+}
+
+func NewEvent(eventType string, data any) (*Event, error) {
+	bz, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return &Event{
+		Id:        "evt_" + uuid.NewString(),
+		Type:      eventType,
+		Timestamp: time.Now().UTC(),
+		Data:      bz,
+	}, nil
+}
+
+// Signer produces the value of the webhook-signature header.
+type Signer interface {
+	Sign(id string, timestamp int64, body []byte) (string, error)
+}
+
+func signedContent(id string, timestamp int64, body []byte) []byte {
+	return []byte(fmt.Sprintf("%s.%d.%s", id, timestamp, body))
+}
+
+type HmacSigner struct {
+	secret []byte
+}
+
+var _ Signer = &HmacSigner{}
+
+func NewHmacSigner(secret []byte) *HmacSigner {
+	return &HmacSigner{secret: secret}
+}
+
+func (s *HmacSigner) Sign(id string, timestamp int64, body []byte) (string, error) {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(signedContent(id, timestamp, body))
+	return "v1," + base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+type Ed25519Signer struct {
+	signer signer.SignerI
+}
+
+var _ Signer = &Ed25519Signer{}
+
+func NewEd25519Signer(signer signer.SignerI) *Ed25519Signer {
+	return &Ed25519Signer{signer: signer}
+}
+
+func (s *Ed25519Signer) Sign(id string, timestamp int64, body []byte) (string, error) {
+	sig, err := s.signer.Sign(signedContent(id, timestamp, body))
+	if err != nil {
+		return "", err
+	}
+	return "v1a," + base64.StdEncoding.EncodeToString(sig), nil
+}
+
+func parseHeaders(header http.Header) (string, int64, []string, error) {
+	id := header.Get(HeaderId)
+	timestamp, err := strconv.ParseInt(header.Get(HeaderTimestamp), 10, 64)
+	if id == "" || err != nil {
+		return "", 0, nil, fmt.Errorf("missing or invalid webhook headers")
+	}
+	return id, timestamp, strings.Fields(header.Get(HeaderSignature)), nil
+}
+
+// VerifyHmac checks that a received webhook was signed with the shared secret.
+func VerifyHmac(secret []byte, header http.Header, body []byte) error {
+	id, timestamp, signatures, err := parseHeaders(header)
+	if err != nil {
+		return err
+	}
+	expected, _ := NewHmacSigner(secret).Sign(id, timestamp, body)
+	for _, sig := range signatures {
+		if hmac.Equal([]byte(sig), []byte(expected)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid webhook signature")
+}
+
+// VerifyEd25519 checks that a received webhook was signed by the public key.
+func VerifyEd25519(publicKey ed25519.PublicKey, header http.Header, body []byte) error {
+	id, timestamp, signatures, err := parseHeaders(header)
+	if err != nil {
+		return err
+	}
+	for _, sig := range signatures {
+		encoded, ok := strings.CutPrefix(sig, "v1a,")
+		if !ok {
+			continue
+		}
+		bz, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			continue
+		}
+		if ed25519.Verify(publicKey, signedContent(id, timestamp, body), bz) {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid webhook signature")
+}
+
+type Endpoint struct {
+	Url    string
+	Signer Signer
+	// Event types to deliver to the endpoint.  All events are delivered if empty.
+	Events []string
+}
+
+func (e *Endpoint) Wants(eventType string) bool {
+	return len(e.Events) == 0 || slices.Contains(e.Events, eventType)
+}
+
+type Options struct {
+	MaxAttempts int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+	// Timeout of each delivery attempt
+	Timeout time.Duration
+	// Events that could not be delivered are appended here, if set.
+	DeadLetterFile string
+}
+
+func DefaultOptions() Options {
+	return Options{
+		MaxAttempts: 8,
+		MinBackoff:  time.Second,
+		MaxBackoff:  5 * time.Minute,
+		Timeout:     10 * time.Second,
+	}
+}
+
+// An event that could not be delivered to an endpoint
+type DeadLetter struct {
+	Url      string    `json:"url"`
+	Event    *Event    `json:"event"`
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error"`
+	Time     time.Time `json:"time"`
+}
+
+type Dispatcher struct {
+	endpoints  []Endpoint
+	opts       Options
+	httpClient *http.Client
+
+	deadLetterLock sync.Mutex
+}
+
+func NewDispatcher(endpoints []Endpoint, opts Options) *Dispatcher {
+	return &Dispatcher{
+		endpoints:  endpoints,
+		opts:       opts,
+		httpClient: &http.Client{Timeout: opts.Timeout},
+	}
+}
+
+// Send delivers the event to every endpoint that wants it, retrying failed deliveries.
+// It returns once every delivery has succeeded or been dead-lettered.
+func (d *Dispatcher) Send(ctx context.Context, event *Event) {
+	wg := sync.WaitGroup{}
+	for i := range d.endpoints {
+		endpoint := &d.endpoints[i]
+		if !endpoint.Wants(event.Type) {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.deliver(ctx, endpoint, event)
+		}()
+	}
+	wg.Wait()
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, endpoint *Endpoint, event *Event) {
+	log := slog.With("url", endpoint.Url, "event", event.Id, "type", event.Type)
+	var lastErr error
+	attempt := 1
+	for ; ; attempt++ {
+		lastErr = d.post(ctx, endpoint, event)
+		if lastErr == nil {
+			log.Debug("delivered webhook", "attempt", attempt)
+			return
+		}
+		if attempt >= d.opts.MaxAttempts {
+			break
+		}
+		delay := d.backoff(attempt - 1)
+		log.Warn("webhook delivery failed", "attempt", attempt, "retry_in", delay, "error", lastErr)
+		if err := sleep(ctx, delay); err != nil {
+			lastErr = fmt.Errorf("%v (gave up: %v)", lastErr, err)
+			break
+		}
+	}
+	log.Error("webhook could not be delivered", "attempts", attempt, "error", lastErr)
+	d.deadLetter(&DeadLetter{
+		Url:      endpoint.Url,
+		Event:    event,
+		Attempts: attempt,
+		Error:    lastErr.Error(),
+		Time:     time.Now().UTC(),
+	})
+}
+
+func (d *Dispatcher) post(ctx context.Context, endpoint *Endpoint, event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderId, event.Id)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	if endpoint.Signer != nil {
+		signature, err := endpoint.Signer.Sign(event.Id, timestamp, body)
+		if err != nil {
+			return fmt.Errorf("failed to sign webhook: %w", err)
+		}
+		req.Header.Set(HeaderSignature, signature)
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.opts.MinBackoff << attempt
+	if delay > d.opts.MaxBackoff || delay <= 0 {
+		delay = d.opts.MaxBackoff
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+func (d *Dispatcher) deadLetter(letter *DeadLetter) {
+	if d.opts.DeadLetterFile == "" {
+		return
+	}
+	d.deadLetterLock.Lock()
+	defer d.deadLetterLock.Unlock()
+	line, err := json.Marshal(letter)
+	if err != nil {
+		slog.Error("failed to encode dead letter", "error", err)
+		return
+	}
+	f, err := os.OpenFile(d.opts.DeadLetterFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		slog.Error("failed to open dead letter file", "error", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		slog.Error("failed to write dead letter", "error", err)
+	}
+}
+
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,122 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cordialsys/offchain/pkg/httpsignature/signer"
+	"github.com/cordialsys/offchain/pkg/webhook"
+	"github.com/stretchr/testify/require"
+)
+
+func testOptions(t *testing.T) webhook.Options {
+	opts := webhook.DefaultOptions()
+	opts.MaxAttempts = 3
+	opts.MinBackoff = time.Millisecond
+	opts.MaxBackoff = 5 * time.Millisecond
+	opts.DeadLetterFile = filepath.Join(t.TempDir(), "dead-letters.jsonl")
+	return opts
+}
+
+func TestDeliverHmac(t *testing.T) {
+	secret := []byte("shared-secret")
+	received := make(chan *webhook.Event, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := webhook.VerifyHmac(secret, r.Header, body); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		event := &webhook.Event{}
+		_ = json.Unmarshal(body, event)
+		received <- event
+	}))
+	defer server.Close()
+
+	event, err := webhook.NewEvent("withdrawal.success", map[string]string{"id": "123"})
+	require.NoError(t, err)
+	dispatcher := webhook.NewDispatcher([]webhook.Endpoint{
+		{Url: server.URL, Signer: webhook.NewHmacSigner(secret)},
+	}, testOptions(t))
+	dispatcher.Send(context.Background(), event)
+
+	got := <-received
+	require.Equal(t, event.Id, got.Id)
+	require.Equal(t, "withdrawal.success", got.Type)
+	require.JSONEq(t, `{"id":"123"}`, string(got.Data))
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"id":"evt_1"}`)
+	header := http.Header{}
+	header.Set(webhook.HeaderId, "evt_1")
+	header.Set(webhook.HeaderTimestamp, "1700000000")
+
+	sig, err := webhook.NewHmacSigner([]byte("secret")).Sign("evt_1", 1700000000, body)
+	require.NoError(t, err)
+	header.Set(webhook.HeaderSignature, sig)
+	require.NoError(t, webhook.VerifyHmac([]byte("secret"), header, body))
+	require.Error(t, webhook.VerifyHmac([]byte("other"), header, body))
+	require.Error(t, webhook.VerifyHmac([]byte("secret"), header, []byte(`{"id":"evt_2"}`)))
+
+	key, err := signer.NewEd25519Signer(strings.Repeat("ab", 32))
+	require.NoError(t, err)
+	sig, err = webhook.NewEd25519Signer(key).Sign("evt_1", 1700000000, body)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(sig, "v1a,"))
+	header.Set(webhook.HeaderSignature, sig)
+	require.NoError(t, webhook.VerifyEd25519(key.PublicKey(), header, body))
+	header.Set(webhook.HeaderTimestamp, "1700000001")
+	require.Error(t, webhook.VerifyEd25519(key.PublicKey(), header, body))
+}
+
+func TestRetryThenDeadLetter(t *testing.T) {
+	calls := &atomic.Int32{}
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	recovering := &atomic.Int32{}
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if recovering.Add(1) < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer flaky.Close()
+
+	opts := testOptions(t)
+	dispatcher := webhook.NewDispatcher([]webhook.Endpoint{
+		{Url: failing.URL},
+		{Url: flaky.URL},
+		// not subscribed
+		{Url: failing.URL, Events: []string{"transfer.success"}},
+	}, opts)
+	event, err := webhook.NewEvent("withdrawal.failed", map[string]string{"id": "123"})
+	require.NoError(t, err)
+	dispatcher.Send(context.Background(), event)
+
+	require.EqualValues(t, 3, calls.Load())
+	require.EqualValues(t, 3, recovering.Load())
+
+	bz, err := os.ReadFile(opts.DeadLetterFile)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(bz)), "\n")
+	require.Len(t, lines, 1)
+	letter := &webhook.DeadLetter{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), letter))
+	require.Equal(t, failing.URL, letter.Url)
+	require.Equal(t, event.Id, letter.Event.Id)
+	require.Equal(t, 3, letter.Attempts)
+	require.Contains(t, letter.Error, "status 503")
+}
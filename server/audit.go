@@ -0,0 +1,82 @@
+package server
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/cordialsys/offchain/pkg/audit"
+	"github.com/cordialsys/offchain/pkg/httpsignature"
+	"github.com/cordialsys/offchain/server/endpoints"
+	"github.com/cordialsys/offchain/server/servererrors"
+	"github.com/gofiber/fiber/v2"
+)
+
+// auditMiddleware records the request and its outcome to the audit log, if one is configured.
+// It must run after authentication.  Requests are refused once the log has failed, so that none
+// are made without being recorded.
+func auditMiddleware(log *audit.Log) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if log == nil {
+			return c.Next()
+		}
+		if err := log.Err(); err != nil {
+			slog.ErrorContext(c.UserContext(), "refusing request as the audit log failed", "error", err, "path", c.Path())
+			return servererrors.Unavailablef("the audit log can't be written")
+		}
+		start := time.Now()
+		handlerErr := c.Next()
+		latency := time.Since(start)
+
+		method, keyId := endpoints.UnwrapIdentity(c)
+		if method == endpoints.AuthNone {
+			// public read endpoint
+			return handlerErr
+		}
+		subaccount, _ := c.Locals("sub-account").(string)
+		entry := &audit.Entry{
+			Time:       start,
+			Method:     c.Method(),
+			Path:       c.Path(),
+			Exchange:   c.Params("exchange"),
+			Auth:       string(method),
+			KeyId:      keyId,
+			SubAccount: subaccount,
+			LatencyMs:  latency.Milliseconds(),
+		}
+		if method == endpoints.AuthHttpSignature {
+			entry.Signature = c.Get(httpsignature.HeaderSignature)
+			entry.SignatureInput = c.Get(httpsignature.HeaderSignatureInput)
+		}
+
+		args := map[string]any{}
+		for key, value := range c.AllParams() {
+			args[key] = value
+		}
+		query := map[string]string{}
+		for key, value := range c.Queries() {
+			query[key] = value
+		}
+		if len(query) > 0 {
+			args["query"] = query
+		}
+		if body, err := audit.NormalizeJSON(c.Body()); err == nil && body != nil {
+			args["body"] = body
+		}
+		entry.Args, _ = audit.Normalize(args)
+
+		entry.Status = statusOf(c, handlerErr)
+		if handlerErr != nil {
+			entry.Error = handlerErr.Error()
+			if apiErr, ok := handlerErr.(*servererrors.ErrorResponse); ok {
+				entry.Error = apiErr.Message
+			}
+		} else {
+			entry.Response, _ = audit.NormalizeJSON(c.Response().Body())
+		}
+
+		if err := log.Append(entry); err != nil {
+			slog.ErrorContext(c.UserContext(), "failed to write audit log", "error", err, "path", entry.Path, "key_id", keyId)
+		}
+		return handlerErr
+	}
+}
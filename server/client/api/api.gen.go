@@ -3,6 +3,12 @@
 // Code generated by github.com/oapi-codegen/oapi-codegen/v2 version v2.4.1 DO NOT EDIT.
 package api
 
+// Defines values for IntentApprovalDecision.
+const (
+	DecisionApprove IntentApprovalDecision = "approve"
+	DecisionReject  IntentApprovalDecision = "reject"
+)
+
 // Defines values for OperationStatus.
 const (
 	Failed  OperationStatus = "failed"
@@ -10,6 +16,18 @@ const (
 	Success OperationStatus = "success"
 )
 
+// Defines values for WithdrawalIntentStatus.
+const (
+	IntentCancelled       WithdrawalIntentStatus = "cancelled"
+	IntentExecuted        WithdrawalIntentStatus = "executed"
+	IntentExecuting       WithdrawalIntentStatus = "executing"
+	IntentExpired         WithdrawalIntentStatus = "expired"
+	IntentFailed          WithdrawalIntentStatus = "failed"
+	IntentPendingApproval WithdrawalIntentStatus = "pending-approval"
+	IntentRejected        WithdrawalIntentStatus = "rejected"
+	IntentScheduled       WithdrawalIntentStatus = "scheduled"
+)
+
 // AccountType defines model for AccountType.
 type AccountType struct {
 	// Aliases Aliases for the account type, e.g. `funding`, `trading`.
@@ -69,6 +87,50 @@ type Balance struct {
 // Decimal Decimal formatted string.
 type Decimal = string
 
+// HistoricalTransfer defines model for HistoricalTransfer.
+type HistoricalTransfer struct {
+	Amount string `json:"amount"`
+
+	// Comment Comment by the exchange on the status of the transfer.
+	Comment *string `json:"comment,omitempty"`
+
+	// From ID of the account the funds were sent from, if known.
+	From *string `json:"from,omitempty"`
+
+	// FromType The account type used by the exchange, if account types are used.  E.g. "ISOLATED_MARGIN" or "trading".  An alias for the account type may also be used.
+	//
+	// See `/exchanges/{exchange}/account-types` endpoint.
+	//
+	// By default, the first account type will be used.
+	FromType *AccountTypeID `json:"from_type,omitempty"`
+
+	// Id ID by the exchange for the transfer
+	Id string `json:"id"`
+
+	// Notes Other exchange-specific metadata about the transfer.
+	Notes *map[string]string `json:"notes,omitempty"`
+
+	// PageToken Page token to list the transfers after this one.
+	PageToken *string `json:"page_token,omitempty"`
+
+	// Status Status of an upstream operation.
+	Status OperationStatus `json:"status"`
+	Symbol string          `json:"symbol"`
+
+	// Timestamp Unix milliseconds of when the transfer was created.
+	Timestamp int64 `json:"timestamp"`
+
+	// To ID of the account the funds were sent to, if known.
+	To *string `json:"to,omitempty"`
+
+	// ToType The account type used by the exchange, if account types are used.  E.g. "ISOLATED_MARGIN" or "trading".  An alias for the account type may also be used.
+	//
+	// See `/exchanges/{exchange}/account-types` endpoint.
+	//
+	// By default, the first account type will be used.
+	ToType *AccountTypeID `json:"to_type,omitempty"`
+}
+
 // HistoricalWithdrawal defines model for HistoricalWithdrawal.
 type HistoricalWithdrawal struct {
 	Amount string `json:"amount"`
@@ -93,6 +155,29 @@ type HistoricalWithdrawal struct {
 	TransactionId *string `json:"transaction_id,omitempty"`
 }
 
+// IntentApproval defines model for IntentApproval.
+type IntentApproval struct {
+	Decision IntentApprovalDecision `json:"decision"`
+
+	// KeyId ID of the approver.
+	KeyId string `json:"key_id"`
+
+	// Timestamp Unix milliseconds of the decision.
+	Timestamp int64 `json:"timestamp"`
+}
+
+// IntentApprovalDecision defines model for IntentApproval.Decision.
+type IntentApprovalDecision string
+
+// IntentDecision defines model for IntentDecision.
+type IntentDecision struct {
+	// KeyId ID of the approver.
+	KeyId string `json:"key_id"`
+
+	// Signature Hex encoded signature by the approver of `approve <digest>` or `reject <digest>`.
+	Signature string `json:"signature"`
+}
+
 // OperationStatus Status of an upstream operation.
 type OperationStatus string
 
@@ -158,10 +243,73 @@ type Withdrawal struct {
 	Symbol  *string    `json:"symbol,omitempty"`
 }
 
+// WithdrawalIntent A withdrawal that is waiting for approval, or for its delay to pass.
+type WithdrawalIntent struct {
+	Approvals []IntentApproval `json:"approvals"`
+
+	// CancelledBy ID of the key that cancelled the intent.
+	CancelledBy *string `json:"cancelled_by,omitempty"`
+
+	// CreatedAt Unix milliseconds of when the intent was created.
+	CreatedAt int64 `json:"created_at"`
+
+	// CreatedBy ID of the key that requested the withdrawal.
+	CreatedBy string `json:"created_by"`
+
+	// Digest Hex encoded SHA-256 of the canonical form of the intent.  Approvers sign `approve <digest>` or `reject <digest>`.
+	Digest string `json:"digest"`
+
+	// Error Error returned by the exchange, if the withdrawal failed.
+	Error    *string `json:"error,omitempty"`
+	Exchange string  `json:"exchange"`
+
+	// ExpiresAt Unix milliseconds after which the intent can no longer be approved.
+	ExpiresAt int64  `json:"expires_at"`
+	Id        string `json:"id"`
+
+	// Quorum Number of approvals needed.
+	Quorum int `json:"quorum"`
+
+	// ScheduledAt Unix milliseconds of when the withdrawal is made, once approved.
+	ScheduledAt *int64                 `json:"scheduled_at,omitempty"`
+	Status      WithdrawalIntentStatus `json:"status"`
+	SubAccount  *string                `json:"sub_account,omitempty"`
+	Withdrawal  Withdrawal             `json:"withdrawal"`
+
+	// WithdrawalId ID of the withdrawal returned by the exchange, once made.
+	WithdrawalId *string `json:"withdrawal_id,omitempty"`
+}
+
+// WithdrawalIntentStatus defines model for WithdrawalIntentStatus.
+type WithdrawalIntentStatus string
+
+// WithdrawalQuote Estimated fee and net amount of a withdrawal.
+type WithdrawalQuote struct {
+	// Amount Decimal formatted string.
+	Amount Decimal `json:"amount"`
+
+	// Fee Decimal formatted string.
+	Fee Decimal `json:"fee"`
+
+	// FeeDeducted If true, the fee is deducted from the amount.  Otherwise the fee is charged on top of the amount.
+	FeeDeducted bool `json:"fee_deducted"`
+
+	// FeeSymbol Symbol of the asset the fee is charged in.
+	FeeSymbol string `json:"fee_symbol"`
+
+	// NetAmount Decimal formatted string.
+	NetAmount Decimal `json:"net_amount"`
+	Network   string  `json:"network"`
+	Symbol    string  `json:"symbol"`
+}
+
 // WithdrawalResponse defines model for WithdrawalResponse.
 type WithdrawalResponse struct {
 	Id string `json:"id"`
 
+	// IntentId ID of the withdrawal intent, if the withdrawal needs approval.  The id is empty until the withdrawal is made.
+	IntentId *string `json:"intent_id,omitempty"`
+
 	// Status Status of an upstream operation.
 	Status OperationStatus `json:"status"`
 }
@@ -169,6 +317,12 @@ type WithdrawalResponse struct {
 // SubAccount defines model for sub-account.
 type SubAccount = string
 
+// GetTransferStatusParams defines parameters for GetTransferStatus.
+type GetTransferStatusParams struct {
+	// SubAccount Optionally specify a sub-account to execute this request on.  May specify the ID or alias for the sub-account.
+	SubAccount *SubAccount `form:"sub-account,omitempty" json:"sub-account,omitempty"`
+}
+
 // ListAllBalancesParams defines parameters for ListAllBalances.
 type ListAllBalancesParams struct {
 	// SubAccount Optionally specify a sub-account to execute this request on.  May specify the ID or alias for the sub-account.
@@ -193,12 +347,28 @@ type GetDepositAddressParams struct {
 	For *string `form:"for,omitempty" json:"for,omitempty"`
 }
 
+// ListTransferHistoryParams defines parameters for ListTransferHistory.
+type ListTransferHistoryParams struct {
+	// SubAccount Optionally specify a sub-account to execute this request on.  May specify the ID or alias for the sub-account.
+	SubAccount *SubAccount `form:"sub-account,omitempty" json:"sub-account,omitempty"`
+	Limit      *int        `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// PageToken The page_token of the last transfer on the previous page, or a timestamp (unix milliseconds) to list the transfers at or before.
+	PageToken *string `form:"page_token,omitempty" json:"page_token,omitempty"`
+}
+
 // CreateTransferParams defines parameters for CreateTransfer.
 type CreateTransferParams struct {
 	// SubAccount Optionally specify a sub-account to execute this request on.  May specify the ID or alias for the sub-account.
 	SubAccount *SubAccount `form:"sub-account,omitempty" json:"sub-account,omitempty"`
 }
 
+// EstimateWithdrawalParams defines parameters for EstimateWithdrawal.
+type EstimateWithdrawalParams struct {
+	// SubAccount Optionally specify a sub-account to execute this request on.  May specify the ID or alias for the sub-account.
+	SubAccount *SubAccount `form:"sub-account,omitempty" json:"sub-account,omitempty"`
+}
+
 // ListWithdrawalsParams defines parameters for ListWithdrawals.
 type ListWithdrawalsParams struct {
 	// SubAccount Optionally specify a sub-account to execute this request on.  May specify the ID or alias for the sub-account.
@@ -214,5 +384,14 @@ type CreateWithdrawalParams struct {
 // CreateTransferJSONRequestBody defines body for CreateTransfer for application/json ContentType.
 type CreateTransferJSONRequestBody = Transfer
 
+// EstimateWithdrawalJSONRequestBody defines body for EstimateWithdrawal for application/json ContentType.
+type EstimateWithdrawalJSONRequestBody = Withdrawal
+
 // CreateWithdrawalJSONRequestBody defines body for CreateWithdrawal for application/json ContentType.
 type CreateWithdrawalJSONRequestBody = Withdrawal
+
+// ApproveWithdrawalIntentJSONRequestBody defines body for ApproveWithdrawalIntent for application/json ContentType.
+type ApproveWithdrawalIntentJSONRequestBody = IntentDecision
+
+// RejectWithdrawalIntentJSONRequestBody defines body for RejectWithdrawalIntent for application/json ContentType.
+type RejectWithdrawalIntentJSONRequestBody = IntentDecision
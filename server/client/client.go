@@ -2,6 +2,8 @@ package client
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -13,7 +15,13 @@ import (
 	oc "github.com/cordialsys/offchain"
 	"github.com/cordialsys/offchain/pkg/httpsignature"
 	"github.com/cordialsys/offchain/pkg/httpsignature/signer"
+	"github.com/cordialsys/offchain/pkg/httpsignature/verifier"
+	"github.com/cordialsys/offchain/pkg/logging"
+	"github.com/cordialsys/offchain/pkg/tracing"
 	"github.com/cordialsys/offchain/server/client/api"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Client represents a client for the offchain server API
@@ -25,6 +33,9 @@ type Client struct {
 	bearerToken string
 	signer      signer.SignerI
 	subAccount  string
+
+	// If set, responses must be signed by the server's key
+	responseVerifier verifier.VerifierI
 }
 
 // ClientOption is a function that configures a Client
@@ -50,6 +61,14 @@ func WithSubAccount(subAccount string) ClientOption {
 	}
 }
 
+// WithResponseVerifier configures the client to check that responses are signed by the server's key, rejecting
+// any that aren't, or that are stale.  Use verifier.WithKeyId to also check the keyid of the signatures.
+func WithResponseVerifier(verifier verifier.VerifierI) ClientOption {
+	return func(c *Client) {
+		c.responseVerifier = verifier
+	}
+}
+
 // WithTimeout configures the client's HTTP timeout
 func WithTimeout(timeout time.Duration) ClientOption {
 	return func(c *Client) {
@@ -57,6 +76,15 @@ func WithTimeout(timeout time.Duration) ClientOption {
 	}
 }
 
+// WithTLSConfig configures the TLS used to connect to the server, e.g. to present a client certificate.
+func WithTLSConfig(config *tls.Config) ClientOption {
+	return func(c *Client) {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = config
+		c.httpClient.Transport = transport
+	}
+}
+
 // NewClient creates a new API client
 func NewClient(baseURL string, options ...ClientOption) *Client {
 	client := &Client{
@@ -78,14 +106,22 @@ type APIError struct {
 	Code    int    `json:"code"`
 	Status  string `json:"status"`
 	Message string `json:"message"`
+	// Identifies the request in the server's logs
+	RequestId string `json:"request_id,omitempty"`
 }
 
 func (e *APIError) Error() string {
+	if e.RequestId != "" {
+		return fmt.Sprintf("[%s] %s (request id %s)", e.Status, e.Message, e.RequestId)
+	}
 	return fmt.Sprintf("[%s] %s", e.Status, e.Message)
 }
 
 // doRequest performs an HTTP request with the appropriate authentication and handles response parsing
-func (c *Client) doRequest(method, path string, queryParams url.Values, body interface{}, result interface{}) error {
+func (c *Client) doRequest(ctx context.Context, method, path string, queryParams url.Values, body interface{}, result interface{}) (err error) {
+	ctx, span := tracing.Start(ctx, fmt.Sprintf("offchain %s %s", method, path), trace.WithSpanKind(trace.SpanKindClient))
+	defer func() { tracing.End(span, err) }()
+
 	// Construct the full URL
 	reqURL, err := url.Parse(c.baseURL)
 	if err != nil {
@@ -112,14 +148,23 @@ func (c *Client) doRequest(method, path string, queryParams url.Values, body int
 		}
 		bodyReader = bytes.NewReader(bodyBytes)
 	}
-	log := slog.With("method", method, "url", reqURL.String(), "path", path, "query", reqURL.RawQuery, "body", string(bodyBytes))
+	log := slog.With("method", method, "url", reqURL, "path", path, "body", logging.Body(bodyBytes))
 
 	// Create the request
-	req, err := http.NewRequest(method, reqURL.String(), bodyReader)
+	req, err := http.NewRequestWithContext(ctx, method, reqURL.String(), bodyReader)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
+	// Propagate the trace context to the server
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	span.SetAttributes(tracing.RequestAttributes(method, reqURL)...)
+
+	// Continue the request id of the request being handled, if any
+	if requestId := logging.RequestId(ctx); requestId != "" {
+		req.Header.Set(logging.RequestIdHeader, requestId)
+	}
+
 	// Set content type for requests with body
 	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
@@ -144,7 +189,7 @@ func (c *Client) doRequest(method, path string, queryParams url.Values, body int
 	} else {
 		log = log.With("auth", "none")
 	}
-	log.Debug("offchain request")
+	log.DebugContext(ctx, "offchain request")
 
 	// Execute the request
 	resp, err := c.httpClient.Do(req)
@@ -152,12 +197,17 @@ func (c *Client) doRequest(method, path string, queryParams url.Values, body int
 		return fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
+	if c.responseVerifier != nil {
+		if _, err := httpsignature.VerifyResponse(resp, c.responseVerifier); err != nil {
+			return fmt.Errorf("could not verify the server's response (status %d): %w", resp.StatusCode, err)
+		}
+	}
 	responseBody, _ := io.ReadAll(resp.Body)
 
-	log.Debug("offchain response", "status", resp.StatusCode, "body", string(responseBody))
+	log.DebugContext(ctx, "offchain response", "status", resp.StatusCode, "body", logging.Body(responseBody))
 
-	// Check for non-200 status codes
-	if resp.StatusCode != http.StatusOK {
+	// Check for non-200 status codes.  Withdrawals that need approval are accepted instead.
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
 		// Try to parse as structured error
 		var apiError APIError
 
@@ -180,26 +230,26 @@ func (c *Client) doRequest(method, path string, queryParams url.Values, body int
 }
 
 // GetBalances retrieves balances for a specified account
-func (c *Client) GetBalances(exchange oc.ExchangeId, accountType string) ([]*api.Balance, error) {
+func (c *Client) GetBalances(ctx context.Context, exchange oc.ExchangeId, accountType string) ([]*api.Balance, error) {
 	queryParams := url.Values{}
 	if accountType != "" {
 		queryParams.Set("type", accountType)
 	}
 
 	var balances []*api.Balance
-	err := c.doRequest(http.MethodGet, fmt.Sprintf("/v1/exchanges/%s/balances", exchange), queryParams, nil, &balances)
+	err := c.doRequest(ctx, http.MethodGet, fmt.Sprintf("/v1/exchanges/%s/balances", exchange), queryParams, nil, &balances)
 	return balances, err
 }
 
 // GetAssets retrieves the list of assets for a specified exchange
-func (c *Client) GetAssets(exchange oc.ExchangeId) ([]*api.Asset, error) {
+func (c *Client) GetAssets(ctx context.Context, exchange oc.ExchangeId) ([]*api.Asset, error) {
 	var assets []*api.Asset
-	err := c.doRequest(http.MethodGet, fmt.Sprintf("/v1/exchanges/%s/assets", exchange), nil, nil, &assets)
+	err := c.doRequest(ctx, http.MethodGet, fmt.Sprintf("/v1/exchanges/%s/assets", exchange), nil, nil, &assets)
 	return assets, err
 }
 
 // GetDepositAddress retrieves a deposit address for a specified symbol and network
-func (c *Client) GetDepositAddress(exchange oc.ExchangeId, symbol oc.SymbolId, network oc.NetworkId, subAccountForMaybe oc.AccountId) (string, error) {
+func (c *Client) GetDepositAddress(ctx context.Context, exchange oc.ExchangeId, symbol oc.SymbolId, network oc.NetworkId, subAccountForMaybe oc.AccountId) (string, error) {
 	queryParams := url.Values{}
 	queryParams.Set("symbol", string(symbol))
 	queryParams.Set("network", string(network))
@@ -208,26 +258,26 @@ func (c *Client) GetDepositAddress(exchange oc.ExchangeId, symbol oc.SymbolId, n
 	}
 
 	var address string
-	err := c.doRequest(http.MethodGet, fmt.Sprintf("/v1/exchanges/%s/deposit-address", exchange), queryParams, nil, &address)
+	err := c.doRequest(ctx, http.MethodGet, fmt.Sprintf("/v1/exchanges/%s/deposit-address", exchange), queryParams, nil, &address)
 	return address, err
 }
 
 // GetAccountTypes retrieves the list of valid account types for an exchange
-func (c *Client) GetAccountTypes(exchange oc.ExchangeId) ([]*api.AccountType, error) {
+func (c *Client) GetAccountTypes(ctx context.Context, exchange oc.ExchangeId) ([]*api.AccountType, error) {
 	var accountTypes []*api.AccountType
-	err := c.doRequest(http.MethodGet, fmt.Sprintf("/v1/exchanges/%s/account-types", exchange), nil, nil, &accountTypes)
+	err := c.doRequest(ctx, http.MethodGet, fmt.Sprintf("/v1/exchanges/%s/account-types", exchange), nil, nil, &accountTypes)
 	return accountTypes, err
 }
 
 // ListSubaccounts retrieves the list of configured subaccounts on an exchange
-func (c *Client) ListSubaccounts(exchange oc.ExchangeId) ([]*api.SubAccountHeader, error) {
+func (c *Client) ListSubaccounts(ctx context.Context, exchange oc.ExchangeId) ([]*api.SubAccountHeader, error) {
 	var subaccounts []*api.SubAccountHeader
-	err := c.doRequest(http.MethodGet, fmt.Sprintf("/v1/exchanges/%s/subaccounts", exchange), nil, nil, &subaccounts)
+	err := c.doRequest(ctx, http.MethodGet, fmt.Sprintf("/v1/exchanges/%s/subaccounts", exchange), nil, nil, &subaccounts)
 	return subaccounts, err
 }
 
 // ListWithdrawalHistory retrieves the withdrawal history for an exchange account
-func (c *Client) ListWithdrawalHistory(exchange oc.ExchangeId, limit int, pageToken string) ([]*api.HistoricalWithdrawal, error) {
+func (c *Client) ListWithdrawalHistory(ctx context.Context, exchange oc.ExchangeId, limit int, pageToken string) ([]*api.HistoricalWithdrawal, error) {
 	queryParams := url.Values{}
 	if limit > 0 {
 		queryParams.Set("limit", fmt.Sprintf("%d", limit))
@@ -237,19 +287,44 @@ func (c *Client) ListWithdrawalHistory(exchange oc.ExchangeId, limit int, pageTo
 	}
 
 	var withdrawals []*api.HistoricalWithdrawal
-	err := c.doRequest(http.MethodGet, fmt.Sprintf("/v1/exchanges/%s/withdrawal-history", exchange), queryParams, nil, &withdrawals)
+	err := c.doRequest(ctx, http.MethodGet, fmt.Sprintf("/v1/exchanges/%s/withdrawal-history", exchange), queryParams, nil, &withdrawals)
 	return withdrawals, err
 }
 
+// ListTransferHistory retrieves the internal transfer history for an exchange account
+func (c *Client) ListTransferHistory(ctx context.Context, exchange oc.ExchangeId, limit int, pageToken string) ([]*api.HistoricalTransfer, error) {
+	queryParams := url.Values{}
+	if limit > 0 {
+		queryParams.Set("limit", fmt.Sprintf("%d", limit))
+	}
+	if pageToken != "" {
+		queryParams.Set("page_token", pageToken)
+	}
+
+	var transfers []*api.HistoricalTransfer
+	err := c.doRequest(ctx, http.MethodGet, fmt.Sprintf("/v1/exchanges/%s/transfer-history", exchange), queryParams, nil, &transfers)
+	return transfers, err
+}
+
 // CreateAccountTransfer performs a transfer between accounts on an exchange
-func (c *Client) CreateAccountTransfer(exchange oc.ExchangeId, transfer *api.Transfer) (*api.TransferResponse, error) {
+func (c *Client) CreateAccountTransfer(ctx context.Context, exchange oc.ExchangeId, transfer *api.Transfer) (*api.TransferResponse, error) {
 	// HTTP signature is required for this endpoint
 	if c.signer == nil {
 		return nil, fmt.Errorf("HTTP signature is required for account transfers")
 	}
 
 	var transferResp api.TransferResponse
-	err := c.doRequest(http.MethodPost, fmt.Sprintf("/v1/exchanges/%s/account-transfer", exchange), nil, transfer, &transferResp)
+	err := c.doRequest(ctx, http.MethodPost, fmt.Sprintf("/v1/exchanges/%s/account-transfer", exchange), nil, transfer, &transferResp)
+	if err != nil {
+		return nil, err
+	}
+	return &transferResp, nil
+}
+
+// GetTransferStatus retrieves the status of an internal account transfer on an exchange
+func (c *Client) GetTransferStatus(ctx context.Context, exchange oc.ExchangeId, id string) (*api.TransferResponse, error) {
+	var transferResp api.TransferResponse
+	err := c.doRequest(ctx, http.MethodGet, fmt.Sprintf("/v1/exchanges/%s/account-transfer/%s", exchange, url.PathEscape(id)), nil, nil, &transferResp)
 	if err != nil {
 		return nil, err
 	}
@@ -257,16 +332,80 @@ func (c *Client) CreateAccountTransfer(exchange oc.ExchangeId, transfer *api.Tra
 }
 
 // CreateWithdrawal initiates a withdrawal from an exchange
-func (c *Client) CreateWithdrawal(exchange oc.ExchangeId, withdrawal *api.Withdrawal) (*api.WithdrawalResponse, error) {
+func (c *Client) CreateWithdrawal(ctx context.Context, exchange oc.ExchangeId, withdrawal *api.Withdrawal) (*api.WithdrawalResponse, error) {
 	// HTTP signature is required for this endpoint
 	if c.signer == nil {
 		return nil, fmt.Errorf("HTTP signature is required for withdrawals")
 	}
 
 	var withdrawalResp api.WithdrawalResponse
-	err := c.doRequest(http.MethodPost, fmt.Sprintf("/v1/exchanges/%s/withdrawal", exchange), nil, withdrawal, &withdrawalResp)
+	err := c.doRequest(ctx, http.MethodPost, fmt.Sprintf("/v1/exchanges/%s/withdrawal", exchange), nil, withdrawal, &withdrawalResp)
 	if err != nil {
 		return nil, err
 	}
 	return &withdrawalResp, nil
 }
+
+// EstimateWithdrawal estimates the fee and net amount of a withdrawal, without executing it
+func (c *Client) EstimateWithdrawal(ctx context.Context, exchange oc.ExchangeId, withdrawal *api.Withdrawal) (*api.WithdrawalQuote, error) {
+	var quote api.WithdrawalQuote
+	err := c.doRequest(ctx, http.MethodPost, fmt.Sprintf("/v1/exchanges/%s/withdrawal/quote", exchange), nil, withdrawal, &quote)
+	if err != nil {
+		return nil, err
+	}
+	return &quote, nil
+}
+
+// ListWithdrawalIntents retrieves the withdrawal intents, newest first
+func (c *Client) ListWithdrawalIntents(ctx context.Context) ([]*api.WithdrawalIntent, error) {
+	var intents []*api.WithdrawalIntent
+	err := c.doRequest(ctx, http.MethodGet, "/v1/withdrawal-intents", nil, nil, &intents)
+	return intents, err
+}
+
+// GetWithdrawalIntent retrieves a withdrawal intent
+func (c *Client) GetWithdrawalIntent(ctx context.Context, id string) (*api.WithdrawalIntent, error) {
+	var intent api.WithdrawalIntent
+	err := c.doRequest(ctx, http.MethodGet, fmt.Sprintf("/v1/withdrawal-intents/%s", url.PathEscape(id)), nil, nil, &intent)
+	if err != nil {
+		return nil, err
+	}
+	return &intent, nil
+}
+
+// CancelWithdrawalIntent cancels a withdrawal intent that is waiting for approval or for its delay
+func (c *Client) CancelWithdrawalIntent(ctx context.Context, id string) (*api.WithdrawalIntent, error) {
+	// HTTP signature is required for this endpoint
+	if c.signer == nil {
+		return nil, fmt.Errorf("HTTP signature is required to cancel withdrawal intents")
+	}
+	var intent api.WithdrawalIntent
+	err := c.doRequest(ctx, http.MethodDelete, fmt.Sprintf("/v1/withdrawal-intents/%s", url.PathEscape(id)), nil, nil, &intent)
+	if err != nil {
+		return nil, err
+	}
+	return &intent, nil
+}
+
+// ApproveWithdrawalIntent submits an approver's approval of a withdrawal intent
+func (c *Client) ApproveWithdrawalIntent(ctx context.Context, id string, decision *api.IntentDecision) (*api.WithdrawalIntent, error) {
+	return c.decideWithdrawalIntent(ctx, id, "approve", decision)
+}
+
+// RejectWithdrawalIntent submits an approver's rejection of a withdrawal intent
+func (c *Client) RejectWithdrawalIntent(ctx context.Context, id string, decision *api.IntentDecision) (*api.WithdrawalIntent, error) {
+	return c.decideWithdrawalIntent(ctx, id, "reject", decision)
+}
+
+func (c *Client) decideWithdrawalIntent(ctx context.Context, id string, action string, decision *api.IntentDecision) (*api.WithdrawalIntent, error) {
+	// HTTP signature is required for this endpoint
+	if c.signer == nil {
+		return nil, fmt.Errorf("HTTP signature is required to %s withdrawal intents", action)
+	}
+	var intent api.WithdrawalIntent
+	err := c.doRequest(ctx, http.MethodPost, fmt.Sprintf("/v1/withdrawal-intents/%s/%s", url.PathEscape(id), action), nil, decision, &intent)
+	if err != nil {
+		return nil, err
+	}
+	return &intent, nil
+}
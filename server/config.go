@@ -1,33 +1,50 @@
 package server
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
+	"log/slog"
 	"os"
+	"time"
 
+	"github.com/cordialsys/offchain/pkg/audit"
 	"github.com/cordialsys/offchain/pkg/hex"
+	"github.com/cordialsys/offchain/pkg/httpsignature/signer"
+	"github.com/cordialsys/offchain/pkg/intent"
 	"github.com/cordialsys/offchain/pkg/secret"
+	"github.com/cordialsys/offchain/pkg/tlsconfig"
+	"github.com/cordialsys/offchain/pkg/tracing"
+	"github.com/cordialsys/offchain/pkg/webhook"
+	"github.com/cordialsys/offchain/server/watcher"
 	"github.com/ilyakaznacheev/cleanenv"
-	"github.com/sirupsen/logrus"
 )
 
 type BearerToken struct {
 	Token secret.Secret `yaml:"token"`
 	Id    string        `yaml:"id"`
+	// If set, the token is only accepted from clients presenting a certificate with this identity
+	ClientCert string `yaml:"client_cert"`
 }
 
 type Algorithm string
 
 const (
+	// ECDSA of the SHA-256 digest, signed by a remote signer (KMS or PKCS#11)
 	K256Sha256 Algorithm = "k256-sha2"
+	P256Sha256 Algorithm = "p256-sha2"
 	Ed25519    Algorithm = "ed25519"
 	Ed255      Algorithm = "ed255" // alias to ed25519
 )
 
 type HttpPublicKey struct {
 	Algorithm Algorithm `yaml:"algorithm"`
-	// Hex encoded
+	// Hex encoded.  ECDSA keys are SEC1 encoded points, compressed or uncompressed.
 	Key hex.Hex `yaml:"key"`
 	Id  string  `yaml:"id"`
+	// If set, signatures by the key are only accepted from clients presenting a certificate with
+	// this identity (the subject common name, or a DNS, email or URI subject alternative name)
+	ClientCert string `yaml:"client_cert"`
 }
 
 type Config struct {
@@ -35,9 +52,179 @@ type Config struct {
 	Origins   []string `yaml:"origins" env-default:""`
 	AnyOrigin bool     `yaml:"any_origin" env-default:"false"`
 
+	// PEM encoded certificate and private key to serve over TLS
+	TlsCert secret.Secret `yaml:"tls_cert"`
+	TlsKey  secret.Secret `yaml:"tls_key"`
+	// PEM encoded bundle of CAs.  If set, clients must present a certificate signed by one of them.
+	TlsClientCa secret.Secret `yaml:"tls_client_ca"`
+
 	PublicReadEndpoints bool            `yaml:"public_read_endpoints"`
 	BearerTokens        []BearerToken   `yaml:"bearer_tokens"`
 	PublicKeys          []HttpPublicKey `yaml:"public_keys"`
+
+	// Hex encoded ed25519 private key to sign responses with, so clients can check they came from the server
+	ResponseSigningKey secret.Secret `yaml:"response_signing_key"`
+	// Sent as the keyid of response signatures
+	ResponseSigningKeyId string `yaml:"response_signing_key_id" env-default:"offchain"`
+
+	// Append-only log of all authenticated requests
+	Audit audit.Config `yaml:"audit"`
+
+	// Notify about withdrawals and transfers as they complete
+	Webhooks WebhooksConfig `yaml:"webhooks"`
+
+	// Caching of secrets loaded from secret managers
+	SecretCache SecretCacheConfig `yaml:"secret_cache"`
+
+	// Readiness checks of the configured accounts
+	Health HealthConfig `yaml:"health"`
+
+	// Export traces using OTLP
+	Tracing tracing.Config `yaml:"tracing"`
+
+	// Rate limits, concurrency caps and request size limits
+	Limits LimitsConfig `yaml:"limits"`
+
+	// Withdrawals that need approval from a quorum of approvers before they're made
+	Approvals ApprovalsConfig `yaml:"approvals"`
+
+	// Cooling-off periods before large withdrawals are made
+	Delays intent.Delays `yaml:"delays"`
+	// File that withdrawal intents are kept in, so they survive restarts.  Required if there are delays.
+	IntentsFile string `yaml:"intents_file"`
+}
+
+// IntentStore opens the store of withdrawal intents, or returns nil to keep them in memory.
+func (cfg *Config) IntentStore() (*intent.Store, error) {
+	if err := cfg.Delays.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid delays: %w", err)
+	}
+	if cfg.IntentsFile == "" {
+		if len(cfg.Delays) > 0 {
+			return nil, fmt.Errorf("delays need an intents_file, so that delayed withdrawals survive restarts")
+		}
+		return nil, nil
+	}
+	store, err := intent.OpenStore(cfg.IntentsFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not open intents_file: %w", err)
+	}
+	return store, nil
+}
+
+// TLSConfig returns the TLS config to serve with, or nil if TLS is not configured.
+func (cfg *Config) TLSConfig(ctx context.Context) (*tls.Config, error) {
+	if cfg.TlsCert == "" && cfg.TlsKey == "" {
+		if cfg.TlsClientCa != "" {
+			return nil, fmt.Errorf("tls_client_ca requires tls_cert and tls_key to be set")
+		}
+		return nil, nil
+	}
+	return tlsconfig.Server(ctx, cfg.TlsCert, cfg.TlsKey, cfg.TlsClientCa)
+}
+
+// ResponseSigner loads the key to sign responses with, or returns nil if responses aren't signed.
+func (cfg *Config) ResponseSigner(ctx context.Context) (signer.SignerI, error) {
+	if cfg.ResponseSigningKey == "" {
+		return nil, nil
+	}
+	value, err := cfg.ResponseSigningKey.Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load response signing key: %w", err)
+	}
+	key, err := signer.NewEd25519Signer(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response signing key: %w", err)
+	}
+	if cfg.ResponseSigningKeyId == "" {
+		return key, nil
+	}
+	return signer.WithKeyId(key, cfg.ResponseSigningKeyId), nil
+}
+
+type SecretCacheConfig struct {
+	// Load secrets each time they're used instead
+	Disabled bool `yaml:"disabled"`
+	// How long a loaded secret is used for
+	Ttl time.Duration `yaml:"ttl" env-default:"5m"`
+	// Secrets used within this long of expiring are reloaded in the background
+	RefreshAhead time.Duration `yaml:"refresh_ahead" env-default:"1m"`
+}
+
+func (cfg *SecretCacheConfig) Options() secret.CacheOptions {
+	opts := secret.DefaultCacheOptions()
+	if cfg.Ttl > 0 {
+		opts.Ttl = cfg.Ttl
+	}
+	if cfg.RefreshAhead > 0 {
+		opts.RefreshAhead = cfg.RefreshAhead
+	}
+	return opts
+}
+
+type HealthConfig struct {
+	// How long the results of the checks are reused for, since each check calls the exchanges
+	CacheTtl time.Duration `yaml:"cache_ttl" env-default:"1m"`
+}
+
+type RateLimit struct {
+	// Requests per second, unlimited if 0
+	Rate float64 `yaml:"rate"`
+	// Requests allowed at once above the rate (defaults to 1 second of requests)
+	Burst int `yaml:"burst"`
+}
+
+func (l RateLimit) Enabled() bool {
+	return l.Rate > 0
+}
+
+func (l RateLimit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return max(1, int(l.Rate))
+}
+
+type LimitsConfig struct {
+	// Requests made with each bearer token or public key
+	PerKey RateLimit `yaml:"per_key"`
+	// Requests from each client IP address
+	PerIp RateLimit `yaml:"per_ip"`
+	// Requests handled at once for each exchange, across all callers.  Unlimited if 0.
+	ExchangeConcurrency int `yaml:"exchange_concurrency"`
+	// Largest request body accepted, in bytes
+	MaxBodySize int `yaml:"max_body_size" env-default:"1048576"`
+}
+
+type ApprovalsConfig struct {
+	// Number of approvals a withdrawal needs.  Withdrawals are made immediately if 0.
+	Quorum int `yaml:"quorum"`
+	// Keys of the approvers, which sign the digest of withdrawal intents.  They don't need to be public_keys.
+	Approvers []HttpPublicKey `yaml:"approvers"`
+	// How long a withdrawal intent can be approved for
+	Expiry time.Duration `yaml:"expiry" env-default:"24h"`
+}
+
+type WebhookEndpoint struct {
+	Url string `yaml:"url"`
+	// Shared secret to sign events using HMAC-SHA256
+	HmacSecret secret.Secret `yaml:"hmac_secret"`
+	// Hex encoded ed25519 private key to sign events with, instead of HMAC
+	Ed25519Key secret.Secret `yaml:"ed25519_key"`
+	// Event types to send to the endpoint, e.g. "withdrawal.success".  All events are sent if empty.
+	Events []string `yaml:"events"`
+}
+
+type WebhooksConfig struct {
+	Endpoints []WebhookEndpoint `yaml:"endpoints"`
+	// How often to poll the exchanges for pending operations (default 30s)
+	PollInterval time.Duration `yaml:"poll_interval"`
+	// Stop watching operations that are still pending after this long (default 24h)
+	MaxWatch time.Duration `yaml:"max_watch"`
+	// Number of delivery attempts before an event is dead-lettered (default 8)
+	MaxAttempts int `yaml:"max_attempts"`
+	// File to append undeliverable events to
+	DeadLetterFile string `yaml:"dead_letter_file"`
 }
 
 const ENV_OFFCHAIN_CONFIG = "OFFCHAIN_CONFIG"
@@ -60,7 +247,7 @@ func LoadConfig(configPathMaybe string) (*Config, error) {
 		return nil, fmt.Errorf("config path is required (maybe set %s)", ENV_OFFCHAIN_CONFIG)
 	}
 
-	logrus.WithField("config", configPathMaybe).Info("loading server configuration")
+	slog.Info("loading server configuration", "config", configPathMaybe)
 	err := cleanenv.ReadConfig(configPathMaybe, &section)
 	if err != nil {
 		return nil, fmt.Errorf("could not read configuration: %v", err)
@@ -68,3 +255,57 @@ func LoadConfig(configPathMaybe string) (*Config, error) {
 
 	return &section.Offchain.Server, nil
 }
+
+// NewWatcher loads the webhook signing keys and creates the watcher, or returns nil if
+// no webhook endpoints are configured.
+func (cfg *WebhooksConfig) NewWatcher(ctx context.Context) (*watcher.Watcher, error) {
+	if len(cfg.Endpoints) == 0 {
+		return nil, nil
+	}
+	endpoints := make([]webhook.Endpoint, len(cfg.Endpoints))
+	for i, endpointCfg := range cfg.Endpoints {
+		if endpointCfg.Url == "" {
+			return nil, fmt.Errorf("webhook endpoint %d has no url", i)
+		}
+		endpoints[i] = webhook.Endpoint{
+			Url:    endpointCfg.Url,
+			Events: endpointCfg.Events,
+		}
+		switch {
+		case endpointCfg.HmacSecret != "" && endpointCfg.Ed25519Key != "":
+			return nil, fmt.Errorf("webhook endpoint %s must set only one of hmac_secret or ed25519_key", endpointCfg.Url)
+		case endpointCfg.HmacSecret != "":
+			value, err := endpointCfg.HmacSecret.Load(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load hmac secret for webhook %s: %w", endpointCfg.Url, err)
+			}
+			endpoints[i].Signer = webhook.NewHmacSigner([]byte(value))
+		case endpointCfg.Ed25519Key != "":
+			value, err := endpointCfg.Ed25519Key.Load(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load ed25519 key for webhook %s: %w", endpointCfg.Url, err)
+			}
+			key, err := signer.NewEd25519Signer(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ed25519 key for webhook %s: %w", endpointCfg.Url, err)
+			}
+			endpoints[i].Signer = webhook.NewEd25519Signer(key)
+		default:
+			return nil, fmt.Errorf("webhook endpoint %s must set hmac_secret or ed25519_key", endpointCfg.Url)
+		}
+	}
+
+	opts := webhook.DefaultOptions()
+	if cfg.MaxAttempts > 0 {
+		opts.MaxAttempts = cfg.MaxAttempts
+	}
+	opts.DeadLetterFile = cfg.DeadLetterFile
+	watcherOpts := watcher.DefaultOptions()
+	if cfg.PollInterval > 0 {
+		watcherOpts.PollInterval = cfg.PollInterval
+	}
+	if cfg.MaxWatch > 0 {
+		watcherOpts.MaxWatch = cfg.MaxWatch
+	}
+	return watcher.New(webhook.NewDispatcher(endpoints, opts), watcherOpts), nil
+}
@@ -3,9 +3,9 @@ package endpoints
 import (
 	oc "github.com/cordialsys/offchain"
 	"github.com/cordialsys/offchain/client"
-	"github.com/cordialsys/offchain/loader"
 	"github.com/cordialsys/offchain/server/client/api"
 	"github.com/cordialsys/offchain/server/servererrors"
+	"github.com/cordialsys/offchain/server/watcher"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -50,7 +50,7 @@ func AccountTransfer(c *fiber.Ctx) error {
 	}
 
 	// Create client
-	cli, err := loader.NewClient(exchangeCfg, secrets)
+	cli, err := newClient(c, exchangeCfg, secrets)
 	if err != nil {
 		return servererrors.InternalErrorf("failed to create client: %s", err)
 	}
@@ -115,10 +115,36 @@ func AccountTransfer(c *fiber.Ctx) error {
 	}
 
 	// Execute transfer
-	resp, err := cli.CreateAccountTransfer(transferArgs)
+	resp, err := cli.CreateAccountTransfer(c.UserContext(), transferArgs)
 	if err != nil {
 		return servererrors.Conflictf("failed to create account transfer: %s", err)
 	}
+	track(c, watcher.NewTransfer(exchangeCfg, secrets, transferArgs, resp))
+
+	return c.JSON(exportAccountTransfer(resp))
+}
+
+// GetTransferStatus returns the status of a previously created account transfer
+func GetTransferStatus(c *fiber.Ctx) error {
+	exchangeCfg, secrets, err := loadAccount(c, c.Params("exchange"))
+	if err != nil {
+		return err
+	}
+
+	id := c.Params("id")
+	if id == "" {
+		return servererrors.BadRequestf("transfer id is required")
+	}
+
+	cli, err := newClient(c, exchangeCfg, secrets)
+	if err != nil {
+		return servererrors.InternalErrorf("failed to create client: %s", err)
+	}
+
+	resp, err := cli.GetTransferStatus(c.UserContext(), id)
+	if err != nil {
+		return servererrors.Conflictf("failed to get transfer status: %s", err)
+	}
 
 	return c.JSON(exportAccountTransfer(resp))
 }
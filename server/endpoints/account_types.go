@@ -50,13 +50,13 @@ func GetAccountTypes(c *fiber.Ctx) error {
 	}
 
 	// Create client with NopAccount (we don't need real credentials for this operation)
-	cli, err := loader.NewClient(exchangeConfig, NopAccount)
+	cli, err := loader.NewClient(c.UserContext(), exchangeConfig, NopAccount)
 	if err != nil {
 		return servererrors.InternalErrorf("failed to create client: %s", err)
 	}
 
 	// Get account types
-	accountTypes, err := cli.ListAccountTypes()
+	accountTypes, err := cli.ListAccountTypes(c.UserContext())
 	if err != nil {
 		return servererrors.Conflictf("failed to get account types: %s", err)
 	}
@@ -2,7 +2,6 @@ package endpoints
 
 import (
 	oc "github.com/cordialsys/offchain"
-	"github.com/cordialsys/offchain/loader"
 	"github.com/cordialsys/offchain/server/client/api"
 	"github.com/cordialsys/offchain/server/servererrors"
 	"github.com/gofiber/fiber/v2"
@@ -31,13 +30,13 @@ func GetAssets(c *fiber.Ctx) error {
 	}
 
 	// Create client
-	cli, err := loader.NewClient(exchangeCfg, account)
+	cli, err := newClient(c, exchangeCfg, account)
 	if err != nil {
 		return servererrors.InternalErrorf("failed to create client: %s", err)
 	}
 
 	// Get assets
-	assets, err := cli.ListAssets()
+	assets, err := cli.ListAssets(c.UserContext())
 	if err != nil {
 		return servererrors.Conflictf("failed to get assets: %s", err)
 	}
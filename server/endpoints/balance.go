@@ -3,7 +3,7 @@ package endpoints
 import (
 	oc "github.com/cordialsys/offchain"
 	"github.com/cordialsys/offchain/client"
-	"github.com/cordialsys/offchain/loader"
+	"github.com/cordialsys/offchain/pkg/metrics"
 	"github.com/cordialsys/offchain/server/client/api"
 	"github.com/cordialsys/offchain/server/servererrors"
 	"github.com/gofiber/fiber/v2"
@@ -35,7 +35,7 @@ func GetBalances(c *fiber.Ctx) error {
 	}
 
 	// Create client
-	cli, err := loader.NewClient(exchangeCfg, account)
+	cli, err := newClient(c, exchangeCfg, account)
 	if err != nil {
 		return servererrors.InternalErrorf("failed to create client: %s", err)
 	}
@@ -44,10 +44,11 @@ func GetBalances(c *fiber.Ctx) error {
 	balanceArgs := client.NewGetBalanceArgs(oc.AccountType(accountType))
 
 	// Get balances
-	assets, err := cli.ListBalances(balanceArgs)
+	assets, err := cli.ListBalances(c.UserContext(), balanceArgs)
 	if err != nil {
 		return servererrors.Conflictf("failed to get balances: %s", err)
 	}
+	metrics.LastBalanceFetch.WithLabelValues(string(exchangeCfg.ExchangeId), SubAccountLabel(c, exchangeCfg)).SetToCurrentTime()
 
 	return c.JSON(exportBalances(assets))
 }
@@ -3,7 +3,6 @@ package endpoints
 import (
 	oc "github.com/cordialsys/offchain"
 	"github.com/cordialsys/offchain/client"
-	"github.com/cordialsys/offchain/loader"
 	"github.com/cordialsys/offchain/server/servererrors"
 	"github.com/gofiber/fiber/v2"
 )
@@ -31,7 +30,7 @@ func GetDepositAddress(c *fiber.Ctx) error {
 	}
 
 	// Create client
-	cli, err := loader.NewClient(exchangeCfg, account)
+	cli, err := newClient(c, exchangeCfg, account)
 	if err != nil {
 		return servererrors.InternalErrorf("failed to create client: %s", err)
 	}
@@ -46,7 +45,7 @@ func GetDepositAddress(c *fiber.Ctx) error {
 	}
 
 	// Get deposit address
-	resp, err := cli.GetDepositAddress(client.NewGetDepositAddressArgs(
+	resp, err := cli.GetDepositAddress(c.UserContext(), client.NewGetDepositAddressArgs(
 		oc.SymbolId(symbol),
 		oc.NetworkId(network),
 		options...,
@@ -0,0 +1,44 @@
+package endpoints
+
+import (
+	"github.com/cordialsys/offchain/server/health"
+	"github.com/gofiber/fiber/v2"
+)
+
+// WrapHealth makes the health checker available to the handlers.
+func WrapHealth(c *fiber.Ctx, checker *health.Checker) {
+	c.Locals("health", checker)
+}
+
+func report(c *fiber.Ctx) *health.Report {
+	checker := c.Locals("health").(*health.Checker)
+	report := checker.Report(c.UserContext(), UnwrapConfig(c))
+	if !report.Ready() {
+		c.Status(fiber.StatusServiceUnavailable)
+	}
+	return report
+}
+
+// Ready reports whether every configured account can be used, without any details.
+func Ready(c *fiber.Ctx) error {
+	report := report(c)
+	return c.JSON(fiber.Map{
+		"status":     report.Status,
+		"checked_at": report.CheckedAt,
+	})
+}
+
+// ExchangesHealth reports the status of each configured account.
+func ExchangesHealth(c *fiber.Ctx) error {
+	return c.JSON(report(c))
+}
+
+// ApiKeyAges reports how old the API key of each configured account is, so keys can be rotated in time.
+func ApiKeyAges(c *fiber.Ctx) error {
+	checker := c.Locals("health").(*health.Checker)
+	report := checker.Report(c.UserContext(), UnwrapConfig(c))
+	return c.JSON(fiber.Map{
+		"checked_at": report.CheckedAt,
+		"api_keys":   report.ApiKeyAges(),
+	})
+}
@@ -2,7 +2,6 @@ package endpoints
 
 import (
 	oc "github.com/cordialsys/offchain"
-	"github.com/cordialsys/offchain/loader"
 	"github.com/cordialsys/offchain/server/client/api"
 	"github.com/cordialsys/offchain/server/servererrors"
 	"github.com/gofiber/fiber/v2"
@@ -27,13 +26,13 @@ func ListSubaccounts(c *fiber.Ctx) error {
 	}
 
 	// Create client
-	cli, err := loader.NewClient(exchangeCfg, account)
+	cli, err := newClient(c, exchangeCfg, account)
 	if err != nil {
 		return servererrors.InternalErrorf("failed to create client: %s", err)
 	}
 
 	// Get subaccounts
-	subaccounts, err := cli.ListSubaccounts()
+	subaccounts, err := cli.ListSubaccounts(c.UserContext())
 	if err != nil {
 		return servererrors.Conflictf("failed to list subaccounts: %s", err)
 	}
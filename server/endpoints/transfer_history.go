@@ -0,0 +1,81 @@
+package endpoints
+
+import (
+	"strconv"
+
+	"github.com/cordialsys/offchain/client"
+	"github.com/cordialsys/offchain/server/client/api"
+	"github.com/cordialsys/offchain/server/servererrors"
+	"github.com/gofiber/fiber/v2"
+)
+
+func exportTransferHistory(resp []*client.TransferHistory) []*api.HistoricalTransfer {
+	transferHistory := make([]*api.HistoricalTransfer, len(resp))
+	for i, t := range resp {
+		transferHistory[i] = &api.HistoricalTransfer{
+			Id:        t.ID,
+			Status:    api.OperationStatus(t.Status),
+			Symbol:    string(t.Symbol),
+			Amount:    t.Amount.String(),
+			Timestamp: t.Timestamp,
+			Comment:   api.As(t.Comment),
+			Notes:     api.As(t.Notes),
+		}
+		if t.PageToken != "" {
+			transferHistory[i].PageToken = api.As(t.PageToken)
+		}
+		if t.From != "" {
+			transferHistory[i].From = api.As(string(t.From))
+		}
+		if t.To != "" {
+			transferHistory[i].To = api.As(string(t.To))
+		}
+		if t.FromType != "" {
+			transferHistory[i].FromType = api.As(string(t.FromType))
+		}
+		if t.ToType != "" {
+			transferHistory[i].ToType = api.As(string(t.ToType))
+		}
+	}
+	return transferHistory
+}
+
+// ListTransferHistory returns the internal transfer history for an exchange account
+func ListTransferHistory(c *fiber.Ctx) error {
+	exchangeCfg, account, err := loadAccount(c, c.Params("exchange"))
+	if err != nil {
+		return err
+	}
+
+	cli, err := newClient(c, exchangeCfg, account)
+	if err != nil {
+		return servererrors.InternalErrorf("failed to create client: %s", err)
+	}
+
+	args := client.NewTransferHistoryArgs()
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			return servererrors.BadRequestf("invalid limit parameter: must be a number")
+		}
+		if limit <= 0 {
+			return servererrors.BadRequestf("invalid limit parameter: must be greater than 0")
+		}
+		args.SetLimit(limit)
+	}
+
+	if pageToken := c.Query("page_token"); pageToken != "" {
+		if _, err := client.ParseTransferCursor(pageToken); err != nil {
+			return servererrors.BadRequestf("invalid page_token parameter: must be the page_token of a transfer or a unix millisecond timestamp")
+		}
+		args.SetPageToken(pageToken)
+	}
+
+	resp, err := cli.ListTransferHistory(c.UserContext(), args)
+	if err != nil {
+		return servererrors.Conflictf("failed to get transfer history: %s", err)
+	}
+
+	return c.JSON(exportTransferHistory(resp))
+}
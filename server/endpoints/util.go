@@ -1,9 +1,16 @@
 package endpoints
 
 import (
+	"context"
+
 	oc "github.com/cordialsys/offchain"
+	"github.com/cordialsys/offchain/loader"
+	"github.com/cordialsys/offchain/pkg/tracing"
 	"github.com/cordialsys/offchain/server/servererrors"
+	"github.com/cordialsys/offchain/server/watcher"
 	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func UnwrapConfig(c *fiber.Ctx) *oc.Config {
@@ -13,23 +20,67 @@ func WrapConfig(c *fiber.Ctx, conf *oc.Config) {
 	c.Locals("conf", conf)
 }
 
+// WrapWatcher makes the watcher available to the handlers, if webhooks are configured.
+func WrapWatcher(c *fiber.Ctx, w *watcher.Watcher) {
+	c.Locals("watcher", w)
+}
+
+// track watches a new operation and notifies about its status, if webhooks are configured.
+func track(c *fiber.Ctx, op *watcher.Operation) {
+	if w, ok := c.Locals("watcher").(*watcher.Watcher); ok && w != nil {
+		w.Track(op)
+	}
+}
+
+// WrapClients makes the client cache available to the handlers.
+func WrapClients(c *fiber.Ctx, clients *loader.ClientCache) {
+	c.Locals("clients", clients)
+}
+
+// newClient returns a client for the account, reusing a cached one if possible.
+func newClient(c *fiber.Ctx, exchangeCfg *oc.ExchangeConfig, account *oc.Account) (loader.Client, error) {
+	clients, _ := c.Locals("clients").(*loader.ClientCache)
+	return cachedClient(c.UserContext(), clients, exchangeCfg, account)
+}
+
+func cachedClient(ctx context.Context, clients *loader.ClientCache, exchangeCfg *oc.ExchangeConfig, account *oc.Account) (loader.Client, error) {
+	if clients != nil {
+		return clients.Get(ctx, exchangeCfg, account)
+	}
+	return loader.NewClient(ctx, exchangeCfg, account)
+}
+
 func loadAccount(c *fiber.Ctx, exchangeId string) (*oc.ExchangeConfig, *oc.Account, error) {
-	conf := UnwrapConfig(c)
+	return loadAccountOf(c, exchangeId, c.Locals("sub-account").(string))
+}
+
+func loadAccountOf(c *fiber.Ctx, exchangeId string, subaccountId string) (*oc.ExchangeConfig, *oc.Account, error) {
+	return LoadAccount(c.UserContext(), UnwrapConfig(c), exchangeId, subaccountId)
+}
+
+// LoadAccount loads the main account of the exchange, or the sub-account if the id or alias is set.
+func LoadAccount(ctx context.Context, conf *oc.Config, exchangeId string, subaccountId string) (_ *oc.ExchangeConfig, _ *oc.Account, err error) {
+	ctx, span := tracing.Start(ctx, "loadAccount", trace.WithAttributes(
+		attribute.String("offchain.exchange", exchangeId),
+	))
+	defer func() { tracing.End(span, err) }()
+
 	exchangeConfig, ok := conf.GetExchange(oc.ExchangeId(exchangeId))
 	if !ok {
 		return nil, nil, servererrors.NotFoundf("exchange not found: %s", exchangeId)
 	}
-	subaccountId := c.Locals("sub-account").(string)
 	if subaccountId == "" {
 		// done
 		acc := exchangeConfig.AsAccount()
-		err := acc.LoadSecrets()
+		err := acc.LoadSecrets(ctx)
 		if err != nil {
 			return nil, nil, servererrors.InternalErrorf("failed to load secrets for main account of %s: %s", exchangeId, err)
 		}
 		return exchangeConfig, acc, nil
 	}
 
+	span.SetAttributes(attribute.String("offchain.subaccount", subAccountLabel(exchangeConfig, subaccountId)))
+
 	var account *oc.Account
 	for _, subaccount := range exchangeConfig.SubAccounts {
 		if string(subaccount.Id) == subaccountId || subaccount.Alias == subaccountId {
@@ -39,9 +90,63 @@ func loadAccount(c *fiber.Ctx, exchangeId string) (*oc.ExchangeConfig, *oc.Accou
 	if account == nil {
 		return nil, nil, servererrors.NotFoundf("subaccount %s for exchange %s not found", subaccountId, exchangeId)
 	}
-	err := account.LoadSecrets()
+	err = account.LoadSecrets(ctx)
 	if err != nil {
 		return nil, nil, servererrors.InternalErrorf("failed to load secrets for %s subaccount %s: %v", exchangeId, subaccountId, err)
 	}
 	return exchangeConfig, account, nil
 }
+
+type AuthMethod string
+
+const (
+	AuthNone          AuthMethod = ""
+	AuthBearer        AuthMethod = "bearer"
+	AuthHttpSignature AuthMethod = "http-signature"
+	// Made by the server for a withdrawal intent, on behalf of the key that requested it
+	AuthIntent AuthMethod = "intent"
+)
+
+// WrapIdentity records how the request was authenticated, and the id of the key or token used.
+func WrapIdentity(c *fiber.Ctx, method AuthMethod, keyId string) {
+	c.Locals("auth", method)
+	c.Locals("key-id", keyId)
+}
+
+func UnwrapIdentity(c *fiber.Ctx) (AuthMethod, string) {
+	method, _ := c.Locals("auth").(AuthMethod)
+	keyId, _ := c.Locals("key-id").(string)
+	return method, keyId
+}
+
+// WrapPublicKey records the public key that signed the request.
+func WrapPublicKey(c *fiber.Ctx, publicKey []byte) {
+	c.Locals("public-key", publicKey)
+}
+
+func UnwrapPublicKey(c *fiber.Ctx) []byte {
+	publicKey, _ := c.Locals("public-key").([]byte)
+	return publicKey
+}
+
+// SubAccountLabel identifies the sub-account of the request in metrics, preferring its alias.
+// Unconfigured sub-accounts are grouped together so that clients can't create arbitrary labels.
+func SubAccountLabel(c *fiber.Ctx, exchangeCfg *oc.ExchangeConfig) string {
+	idOrAlias, _ := c.Locals("sub-account").(string)
+	return subAccountLabel(exchangeCfg, idOrAlias)
+}
+
+func subAccountLabel(exchangeCfg *oc.ExchangeConfig, idOrAlias string) string {
+	if idOrAlias == "" {
+		return "main"
+	}
+	if exchangeCfg != nil {
+		if subaccount, ok := exchangeCfg.ResolveSubAccount(idOrAlias); ok {
+			if subaccount.Alias != "" {
+				return subaccount.Alias
+			}
+			return string(subaccount.Id)
+		}
+	}
+	return "unknown"
+}
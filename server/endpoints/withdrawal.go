@@ -3,9 +3,9 @@ package endpoints
 import (
 	oc "github.com/cordialsys/offchain"
 	"github.com/cordialsys/offchain/client"
-	"github.com/cordialsys/offchain/loader"
 	"github.com/cordialsys/offchain/server/client/api"
 	"github.com/cordialsys/offchain/server/servererrors"
+	"github.com/cordialsys/offchain/server/watcher"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -16,56 +16,70 @@ func exportWithdrawal(resp *client.WithdrawalResponse) *api.WithdrawalResponse {
 	}
 }
 
-// CreateWithdrawal handles withdrawal requests from an exchange
-func CreateWithdrawal(c *fiber.Ctx) error {
-	exchangeCfg, secrets, err := loadAccount(c, c.Params("exchange"))
-	if err != nil {
-		return err
-	}
-
+// parse and validate the withdrawal request body
+func parseWithdrawalArgs(c *fiber.Ctx) (client.WithdrawalArgs, error) {
 	// Parse request body
 	var req api.Withdrawal
 	if err := c.BodyParser(&req); err != nil {
-		return servererrors.BadRequestf("invalid request body: %s", err)
+		return client.WithdrawalArgs{}, servererrors.BadRequestf("invalid request body: %s", err)
 	}
 
 	// Validate required fields
 	if req.Address == "" {
-		return servererrors.BadRequestf("to address is required")
+		return client.WithdrawalArgs{}, servererrors.BadRequestf("to address is required")
 	}
 
 	symbol := oc.SymbolId(api.DerefOrZero(req.Symbol))
 	network := oc.NetworkId(api.DerefOrZero(req.Network))
 	if symbol == "" {
-		return servererrors.BadRequestf("symbol is required")
+		return client.WithdrawalArgs{}, servererrors.BadRequestf("symbol is required")
 	}
 
 	if network == "" {
-		return servererrors.BadRequestf("network is required")
+		return client.WithdrawalArgs{}, servererrors.BadRequestf("network is required")
 	}
 
 	amount, err := oc.NewAmountFromString(req.Amount)
 	if err != nil {
-		return servererrors.BadRequestf("invalid amount: %s", err)
+		return client.WithdrawalArgs{}, servererrors.BadRequestf("invalid amount: %s", err)
+	}
+
+	return client.NewWithdrawalArgs(
+		oc.Address(req.Address),
+		symbol,
+		network,
+		amount,
+	), nil
+}
+
+// CreateWithdrawal handles withdrawal requests from an exchange
+func CreateWithdrawal(c *fiber.Ctx) error {
+	exchangeCfg, secrets, err := loadAccount(c, c.Params("exchange"))
+	if err != nil {
+		return err
+	}
+
+	args, err := parseWithdrawalArgs(c)
+	if err != nil {
+		return err
+	}
+	if held, err := holdWithdrawal(c, args); held || err != nil {
+		return err
 	}
 
 	// Create client
-	cli, err := loader.NewClient(exchangeCfg, secrets)
+	cli, err := newClient(c, exchangeCfg, secrets)
 	if err != nil {
 		return servererrors.InternalErrorf("failed to create client: %s", err)
 	}
 
 	// Create withdrawal
-	resp, err := cli.CreateWithdrawal(client.NewWithdrawalArgs(
-		oc.Address(req.Address),
-		symbol,
-		network,
-		amount,
-	))
+	resp, err := cli.CreateWithdrawal(c.UserContext(), args)
 
 	if err != nil {
 		return servererrors.Conflictf("failed to create withdrawal: %s", err)
 	}
+	track(c, watcher.NewWithdrawal(exchangeCfg, secrets, args, resp))
 
 	return c.JSON(exportWithdrawal(resp))
 }
@@ -4,7 +4,6 @@ import (
 	"strconv"
 
 	"github.com/cordialsys/offchain/client"
-	"github.com/cordialsys/offchain/loader"
 	"github.com/cordialsys/offchain/server/client/api"
 	"github.com/cordialsys/offchain/server/servererrors"
 	"github.com/gofiber/fiber/v2"
@@ -38,7 +37,7 @@ func ListWithdrawalHistory(c *fiber.Ctx) error {
 	}
 
 	// Create client
-	cli, err := loader.NewClient(exchangeCfg, account)
+	cli, err := newClient(c, exchangeCfg, account)
 	if err != nil {
 		return servererrors.InternalErrorf("failed to create client: %s", err)
 	}
@@ -64,7 +63,7 @@ func ListWithdrawalHistory(c *fiber.Ctx) error {
 	}
 
 	// Get withdrawal history
-	resp, err := cli.ListWithdrawalHistory(args)
+	resp, err := cli.ListWithdrawalHistory(c.UserContext(), args)
 	if err != nil {
 		return servererrors.Conflictf("failed to get withdrawal history: %s", err)
 	}
@@ -0,0 +1,391 @@
+package endpoints
+
+import (
+	"context"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+	"time"
+
+	oc "github.com/cordialsys/offchain"
+	"github.com/cordialsys/offchain/client"
+	"github.com/cordialsys/offchain/loader"
+	"github.com/cordialsys/offchain/pkg/audit"
+	"github.com/cordialsys/offchain/pkg/intent"
+	"github.com/cordialsys/offchain/server/client/api"
+	"github.com/cordialsys/offchain/server/servererrors"
+	"github.com/cordialsys/offchain/server/watcher"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Intents holds the withdrawals that are waiting for approval or for their delay, and makes them once
+// they're ready.
+type Intents struct {
+	Store *intent.Store
+	// Optional, withdrawals are only delayed if nil
+	Approvals *intent.Policy
+	Delays    intent.Delays
+	// The exchange configuration currently in use
+	Config  func() *oc.Config
+	Clients *loader.ClientCache
+	Watcher *watcher.Watcher
+	// Optional, records the withdrawals made for intents
+	Audit *audit.Log
+}
+
+// WrapIntents makes the withdrawal intents available to the handlers, if withdrawals can be held.
+func WrapIntents(c *fiber.Ctx, intents *Intents) {
+	c.Locals("intents", intents)
+}
+
+func unwrapIntents(c *fiber.Ctx) *Intents {
+	intents, _ := c.Locals("intents").(*Intents)
+	return intents
+}
+
+func (intents *Intents) quorum() int {
+	if intents.Approvals.Enabled() {
+		return intents.Approvals.Quorum
+	}
+	return 0
+}
+
+func exportIntent(i *intent.Intent, quorum int) *api.WithdrawalIntent {
+	approvals := make([]api.IntentApproval, len(i.Approvals))
+	for j, approval := range i.Approvals {
+		approvals[j] = api.IntentApproval{
+			KeyId:     approval.KeyId,
+			Decision:  api.IntentApprovalDecision(approval.Decision),
+			Timestamp: approval.Time.UnixMilli(),
+		}
+	}
+	exported := &api.WithdrawalIntent{
+		Id:       i.Id,
+		Exchange: i.Exchange,
+		Withdrawal: api.Withdrawal{
+			Address: i.Address,
+			Symbol:  api.As(i.Symbol),
+			Network: api.As(i.Network),
+			Amount:  i.Amount,
+		},
+		Status:    api.WithdrawalIntentStatus(i.Status),
+		Digest:    hex.EncodeToString(i.Digest()),
+		CreatedBy: i.CreatedBy,
+		CreatedAt: i.CreatedAt.UnixMilli(),
+		ExpiresAt: i.ExpiresAt.UnixMilli(),
+		Quorum:    quorum,
+		Approvals: approvals,
+	}
+	if i.SubAccount != "" {
+		exported.SubAccount = api.As(i.SubAccount)
+	}
+	if !i.ScheduledAt.IsZero() {
+		exported.ScheduledAt = api.As(i.ScheduledAt.UnixMilli())
+	}
+	if i.CancelledBy != "" {
+		exported.CancelledBy = api.As(i.CancelledBy)
+	}
+	if i.WithdrawalId != "" {
+		exported.WithdrawalId = api.As(i.WithdrawalId)
+	}
+	if i.Error != "" {
+		exported.Error = api.As(i.Error)
+	}
+	return exported
+}
+
+// holdWithdrawal creates an intent instead of making the withdrawal, if it needs approval or is delayed.
+// It returns false if the withdrawal should be made now.
+func holdWithdrawal(c *fiber.Ctx, args client.WithdrawalArgs) (bool, error) {
+	intents := unwrapIntents(c)
+	if intents == nil {
+		return false, nil
+	}
+	_, keyId := UnwrapIdentity(c)
+	subaccount, _ := c.Locals("sub-account").(string)
+	// the intent outlives the request, so nothing can refer to fiber's buffers
+	withdrawal := intent.Withdrawal{
+		Exchange:   strings.Clone(c.Params("exchange")),
+		SubAccount: strings.Clone(subaccount),
+		Address:    string(args.GetAddress()),
+		Symbol:     string(args.GetSymbol()),
+		Network:    string(args.GetNetwork()),
+		Amount:     args.GetAmount().String(),
+	}
+	delay, err := intents.Delays.For(withdrawal)
+	if err != nil {
+		return false, servererrors.BadRequestf("%v", err)
+	}
+	if !intents.Approvals.Enabled() && delay == 0 {
+		return false, nil
+	}
+
+	now := time.Now()
+	expiry := time.Duration(0)
+	if intents.Approvals.Enabled() {
+		expiry = intents.Approvals.Expiry
+	}
+	i := intent.New(withdrawal, strings.Clone(keyId), now, expiry)
+	// so that the same key can't approve it under another id
+	i.CreatedByKey = UnwrapPublicKey(c)
+	if !intents.Approvals.Enabled() {
+		i.Schedule(now, delay)
+	}
+	if err := intents.Store.Create(i); err != nil {
+		return false, servererrors.InternalErrorf("failed to create withdrawal intent: %s", err)
+	}
+	slog.InfoContext(c.UserContext(), "withdrawal held", "intent", i.Id, "status", i.Status,
+		"digest", hex.EncodeToString(i.Digest()), "quorum", intents.quorum(), "delay", delay.String())
+
+	return true, c.Status(fiber.StatusAccepted).JSON(&api.WithdrawalResponse{
+		Status:   api.Pending,
+		IntentId: api.As(i.Id),
+	})
+}
+
+// auditExecution records the withdrawal made for the intent, which isn't covered by the audit of any request
+// when it's made after a delay.  The entry is attributed to the key that requested the withdrawal.
+func (intents *Intents) auditExecution(ctx context.Context, i *intent.Intent, start time.Time, resp *client.WithdrawalResponse, err error) {
+	if intents.Audit == nil {
+		return
+	}
+	entry := &audit.Entry{
+		Time:       start,
+		Method:     audit.MethodExecute,
+		Path:       "/v1/withdrawal-intents/" + i.Id,
+		Exchange:   i.Exchange,
+		Auth:       string(AuthIntent),
+		KeyId:      i.CreatedBy,
+		SubAccount: i.SubAccount,
+		LatencyMs:  time.Since(start).Milliseconds(),
+	}
+	entry.Args, _ = audit.Normalize(map[string]any{"intent": i.Id, "withdrawal": i.Withdrawal})
+	if err != nil {
+		entry.Status = fiber.StatusInternalServerError
+		entry.Error = err.Error()
+		if apiErr, ok := err.(*servererrors.ErrorResponse); ok {
+			entry.Status = apiErr.HttpStatus()
+			entry.Error = apiErr.Message
+		}
+	} else {
+		entry.Status = fiber.StatusOK
+		entry.Response, _ = audit.Normalize(exportWithdrawal(resp))
+	}
+	if err := intents.Audit.Append(entry); err != nil {
+		slog.ErrorContext(ctx, "failed to write audit log", "error", err, "intent", i.Id)
+	}
+}
+
+// Execute makes the withdrawal of an intent that has moved to executing, and records the result.
+func (intents *Intents) Execute(ctx context.Context, i *intent.Intent) (*intent.Intent, error) {
+	start := time.Now()
+	record := func(resp *client.WithdrawalResponse, err error) (*intent.Intent, error) {
+		if err != nil {
+			slog.ErrorContext(ctx, "withdrawal intent failed", "intent", i.Id, "error", err)
+		} else {
+			slog.InfoContext(ctx, "withdrawal intent executed", "intent", i.Id, "withdrawal", resp.ID)
+		}
+		intents.auditExecution(ctx, i, start, resp, err)
+		return intents.Store.Update(i.Id, func(i *intent.Intent) error {
+			if err != nil {
+				i.Status = intent.StatusFailed
+				i.Error = err.Error()
+			} else {
+				i.Status = intent.StatusExecuted
+				i.WithdrawalId = resp.ID
+			}
+			return nil
+		})
+	}
+
+	exchangeCfg, secrets, err := LoadAccount(ctx, intents.Config(), i.Exchange, i.SubAccount)
+	if err != nil {
+		return record(nil, err)
+	}
+	amount, err := oc.NewAmountFromString(i.Amount)
+	if err != nil {
+		return record(nil, err)
+	}
+	args := client.NewWithdrawalArgs(
+		oc.Address(i.Address),
+		oc.SymbolId(i.Symbol),
+		oc.NetworkId(i.Network),
+		amount,
+	)
+	cli, err := cachedClient(ctx, intents.Clients, exchangeCfg, secrets)
+	if err != nil {
+		return record(nil, err)
+	}
+	resp, err := cli.CreateWithdrawal(ctx, args)
+	if err != nil {
+		return record(nil, err)
+	}
+	if intents.Watcher != nil {
+		intents.Watcher.Track(watcher.NewWithdrawal(exchangeCfg, secrets, args, resp))
+	}
+	return record(resp, nil)
+}
+
+// ExecuteDue makes the withdrawals of the scheduled intents whose delay has passed.  They stay scheduled
+// while the audit log can't be written, so that none are made without being recorded.
+func (intents *Intents) ExecuteDue(ctx context.Context, now time.Time, timeout time.Duration) {
+	if err := intents.Store.Expire(now); err != nil {
+		slog.ErrorContext(ctx, "failed to expire withdrawal intents", "error", err)
+	}
+	if intents.Audit != nil && intents.Audit.Err() != nil {
+		slog.ErrorContext(ctx, "not making scheduled withdrawals as the audit log failed", "error", intents.Audit.Err())
+		return
+	}
+	due, err := intents.Store.TakeDue(now)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to take scheduled withdrawal intents", "error", err)
+		return
+	}
+	for _, i := range due {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		if _, err := intents.Execute(ctx, i); err != nil {
+			slog.ErrorContext(ctx, "failed to record withdrawal", "intent", i.Id, "error", err)
+		}
+		cancel()
+	}
+}
+
+// Run makes the withdrawals of scheduled intents as they become due, until the context is cancelled.
+func (intents *Intents) Run(ctx context.Context, interval time.Duration, timeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			intents.ExecuteDue(ctx, now, timeout)
+		}
+	}
+}
+
+func loadIntents(c *fiber.Ctx) (*Intents, error) {
+	intents := unwrapIntents(c)
+	if intents == nil {
+		return nil, servererrors.NotFoundf("withdrawals are neither approved nor delayed")
+	}
+	if err := intents.Store.Expire(time.Now()); err != nil {
+		return nil, servererrors.InternalErrorf("failed to expire withdrawal intents: %s", err)
+	}
+	return intents, nil
+}
+
+// ListWithdrawalIntents lists the withdrawal intents, newest first
+func ListWithdrawalIntents(c *fiber.Ctx) error {
+	intents, err := loadIntents(c)
+	if err != nil {
+		return err
+	}
+	exported := []*api.WithdrawalIntent{}
+	for _, i := range intents.Store.List() {
+		exported = append(exported, exportIntent(i, intents.quorum()))
+	}
+	return c.JSON(exported)
+}
+
+// GetWithdrawalIntent looks up a withdrawal intent by id
+func GetWithdrawalIntent(c *fiber.Ctx) error {
+	intents, err := loadIntents(c)
+	if err != nil {
+		return err
+	}
+	i, ok := intents.Store.Get(c.Params("id"))
+	if !ok {
+		return servererrors.NotFoundf("withdrawal intent not found: %s", c.Params("id"))
+	}
+	return c.JSON(exportIntent(i, intents.quorum()))
+}
+
+// CancelWithdrawalIntent cancels a withdrawal intent that is waiting for approval or for its delay
+func CancelWithdrawalIntent(c *fiber.Ctx) error {
+	intents, err := loadIntents(c)
+	if err != nil {
+		return err
+	}
+	id := strings.Clone(c.Params("id"))
+	if _, ok := intents.Store.Get(id); !ok {
+		return servererrors.NotFoundf("withdrawal intent not found: %s", id)
+	}
+	_, keyId := UnwrapIdentity(c)
+	i, err := intents.Store.Update(id, func(i *intent.Intent) error {
+		return i.Cancel(strings.Clone(keyId))
+	})
+	if err != nil {
+		return servererrors.Conflictf("could not cancel withdrawal intent: %s", err)
+	}
+	slog.InfoContext(c.UserContext(), "withdrawal intent cancelled", "intent", i.Id, "key", keyId)
+	return c.JSON(exportIntent(i, intents.quorum()))
+}
+
+func decideIntent(c *fiber.Ctx, decision intent.Decision) error {
+	intents, err := loadIntents(c)
+	if err != nil {
+		return err
+	}
+	policy := intents.Approvals
+	if !policy.Enabled() {
+		return servererrors.NotFoundf("withdrawals don't need approval")
+	}
+	var req api.IntentDecision
+	if err := c.BodyParser(&req); err != nil {
+		return servererrors.BadRequestf("invalid request body: %s", err)
+	}
+	if req.KeyId == "" {
+		return servererrors.BadRequestf("key_id is required")
+	}
+	signature, err := hex.DecodeString(req.Signature)
+	if err != nil {
+		return servererrors.BadRequestf("invalid signature: %s", err)
+	}
+	id := strings.Clone(c.Params("id"))
+	if _, ok := intents.Store.Get(id); !ok {
+		return servererrors.NotFoundf("withdrawal intent not found: %s", id)
+	}
+	if _, ok := policy.Approver(req.KeyId); !ok {
+		return servererrors.Forbiddenf("%s is not an approver", req.KeyId)
+	}
+
+	i, err := intents.Store.Update(id, func(i *intent.Intent) error {
+		now := time.Now()
+		if err := policy.Decide(i, req.KeyId, decision, signature, now); err != nil {
+			return err
+		}
+		if i.Status == intent.StatusExecuting {
+			// approved, but it may still have to wait for its delay
+			delay, err := intents.Delays.For(i.Withdrawal)
+			if err != nil {
+				return err
+			}
+			i.Schedule(now, delay)
+		}
+		return nil
+	})
+	if err != nil {
+		return servererrors.Conflictf("could not %s withdrawal intent: %s", decision, err)
+	}
+	slog.InfoContext(c.UserContext(), "withdrawal intent decision", "intent", i.Id, "approver", req.KeyId, "decision", decision, "status", i.Status)
+
+	// only the approval that reaches the quorum moves the intent to executing
+	if i.Status == intent.StatusExecuting {
+		i, err = intents.Execute(c.UserContext(), i)
+		if err != nil {
+			return servererrors.InternalErrorf("failed to record withdrawal: %s", err)
+		}
+	}
+	return c.JSON(exportIntent(i, intents.quorum()))
+}
+
+// ApproveWithdrawalIntent adds an approver's approval, and makes the withdrawal once the quorum is reached
+func ApproveWithdrawalIntent(c *fiber.Ctx) error {
+	return decideIntent(c, intent.Approve)
+}
+
+// RejectWithdrawalIntent rejects a withdrawal intent
+func RejectWithdrawalIntent(c *fiber.Ctx) error {
+	return decideIntent(c, intent.Reject)
+}
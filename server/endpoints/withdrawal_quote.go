@@ -0,0 +1,47 @@
+package endpoints
+
+import (
+	"github.com/cordialsys/offchain/client"
+	"github.com/cordialsys/offchain/server/client/api"
+	"github.com/cordialsys/offchain/server/servererrors"
+	"github.com/gofiber/fiber/v2"
+)
+
+func exportWithdrawalQuote(resp *client.WithdrawalQuote) *api.WithdrawalQuote {
+	return &api.WithdrawalQuote{
+		Symbol:      string(resp.Symbol),
+		Network:     string(resp.Network),
+		Amount:      resp.Amount.String(),
+		Fee:         resp.Fee.String(),
+		FeeSymbol:   string(resp.FeeSymbol),
+		NetAmount:   resp.NetAmount.String(),
+		FeeDeducted: resp.FeeDeducted,
+	}
+}
+
+// EstimateWithdrawal returns the fee and net amount of a withdrawal, without executing it
+func EstimateWithdrawal(c *fiber.Ctx) error {
+	exchangeCfg, account, err := loadAccount(c, c.Params("exchange"))
+	if err != nil {
+		return err
+	}
+
+	args, err := parseWithdrawalArgs(c)
+	if err != nil {
+		return err
+	}
+
+	// Create client
+	cli, err := newClient(c, exchangeCfg, account)
+	if err != nil {
+		return servererrors.InternalErrorf("failed to create client: %s", err)
+	}
+
+	// Estimate withdrawal
+	resp, err := cli.EstimateWithdrawal(c.UserContext(), args)
+	if err != nil {
+		return servererrors.Conflictf("failed to estimate withdrawal: %s", err)
+	}
+
+	return c.JSON(exportWithdrawalQuote(resp))
+}
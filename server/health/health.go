@@ -0,0 +1,276 @@
+// Package health checks that each configured account can be used: that its secrets load, that the
+// exchange accepts its API key, and that the key has the expected permissions.
+//
+// Checks make authenticated calls to the exchanges, so results are cached.
+package health
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	oc "github.com/cordialsys/offchain"
+	"github.com/cordialsys/offchain/client"
+	"github.com/cordialsys/offchain/loader"
+)
+
+type Status string
+
+const (
+	StatusOk Status = "ok"
+	// The account works, but its API key is more permissive than it should be, or is about to expire
+	StatusWarning Status = "warning"
+	StatusError   Status = "error"
+)
+
+// Keys expiring sooner than this are reported with a warning
+const ExpiryWarning = 14 * 24 * time.Hour
+
+type AccountStatus struct {
+	Exchange   oc.ExchangeId `json:"exchange"`
+	Account    string        `json:"account"`
+	SubAccount bool          `json:"sub_account"`
+	Status     Status        `json:"status"`
+	// Secrets were loaded from their configured backends
+	SecretsLoaded bool `json:"secrets_loaded"`
+	// The exchange accepted an authenticated request
+	Authenticated bool               `json:"authenticated"`
+	ApiKey        *client.ApiKeyInfo `json:"api_key,omitempty"`
+	Error         string             `json:"error,omitempty"`
+	Warnings      []string           `json:"warnings,omitempty"`
+	LatencyMs     int64              `json:"latency_ms"`
+}
+
+func (s *AccountStatus) warn(format string, args ...any) {
+	s.Warnings = append(s.Warnings, fmt.Sprintf(format, args...))
+	if s.Status == StatusOk {
+		s.Status = StatusWarning
+	}
+}
+
+func (s *AccountStatus) fail(err error) *AccountStatus {
+	s.Status = StatusError
+	s.Error = err.Error()
+	return s
+}
+
+type Report struct {
+	Status    Status           `json:"status"`
+	CheckedAt time.Time        `json:"checked_at"`
+	Accounts  []*AccountStatus `json:"accounts"`
+}
+
+// Ready is true if every account can be used.
+func (r *Report) Ready() bool {
+	return r.Status != StatusError
+}
+
+// Check checks every account in the config concurrently.
+func Check(ctx context.Context, conf *oc.Config) *Report {
+	checks := []func() *AccountStatus{}
+	for _, exchangeCfg := range conf.Exchanges {
+		checks = append(checks, func() *AccountStatus {
+			return CheckAccount(ctx, exchangeCfg, exchangeCfg.AsAccount())
+		})
+		for _, subaccount := range exchangeCfg.SubAccounts {
+			checks = append(checks, func() *AccountStatus {
+				return CheckAccount(ctx, exchangeCfg, subaccount.AsAccount())
+			})
+		}
+	}
+	accounts := make([]*AccountStatus, len(checks))
+	wg := sync.WaitGroup{}
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			accounts[i] = check()
+		}()
+	}
+	wg.Wait()
+	slices.SortStableFunc(accounts, func(a, b *AccountStatus) int {
+		return cmp.Or(
+			cmp.Compare(a.Exchange, b.Exchange),
+			// main account first
+			cmp.Compare(boolToInt(a.SubAccount), boolToInt(b.SubAccount)),
+			cmp.Compare(a.Account, b.Account),
+		)
+	})
+
+	report := &Report{
+		Status:    StatusOk,
+		CheckedAt: time.Now().UTC(),
+		Accounts:  accounts,
+	}
+	for _, account := range accounts {
+		switch {
+		case account.Status == StatusError:
+			report.Status = StatusError
+		case account.Status == StatusWarning && report.Status == StatusOk:
+			report.Status = StatusWarning
+		}
+	}
+	return report
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// CheckAccount loads the account's secrets and makes a cheap authenticated call to the exchange.
+func CheckAccount(ctx context.Context, exchangeCfg *oc.ExchangeConfig, account *oc.Account) *AccountStatus {
+	start := time.Now()
+	name := "main"
+	if account.SubAccount {
+		name = string(account.Id)
+		if account.Alias != "" {
+			name = account.Alias
+		}
+	}
+	status := &AccountStatus{
+		Exchange:   exchangeCfg.ExchangeId,
+		Account:    name,
+		SubAccount: account.SubAccount,
+		Status:     StatusOk,
+	}
+	defer func() {
+		status.LatencyMs = time.Since(start).Milliseconds()
+	}()
+
+	if err := account.LoadSecrets(ctx); err != nil {
+		return status.fail(fmt.Errorf("failed to load secrets: %w", err))
+	}
+	cli, err := loader.NewClient(ctx, exchangeCfg, account)
+	if err != nil {
+		return status.fail(fmt.Errorf("failed to create client: %w", err))
+	}
+	status.SecretsLoaded = true
+
+	info, ok, err := cli.GetApiKeyInfo(ctx)
+	if err != nil {
+		return status.fail(fmt.Errorf("failed to get api key permissions: %w", err))
+	}
+	if !ok {
+		// the exchange doesn't report permissions, so check the key by reading balances instead
+		accountType, _ := exchangeCfg.FirstAccountType()
+		_, err := cli.ListBalances(ctx, client.NewGetBalanceArgs(accountType.Type))
+		if err != nil {
+			return status.fail(fmt.Errorf("failed to get balances: %w", err))
+		}
+		status.Authenticated = true
+		return status
+	}
+	status.Authenticated = true
+	status.ApiKey = info
+	if info.CreatedAt == nil && account.SubAccount {
+		info.CreatedAt = subAccountKeyCreatedAt(ctx, exchangeCfg, account)
+	}
+
+	if !info.IpRestricted {
+		status.warn("api key is not restricted to any IP addresses")
+	}
+	if !info.Withdraw && !account.SubAccount {
+		status.warn("api key cannot withdraw")
+	}
+	if info.ExpiresAt != nil && time.Until(*info.ExpiresAt) < ExpiryWarning {
+		status.warn("api key expires at %s", info.ExpiresAt.Format(time.RFC3339))
+	}
+	return status
+}
+
+// subAccountKeyCreatedAt asks the main account when the sub-account's key was created, for exchanges that
+// only report it there.
+func subAccountKeyCreatedAt(ctx context.Context, exchangeCfg *oc.ExchangeConfig, account *oc.Account) *time.Time {
+	mainAccount := exchangeCfg.AsAccount()
+	if err := mainAccount.LoadSecrets(ctx); err != nil {
+		return nil
+	}
+	mainClient, err := loader.NewClient(ctx, exchangeCfg, mainAccount)
+	if err != nil {
+		return nil
+	}
+	manager, ok := mainClient.ApiKeyManager()
+	if !ok {
+		return nil
+	}
+	apiKey, err := account.ApiKeyRef.Load(ctx)
+	if err != nil {
+		return nil
+	}
+	info, err := manager.GetSubAccountApiKeyInfo(ctx, account.Id, apiKey)
+	if err != nil {
+		slog.DebugContext(ctx, "could not look up sub-account api key", "exchange", exchangeCfg.ExchangeId, "error", err)
+		return nil
+	}
+	return info.CreatedAt
+}
+
+type ApiKeyAge struct {
+	Exchange   oc.ExchangeId `json:"exchange"`
+	Account    string        `json:"account"`
+	SubAccount bool          `json:"sub_account"`
+	// When the key was created, if the exchange reports it
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	AgeDays   *int       `json:"age_days,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// ApiKeyAges reports how old the API key of each account is.
+func (r *Report) ApiKeyAges() []*ApiKeyAge {
+	ages := make([]*ApiKeyAge, len(r.Accounts))
+	for i, account := range r.Accounts {
+		age := &ApiKeyAge{
+			Exchange:   account.Exchange,
+			Account:    account.Account,
+			SubAccount: account.SubAccount,
+			Error:      account.Error,
+		}
+		if account.ApiKey != nil {
+			age.CreatedAt = account.ApiKey.CreatedAt
+			age.ExpiresAt = account.ApiKey.ExpiresAt
+			if age.CreatedAt != nil {
+				days := int(r.CheckedAt.Sub(*age.CreatedAt) / (24 * time.Hour))
+				age.AgeDays = &days
+			}
+		}
+		ages[i] = age
+	}
+	return ages
+}
+
+// Checker caches the last report, so that frequent probes don't make calls to the exchanges.
+type Checker struct {
+	ttl time.Duration
+
+	lock   sync.Mutex
+	conf   *oc.Config
+	report *Report
+}
+
+func NewChecker(ttl time.Duration) *Checker {
+	return &Checker{ttl: ttl}
+}
+
+// Report returns the cached report, checking again if it's expired or the config has changed.
+// Concurrent callers wait for the same check.
+func (c *Checker) Report(ctx context.Context, conf *oc.Config) *Report {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.report != nil && c.conf == conf && time.Since(c.report.CheckedAt) < c.ttl {
+		return c.report
+	}
+	// the result is shared, so it shouldn't fail because the first caller went away
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), oc.DefaultTimeout)
+	defer cancel()
+	c.report = Check(ctx, conf)
+	c.conf = conf
+	return c.report
+}
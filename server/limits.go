@@ -0,0 +1,123 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	oc "github.com/cordialsys/offchain"
+	"github.com/cordialsys/offchain/server/endpoints"
+	"github.com/cordialsys/offchain/server/servererrors"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/time/rate"
+)
+
+// Limiters that haven't been used for this long are forgotten
+const limiterIdleTimeout = 10 * time.Minute
+
+// Retry-After sent when an exchange has too many requests in flight
+const concurrencyRetryAfter = time.Second
+
+type limiterEntry struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// keyedLimiter rate limits each key (e.g. an IP address) separately.
+type keyedLimiter struct {
+	limit RateLimit
+
+	lock      sync.Mutex
+	limiters  map[string]*limiterEntry
+	lastSweep time.Time
+}
+
+func newKeyedLimiter(limit RateLimit) *keyedLimiter {
+	return &keyedLimiter{
+		limit:     limit,
+		limiters:  map[string]*limiterEntry{},
+		lastSweep: time.Now(),
+	}
+}
+
+// allow returns how long to wait before retrying if the request is over the limit.
+func (l *keyedLimiter) allow(key string) (time.Duration, bool) {
+	now := time.Now()
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if now.Sub(l.lastSweep) > limiterIdleTimeout {
+		for k, entry := range l.limiters {
+			if now.Sub(entry.lastSeen) > limiterIdleTimeout {
+				delete(l.limiters, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	entry, ok := l.limiters[key]
+	if !ok {
+		entry = &limiterEntry{limiter: rate.NewLimiter(rate.Limit(l.limit.Rate), l.limit.burst())}
+		l.limiters[key] = entry
+	}
+	entry.lastSeen = now
+	reservation := entry.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return delay, false
+	}
+	return 0, true
+}
+
+// ipLimitMiddleware limits the requests from each client IP address, before authentication.
+func ipLimitMiddleware(limit RateLimit) fiber.Handler {
+	limiter := newKeyedLimiter(limit)
+	return func(c *fiber.Ctx) error {
+		if delay, ok := limiter.allow(c.IP()); !ok {
+			return servererrors.ResourceExhaustedf(delay, "too many requests from %s", c.IP())
+		}
+		return c.Next()
+	}
+}
+
+// limitMiddleware limits the requests made with each key, and the requests in flight to each exchange.
+// It should be placed after authentication.
+func limitMiddleware(limits LimitsConfig, config func() *oc.Config) fiber.Handler {
+	var keyLimiter *keyedLimiter
+	if limits.PerKey.Enabled() {
+		keyLimiter = newKeyedLimiter(limits.PerKey)
+	}
+	inFlightLock := sync.Mutex{}
+	inFlight := map[oc.ExchangeId]int{}
+
+	return func(c *fiber.Ctx) error {
+		if method, keyId := endpoints.UnwrapIdentity(c); keyLimiter != nil && method != endpoints.AuthNone {
+			if delay, ok := keyLimiter.allow(string(method) + "/" + keyId); !ok {
+				return servererrors.ResourceExhaustedf(delay, "too many requests for %s %s", method, keyId)
+			}
+		}
+
+		if limits.ExchangeConcurrency <= 0 {
+			return c.Next()
+		}
+		// key by the configured id, the param is only valid during this request
+		cfg, ok := config().GetExchange(oc.ExchangeId(c.Params("exchange")))
+		if !ok {
+			// not found
+			return c.Next()
+		}
+		exchange := cfg.ExchangeId
+		inFlightLock.Lock()
+		if inFlight[exchange] >= limits.ExchangeConcurrency {
+			inFlightLock.Unlock()
+			return servererrors.ResourceExhaustedf(concurrencyRetryAfter, "too many requests in progress for %s", exchange)
+		}
+		inFlight[exchange]++
+		inFlightLock.Unlock()
+		defer func() {
+			inFlightLock.Lock()
+			inFlight[exchange]--
+			inFlightLock.Unlock()
+		}()
+		return c.Next()
+	}
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	oc "github.com/cordialsys/offchain"
+	"github.com/cordialsys/offchain/server/servererrors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func limitedApp(limits LimitsConfig, handler fiber.Handler) *fiber.App {
+	config := &oc.Config{Exchanges: map[oc.ExchangeId]*oc.ExchangeConfig{}}
+	for _, id := range []oc.ExchangeId{oc.Binance, oc.Okx, oc.Bybit} {
+		config.Exchanges[id] = &oc.ExchangeConfig{ExchangeId: id}
+	}
+	app := fiber.New(fiber.Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			return err.(*servererrors.ErrorResponse).Send(c)
+		},
+	})
+	app.Get("/v1/exchanges/:exchange", limitMiddleware(limits, func() *oc.Config { return config }), handler)
+	return app
+}
+
+func TestExchangeConcurrencySequential(t *testing.T) {
+	app := limitedApp(LimitsConfig{ExchangeConcurrency: 1}, func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	// a real listener, so that requests on the same connection reuse its buffers
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go app.Listener(ln)
+	defer app.Shutdown()
+	client := &http.Client{Transport: &http.Transport{MaxConnsPerHost: 1}}
+
+	// each request finishes before the next, so none are limited
+	for i := 0; i < 10; i++ {
+		for _, exchange := range []string{"binance", "okx", "bybit", "unknown"} {
+			resp, err := client.Get("http://" + ln.Addr().String() + "/v1/exchanges/" + exchange)
+			require.NoError(t, err)
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			require.Equal(t, fiber.StatusOK, resp.StatusCode, "request %d to %s", i, exchange)
+		}
+	}
+}
+
+func TestExchangeConcurrencyInFlight(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	app := limitedApp(LimitsConfig{ExchangeConcurrency: 1}, func(c *fiber.Ctx) error {
+		if c.Query("block") != "" {
+			close(started)
+			<-release
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	done := make(chan int)
+	go func() {
+		resp, err := app.Test(httptest.NewRequest("GET", "/v1/exchanges/binance?block=1", nil), -1)
+		if err != nil {
+			done <- 0
+			return
+		}
+		done <- resp.StatusCode
+	}()
+	<-started
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/v1/exchanges/binance", nil), -1)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/v1/exchanges/okx", nil), -1)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	close(release)
+	require.Equal(t, fiber.StatusOK, <-done)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/v1/exchanges/binance", nil), -1)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
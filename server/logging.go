@@ -0,0 +1,75 @@
+package server
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/cordialsys/offchain/pkg/logging"
+	"github.com/cordialsys/offchain/server/endpoints"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Request ids sent by callers are only used if they're reasonable to put in logs
+const maxRequestIdLength = 128
+
+func validRequestId(requestId string) bool {
+	if requestId == "" || len(requestId) > maxRequestIdLength {
+		return false
+	}
+	for _, r := range requestId {
+		if r <= ' ' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// requestIdMiddleware continues the caller's X-Request-Id, or generates one, and returns it in the response.
+// Log records made with the request's context are tagged with it.
+func requestIdMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestId := c.Get(logging.RequestIdHeader)
+		if !validRequestId(requestId) {
+			requestId = uuid.NewString()
+		}
+		c.Set(logging.RequestIdHeader, requestId)
+		c.SetUserContext(logging.WithRequestId(c.UserContext(), requestId))
+		return c.Next()
+	}
+}
+
+// requestLogMiddleware logs each request once it's been handled.
+func requestLogMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		status := statusOf(c, err)
+		attrs := []any{
+			"method", c.Method(),
+			"path", c.Path(),
+			"route", c.Route().Path,
+			"status", status,
+			"latency_ms", time.Since(start).Milliseconds(),
+			"ip", c.IP(),
+		}
+		if method, keyId := endpoints.UnwrapIdentity(c); method != endpoints.AuthNone {
+			attrs = append(attrs, "auth", method, "key_id", keyId)
+		}
+		if exchange := c.Params("exchange"); exchange != "" {
+			attrs = append(attrs, "exchange", exchange)
+		}
+		if err != nil {
+			attrs = append(attrs, "error", err)
+		}
+		level := slog.LevelInfo
+		if status >= fiber.StatusInternalServerError {
+			level = slog.LevelError
+		} else if status >= fiber.StatusBadRequest {
+			level = slog.LevelWarn
+		}
+		slog.Log(c.UserContext(), level, "request", attrs...)
+		return err
+	}
+}
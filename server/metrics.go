@@ -0,0 +1,56 @@
+package server
+
+import (
+	"time"
+
+	oc "github.com/cordialsys/offchain"
+	"github.com/cordialsys/offchain/pkg/metrics"
+	"github.com/cordialsys/offchain/server/endpoints"
+	"github.com/cordialsys/offchain/server/servererrors"
+	"github.com/gofiber/fiber/v2"
+)
+
+// statusOf returns the status the response will have, once any error has been handled.
+func statusOf(c *fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+	if apiErr, ok := err.(*servererrors.ErrorResponse); ok {
+		return apiErr.HttpStatus()
+	}
+	if fiberErr, ok := err.(*fiber.Error); ok {
+		return fiberErr.Code
+	}
+	return fiber.StatusInternalServerError
+}
+
+// metricsMiddleware counts and times every request, labelled by the matched route.
+func metricsMiddleware(config func() *oc.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+		duration := time.Since(start)
+
+		// the route and params are those of the handler that was matched
+		endpoint := c.Route().Path
+		exchange := ""
+		var exchangeCfg *oc.ExchangeConfig
+		if id := c.Params("exchange"); id != "" {
+			if cfg, ok := config().GetExchange(oc.ExchangeId(id)); ok {
+				exchange = string(cfg.ExchangeId)
+				exchangeCfg = cfg
+			} else {
+				exchange = "unknown"
+			}
+		}
+		if endpoint == "/" && c.Path() != "/" {
+			endpoint = "unmatched"
+		}
+		subaccount := endpoints.SubAccountLabel(c, exchangeCfg)
+		outcome := metrics.OutcomeForStatus(statusOf(c, err))
+
+		metrics.Requests.WithLabelValues(exchange, endpoint, subaccount, outcome).Inc()
+		metrics.RequestDuration.WithLabelValues(exchange, endpoint, subaccount, outcome).Observe(duration.Seconds())
+		return err
+	}
+}
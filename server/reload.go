@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Changes to the config file are often made in several writes, so wait for them to settle.
+const reloadDebounce = 500 * time.Millisecond
+
+func fileHash(path string) ([32]byte, error) {
+	bz, err := os.ReadFile(path)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return sha256.Sum256(bz), nil
+}
+
+// WatchConfig calls reload when the contents of the config file change, or when the process
+// receives SIGHUP, until the context is cancelled.
+//
+// The directory of the file is watched rather than the file itself, so that editors replacing the
+// file and symlink swaps (e.g. kubernetes config maps) are noticed.
+func WatchConfig(ctx context.Context, path string, reload func(ctx context.Context) error) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return err
+	}
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	go func() {
+		defer watcher.Close()
+		defer signal.Stop(hangup)
+
+		lastHash, _ := fileHash(path)
+		debounce := time.NewTimer(reloadDebounce)
+		debounce.Stop()
+
+		doReload := func(reason string) {
+			log := slog.With("config", path, "reason", reason)
+			if err := reload(ctx); err != nil {
+				log.Error("rejected invalid configuration, keeping the current configuration", "error", err)
+				return
+			}
+			log.Info("reloaded configuration")
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hangup:
+				lastHash, _ = fileHash(path)
+				doReload("sighup")
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Has(fsnotify.Chmod) {
+					continue
+				}
+				debounce.Reset(reloadDebounce)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Warn("error watching config file", "config", path, "error", err)
+			case <-debounce.C:
+				hash, err := fileHash(path)
+				if err != nil || hash == lastHash {
+					// removed (likely about to be replaced) or unchanged
+					continue
+				}
+				lastHash = hash
+				doReload("file changed")
+			}
+		}
+	}()
+	return nil
+}
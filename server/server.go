@@ -2,54 +2,159 @@ package server
 
 import (
 	"context"
+	"crypto/subtle"
+	"crypto/tls"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"os"
 	"os/signal"
 	"runtime/debug"
 	"slices"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	oc "github.com/cordialsys/offchain"
+	"github.com/cordialsys/offchain/loader"
+	"github.com/cordialsys/offchain/pkg/audit"
 	"github.com/cordialsys/offchain/pkg/httpsignature"
+	"github.com/cordialsys/offchain/pkg/httpsignature/signer"
 	"github.com/cordialsys/offchain/pkg/httpsignature/verifier"
+	"github.com/cordialsys/offchain/pkg/intent"
+	"github.com/cordialsys/offchain/pkg/metrics"
+	"github.com/cordialsys/offchain/pkg/tlsconfig"
 	"github.com/cordialsys/offchain/server/endpoints"
+	"github.com/cordialsys/offchain/server/health"
 	"github.com/cordialsys/offchain/server/servererrors"
+	"github.com/cordialsys/offchain/server/watcher"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 )
 
+// Deadline for handling a request, after which there's no one left to write the response to.
+const requestTimeout = 30 * time.Second
+
 // Server represents the HTTP server
 type Server struct {
-	app    *fiber.App
-	ocConf *oc.Config
+	app     *fiber.App
+	current atomic.Pointer[state]
+	// Set if withdrawals can be held
+	intents *endpoints.Intents
 	ServerArgs
 }
 
+// The configuration that can be reloaded while the server is running
+type state struct {
+	conf         *oc.Config
+	bearerTokens []Token
+	publicKeys   []PublicKey
+	// keys with an id, for signatures that name their key
+	publicKeysById map[string]PublicKey
+}
+
 type ServerArgs struct {
 	Listen    string
 	AnyOrigin bool
 	Origins   []string
 
-	BearerTokens        []string
-	PublicKeys          []verifier.VerifierI
+	BearerTokens        []Token
+	PublicKeys          []PublicKey
 	PublicReadEndpoints bool
+
+	// Optional log of all authenticated requests
+	Audit *audit.Log
+	// Optional watcher that sends webhooks as withdrawals and transfers complete
+	Watcher *watcher.Watcher
+	// Serve over TLS if set
+	TLS *tls.Config
+	// Caches the results of the readiness checks.  Defaults to checking at most once a minute.
+	Health *health.Checker
+	// Optional cache of clients for each account
+	Clients *loader.ClientCache
+	// Rate limits, concurrency caps and request size limits
+	Limits LimitsConfig
+	// Signs responses if set
+	ResponseSigner signer.SignerI
+	// If set, withdrawals need approval from a quorum of approvers before they're made
+	Approvals *intent.Policy
+	// Cooling-off periods before large withdrawals are made
+	Delays intent.Delays
+	// Holds the withdrawals that are waiting for approval or for their delay.  Defaults to keeping them in memory.
+	Intents *intent.Store
+}
+
+// A key authorized to sign requests, identified by its id in the configuration.
+type PublicKey struct {
+	Id string
+	verifier.VerifierI
+	// If set, the identity of the client certificate that must be presented with the signature
+	ClientCert string
+}
+
+// A loaded bearer token, identified by its id in the configuration.
+type Token struct {
+	Id    string
+	Token string
+	// If set, the identity of the client certificate that must be presented with the token
+	ClientCert string
+}
+
+// clientCertIdentities returns the identities of the client's verified certificate, if any.
+func clientCertIdentities(c *fiber.Ctx) []string {
+	state := c.Context().TLSConnectionState()
+	if state == nil || len(state.VerifiedChains) == 0 {
+		return nil
+	}
+	return tlsconfig.Identities(state.VerifiedChains[0][0])
+}
+
+// presentsClientCert checks that the client presented a certificate with the required identity, if any.
+func presentsClientCert(c *fiber.Ctx, identity string) bool {
+	return identity == "" || slices.Contains(clientCertIdentities(c), identity)
 }
 
 // New creates a new server instance
 func New(ocConf *oc.Config, args ServerArgs) *Server {
+	if args.Health == nil {
+		args.Health = health.NewChecker(time.Minute)
+	}
+	if (args.Approvals.Enabled() || len(args.Delays) > 0) && args.Intents == nil {
+		args.Intents = intent.NewStore()
+	}
+	s := &Server{
+		ServerArgs: args,
+	}
+	if args.Intents != nil {
+		s.intents = &endpoints.Intents{
+			Store:     args.Intents,
+			Approvals: args.Approvals,
+			Delays:    args.Delays,
+			Config:    s.Config,
+			Clients:   args.Clients,
+			Watcher:   args.Watcher,
+			Audit:     args.Audit,
+		}
+	}
+	s.Reload(ocConf, args.BearerTokens, args.PublicKeys)
+
 	app := fiber.New(fiber.Config{
 		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 30 * time.Second,
+		WriteTimeout: requestTimeout,
 		IdleTimeout:  120 * time.Second,
+		BodyLimit:    args.Limits.MaxBodySize,
+		// started is logged instead
+		DisableStartupMessage: true,
 
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			if apiErr, ok := err.(*servererrors.ErrorResponse); ok {
 				return apiErr.Send(c)
+			} else if fiberErr, ok := err.(*fiber.Error); ok {
+				// e.g. the body is too large
+				return servererrors.NewErrorf(fiberErr.Code, "%s", fiberErr.Message).(*servererrors.ErrorResponse).Send(c)
 			} else {
 				return servererrors.InternalErrorf("%v", err)
 			}
@@ -57,13 +162,22 @@ func New(ocConf *oc.Config, args ServerArgs) *Server {
 	})
 
 	// Add middleware
+	app.Use(requestIdMiddleware())
+	if args.ResponseSigner != nil {
+		app.Use(responseSigningMiddleware(args.ResponseSigner))
+	}
 	app.Use(recover.New(recover.Config{
 		EnableStackTrace: true,
 		StackTraceHandler: func(c *fiber.Ctx, e interface{}) {
-			fmt.Printf("[PANIC] %v\n%s\n", e, debug.Stack())
+			slog.ErrorContext(c.UserContext(), "panic", "error", e, "stack", string(debug.Stack()))
 		},
 	}))
-	app.Use(logger.New())
+	app.Use(requestLogMiddleware())
+	app.Use(metricsMiddleware(s.Config))
+	app.Use(tracingMiddleware())
+	if args.Limits.PerIp.Enabled() {
+		app.Use(ipLimitMiddleware(args.Limits.PerIp))
+	}
 
 	allowOrigins := []string{"http://localhost:3000"}
 	if args.AnyOrigin {
@@ -76,7 +190,18 @@ func New(ocConf *oc.Config, args ServerArgs) *Server {
 		AllowOrigins: strings.Join(allowOrigins, ","),
 	}))
 	app.Use(func(c *fiber.Ctx) error {
-		c.Locals("conf", ocConf)
+		// bound the work done for the request, including the calls to the exchange
+		ctx, cancel := context.WithTimeout(c.UserContext(), requestTimeout)
+		defer cancel()
+		c.SetUserContext(ctx)
+
+		current := s.current.Load()
+		c.Locals("conf", current.conf)
+		c.Locals("state", current)
+		endpoints.WrapWatcher(c, args.Watcher)
+		endpoints.WrapHealth(c, args.Health)
+		endpoints.WrapClients(c, args.Clients)
+		endpoints.WrapIntents(c, s.intents)
 
 		// read subaccount if used in header or query
 		subaccount := c.Get("sub-account")
@@ -95,25 +220,48 @@ func New(ocConf *oc.Config, args ServerArgs) *Server {
 		})
 	})
 
+	// count the rejection before returning the error
+	reject := func(c *fiber.Ctx, reason string, err error) error {
+		metrics.AuthRejections.WithLabelValues(c.Route().Path, reason).Inc()
+		return err
+	}
+
 	httpSigAuth := func(c *fiber.Ctx) error {
-		verified := false
-		lastErr := fmt.Errorf("invalid signature")
 		requiredHeaders := []string{}
 		if c.Get("sub-account") != "" {
 			// ensure sub-account is in the signature input if it is used
 			requiredHeaders = append(requiredHeaders, "sub-account")
 		}
-		for _, key := range args.PublicKeys {
-			_, lastErr := httpsignature.VerifyFiber(c, key, requiredHeaders...)
-			if lastErr == nil {
-				verified = true
-				break
+		params, err := httpsignature.ParseSigParams(c.Get(httpsignature.HeaderSignatureInput))
+		if err != nil {
+			return reject(c, "invalid_signature", servererrors.Unauthorizedf("%v", err))
+		}
+		state := c.Locals("state").(*state)
+		// signatures without a keyid are checked against every key
+		keys := state.publicKeys
+		if keyId, ok := params.KeyId(); ok {
+			key, ok := state.publicKeysById[keyId]
+			if !ok {
+				return reject(c, "unknown_key", servererrors.Unauthorizedf("unknown key id %s", keyId))
 			}
+			keys = []PublicKey{key}
 		}
-		if !verified {
-			return servererrors.Unauthorizedf("%v", lastErr)
+		lastErr := fmt.Errorf("invalid signature")
+		for _, key := range keys {
+			if _, err := httpsignature.VerifyFiber(c, key, requiredHeaders...); err != nil {
+				lastErr = err
+				continue
+			}
+			if !presentsClientCert(c, key.ClientCert) {
+				return reject(c, "client_cert_mismatch", servererrors.Unauthorizedf("key %s is not authorized with this client certificate", key.Id))
+			}
+			endpoints.WrapIdentity(c, endpoints.AuthHttpSignature, key.Id)
+			if publicKey, ok := key.VerifierI.(verifier.PublicKeyVerifier); ok {
+				endpoints.WrapPublicKey(c, publicKey.PublicKey())
+			}
+			return c.Next()
 		}
-		return c.Next()
+		return reject(c, "invalid_signature", servererrors.Unauthorizedf("%v", lastErr))
 	}
 
 	bearerOrHttpSigAuth := func(c *fiber.Ctx) error {
@@ -131,58 +279,130 @@ func New(ocConf *oc.Config, args ServerArgs) *Server {
 				return httpSigAuth(c)
 			}
 
-			return servererrors.BadRequestf("no authorization header or http-signature")
+			return reject(c, "missing_credentials", servererrors.BadRequestf("no authorization header or http-signature"))
 		}
 		if parts[0] != "Bearer" {
-			return servererrors.BadRequestf("expected Bearer token in authorization header")
+			return reject(c, "malformed_authorization", servererrors.BadRequestf("expected Bearer token in authorization header"))
 		}
 		token := parts[1]
-		if slices.Contains(args.BearerTokens, token) {
-			return c.Next()
+		for _, bearer := range c.Locals("state").(*state).bearerTokens {
+			if subtle.ConstantTimeCompare([]byte(bearer.Token), []byte(token)) == 1 {
+				if !presentsClientCert(c, bearer.ClientCert) {
+					return reject(c, "client_cert_mismatch", servererrors.Unauthorizedf("bearer token %s is not authorized with this client certificate", bearer.Id))
+				}
+				endpoints.WrapIdentity(c, endpoints.AuthBearer, bearer.Id)
+				return c.Next()
+			}
 		}
-		return servererrors.Unauthorizedf("invalid bearer token")
+		return reject(c, "invalid_bearer_token", servererrors.Unauthorizedf("invalid bearer token"))
 	}
 
+	audited := auditMiddleware(args.Audit)
+	limited := limitMiddleware(args.Limits, s.Config)
+
+	// Readiness of the configured accounts.  Only the details need authentication.
+	app.Get("/ready", endpoints.Ready)
+	app.Get("/health/exchanges", bearerOrHttpSigAuth, endpoints.ExchangesHealth)
+	app.Get("/health/api-keys", bearerOrHttpSigAuth, endpoints.ApiKeyAges)
+
+	// Metrics, authenticated like the read endpoints
+	app.Get("/metrics", bearerOrHttpSigAuth, adaptor.HTTPHandler(metrics.Handler()))
+
 	// API routes
 	v1 := app.Group("/v1")
 	// public
-	v1.Get("/exchanges/:exchange/assets", endpoints.GetAssets)
-	v1.Get("/exchanges/:exchange/account-types", endpoints.GetAccountTypes)
+	v1.Get("/exchanges/:exchange/assets", limited, endpoints.GetAssets)
+	v1.Get("/exchanges/:exchange/account-types", limited, endpoints.GetAccountTypes)
 
 	// bearer or http sig auth
-	v1.Get("/exchanges/:exchange/balances", bearerOrHttpSigAuth, endpoints.GetBalances)
-	v1.Get("/exchanges/:exchange/deposit-address", bearerOrHttpSigAuth, endpoints.GetDepositAddress)
-	v1.Get("/exchanges/:exchange/subaccounts", bearerOrHttpSigAuth, endpoints.ListSubaccounts)
-	v1.Get("/exchanges/:exchange/withdrawal-history", bearerOrHttpSigAuth, endpoints.ListWithdrawalHistory)
+	v1.Get("/exchanges/:exchange/balances", bearerOrHttpSigAuth, limited, audited, endpoints.GetBalances)
+	v1.Get("/exchanges/:exchange/deposit-address", bearerOrHttpSigAuth, limited, audited, endpoints.GetDepositAddress)
+	v1.Get("/exchanges/:exchange/subaccounts", bearerOrHttpSigAuth, limited, audited, endpoints.ListSubaccounts)
+	v1.Get("/exchanges/:exchange/withdrawal-history", bearerOrHttpSigAuth, limited, audited, endpoints.ListWithdrawalHistory)
+	v1.Get("/exchanges/:exchange/transfer-history", bearerOrHttpSigAuth, limited, audited, endpoints.ListTransferHistory)
+	v1.Get("/exchanges/:exchange/account-transfer/:id", bearerOrHttpSigAuth, limited, audited, endpoints.GetTransferStatus)
+	v1.Post("/exchanges/:exchange/withdrawal/quote", bearerOrHttpSigAuth, limited, audited, endpoints.EstimateWithdrawal)
 
 	// http sig auth only
-	v1.Post("/exchanges/:exchange/account-transfer", httpSigAuth, endpoints.AccountTransfer)
-	v1.Post("/exchanges/:exchange/withdrawal", httpSigAuth, endpoints.CreateWithdrawal)
+	v1.Post("/exchanges/:exchange/account-transfer", httpSigAuth, limited, audited, endpoints.AccountTransfer)
+	v1.Post("/exchanges/:exchange/withdrawal", httpSigAuth, limited, audited, endpoints.CreateWithdrawal)
 
-	return &Server{
-		app:        app,
-		ocConf:     ocConf,
-		ServerArgs: args,
+	// withdrawals waiting for approval
+	v1.Get("/withdrawal-intents", bearerOrHttpSigAuth, limited, endpoints.ListWithdrawalIntents)
+	v1.Get("/withdrawal-intents/:id", bearerOrHttpSigAuth, limited, endpoints.GetWithdrawalIntent)
+	v1.Delete("/withdrawal-intents/:id", httpSigAuth, limited, audited, endpoints.CancelWithdrawalIntent)
+	v1.Post("/withdrawal-intents/:id/approve", httpSigAuth, limited, audited, endpoints.ApproveWithdrawalIntent)
+	v1.Post("/withdrawal-intents/:id/reject", httpSigAuth, limited, audited, endpoints.RejectWithdrawalIntent)
+
+	s.app = app
+	return s
+}
+
+// Config returns the exchange configuration currently in use.
+func (s *Server) Config() *oc.Config {
+	return s.current.Load().conf
+}
+
+// Reload swaps in new configuration and credentials.  Requests already being handled finish
+// using the previous configuration.
+func (s *Server) Reload(ocConf *oc.Config, bearerTokens []Token, publicKeys []PublicKey) {
+	publicKeysById := map[string]PublicKey{}
+	for _, key := range publicKeys {
+		if key.Id != "" {
+			publicKeysById[key.Id] = key
+		}
+	}
+	s.current.Store(&state{
+		conf:           ocConf,
+		bearerTokens:   bearerTokens,
+		publicKeys:     publicKeys,
+		publicKeysById: publicKeysById,
+	})
+	if s.Clients != nil {
+		// clients are cached per exchange config
+		s.Clients.Clear()
 	}
 }
 
+// RunIntents makes the withdrawals of delayed intents as their delay passes, until the context is cancelled.
+func (s *Server) RunIntents(ctx context.Context) {
+	if s.intents == nil {
+		return
+	}
+	s.intents.Run(ctx, time.Second, requestTimeout)
+}
+
 // Start begins listening for requests
 func (s *Server) Start() error {
 	// Start server in a goroutine so we can handle graceful shutdown
 	go func() {
+		fail := func(err error) {
+			slog.Error("failed to start server", "listen", s.Listen, "error", err)
+			os.Exit(1)
+		}
+		if s.TLS != nil {
+			ln, err := net.Listen("tcp", s.Listen)
+			if err != nil {
+				fail(err)
+			}
+			if err := s.app.Listener(tls.NewListener(ln, s.TLS)); err != nil {
+				fail(err)
+			}
+			return
+		}
 		if err := s.app.Listen(s.Listen); err != nil {
-			log.Fatalf("Error starting server: %v", err)
+			fail(err)
 		}
 	}()
 
-	fmt.Printf("Server started on %s\n", s.Listen)
+	slog.Info("server started", "listen", s.Listen, "tls", s.TLS != nil)
 
 	// Wait for interrupt signal to gracefully shut down the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	fmt.Println("Shutting down server...")
+	slog.Info("shutting down server")
 
 	// Shutdown with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
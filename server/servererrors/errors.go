@@ -2,7 +2,10 @@ package servererrors
 
 import (
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -12,15 +15,27 @@ type ErrorResponse struct {
 	Code       int    `json:"code"`
 	Status     string `json:"status"`
 	Message    string `json:"message"`
+	RequestId  string `json:"request_id,omitempty"`
 	httpStatus int    `json:"-"`
+	// sent as the Retry-After header, if set
+	retryAfter time.Duration
 }
 
 func (e *ErrorResponse) Error() string {
 	return fmt.Sprintf("code: %d, status: %s, message: %s", e.Code, e.Status, e.Message)
 }
+func (e *ErrorResponse) HttpStatus() int {
+	return e.httpStatus
+}
 func (e *ErrorResponse) Send(c *fiber.Ctx) error {
+	if e.retryAfter > 0 {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(e.retryAfter.Seconds()))))
+	}
+	// the error may be shared, so the request id is set on a copy
+	response := *e
+	response.RequestId = c.GetRespHeader(fiber.HeaderXRequestID)
 	c.Status(e.httpStatus)
-	return c.JSON(e)
+	return c.JSON(&response)
 }
 
 // HTTP status code to gRPC code mapping
@@ -187,6 +202,13 @@ func NotImplementedf(format string, args ...interface{}) error {
 	return NewErrorf(http.StatusNotImplemented, format, args...)
 }
 
+// ResourceExhaustedf sends a 429 Too Many Requests error, telling the client when to retry
+func ResourceExhaustedf(retryAfter time.Duration, format string, args ...interface{}) error {
+	err := sendError(http.StatusTooManyRequests, fmt.Sprintf(format, args...)).(*ErrorResponse)
+	err.retryAfter = retryAfter
+	return err
+}
+
 // Unavailablef sends a 503 Service Unavailable error with formatted message
 func Unavailablef(format string, args ...interface{}) error {
 	return NewErrorf(http.StatusServiceUnavailable, format, args...)
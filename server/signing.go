@@ -0,0 +1,29 @@
+package server
+
+import (
+	"log/slog"
+
+	"github.com/cordialsys/offchain/pkg/httpsignature"
+	"github.com/cordialsys/offchain/pkg/httpsignature/signer"
+	"github.com/gofiber/fiber/v2"
+)
+
+// responseSigningMiddleware signs every response, including errors, over its status and body and the
+// request it answers.
+func responseSigningMiddleware(s signer.SignerI) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := c.Next(); err != nil {
+			// write the error response now so that it's signed, as fiber would after the middleware returns
+			if catch := c.App().ErrorHandler(c, err); catch != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+		if err := httpsignature.SignResponseFiber(c, s); err != nil {
+			// the caller can't check an unsigned response, so don't send it
+			slog.ErrorContext(c.UserContext(), "failed to sign response", "error", err)
+			c.Response().Reset()
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cordialsys/offchain/pkg/tracing"
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestCarrier reads the trace context headers sent by the caller.
+type requestCarrier struct {
+	c *fiber.Ctx
+}
+
+var _ propagation.TextMapCarrier = requestCarrier{}
+
+func (r requestCarrier) Get(key string) string {
+	return r.c.Get(key)
+}
+
+func (r requestCarrier) Set(key string, value string) {
+	r.c.Request().Header.Set(key, value)
+}
+
+func (r requestCarrier) Keys() []string {
+	keys := []string{}
+	for key := range r.c.GetReqHeaders() {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// tracingMiddleware starts a span for each request, continuing any trace started by the caller.
+func tracingMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// spans are exported after the request, so nothing can refer to fiber's buffers
+		method := strings.Clone(c.Method())
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), requestCarrier{c})
+		ctx, span := tracing.Start(ctx, method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", method),
+				attribute.String("url.path", strings.Clone(c.Path())),
+			),
+		)
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+
+		// the route is known once the request has been handled
+		route := c.Route().Path
+		span.SetName(fmt.Sprintf("%s %s", method, route))
+		span.SetAttributes(
+			attribute.String("http.route", route),
+			attribute.Int("http.response.status_code", statusOf(c, err)),
+		)
+		if exchange := c.Params("exchange"); exchange != "" {
+			span.SetAttributes(attribute.String("offchain.exchange", strings.Clone(exchange)))
+		}
+		tracing.End(span, err)
+		return err
+	}
+}
@@ -0,0 +1,271 @@
+// Package watcher follows withdrawals and transfers created through the server until they
+// complete, and sends a webhook event each time the status of one changes.
+//
+// Operations are tracked in memory, so operations still pending when the server restarts are
+// no longer watched.
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	oc "github.com/cordialsys/offchain"
+	"github.com/cordialsys/offchain/client"
+	"github.com/cordialsys/offchain/loader"
+	"github.com/cordialsys/offchain/pkg/webhook"
+)
+
+type Kind string
+
+const (
+	KindWithdrawal Kind = "withdrawal"
+	KindTransfer   Kind = "transfer"
+)
+
+// The data of the webhook events sent by the watcher.  The event type is "<kind>.<status>",
+// e.g. "withdrawal.success".
+type Operation struct {
+	Kind          Kind                   `json:"kind"`
+	Id            string                 `json:"id"`
+	Exchange      oc.ExchangeId          `json:"exchange"`
+	SubAccount    string                 `json:"sub_account,omitempty"`
+	Status        client.OperationStatus `json:"status"`
+	Symbol        oc.SymbolId            `json:"symbol,omitempty"`
+	Network       oc.NetworkId           `json:"network,omitempty"`
+	Amount        string                 `json:"amount,omitempty"`
+	Address       oc.Address             `json:"address,omitempty"`
+	TransactionId client.TransactionId   `json:"transaction_id,omitempty"`
+	CreatedAt     time.Time              `json:"created_at"`
+	UpdatedAt     time.Time              `json:"updated_at"`
+
+	exchangeCfg *oc.ExchangeConfig
+	account     *oc.Account
+}
+
+func (op *Operation) key() string {
+	return fmt.Sprintf("%s/%s/%s/%s", op.Kind, op.Exchange, op.SubAccount, op.Id)
+}
+
+func (op *Operation) EventType() string {
+	return fmt.Sprintf("%s.%s", op.Kind, op.Status)
+}
+
+func NewWithdrawal(exchangeCfg *oc.ExchangeConfig, account *oc.Account, args client.WithdrawalArgs, resp *client.WithdrawalResponse) *Operation {
+	op := newOperation(KindWithdrawal, exchangeCfg, account, resp.ID, resp.Status, args.GetSymbol(), args.GetNetwork(), args.GetAmount())
+	op.Address = args.GetAddress()
+	return op
+}
+
+func NewTransfer(exchangeCfg *oc.ExchangeConfig, account *oc.Account, args client.AccountTransferArgs, resp *client.TransferStatus) *Operation {
+	return newOperation(KindTransfer, exchangeCfg, account, resp.ID, resp.Status, args.GetSymbol(), "", args.GetAmount())
+}
+
+func newOperation(kind Kind, exchangeCfg *oc.ExchangeConfig, account *oc.Account, id string, status client.OperationStatus, symbol oc.SymbolId, network oc.NetworkId, amount oc.Amount) *Operation {
+	subaccount := ""
+	if account.SubAccount {
+		subaccount = string(account.Id)
+	}
+	if status == "" {
+		status = client.OperationStatusPending
+	}
+	now := time.Now().UTC()
+	return &Operation{
+		Kind:        kind,
+		Id:          id,
+		Exchange:    exchangeCfg.ExchangeId,
+		SubAccount:  subaccount,
+		Status:      status,
+		Symbol:      symbol,
+		Network:     network,
+		Amount:      amount.String(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		exchangeCfg: exchangeCfg,
+		account:     account,
+	}
+}
+
+// Allowance for the exchange's clock being behind ours, when listing withdrawals since an operation was created
+const clockSkew = 5 * time.Minute
+
+type Options struct {
+	// How often pending operations are polled
+	PollInterval time.Duration
+	// Operations still pending after this long are no longer watched
+	MaxWatch time.Duration
+}
+
+func DefaultOptions() Options {
+	return Options{
+		PollInterval: 30 * time.Second,
+		MaxWatch:     24 * time.Hour,
+	}
+}
+
+type Watcher struct {
+	dispatcher *webhook.Dispatcher
+	opts       Options
+
+	lock       sync.Mutex
+	operations map[string]*Operation
+	ctx        context.Context
+	deliveries sync.WaitGroup
+}
+
+func New(dispatcher *webhook.Dispatcher, opts Options) *Watcher {
+	return &Watcher{
+		dispatcher: dispatcher,
+		opts:       opts,
+		operations: map[string]*Operation{},
+		ctx:        context.Background(),
+	}
+}
+
+// Track sends an event for the new operation, and watches it if it's still pending.
+func (w *Watcher) Track(op *Operation) {
+	w.notify(op)
+	if op.Status != client.OperationStatusPending {
+		return
+	}
+	if op.Id == "" {
+		slog.Warn("cannot watch operation without an id", "kind", op.Kind, "exchange", op.Exchange)
+		return
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.operations[op.key()] = op
+}
+
+// Run polls the pending operations until the context is cancelled, then waits for any
+// webhooks still being delivered.
+func (w *Watcher) Run(ctx context.Context) {
+	w.lock.Lock()
+	w.ctx = ctx
+	w.lock.Unlock()
+
+	ticker := time.NewTicker(w.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			w.deliveries.Wait()
+			return
+		case <-ticker.C:
+			w.poll(ctx)
+		}
+	}
+}
+
+func (w *Watcher) pending() []*Operation {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	ops := make([]*Operation, 0, len(w.operations))
+	for _, op := range w.operations {
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+func (w *Watcher) poll(ctx context.Context) {
+	for _, op := range w.pending() {
+		log := slog.With("kind", op.Kind, "id", op.Id, "exchange", op.Exchange)
+		if time.Since(op.CreatedAt) > w.opts.MaxWatch {
+			log.Warn("operation still pending, no longer watching", "created_at", op.CreatedAt)
+			w.forget(op)
+			continue
+		}
+
+		updated, err := w.fetch(ctx, op)
+		if err != nil {
+			log.Warn("failed to poll operation", "error", err)
+			continue
+		}
+		if updated.Status == op.Status {
+			continue
+		}
+		log.Info("operation status changed", "from", op.Status, "to", updated.Status)
+		if updated.Status != client.OperationStatusPending {
+			w.forget(op)
+		}
+		w.notify(updated)
+
+		w.lock.Lock()
+		if _, ok := w.operations[op.key()]; ok {
+			w.operations[op.key()] = updated
+		}
+		w.lock.Unlock()
+	}
+}
+
+func (w *Watcher) forget(op *Operation) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	delete(w.operations, op.key())
+}
+
+// fetch returns a copy of the operation with the latest status from the exchange.
+func (w *Watcher) fetch(ctx context.Context, op *Operation) (*Operation, error) {
+	cli, err := loader.NewClient(ctx, op.exchangeCfg, op.account)
+	if err != nil {
+		return nil, err
+	}
+	updated := *op
+	switch op.Kind {
+	case KindTransfer:
+		status, err := cli.GetTransferStatus(ctx, op.Id)
+		if err != nil {
+			return nil, err
+		}
+		updated.Status = status.Status
+	case KindWithdrawal:
+		// look up the withdrawal by id where the exchange supports it, and otherwise only list those
+		// made since it was created, so it isn't missed when there are more withdrawals than fit a page
+		args := client.NewWithdrawalHistoryArgs().
+			WithId(op.Id).
+			WithStartTime(op.CreatedAt.Add(-clockSkew))
+		history, err := cli.ListWithdrawalHistory(ctx, args)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, withdrawal := range history {
+			if withdrawal.ID == op.Id {
+				updated.Status = withdrawal.Status
+				updated.TransactionId = withdrawal.TransactionId
+				found = true
+				break
+			}
+		}
+		if !found {
+			return op, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown operation kind: %s", op.Kind)
+	}
+	if updated.Status == "" {
+		updated.Status = client.OperationStatusPending
+	}
+	updated.UpdatedAt = time.Now().UTC()
+	return &updated, nil
+}
+
+// notify delivers the event in the background, so slow endpoints don't hold up requests or polling.
+func (w *Watcher) notify(op *Operation) {
+	event, err := webhook.NewEvent(op.EventType(), op)
+	if err != nil {
+		slog.Error("failed to create webhook event", "error", err)
+		return
+	}
+	w.lock.Lock()
+	ctx := w.ctx
+	w.lock.Unlock()
+
+	w.deliveries.Add(1)
+	go func() {
+		defer w.deliveries.Done()
+		w.dispatcher.Send(ctx, event)
+	}()
+}
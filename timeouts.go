@@ -0,0 +1,41 @@
+package offchain
+
+import "time"
+
+type Operation string
+
+const (
+	OperationDefault               Operation = "default"
+	OperationListAssets            Operation = "list_assets"
+	OperationListBalances          Operation = "list_balances"
+	OperationCreateAccountTransfer Operation = "create_account_transfer"
+	OperationGetTransferStatus     Operation = "get_transfer_status"
+	OperationCreateWithdrawal      Operation = "create_withdrawal"
+	OperationEstimateWithdrawal    Operation = "estimate_withdrawal"
+	OperationGetDepositAddress     Operation = "get_deposit_address"
+	OperationListWithdrawalHistory Operation = "list_withdrawal_history"
+	OperationListTransferHistory   Operation = "list_transfer_history"
+	OperationGetApiKeyInfo         Operation = "get_api_key_info"
+	OperationManageApiKeys         Operation = "manage_api_keys"
+)
+
+// Used when no timeout is configured for an operation.  This is below the server's write timeout.
+const DefaultTimeout = 25 * time.Second
+
+// Deadlines for each operation on an exchange.  The "default" entry applies to
+// operations that are not listed.  Example:
+//
+//	timeouts:
+//	  default: 10s
+//	  create_withdrawal: 20s
+type Timeouts map[Operation]time.Duration
+
+func (t Timeouts) For(op Operation) time.Duration {
+	if timeout, ok := t[op]; ok && timeout > 0 {
+		return timeout
+	}
+	if timeout, ok := t[OperationDefault]; ok && timeout > 0 {
+		return timeout
+	}
+	return DefaultTimeout
+}
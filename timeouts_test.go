@@ -0,0 +1,26 @@
+package offchain_test
+
+import (
+	"testing"
+	"time"
+
+	oc "github.com/cordialsys/offchain"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestTimeouts(t *testing.T) {
+	var cfg oc.ExchangeClientConfig
+	err := yaml.Unmarshal([]byte(`
+timeouts:
+  default: 10s
+  create_withdrawal: 1m
+`), &cfg)
+	require.NoError(t, err)
+
+	require.Equal(t, time.Minute, cfg.Timeouts.For(oc.OperationCreateWithdrawal))
+	require.Equal(t, 10*time.Second, cfg.Timeouts.For(oc.OperationListBalances))
+
+	var empty oc.Timeouts
+	require.Equal(t, oc.DefaultTimeout, empty.For(oc.OperationListBalances))
+}